@@ -13,6 +13,7 @@ import (
 
 	"github.com/mosaxiv/clawlet/config"
 	"github.com/mosaxiv/clawlet/llm"
+	"github.com/mosaxiv/clawlet/mcp"
 	"github.com/mosaxiv/clawlet/memory"
 	"github.com/mosaxiv/clawlet/paths"
 	"github.com/mosaxiv/clawlet/session"
@@ -24,8 +25,17 @@ type Options struct {
 	Config       *config.Config
 	WorkspaceDir string
 	SessionKey   string
+	// SessionStore persists the session. Default: JSONL files under
+	// ~/.clawlet/sessions.
+	SessionStore session.Store
 	MaxIters     int
 	Verbose      bool
+	// OnLLMAttempts receives the provider attempts made for each LLM request.
+	OnLLMAttempts func([]llm.Attempt)
+	// Tools are registered alongside the built-in tools.
+	Tools []tools.Tool
+	// OnToolCall is called after every tool execution.
+	OnToolCall func(tools.CallInfo)
 }
 
 type Agent struct {
@@ -38,8 +48,9 @@ type Agent struct {
 	llm   *llm.Client
 	tools *tools.Registry
 
-	sessionDir string
+	store      session.Store
 	sess       *session.Session
+	transcript *session.TranscriptPolicy
 
 	consolidationMu      sync.Mutex
 	consolidationRunning bool
@@ -62,9 +73,12 @@ func New(opts Options) (*Agent, error) {
 	if err := paths.EnsureStateDirs(); err != nil {
 		return nil, err
 	}
-	sdir := paths.SessionsDir()
+	store := opts.SessionStore
+	if store == nil {
+		store = session.NewJSONLStore(paths.SessionsDir())
+	}
 
-	sess, err := session.Load(sdir, opts.SessionKey)
+	sess, err := store.Load(opts.SessionKey)
 	if err != nil {
 		return nil, err
 	}
@@ -72,27 +86,31 @@ func New(opts Options) (*Agent, error) {
 		sess = session.New(opts.SessionKey)
 	}
 
-	c := &llm.Client{
-		Provider:    opts.Config.LLM.Provider,
-		BaseURL:     opts.Config.LLM.BaseURL,
-		APIKey:      opts.Config.LLM.APIKey,
-		Model:       opts.Config.LLM.Model,
-		MaxTokens:   opts.Config.Agents.Defaults.MaxTokensValue(),
-		Temperature: opts.Config.Agents.Defaults.Temperature,
-		Headers:     opts.Config.LLM.Headers,
+	transcript, err := newTranscriptPolicy(opts.Config)
+	if err != nil {
+		return nil, err
 	}
 
+	c := newLLMClient(opts.Config, opts.Config.LLM.Model, opts.OnLLMAttempts)
+
 	treg := &tools.Registry{
 		WorkspaceDir:        wsAbs,
 		RestrictToWorkspace: opts.Config.Tools.RestrictToWorkspaceValue(),
 		ExecTimeout:         time.Duration(opts.Config.Tools.Exec.TimeoutSec) * time.Second,
 		BraveAPIKey:         opts.Config.Tools.Web.BraveAPIKey,
+		MCP:                 mcp.NewManager(opts.Config.Tools.MCP.Servers),
+		OnCall:              opts.OnToolCall,
 		ReadSkill: func(name string) (string, bool) {
 			// CLI agent doesn't have a skills loader; use the embedded loader via workspace.
 			l := skills.New(wsAbs)
 			return l.Load(name)
 		},
 	}
+	for _, t := range opts.Tools {
+		if err := treg.Register(t); err != nil {
+			return nil, err
+		}
+	}
 	memMgr, err := memory.NewIndexManager(opts.Config, wsAbs)
 	if err != nil {
 		return nil, err
@@ -107,29 +125,43 @@ func New(opts Options) (*Agent, error) {
 		verbose:      opts.Verbose,
 		llm:          c,
 		tools:        treg,
-		sessionDir:   sdir,
+		store:        store,
 		sess:         sess,
+		transcript:   transcript,
 	}, nil
 }
 
+// Close stops MCP servers started for this agent.
+func (a *Agent) Close() error {
+	return a.tools.MCP.Close()
+}
+
 func (a *Agent) Process(ctx context.Context, input string) (string, error) {
+	return a.ProcessStream(ctx, input, nil)
+}
+
+// ProcessStream is like Process but reports assistant text deltas to onDelta
+// as they are generated.
+func (a *Agent) ProcessStream(ctx context.Context, input string, onDelta func(string)) (string, error) {
 	a.scheduleConsolidation()
 
-	sys := a.systemPrompt()
+	mem := a.memoryView()
+	sys := a.systemPrompt(mem)
 	history := a.sess.History(a.memoryWindow)
 	messages := make([]llm.Message, 0, 1+len(history)+1)
 	messages = append(messages, llm.Message{Role: "system", Content: sys})
-	for _, m := range history {
-		messages = append(messages, llm.Message{Role: m.Role, Content: m.Content})
-	}
+	messages = append(messages, historyMessages(history)...)
 	messages = append(messages, llm.Message{Role: "user", Content: input})
 
 	toolsDefs := a.tools.Definitions()
 
+	stream := newTurnStream(onDelta)
+	window := newContextWindow(contextBudget(a.cfg, a.cfg.LLM.Model), len(messages)-1, toolsDefs)
 	var final string
 	toolsUsed := make([]string, 0, 8)
 	for iter := 0; iter < a.maxIters; iter++ {
-		res, err := a.llm.Chat(ctx, messages, toolsDefs)
+		res, fitted, err := window.chat(ctx, stream, a.llm, messages, toolsDefs)
+		messages = fitted
 		if err != nil {
 			return "", err
 		}
@@ -138,7 +170,7 @@ func (a *Agent) Process(ctx context.Context, input string) (string, error) {
 			for _, tc := range res.ToolCalls {
 				toolsUsed = append(toolsUsed, tc.Name)
 			}
-			messages = appendToolRound(messages, res.Content, res.ToolCalls, func(tc llm.ToolCall) string {
+			messages = appendToolRound(messages, res.Content, res.ToolCalls, newToolRoundOptions(a.cfg, a.tools), func(tc llm.ToolCall) string {
 				if a.verbose {
 					fmt.Fprintf(os.Stderr, "tool: %s %s\n", tc.Name, previewJSON(tc.Arguments, 200))
 				}
@@ -146,6 +178,7 @@ func (a *Agent) Process(ctx context.Context, input string) (string, error) {
 					Channel:    "cli",
 					ChatID:     "direct",
 					SessionKey: a.sess.Key,
+					Memory:     &mem,
 				}, tc.Name, tc.Arguments)
 				if err != nil {
 					return "error: " + err.Error()
@@ -162,9 +195,8 @@ func (a *Agent) Process(ctx context.Context, input string) (string, error) {
 		final = "(no response)"
 	}
 
-	a.sess.Add("user", input)
-	a.sess.AddWithTools("assistant", final, toolsUsed)
-	_ = session.Save(a.sessionDir, a.sess)
+	a.sess.AddMessages(turnTranscript(input, window.turn(messages), final, toolsUsed, a.transcript))
+	_ = a.store.Save(a.sess)
 	return final, nil
 }
 
@@ -194,7 +226,7 @@ func (a *Agent) scheduleConsolidation() {
 		cctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 		defer cancel()
 
-		done, err := maybeConsolidateSession(cctx, a.workspace, a.sess, a.memoryWindow, func(ctx context.Context, currentMemory, conversation string) (string, string, error) {
+		done, err := maybeConsolidateSession(cctx, a.memoryView(), a.sess, a.memoryWindow, func(ctx context.Context, currentMemory, conversation string) (string, []memory.FactChange, error) {
 			return summarizeConsolidationWithLLM(ctx, a.llm, currentMemory, conversation)
 		})
 		if err != nil {
@@ -206,13 +238,18 @@ func (a *Agent) scheduleConsolidation() {
 		if !done {
 			return
 		}
-		if err := session.Save(a.sessionDir, a.sess); err != nil && a.verbose {
+		if err := a.store.Save(a.sess); err != nil && a.verbose {
 			fmt.Fprintf(os.Stderr, "consolidation save error: %v\n", err)
 		}
 	}()
 }
 
-func (a *Agent) systemPrompt() string {
+// memoryView is the memory of the CLI session, which has no user scope.
+func (a *Agent) memoryView() memory.View {
+	return memory.ViewFor(a.cfg.Agents.Defaults.MemoryScopes, a.workspace, "cli", "", a.sess.Key)
+}
+
+func (a *Agent) systemPrompt(mem memory.View) string {
 	now := time.Now().Format("2006-01-02 15:04 (Mon)")
 	ws := a.workspace
 	rt := fmt.Sprintf("%s/%s Go %s", runtime.GOOS, runtime.GOARCH, runtime.Version())
@@ -246,10 +283,9 @@ func (a *Agent) systemPrompt() string {
 	}
 
 	// Memory (long-term + today's notes)
-	mem := memory.New(ws).GetContext()
-	if strings.TrimSpace(mem) != "" {
+	if memText := mem.Context(); strings.TrimSpace(memText) != "" {
 		b.WriteString("# Memory\n\n")
-		b.WriteString(mem)
+		b.WriteString(memText)
 		b.WriteString("\n\n")
 	}
 	return b.String()
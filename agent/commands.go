@@ -0,0 +1,281 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/llm"
+	"github.com/mosaxiv/clawlet/session"
+)
+
+// modelMetaKey is the session metadata key holding a /model override.
+const modelMetaKey = "model"
+
+// maxMemoryReplyChars keeps /memory replies within chat message limits.
+const maxMemoryReplyChars = 3500
+
+// errTurnStopped is the cancellation cause of a turn ended with /stop.
+var errTurnStopped = errors.New("turn stopped")
+
+type chatCommand struct {
+	name  string
+	args  string
+	help  string
+	admin bool
+}
+
+// chatCommands are handled by the loop instead of being sent to the LLM.
+var chatCommands = []chatCommand{
+	{name: "new", help: "start a new conversation (the current one is archived)"},
+	{name: "reset", help: "same as /new"},
+	{name: "model", args: "[name|default]", help: "show or switch the model for this chat", admin: true},
+	{name: "status", help: "show session, token and memory stats"},
+	{name: "stop", help: "cancel the reply in progress"},
+	{name: "memory", help: "show long-term memory (MEMORY.md)", admin: true},
+	{name: "help", help: "list commands"},
+}
+
+type commandCall struct {
+	chatCommand
+	arg string
+}
+
+// parseCommand recognizes "/name args" and "/name@bot args". Unknown commands
+// are not recognized, so they reach the LLM as ordinary text.
+func parseCommand(text string) (commandCall, bool) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "/") {
+		return commandCall{}, false
+	}
+	head, arg := text[1:], ""
+	if i := strings.IndexFunc(head, unicode.IsSpace); i >= 0 {
+		head, arg = head[:i], strings.TrimSpace(head[i:])
+	}
+	head, _, _ = strings.Cut(head, "@")
+	head = strings.ToLower(head)
+	for _, c := range chatCommands {
+		if c.name == head {
+			return commandCall{chatCommand: c, arg: arg}, true
+		}
+	}
+	return commandCall{}, false
+}
+
+// command returns the chat command carried by msg, if commands are enabled.
+func (l *Loop) command(msg bus.InboundMessage) (commandCall, bool) {
+	if msg.Channel == "system" || !l.cfg.Commands.EnabledValue() {
+		return commandCall{}, false
+	}
+	return parseCommand(msg.Content)
+}
+
+// isAdmin reports whether the sender of msg is listed in commands.admins,
+// either by ID or as "channel:id". Compound IDs ("123|name") match on any part.
+func (l *Loop) isAdmin(msg bus.InboundMessage) bool {
+	admins := l.cfg.Commands.Admins
+	if len(admins) == 0 {
+		return false
+	}
+	for id := range strings.SplitSeq(msg.SenderID, "|") {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		if slices.Contains(admins, id) || slices.Contains(admins, msg.Channel+":"+id) {
+			return true
+		}
+	}
+	return false
+}
+
+// runCommand executes cmd for the session of msg and returns the reply.
+func (l *Loop) runCommand(ctx context.Context, msg bus.InboundMessage, sessionKey string, cmd commandCall) string {
+	if cmd.admin && !l.isAdmin(msg) {
+		return "/" + cmd.name + " is restricted to admins (commands.admins in the config)."
+	}
+	switch cmd.name {
+	case "new", "reset":
+		return l.resetSession(sessionKey)
+	case "model":
+		return l.switchModel(sessionKey, cmd.arg)
+	case "status":
+		return l.sessionStatus(ctx, sessionKey, msg.Channel, msg.ChatID)
+	case "stop":
+		dropped := l.dispatch.drop(sessionKey)
+		if !l.cancelTurn(sessionKey, errTurnStopped) && dropped == 0 {
+			return "Nothing to stop."
+		}
+		return "Stopped."
+	case "memory":
+		sess, err := l.sessions.GetOrCreate(sessionKey)
+		if err != nil {
+			return "error: " + err.Error()
+		}
+		mem := strings.TrimSpace(l.memoryView(sess, msg.Channel).LongTerm())
+		if mem == "" {
+			return "MEMORY.md is empty."
+		}
+		if len(mem) > maxMemoryReplyChars {
+			n := maxMemoryReplyChars
+			for n > 0 && !utf8.RuneStart(mem[n]) {
+				n--
+			}
+			mem = mem[:n] + "\n… (truncated)"
+		}
+		return mem
+	default:
+		return commandHelp()
+	}
+}
+
+func commandHelp() string {
+	var b strings.Builder
+	b.WriteString("Commands:\n")
+	for _, c := range chatCommands {
+		b.WriteString("/" + c.name)
+		if c.args != "" {
+			b.WriteString(" " + c.args)
+		}
+		b.WriteString(" - " + c.help)
+		if c.admin {
+			b.WriteString(" (admin)")
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func (l *Loop) resetSession(sessionKey string) string {
+	l.cancelTurn(sessionKey, errTurnStopped)
+	path, err := l.sessions.Reset(sessionKey, l.archiveDir)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	if path == "" {
+		return "Started a new conversation."
+	}
+	return "Started a new conversation. The previous one was archived."
+}
+
+func (l *Loop) switchModel(sessionKey, arg string) string {
+	sess, err := l.sessions.GetOrCreate(sessionKey)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	switch strings.ToLower(arg) {
+	case "":
+		if routed := sess.MetaString(modelMetaKey); routed != "" {
+			return "Model: " + routed + " (this chat; /model default to undo)"
+		}
+		return "Model: " + l.model
+	case "default":
+		sess.SetMeta(modelMetaKey, "")
+	default:
+		sess.SetMeta(modelMetaKey, arg)
+	}
+	if err := l.sessions.Save(sess); err != nil {
+		return "error: " + err.Error()
+	}
+	client, _ := l.clientFor(sess)
+	return fmt.Sprintf("Model for this chat: %s (%s)", client.Model, client.Provider)
+}
+
+func (l *Loop) sessionStatus(ctx context.Context, sessionKey, channel, chatID string) string {
+	sess, err := l.sessions.GetOrCreate(sessionKey)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	client, model := l.clientFor(sess)
+	history := sess.History(l.memoryWindow)
+	mem := l.memoryView(sess, channel)
+	prompt := append([]llm.Message{{Role: "system", Content: l.buildSystemPrompt(channel, chatID, mem)}}, historyMessages(history)...)
+	tokens := estimateMessagesTokens(prompt) + estimateToolDefsTokens(l.tools.Definitions())
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Session: %s\n", sessionKey)
+	fmt.Fprintf(&b, "Model: %s (%s)", model, client.Provider)
+	if sess.MetaString(modelMetaKey) != "" {
+		b.WriteString(" [set with /model]")
+	}
+	fmt.Fprintf(&b, "\nMessages: %d stored, %d in context\n", len(sess.History(0)), len(history))
+	fmt.Fprintf(&b, "Context: ~%d of %d tokens\n", tokens, contextBudget(l.cfg, model))
+	if l.turnRunning(sessionKey) {
+		b.WriteString("Turn: running (/stop to cancel)\n")
+	}
+	fmt.Fprintf(&b, "Memory: MEMORY.md %d bytes", len(mem.LongTerm()))
+	if mem.Scoped {
+		kinds := make([]string, len(mem.Scopes))
+		for i, sc := range mem.Scopes {
+			kinds[i] = sc.Kind
+		}
+		fmt.Fprintf(&b, " (scopes: %s)", strings.Join(kinds, ", "))
+	}
+	if l.tools.MemorySearch != nil {
+		if st := l.tools.MemorySearch.Status(ctx); st.Enabled {
+			fmt.Fprintf(&b, ", index %d files / %d chunks", st.Files, st.Chunks)
+			if st.Watching {
+				fmt.Fprintf(&b, " (watch: %s, %d queued)", st.WatchMode, st.QueuedFiles)
+			}
+			if st.LastSyncAtMS > 0 {
+				fmt.Fprintf(&b, ", synced %s", time.UnixMilli(st.LastSyncAtMS).Format("15:04:05"))
+			}
+		}
+	}
+	return b.String()
+}
+
+// clientFor returns the LLM client and model name for sess, honoring a
+// /model override.
+func (l *Loop) clientFor(sess *session.Session) (*llm.Client, string) {
+	routed := sess.MetaString(modelMetaKey)
+	if routed == "" {
+		return l.llm, l.model
+	}
+	if c, ok := l.modelClients.Load(routed); ok {
+		client := c.(*llm.Client)
+		return client, client.Model
+	}
+	client := newRoutedLLMClient(l.cfg, l.cfg.RoutedLLM(routed), l.onLLMAttempts)
+	c, _ := l.modelClients.LoadOrStore(routed, client)
+	client = c.(*llm.Client)
+	return client, client.Model
+}
+
+// activeTurn is a turn in progress that /stop can cancel.
+type activeTurn struct {
+	cancel context.CancelCauseFunc
+}
+
+// startTurn registers a cancellable turn for sessionKey. The returned func
+// must be called when the turn ends.
+func (l *Loop) startTurn(ctx context.Context, sessionKey string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	t := &activeTurn{cancel: cancel}
+	l.turns.Store(sessionKey, t)
+	return ctx, func() {
+		l.turns.CompareAndDelete(sessionKey, t)
+		cancel(nil)
+	}
+}
+
+// cancelTurn cancels the turn in progress for sessionKey with cause.
+func (l *Loop) cancelTurn(sessionKey string, cause error) bool {
+	v, ok := l.turns.LoadAndDelete(sessionKey)
+	if !ok {
+		return false
+	}
+	v.(*activeTurn).cancel(cause)
+	return true
+}
+
+func (l *Loop) turnRunning(sessionKey string) bool {
+	_, ok := l.turns.Load(sessionKey)
+	return ok
+}
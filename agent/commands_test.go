@@ -0,0 +1,170 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/llm"
+	"github.com/mosaxiv/clawlet/session"
+)
+
+func TestParseCommand(t *testing.T) {
+	cases := []struct {
+		in   string
+		name string
+		arg  string
+		ok   bool
+	}{
+		{"/help", "help", "", true},
+		{"  /Model   anthropic/claude-sonnet-4-5 ", "model", "anthropic/claude-sonnet-4-5", true},
+		{"/status@clawlet_bot", "status", "", true},
+		{"/stop\nplease", "stop", "please", true},
+		{"/unknown", "", "", false},
+		{"/etc/hosts is broken", "", "", false},
+		{"hello /help", "", "", false},
+	}
+	for _, c := range cases {
+		got, ok := parseCommand(c.in)
+		if ok != c.ok || got.name != c.name || got.arg != c.arg {
+			t.Errorf("parseCommand(%q) = %q %q %v", c.in, got.name, got.arg, ok)
+		}
+	}
+}
+
+func TestLoopIsAdmin(t *testing.T) {
+	l := &Loop{cfg: &config.Config{Commands: config.CommandsConfig{Admins: []string{"42", "slack:U1"}}}}
+	cases := []struct {
+		channel, sender string
+		want            bool
+	}{
+		{"telegram", "42", true},
+		{"telegram", "42|alice", true},
+		{"slack", "U1", true},
+		{"discord", "U1", false},
+		{"telegram", "7", false},
+	}
+	for _, c := range cases {
+		if got := l.isAdmin(bus.InboundMessage{Channel: c.channel, SenderID: c.sender}); got != c.want {
+			t.Errorf("isAdmin(%s, %s) = %v", c.channel, c.sender, got)
+		}
+	}
+}
+
+// newTestLoop returns a loop talking to srv with "42" as admin.
+func newTestLoop(t *testing.T, srv *httptest.Server, configure func(*config.Config)) *Loop {
+	t.Helper()
+	cfg := &config.Config{
+		LLM:      config.LLMConfig{Provider: "openai", BaseURL: srv.URL, Model: "base-model"},
+		Commands: config.CommandsConfig{Admins: []string{"42"}},
+	}
+	if configure != nil {
+		configure(cfg)
+	}
+	l, err := NewLoop(LoopOptions{
+		Config:             cfg,
+		WorkspaceDir:       t.TempDir(),
+		Bus:                bus.New(64),
+		Sessions:           session.NewManager(session.NewJSONLStore(t.TempDir()), 0),
+		SessionsArchiveDir: t.TempDir(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+	return l
+}
+
+func TestLoopCommands_ModelAndReset(t *testing.T) {
+	var mu sync.Mutex
+	var models []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string `json:"model"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		models = append(models, req.Model)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	defer srv.Close()
+	l := newTestLoop(t, srv, nil)
+
+	send := func(sender, text string) string {
+		t.Helper()
+		out, _, err := l.processInbound(t.Context(), bus.InboundMessage{Channel: "telegram", ChatID: "c1", SenderID: sender, Content: text})
+		if err != nil {
+			t.Fatalf("%s: %v", text, err)
+		}
+		return out
+	}
+
+	if out := send("7", "/model other-model"); !strings.Contains(out, "restricted to admins") {
+		t.Fatalf("non-admin /model: %q", out)
+	}
+	if out := send("42", "/model other-model"); !strings.Contains(out, "other-model") {
+		t.Fatalf("/model: %q", out)
+	}
+	send("7", "hello")
+	if out := send("7", "/status"); !strings.Contains(out, "other-model") || !strings.Contains(out, "Messages: 2 stored") {
+		t.Fatalf("/status: %q", out)
+	}
+	if out := send("7", "/new"); !strings.Contains(out, "archived") {
+		t.Fatalf("/new: %q", out)
+	}
+	send("7", "/unknown is not a command")
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(models) != 2 || models[0] != "other-model" || models[1] != "base-model" {
+		t.Fatalf("models=%v", models)
+	}
+	sess, _ := l.sessions.GetOrCreate("telegram:c1")
+	if h := sess.History(0); len(h) != 2 || h[0].Content != "/unknown is not a command" {
+		t.Fatalf("history after /new=%+v", h)
+	}
+}
+
+func TestLoopCommands_StopCancelsTurn(t *testing.T) {
+	started := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		started <- struct{}{}
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+	l := newTestLoop(t, srv, nil)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := l.processDirect(t.Context(), llm.Message{Role: "user", Content: "think hard"}, "think hard", "cli:c1", "cli", "c1", nil)
+		done <- err
+	}()
+	<-started
+
+	out, _, err := l.processInbound(t.Context(), bus.InboundMessage{Channel: "cli", ChatID: "c1", SenderID: "7", Content: "/stop"})
+	if err != nil || out != "Stopped." {
+		t.Fatalf("/stop: %q %v", out, err)
+	}
+	select {
+	case err := <-done:
+		if !errors.Is(err, errTurnStopped) {
+			t.Fatalf("err=%v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("turn was not cancelled")
+	}
+	if l.turnRunning("cli:c1") {
+		t.Fatal("turn still registered")
+	}
+}
@@ -11,11 +11,13 @@ import (
 	"github.com/mosaxiv/clawlet/session"
 )
 
-type summarizeConsolidationFunc func(ctx context.Context, currentMemory, conversation string) (historyEntry, memoryUpdate string, err error)
+// summarizeConsolidationFunc summarizes conversation for HISTORY.md and
+// returns the fact-level edits of long-term memory it calls for.
+type summarizeConsolidationFunc func(ctx context.Context, currentMemory, conversation string) (historyEntry string, changes []memory.FactChange, err error)
 
 func maybeConsolidateSession(
 	ctx context.Context,
-	workspace string,
+	mem memory.View,
 	sess *session.Session,
 	memoryWindow int,
 	summarize summarizeConsolidationFunc,
@@ -34,10 +36,13 @@ func maybeConsolidateSession(
 		return false, nil
 	}
 	conversation := formatConsolidationConversation(oldMessages)
-	store := memory.New(workspace)
-	currentMemory := store.ReadLongTerm()
+	// Picks up hand edits, so every line of MEMORY.md carries its id.
+	if err := mem.LoadFacts(); err != nil {
+		return false, err
+	}
+	currentMemory := mem.LongTerm()
 
-	historyEntry, memoryUpdate, err := summarize(ctx, currentMemory, conversation)
+	historyEntry, changes, err := summarize(ctx, currentMemory, conversation)
 	if err != nil {
 		return false, err
 	}
@@ -46,22 +51,21 @@ func maybeConsolidateSession(
 	}
 
 	if strings.TrimSpace(historyEntry) != "" {
-		if err := store.AppendHistory(historyEntry); err != nil {
+		if err := mem.AppendHistory(historyEntry); err != nil {
 			return false, err
 		}
 	}
-	memoryUpdate = strings.TrimSpace(memoryUpdate)
-	if memoryUpdate != "" && memoryUpdate != strings.TrimSpace(currentMemory) {
-		if err := store.WriteLongTerm(memoryUpdate + "\n"); err != nil {
+	if len(changes) > 0 {
+		if _, err := mem.ApplyFacts(changes, sess.Key); err != nil {
 			return false, err
 		}
 	}
 	return true, nil
 }
 
-func summarizeConsolidationWithLLM(ctx context.Context, c *llm.Client, currentMemory, conversation string) (string, string, error) {
+func summarizeConsolidationWithLLM(ctx context.Context, c *llm.Client, currentMemory, conversation string) (string, []memory.FactChange, error) {
 	if c == nil {
-		return "", "", fmt.Errorf("llm client is nil")
+		return "", nil, fmt.Errorf("llm client is nil")
 	}
 	prompt := buildConsolidationPrompt(currentMemory, conversation)
 	res, err := c.Chat(ctx, []llm.Message{
@@ -69,12 +73,12 @@ func summarizeConsolidationWithLLM(ctx context.Context, c *llm.Client, currentMe
 		{Role: "user", Content: prompt},
 	}, nil)
 	if err != nil {
-		return "", "", err
+		return "", nil, err
 	}
 
 	text := strings.TrimSpace(res.Content)
 	if text == "" {
-		return "", "", fmt.Errorf("empty consolidation response")
+		return "", nil, fmt.Errorf("empty consolidation response")
 	}
 	if strings.HasPrefix(text, "```") {
 		if i := strings.Index(text, "\n"); i >= 0 {
@@ -85,13 +89,29 @@ func summarizeConsolidationWithLLM(ctx context.Context, c *llm.Client, currentMe
 	}
 
 	var parsed struct {
-		HistoryEntry string `json:"history_entry"`
-		MemoryUpdate string `json:"memory_update"`
+		HistoryEntry  string `json:"history_entry"`
+		MemoryChanges struct {
+			Add    []memory.FactChange `json:"add"`
+			Update []memory.FactChange `json:"update"`
+			Forget []string            `json:"forget"`
+		} `json:"memory_changes"`
 	}
 	if err := json.Unmarshal([]byte(text), &parsed); err != nil {
-		return "", "", fmt.Errorf("parse consolidation json: %w", err)
+		return "", nil, fmt.Errorf("parse consolidation json: %w", err)
+	}
+	var changes []memory.FactChange
+	for _, c := range parsed.MemoryChanges.Add {
+		changes = append(changes, memory.FactChange{Text: c.Text, Tags: c.Tags, Scope: c.Scope})
+	}
+	for _, c := range parsed.MemoryChanges.Update {
+		if strings.TrimSpace(c.ID) != "" {
+			changes = append(changes, memory.FactChange{ID: c.ID, Text: c.Text, Tags: c.Tags})
+		}
+	}
+	for _, id := range parsed.MemoryChanges.Forget {
+		changes = append(changes, memory.FactChange{ID: id, Forget: true})
 	}
-	return strings.TrimSpace(parsed.HistoryEntry), strings.TrimSpace(parsed.MemoryUpdate), nil
+	return strings.TrimSpace(parsed.HistoryEntry), changes, nil
 }
 
 func formatConsolidationConversation(msgs []session.Message) string {
@@ -100,6 +120,11 @@ func formatConsolidationConversation(msgs []session.Message) string {
 	}
 	lines := make([]string, 0, len(msgs))
 	for _, m := range msgs {
+		// Tool results are too verbose for the summary; the final assistant
+		// message already lists the tools used.
+		if m.Role == "tool" {
+			continue
+		}
 		content := strings.TrimSpace(m.Content)
 		if content == "" {
 			continue
@@ -147,7 +172,10 @@ func buildConsolidationPrompt(currentMemory, conversation string) string {
 	return fmt.Sprintf(`You are a memory consolidation agent. Process this conversation and return a JSON object with exactly two keys:
 
 1. "history_entry": A paragraph (2-5 sentences) summarizing key events, decisions, and topics. Start with a timestamp like [YYYY-MM-DD HH:MM].
-2. "memory_update": Updated long-term memory content. Add durable facts (preferences, profile, project context, decisions). If nothing new, return existing content unchanged.
+2. "memory_changes": Edits to long-term memory, which lists one fact per line with its id in brackets:
+   {"add": [{"text": "...", "tags": ["preferences"]}], "update": [{"id": "f3", "text": "..."}], "forget": ["f7"]}
+   Add new durable facts (preferences, profile, project context, decisions), one short sentence each. Update a fact the conversation corrects, forget one it shows is wrong or obsolete. Leave every other fact alone; facts that are not mentioned are kept. If nothing changed, return {}.
+   If the memory below is split into scopes, an add may set "scope" to one of them: "user" for facts about the person, "chat" for context of this conversation, "global" for facts every chat may see.
 
 ## Current Long-term Memory
 %s
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/mosaxiv/clawlet/memory"
 	"github.com/mosaxiv/clawlet/session"
 )
 
@@ -18,7 +19,7 @@ func TestMaybeConsolidateSession_NoOpWhenUnderWindow(t *testing.T) {
 		sess.Add("assistant", "reply")
 	}
 
-	done, err := maybeConsolidateSession(context.Background(), ws, sess, 20, nil)
+	done, err := maybeConsolidateSession(context.Background(), memory.GlobalView(ws), sess, 20, nil)
 	if err != nil {
 		t.Fatalf("maybeConsolidateSession error: %v", err)
 	}
@@ -38,16 +39,16 @@ func TestMaybeConsolidateSession_TrimAndArchive(t *testing.T) {
 		sess.AddWithTools("assistant", "answer", []string{"read_file", "exec"})
 	}
 
-	summarize := func(ctx context.Context, currentMemory, conversation string) (string, string, error) {
+	summarize := func(ctx context.Context, currentMemory, conversation string) (string, []memory.FactChange, error) {
 		if !strings.Contains(conversation, "USER: question") {
 			t.Fatalf("unexpected conversation: %s", conversation)
 		}
 		if !strings.Contains(conversation, "ASSISTANT [tools: read_file, exec]: answer") {
 			t.Fatalf("missing tools_used in conversation: %s", conversation)
 		}
-		return "[2026-02-13 23:20] archived summary", "# Long-term Memory\n\n- prefers concise Japanese\n", nil
+		return "[2026-02-13 23:20] archived summary", []memory.FactChange{{Text: "prefers concise Japanese", Tags: []string{"preferences"}}}, nil
 	}
-	done, err := maybeConsolidateSession(context.Background(), ws, sess, 20, summarize)
+	done, err := maybeConsolidateSession(context.Background(), memory.GlobalView(ws), sess, 20, summarize)
 	if err != nil {
 		t.Fatalf("maybeConsolidateSession error: %v", err)
 	}
@@ -78,11 +79,52 @@ func TestMaybeConsolidateSession_TrimAndArchive(t *testing.T) {
 	if err != nil {
 		t.Fatalf("read MEMORY.md: %v", err)
 	}
-	if !strings.Contains(string(mem), "prefers concise Japanese") {
+	if !strings.Contains(string(mem), "- [f1] prefers concise Japanese") {
 		t.Fatalf("memory not updated: %s", string(mem))
 	}
 }
 
+func TestMaybeConsolidateSession_AppliesFactChanges(t *testing.T) {
+	ws := t.TempDir()
+	store := memory.New(ws)
+	if err := store.WriteLongTerm("# Long-term Memory\n\n- lives in Osaka\n- works at Acme\n"); err != nil {
+		t.Fatal(err)
+	}
+	sess := session.New("telegram:1")
+	for range 15 {
+		sess.Add("user", "question")
+		sess.Add("assistant", "answer")
+	}
+	summarize := func(ctx context.Context, currentMemory, conversation string) (string, []memory.FactChange, error) {
+		if !strings.Contains(currentMemory, "[f1] lives in Osaka") || !strings.Contains(currentMemory, "[f2] works at Acme") {
+			t.Fatalf("currentMemory without ids: %s", currentMemory)
+		}
+		// Nothing about Acme came up: it must survive.
+		return "", []memory.FactChange{
+			{ID: "f1", Text: "lives in Kyoto"},
+			{Text: "has a cat named Miso"},
+			{ID: "f9", Forget: true},
+		}, nil
+	}
+	if done, err := maybeConsolidateSession(context.Background(), memory.GlobalView(ws), sess, 20, summarize); err != nil || !done {
+		t.Fatalf("done=%v err=%v", done, err)
+	}
+	facts, err := store.LoadFacts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var texts []string
+	for _, f := range facts {
+		texts = append(texts, f.ID+" "+f.Text)
+	}
+	if got := strings.Join(texts, "; "); got != "f1 lives in Kyoto; f2 works at Acme; f3 has a cat named Miso" {
+		t.Fatalf("facts=%s", got)
+	}
+	if facts[2].Session != "telegram:1" {
+		t.Fatalf("session=%q", facts[2].Session)
+	}
+}
+
 func TestMaybeConsolidateSession_SummarizeError_NoTrim(t *testing.T) {
 	ws := t.TempDir()
 	sess := session.New("cli:test")
@@ -91,10 +133,10 @@ func TestMaybeConsolidateSession_SummarizeError_NoTrim(t *testing.T) {
 		sess.Add("assistant", "answer")
 	}
 
-	summarize := func(ctx context.Context, currentMemory, conversation string) (string, string, error) {
-		return "", "", context.DeadlineExceeded
+	summarize := func(ctx context.Context, currentMemory, conversation string) (string, []memory.FactChange, error) {
+		return "", nil, context.DeadlineExceeded
 	}
-	done, err := maybeConsolidateSession(context.Background(), ws, sess, 20, summarize)
+	done, err := maybeConsolidateSession(context.Background(), memory.GlobalView(ws), sess, 20, summarize)
 	if err == nil {
 		t.Fatalf("expected error")
 	}
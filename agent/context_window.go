@@ -0,0 +1,247 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/llm"
+)
+
+const (
+	// oldToolOutputChars caps tool results from earlier rounds of a turn once
+	// the prompt is over budget.
+	oldToolOutputChars = 2000
+	// minToolOutputChars is the floor when tool results of the latest round
+	// have to be cut as well.
+	minToolOutputChars = 1000
+	// imagePartTokens is a rough per-image cost.
+	imagePartTokens = 1000
+)
+
+// estimateTokens approximates the token count of s (about 4 bytes per token).
+func estimateTokens(s string) int {
+	return (len(s) + 3) / 4
+}
+
+func estimateMessageTokens(m llm.Message) int {
+	n := 4 + estimateTokens(m.Content)
+	for _, p := range m.Parts {
+		if p.Type == llm.ContentPartTypeImage {
+			n += imagePartTokens
+			continue
+		}
+		n += estimateTokens(p.Text)
+	}
+	for _, tc := range m.ToolCalls {
+		n += 4 + estimateTokens(tc.Function.Name) + estimateTokens(tc.Function.Arguments)
+	}
+	return n
+}
+
+func estimateMessagesTokens(messages []llm.Message) int {
+	n := 0
+	for _, m := range messages {
+		n += estimateMessageTokens(m)
+	}
+	return n
+}
+
+func estimateToolDefsTokens(defs []llm.ToolDefinition) int {
+	if len(defs) == 0 {
+		return 0
+	}
+	b, err := json.Marshal(defs)
+	if err != nil {
+		return 0
+	}
+	return estimateTokens(string(b))
+}
+
+// modelContextTokens guesses the context window of a model from its name.
+func modelContextTokens(provider, model string) int {
+	m := strings.ToLower(model)
+	name := m[strings.LastIndex(m, "/")+1:]
+	switch {
+	case strings.Contains(m, "gemini"), strings.Contains(m, "gpt-4.1"):
+		return 1_000_000
+	case strings.Contains(m, "gpt-5"):
+		return 400_000
+	case strings.Contains(m, "claude"), strings.HasPrefix(name, "o1"), strings.HasPrefix(name, "o3"), strings.HasPrefix(name, "o4"):
+		return 200_000
+	case strings.Contains(m, "gpt-4o"), strings.Contains(m, "gpt-4-turbo"), strings.Contains(m, "deepseek"):
+		return 128_000
+	case strings.EqualFold(provider, "ollama"):
+		return 8192
+	default:
+		return 128_000
+	}
+}
+
+// contextBudget is the number of prompt tokens available for model, after
+// reserving room for the reply.
+func contextBudget(cfg *config.Config, model string) int {
+	window := cfg.Agents.Defaults.ContextTokens
+	if window <= 0 {
+		window = modelContextTokens(cfg.LLM.Provider, model)
+	}
+	budget := window - cfg.Agents.Defaults.MaxTokensValue()
+	if budget < window/2 {
+		budget = window / 2
+	}
+	return budget
+}
+
+// contextWindow keeps the prompt of one turn within a token budget. History
+// before turnStart may be dropped; messages of the current turn are kept but
+// their tool results may be truncated.
+type contextWindow struct {
+	budget    int
+	turnStart int
+	compacted bool
+}
+
+func newContextWindow(budget, turnStart int, defs []llm.ToolDefinition) *contextWindow {
+	budget -= estimateToolDefsTokens(defs)
+	if budget < 1024 {
+		budget = 1024
+	}
+	return &contextWindow{budget: budget, turnStart: turnStart}
+}
+
+// fit trims messages until they fit the budget:
+//  1. truncate tool results from earlier rounds of the turn,
+//  2. drop the oldest history (keeping the system prompt),
+//  3. truncate the largest remaining tool results.
+func (w *contextWindow) fit(messages []llm.Message) []llm.Message {
+	total := estimateMessagesTokens(messages)
+	if total <= w.budget {
+		return messages
+	}
+
+	latest := lastToolRoundStart(messages)
+	for i := w.turnStart; i < latest; i++ {
+		if messages[i].Role != "tool" {
+			continue
+		}
+		before := estimateMessageTokens(messages[i])
+		messages[i].Content = truncateMiddle(messages[i].Content, oldToolOutputChars)
+		total -= before - estimateMessageTokens(messages[i])
+	}
+
+	// Drop history from the front; a leading tool result without its
+	// assistant call would be rejected by providers, so drop those as well.
+	first := 0
+	if len(messages) > 0 && messages[0].Role == "system" {
+		first = 1
+	}
+	drop := 0
+	for total > w.budget && first+drop < w.turnStart {
+		total -= estimateMessageTokens(messages[first+drop])
+		drop++
+		for first+drop < w.turnStart && messages[first+drop].Role == "tool" {
+			total -= estimateMessageTokens(messages[first+drop])
+			drop++
+		}
+	}
+	if drop > 0 {
+		messages = append(messages[:first], messages[first+drop:]...)
+		w.turnStart -= drop
+	}
+
+	if total > w.budget {
+		w.truncateLargestToolResults(messages, total)
+	}
+	return messages
+}
+
+func (w *contextWindow) truncateLargestToolResults(messages []llm.Message, total int) {
+	idx := make([]int, 0, 8)
+	for i := w.turnStart; i < len(messages); i++ {
+		if messages[i].Role == "tool" && len(messages[i].Content) > minToolOutputChars {
+			idx = append(idx, i)
+		}
+	}
+	sort.Slice(idx, func(a, b int) bool {
+		return len(messages[idx[a]].Content) > len(messages[idx[b]].Content)
+	})
+	for _, i := range idx {
+		if total <= w.budget {
+			break
+		}
+		excessChars := (total - w.budget) * 4
+		keep := max(len(messages[i].Content)-excessChars, minToolOutputChars)
+		before := estimateMessageTokens(messages[i])
+		messages[i].Content = truncateMiddle(messages[i].Content, keep)
+		total -= before - estimateMessageTokens(messages[i])
+	}
+}
+
+// compact shrinks the budget after the provider rejected the prompt as too
+// long and re-fits messages. It only applies once per turn.
+func (w *contextWindow) compact(messages []llm.Message) ([]llm.Message, bool) {
+	if w.compacted {
+		return messages, false
+	}
+	w.compacted = true
+	w.budget = min(w.budget, estimateMessagesTokens(messages)) / 2
+	return w.fit(messages), true
+}
+
+// lastToolRoundStart returns the index of the last assistant message that
+// requested tool calls, or len(messages) if there is none.
+func lastToolRoundStart(messages []llm.Message) int {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "assistant" && len(messages[i].ToolCalls) > 0 {
+			return i
+		}
+	}
+	return len(messages)
+}
+
+// truncateMiddle shortens s to about maxChars bytes, keeping its head and
+// tail and noting how much was removed.
+func truncateMiddle(s string, maxChars int) string {
+	if len(s) <= maxChars {
+		return s
+	}
+	head := maxChars * 2 / 3
+	tail := maxChars - head
+	for head > 0 && !utf8.RuneStart(s[head]) {
+		head--
+	}
+	cut := len(s) - tail
+	for cut < len(s) && !utf8.RuneStart(s[cut]) {
+		cut++
+	}
+	return s[:head] + fmt.Sprintf("\n…[%d chars omitted to fit the context window]…\n", cut-head) + s[cut:]
+}
+
+// chat fits messages into w and calls the model. If the provider still rejects
+// the prompt as too long, the window is compacted and the call retried once.
+// The (possibly trimmed) messages are returned for the rest of the turn.
+func (w *contextWindow) chat(ctx context.Context, s *turnStream, client *llm.Client, messages []llm.Message, defs []llm.ToolDefinition) (*llm.ChatResult, []llm.Message, error) {
+	messages = w.fit(messages)
+	res, err := s.chat(ctx, client, messages, defs)
+	if err == nil || !llm.IsContextLengthError(err) {
+		return res, messages, err
+	}
+	messages, ok := w.compact(messages)
+	if !ok {
+		return nil, messages, err
+	}
+	res, err = s.chat(ctx, client, messages, defs)
+	return res, messages, err
+}
+
+// turn returns the messages that followed the current user message.
+func (w *contextWindow) turn(messages []llm.Message) []llm.Message {
+	if w.turnStart+1 >= len(messages) {
+		return nil
+	}
+	return messages[w.turnStart+1:]
+}
@@ -0,0 +1,161 @@
+package agent
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/llm"
+)
+
+func TestContextWindowFit_UnderBudgetUnchanged(t *testing.T) {
+	msgs := []llm.Message{
+		{Role: "system", Content: "sys"},
+		{Role: "user", Content: "hi"},
+	}
+	w := &contextWindow{budget: 1000, turnStart: 1}
+	out := w.fit(msgs)
+	if len(out) != 2 || out[1].Content != "hi" {
+		t.Fatalf("out=%+v", out)
+	}
+}
+
+func TestContextWindowFit_TruncatesOldToolOutputsThenDropsHistory(t *testing.T) {
+	big := strings.Repeat("x", 20000)
+	msgs := []llm.Message{
+		{Role: "system", Content: "sys"},
+		{Role: "user", Content: strings.Repeat("old question ", 200)},
+		{Role: "assistant", Content: strings.Repeat("old answer ", 200)},
+		{Role: "user", Content: "read two files"},
+		{Role: "assistant", ToolCalls: []llm.ToolCallPayload{{ID: "1", Function: llm.ToolCallPayloadFunc{Name: "read_file"}}}},
+		{Role: "tool", ToolCallID: "1", Content: big},
+		{Role: "assistant", ToolCalls: []llm.ToolCallPayload{{ID: "2", Function: llm.ToolCallPayloadFunc{Name: "read_file"}}}},
+		{Role: "tool", ToolCallID: "2", Content: "latest result"},
+	}
+	w := &contextWindow{budget: 700, turnStart: 3}
+	out := w.fit(msgs)
+
+	if out[0].Role != "system" {
+		t.Fatalf("system prompt dropped: %+v", out[0])
+	}
+	if len(out) != 6 || w.turnStart != 1 || out[1].Content != "read two files" {
+		t.Fatalf("expected history dropped, len=%d turnStart=%d first=%q", len(out), w.turnStart, out[1].Content)
+	}
+	if len(out[3].Content) >= len(big) || !strings.Contains(out[3].Content, "omitted to fit the context window") {
+		t.Fatalf("old tool output not truncated: %d chars", len(out[3].Content))
+	}
+	if out[5].Content != "latest result" {
+		t.Fatalf("latest tool output changed: %q", out[5].Content)
+	}
+	if estimateMessagesTokens(out) > w.budget {
+		t.Fatalf("still over budget: %d > %d", estimateMessagesTokens(out), w.budget)
+	}
+}
+
+func TestContextWindowFit_DropsOrphanedToolResults(t *testing.T) {
+	msgs := []llm.Message{
+		{Role: "system", Content: "sys"},
+		{Role: "assistant", ToolCalls: []llm.ToolCallPayload{{ID: "1"}}},
+		{Role: "tool", ToolCallID: "1", Content: strings.Repeat("y", 4000)},
+		{Role: "assistant", Content: "done"},
+		{Role: "user", Content: "next"},
+	}
+	w := &contextWindow{budget: 20, turnStart: 4}
+	out := w.fit(msgs)
+	for _, m := range out[1:] {
+		if m.Role == "tool" {
+			t.Fatalf("orphaned tool result kept: %+v", out)
+		}
+	}
+	if out[len(out)-1].Content != "next" {
+		t.Fatalf("current turn dropped: %+v", out)
+	}
+}
+
+func TestContextWindowFit_TruncatesLatestToolResultAsLastResort(t *testing.T) {
+	msgs := []llm.Message{
+		{Role: "system", Content: "sys"},
+		{Role: "user", Content: "fetch"},
+		{Role: "assistant", ToolCalls: []llm.ToolCallPayload{{ID: "1"}}},
+		{Role: "tool", ToolCallID: "1", Content: strings.Repeat("z", 40000)},
+	}
+	w := &contextWindow{budget: 2000, turnStart: 1}
+	out := w.fit(msgs)
+	if got := len(out[3].Content); got > 9000 {
+		t.Fatalf("tool output not truncated: %d", got)
+	}
+}
+
+func TestTruncateMiddle_KeepsValidUTF8(t *testing.T) {
+	s := strings.Repeat("日本語", 500)
+	out := truncateMiddle(s, 100)
+	if !utf8.ValidString(out) {
+		t.Fatal("invalid utf-8 after truncation")
+	}
+	if !strings.HasPrefix(out, "日") || !strings.HasSuffix(out, "語") {
+		t.Fatalf("out=%q", out)
+	}
+}
+
+func TestContextBudget(t *testing.T) {
+	cfg := config.Default()
+	cfg.Agents.Defaults.ContextTokens = 32000
+	cfg.Agents.Defaults.MaxTokens = 8000
+	if got := contextBudget(cfg, "whatever"); got != 24000 {
+		t.Fatalf("budget=%d", got)
+	}
+	cfg.Agents.Defaults.ContextTokens = 0
+	if got := contextBudget(cfg, "anthropic/claude-sonnet-4-5"); got != 200000-8000 {
+		t.Fatalf("claude budget=%d", got)
+	}
+}
+
+func TestContextWindowChat_CompactsOnceOnContextLengthError(t *testing.T) {
+	var calls atomic.Int32
+	var sizes []int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		sizes = append(sizes, len(b))
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, `{"error":{"code":"context_length_exceeded","message":"maximum context length exceeded"}}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`)
+	}))
+	defer srv.Close()
+
+	client := &llm.Client{Provider: "openai", BaseURL: srv.URL, Model: "m"}
+	msgs := []llm.Message{{Role: "system", Content: "sys"}}
+	for range 20 {
+		msgs = append(msgs,
+			llm.Message{Role: "user", Content: strings.Repeat("q", 400)},
+			llm.Message{Role: "assistant", Content: strings.Repeat("a", 400)},
+		)
+	}
+	msgs = append(msgs, llm.Message{Role: "user", Content: "now"})
+
+	w := &contextWindow{budget: 1_000_000, turnStart: len(msgs) - 1}
+	res, fitted, err := w.chat(t.Context(), nil, client, msgs, nil)
+	if err != nil || res.Content != "ok" {
+		t.Fatalf("res=%+v err=%v", res, err)
+	}
+	if calls.Load() != 2 || sizes[1] >= sizes[0] {
+		t.Fatalf("calls=%d sizes=%v", calls.Load(), sizes)
+	}
+	if fitted[len(fitted)-1].Content != "now" || len(fitted) >= len(msgs) {
+		t.Fatalf("fitted len=%d", len(fitted))
+	}
+
+	// A second context error in the same turn is returned as is.
+	calls.Store(0)
+	if _, _, err := w.chat(t.Context(), nil, client, fitted, nil); err == nil || !llm.IsContextLengthError(err) {
+		t.Fatalf("expected context length error, got %v", err)
+	}
+}
@@ -0,0 +1,254 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+)
+
+// Queue modes for messages arriving while their session is busy.
+const (
+	queueModeCollect   = "collect"
+	queueModeInterrupt = "interrupt"
+)
+
+// errTurnInterrupted is the cancellation cause of a turn superseded by a
+// newer message in "interrupt" queue mode.
+var errTurnInterrupted = errors.New("turn interrupted by a newer message")
+
+func checkQueueMode(cfg *config.Config) (string, error) {
+	mode := cfg.Agents.Defaults.QueueModeValue()
+	switch mode {
+	case queueModeCollect, queueModeInterrupt:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown agents.defaults.queueMode: %s (expected %s or %s)", mode, queueModeCollect, queueModeInterrupt)
+	}
+}
+
+// dispatcher runs inbound messages one session at a time: turns of different
+// sessions run in parallel (at most cap(slots) at once), while messages for a
+// busy session wait and are then answered in a single turn. Direct turns
+// (API, heartbeat, cron) take the same session locks and worker slots.
+type dispatcher struct {
+	loop  *Loop
+	slots chan struct{}
+
+	mu     sync.Mutex
+	queues map[string]*sessionQueue
+	locks  map[string]*sessionLock
+	wg     sync.WaitGroup
+}
+
+type sessionQueue struct {
+	pending []bus.InboundMessage
+}
+
+// sessionLock is held by the turn running in a session; refs counts the
+// holder and waiters so the lock is dropped once nobody needs it.
+type sessionLock struct {
+	held chan struct{}
+	refs int
+}
+
+func newDispatcher(l *Loop, workers int) *dispatcher {
+	return &dispatcher{
+		loop:   l,
+		slots:  make(chan struct{}, max(1, workers)),
+		queues: map[string]*sessionQueue{},
+		locks:  map[string]*sessionLock{},
+	}
+}
+
+// acquire waits until no other turn runs in session key and a worker slot
+// is free. The returned func releases both.
+func (d *dispatcher) acquire(ctx context.Context, key string) (func(), error) {
+	d.mu.Lock()
+	lk, ok := d.locks[key]
+	if !ok {
+		lk = &sessionLock{held: make(chan struct{}, 1)}
+		d.locks[key] = lk
+	}
+	lk.refs++
+	d.mu.Unlock()
+	unref := func() {
+		d.mu.Lock()
+		if lk.refs--; lk.refs == 0 {
+			delete(d.locks, key)
+		}
+		d.mu.Unlock()
+	}
+
+	select {
+	case lk.held <- struct{}{}:
+	case <-ctx.Done():
+		unref()
+		return nil, ctx.Err()
+	}
+	select {
+	case d.slots <- struct{}{}:
+	case <-ctx.Done():
+		<-lk.held
+		unref()
+		return nil, ctx.Err()
+	}
+	return func() {
+		<-d.slots
+		<-lk.held
+		unref()
+	}, nil
+}
+
+// enqueue schedules msg for its session, starting a worker for the session
+// if none is running.
+func (d *dispatcher) enqueue(ctx context.Context, key string, msg bus.InboundMessage) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if q, ok := d.queues[key]; ok {
+		q.pending = append(q.pending, msg)
+		if d.loop.queueMode == queueModeInterrupt && !d.loop.isBarrier(msg) {
+			d.loop.cancelTurn(key, errTurnInterrupted)
+		}
+		return
+	}
+	d.queues[key] = &sessionQueue{pending: []bus.InboundMessage{msg}}
+	d.wg.Add(1)
+	go d.drain(ctx, key)
+}
+
+// drop discards the messages waiting for key and returns how many there were.
+func (d *dispatcher) drop(key string) int {
+	d.mu.Lock()
+	q, ok := d.queues[key]
+	if !ok {
+		d.mu.Unlock()
+		return 0
+	}
+	dropped := q.pending
+	q.pending = nil
+	d.mu.Unlock()
+	notifyDropped(dropped, errTurnStopped)
+	return len(dropped)
+}
+
+// notifyDropped reports messages that will never be answered to their OnDone.
+func notifyDropped(msgs []bus.InboundMessage, err error) {
+	for _, m := range msgs {
+		if m.OnDone != nil {
+			m.OnDone("", err)
+		}
+	}
+}
+
+// drain processes the queue of key until it is empty.
+func (d *dispatcher) drain(ctx context.Context, key string) {
+	defer d.wg.Done()
+	for {
+		release, err := d.acquire(ctx, key)
+		if err != nil {
+			d.mu.Lock()
+			dropped := d.queues[key].pending
+			delete(d.queues, key)
+			d.mu.Unlock()
+			notifyDropped(dropped, err)
+			return
+		}
+
+		d.mu.Lock()
+		q := d.queues[key]
+		msg, n := d.loop.coalesce(q.pending)
+		q.pending = q.pending[n:]
+		d.mu.Unlock()
+
+		var reply string
+		if n > 0 {
+			reply, err = d.loop.handleInbound(ctx, msg)
+		}
+		release()
+
+		interrupted := errors.Is(err, errTurnInterrupted)
+		if n > 0 && !interrupted && msg.OnDone != nil {
+			msg.OnDone(reply, err)
+		}
+		d.mu.Lock()
+		if interrupted {
+			// Answer the interrupted message together with the newer ones.
+			q.pending = append([]bus.InboundMessage{msg}, q.pending...)
+		}
+		if len(q.pending) == 0 || ctx.Err() != nil {
+			dropped := q.pending
+			delete(d.queues, key)
+			d.mu.Unlock()
+			notifyDropped(dropped, ctx.Err())
+			return
+		}
+		d.mu.Unlock()
+	}
+}
+
+// wait blocks until all session workers have returned.
+func (d *dispatcher) wait() {
+	d.wg.Wait()
+}
+
+// isBarrier reports whether msg must be handled on its own rather than merged
+// with neighbouring messages: chat commands, subagent announcements and
+// messages waiting for their own reply.
+func (l *Loop) isBarrier(msg bus.InboundMessage) bool {
+	if msg.Channel == "system" || msg.OnDone != nil {
+		return true
+	}
+	_, ok := l.command(msg)
+	return ok
+}
+
+// coalesce merges the leading messages of pending into one and reports how
+// many it consumed. A barrier message is only ever taken alone.
+func (l *Loop) coalesce(pending []bus.InboundMessage) (bus.InboundMessage, int) {
+	if len(pending) == 0 {
+		return bus.InboundMessage{}, 0
+	}
+	if l.isBarrier(pending[0]) {
+		return pending[0], 1
+	}
+	n := 1
+	for n < len(pending) && !l.isBarrier(pending[n]) {
+		n++
+	}
+	if n == 1 {
+		return pending[0], 1
+	}
+	// Reply to the latest message; keep every text and attachment.
+	merged := pending[n-1]
+	texts := make([]string, 0, n)
+	merged.Attachments = nil
+	for _, m := range pending[:n] {
+		if t := strings.TrimSpace(m.Content); t != "" {
+			texts = append(texts, t)
+		}
+		merged.Attachments = append(merged.Attachments, m.Attachments...)
+	}
+	merged.Content = strings.Join(texts, "\n\n")
+	return merged, n
+}
+
+// inboundSessionKey is the session a message is answered in. Subagent
+// announcements on the "system" channel go to the session they came from.
+func inboundSessionKey(msg bus.InboundMessage) string {
+	if msg.Channel == "system" {
+		originCh, originChat := parseOrigin(msg.ChatID)
+		if originCh == "" || originChat == "" {
+			return "cli:" + msg.ChatID
+		}
+		return originCh + ":" + originChat
+	}
+	if strings.TrimSpace(msg.SessionKey) != "" {
+		return msg.SessionKey
+	}
+	return msg.Channel + ":" + msg.ChatID
+}
@@ -0,0 +1,257 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/session"
+)
+
+func TestCoalesce(t *testing.T) {
+	l := &Loop{cfg: &config.Config{}}
+	pending := []bus.InboundMessage{
+		{Channel: "telegram", ChatID: "1", Content: "first"},
+		{Channel: "telegram", ChatID: "1", Content: "second", Attachments: []bus.Attachment{{Name: "a.png"}}, Delivery: bus.Delivery{MessageID: "m2"}},
+		{Channel: "telegram", ChatID: "1", Content: "/status"},
+		{Channel: "telegram", ChatID: "1", Content: "third"},
+	}
+	msg, n := l.coalesce(pending)
+	if n != 2 || msg.Content != "first\n\nsecond" || len(msg.Attachments) != 1 || msg.Delivery.MessageID != "m2" {
+		t.Fatalf("n=%d msg=%+v", n, msg)
+	}
+	msg, n = l.coalesce(pending[2:])
+	if n != 1 || msg.Content != "/status" {
+		t.Fatalf("n=%d msg=%+v", n, msg)
+	}
+}
+
+// blockingLLM is a chat completions server whose requests wait for release.
+type blockingLLM struct {
+	srv      *httptest.Server
+	started  chan string // last user message of each request
+	release  chan struct{}
+	canceled chan struct{}
+}
+
+func newBlockingLLM(t *testing.T) *blockingLLM {
+	b := &blockingLLM{
+		started:  make(chan string, 16),
+		release:  make(chan struct{}, 16),
+		canceled: make(chan struct{}, 16),
+	}
+	b.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []struct {
+				Role    string `json:"role"`
+				Content any    `json:"content"`
+			} `json:"messages"`
+		}
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &req)
+		last, _ := req.Messages[len(req.Messages)-1].Content.(string)
+		b.started <- last
+		select {
+		case <-b.release:
+		case <-r.Context().Done():
+			b.canceled <- struct{}{}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`)
+	}))
+	t.Cleanup(b.srv.Close)
+	return b
+}
+
+func (b *blockingLLM) next(t *testing.T) string {
+	t.Helper()
+	select {
+	case s := <-b.started:
+		return s
+	case <-time.After(5 * time.Second):
+		t.Fatal("no LLM request")
+		return ""
+	}
+}
+
+func runTestLoop(t *testing.T, l *Loop) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		_ = l.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func publish(t *testing.T, l *Loop, chatID, text string) {
+	t.Helper()
+	if err := l.bus.PublishInbound(t.Context(), bus.InboundMessage{Channel: "telegram", ChatID: chatID, SenderID: "7", Content: text}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func waitPending(t *testing.T, l *Loop, key string, n int) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		l.dispatch.mu.Lock()
+		q := l.dispatch.queues[key]
+		got := q != nil && len(q.pending) == n
+		l.dispatch.mu.Unlock()
+		if got {
+			return
+		}
+	}
+	t.Fatalf("%s: %d messages never queued", key, n)
+}
+
+func TestLoopRun_SessionsRunInParallel(t *testing.T) {
+	llmSrv := newBlockingLLM(t)
+	l := newTestLoop(t, llmSrv.srv, nil)
+	runTestLoop(t, l)
+
+	publish(t, l, "a", "from a")
+	publish(t, l, "b", "from b")
+	// Both turns are in flight before either is released.
+	got := map[string]bool{llmSrv.next(t): true, llmSrv.next(t): true}
+	if !got["from a"] || !got["from b"] {
+		t.Fatalf("requests=%v", got)
+	}
+	llmSrv.release <- struct{}{}
+	llmSrv.release <- struct{}{}
+}
+
+func TestLoopRun_CollectsMessagesOfBusySession(t *testing.T) {
+	llmSrv := newBlockingLLM(t)
+	l := newTestLoop(t, llmSrv.srv, nil)
+	runTestLoop(t, l)
+
+	publish(t, l, "a", "one")
+	if got := llmSrv.next(t); got != "one" {
+		t.Fatalf("first=%q", got)
+	}
+	publish(t, l, "a", "two")
+	publish(t, l, "a", "three")
+	waitPending(t, l, "telegram:a", 2)
+	llmSrv.release <- struct{}{}
+	if got := llmSrv.next(t); got != "two\n\nthree" {
+		t.Fatalf("second=%q", got)
+	}
+	llmSrv.release <- struct{}{}
+}
+
+func TestLoopRun_InterruptRestartsWithPendingMessages(t *testing.T) {
+	llmSrv := newBlockingLLM(t)
+	l := newTestLoop(t, llmSrv.srv, func(cfg *config.Config) {
+		cfg.Agents.Defaults.QueueMode = "interrupt"
+	})
+	runTestLoop(t, l)
+
+	publish(t, l, "a", "one")
+	llmSrv.next(t)
+	publish(t, l, "a", "actually, two")
+	select {
+	case <-llmSrv.canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("turn was not interrupted")
+	}
+	if got := llmSrv.next(t); got != "one\n\nactually, two" {
+		t.Fatalf("restarted with %q", got)
+	}
+	llmSrv.release <- struct{}{}
+}
+
+func TestNewLoop_RejectsUnknownQueueMode(t *testing.T) {
+	_, err := NewLoop(LoopOptions{
+		Config:       &config.Config{Agents: config.AgentsConfig{Defaults: config.AgentDefaultsConfig{QueueMode: "drop"}}},
+		WorkspaceDir: t.TempDir(),
+		Bus:          bus.New(1),
+		Sessions:     session.NewManager(session.NewJSONLStore(t.TempDir()), 0),
+	})
+	if err == nil || !strings.Contains(err.Error(), "queueMode") {
+		t.Fatalf("err=%v", err)
+	}
+}
+
+func TestLoopRun_OnDoneReceivesReply(t *testing.T) {
+	llmSrv := newBlockingLLM(t)
+	l := newTestLoop(t, llmSrv.srv, func(cfg *config.Config) {
+		streaming := false
+		cfg.Agents.Defaults.Streaming = &streaming
+	})
+	runTestLoop(t, l)
+
+	type result struct {
+		reply string
+		err   error
+	}
+	done := make(chan result, 2)
+	onDone := func(reply string, err error) { done <- result{reply, err} }
+	publish(t, l, "a", "first")
+	llmSrv.next(t)
+	// Queued behind "first", but answered on its own.
+	for _, text := range []string{"scheduled", "later"} {
+		msg := bus.InboundMessage{Channel: "telegram", ChatID: "a", SenderID: "cron:1", Content: text}
+		if text == "scheduled" {
+			msg.OnDone = onDone
+		}
+		if err := l.bus.PublishInbound(t.Context(), msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitPending(t, l, "telegram:a", 2)
+	llmSrv.release <- struct{}{}
+	if got := llmSrv.next(t); got != "scheduled" {
+		t.Fatalf("second=%q", got)
+	}
+	llmSrv.release <- struct{}{}
+	select {
+	case r := <-done:
+		if r.err != nil || r.reply != "ok" {
+			t.Fatalf("OnDone(%q, %v)", r.reply, r.err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnDone not called")
+	}
+	llmSrv.next(t)
+	llmSrv.release <- struct{}{}
+}
+
+func TestProcessDirect_WaitsForBusySession(t *testing.T) {
+	llmSrv := newBlockingLLM(t)
+	l := newTestLoop(t, llmSrv.srv, nil)
+	runTestLoop(t, l)
+
+	publish(t, l, "a", "from chat")
+	llmSrv.next(t)
+	done := make(chan error, 1)
+	go func() {
+		_, err := l.ProcessDirect(t.Context(), "from api", "telegram:a", "api", "a")
+		done <- err
+	}()
+	select {
+	case got := <-llmSrv.started:
+		t.Fatalf("direct turn %q ran alongside the chat turn", got)
+	case <-time.After(100 * time.Millisecond):
+	}
+	llmSrv.release <- struct{}{}
+	if got := llmSrv.next(t); got != "from api" {
+		t.Fatalf("second=%q", got)
+	}
+	llmSrv.release <- struct{}{}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,47 @@
+package agent
+
+import (
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/llm"
+)
+
+// newLLMClient builds the chat client for the routed primary model, with the
+// configured fallback chain and retry policy.
+func newLLMClient(cfg *config.Config, model string, onAttempts func([]llm.Attempt)) *llm.Client {
+	primary := cfg.LLM
+	primary.Model = model
+	return newRoutedLLMClient(cfg, primary, onAttempts)
+}
+
+// newRoutedLLMClient is like newLLMClient but talks to the endpoint primary,
+// e.g. a model picked with /model.
+func newRoutedLLMClient(cfg *config.Config, primary config.LLMConfig, onAttempts func([]llm.Attempt)) *llm.Client {
+	defaults := cfg.Agents.Defaults
+	c := &llm.Client{
+		Provider:    primary.Provider,
+		BaseURL:     primary.BaseURL,
+		APIKey:      primary.APIKey,
+		Model:       primary.Model,
+		MaxTokens:   defaults.MaxTokensValue(),
+		Temperature: defaults.Temperature,
+		Headers:     primary.Headers,
+		Retry: llm.RetryPolicy{
+			MaxAttempts:    defaults.Retry.MaxAttemptsValue(),
+			InitialBackoff: defaults.Retry.InitialBackoffValue(),
+			MaxBackoff:     defaults.Retry.MaxBackoffValue(),
+		},
+		OnAttempts: onAttempts,
+	}
+	for _, fb := range cfg.FallbackLLMs() {
+		c.Fallbacks = append(c.Fallbacks, &llm.Client{
+			Provider:    fb.Provider,
+			BaseURL:     fb.BaseURL,
+			APIKey:      fb.APIKey,
+			Model:       fb.Model,
+			MaxTokens:   c.MaxTokens,
+			Temperature: c.Temperature,
+			Headers:     fb.Headers,
+		})
+	}
+	return c
+}
@@ -2,6 +2,7 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -13,8 +14,10 @@ import (
 	"github.com/mosaxiv/clawlet/config"
 	"github.com/mosaxiv/clawlet/cron"
 	"github.com/mosaxiv/clawlet/llm"
+	"github.com/mosaxiv/clawlet/mcp"
 	"github.com/mosaxiv/clawlet/media"
 	"github.com/mosaxiv/clawlet/memory"
+	"github.com/mosaxiv/clawlet/paths"
 	"github.com/mosaxiv/clawlet/session"
 	"github.com/mosaxiv/clawlet/skills"
 	"github.com/mosaxiv/clawlet/tools"
@@ -27,18 +30,26 @@ type Loop struct {
 	maxIters     int
 	memoryWindow int
 
-	bus      *bus.Bus
-	sessions *session.Manager
-	skills   *skills.Loader
+	bus        *bus.Bus
+	sessions   *session.Manager
+	transcript *session.TranscriptPolicy
+	skills     *skills.Loader
+	archiveDir string
 
-	llm   *llm.Client
-	tools *tools.Registry
+	llm           *llm.Client
+	onLLMAttempts func([]llm.Attempt)
+	modelClients  sync.Map // routed model -> *llm.Client, for /model
+	tools         *tools.Registry
 
-	cron *cron.Service
+	cron     *cron.Service
+	memIndex *memory.IndexManager
 
 	verbose bool
 
 	consolidationInFlight sync.Map
+	turns                 sync.Map // session key -> *activeTurn
+	dispatch              *dispatcher
+	queueMode             string
 }
 
 type LoopOptions struct {
@@ -52,6 +63,15 @@ type LoopOptions struct {
 	Cron         *cron.Service
 	Spawn        func(ctx context.Context, task, label, originChannel, originChatID string) (string, error)
 	Verbose      bool
+	// OnLLMAttempts receives the provider attempts made for each LLM request.
+	OnLLMAttempts func([]llm.Attempt)
+	// Tools are registered alongside the built-in tools.
+	Tools []tools.Tool
+	// OnToolCall is called after every tool execution.
+	OnToolCall func(tools.CallInfo)
+	// SessionsArchiveDir receives sessions reset with /new. Default:
+	// ~/.clawlet/sessions/archive.
+	SessionsArchiveDir string
 }
 
 func NewLoop(opts LoopOptions) (*Loop, error) {
@@ -86,14 +106,19 @@ func NewLoop(opts LoopOptions) (*Loop, error) {
 		sloader = skills.New(ws)
 	}
 
-	client := &llm.Client{
-		Provider:    opts.Config.LLM.Provider,
-		BaseURL:     opts.Config.LLM.BaseURL,
-		APIKey:      opts.Config.LLM.APIKey,
-		Model:       model,
-		MaxTokens:   opts.Config.Agents.Defaults.MaxTokensValue(),
-		Temperature: opts.Config.Agents.Defaults.Temperature,
-		Headers:     opts.Config.LLM.Headers,
+	transcript, err := newTranscriptPolicy(opts.Config)
+	if err != nil {
+		return nil, err
+	}
+	queueMode, err := checkQueueMode(opts.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	client := newLLMClient(opts.Config, model, opts.OnLLMAttempts)
+	archiveDir := opts.SessionsArchiveDir
+	if strings.TrimSpace(archiveDir) == "" {
+		archiveDir = paths.SessionsArchiveDir()
 	}
 
 	treg := &tools.Registry{
@@ -104,8 +129,11 @@ func NewLoop(opts LoopOptions) (*Loop, error) {
 		Outbound: func(ctx context.Context, msg bus.OutboundMessage) error {
 			return opts.Bus.PublishOutbound(ctx, msg)
 		},
-		Spawn: opts.Spawn,
-		Cron:  opts.Cron,
+		Spawn:        opts.Spawn,
+		Cron:         opts.Cron,
+		CronTimezone: opts.Config.Cron.DefaultTimezone,
+		MCP:          mcp.NewManager(opts.Config.Tools.MCP.Servers),
+		OnCall:       opts.OnToolCall,
 		ReadSkill: func(name string) (string, bool) {
 			if sloader == nil {
 				return "", false
@@ -113,26 +141,38 @@ func NewLoop(opts LoopOptions) (*Loop, error) {
 			return sloader.Load(name)
 		},
 	}
+	for _, t := range opts.Tools {
+		if err := treg.Register(t); err != nil {
+			return nil, err
+		}
+	}
 	memMgr, err := memory.NewIndexManager(opts.Config, ws)
 	if err != nil {
 		return nil, err
 	}
 	treg.MemorySearch = memMgr
 
-	return &Loop{
-		cfg:          opts.Config,
-		workspace:    ws,
-		model:        model,
-		maxIters:     opts.MaxIters,
-		memoryWindow: memoryWindow,
-		bus:          opts.Bus,
-		sessions:     smgr,
-		skills:       sloader,
-		llm:          client,
-		tools:        treg,
-		cron:         opts.Cron,
-		verbose:      opts.Verbose,
-	}, nil
+	l := &Loop{
+		cfg:           opts.Config,
+		workspace:     ws,
+		model:         model,
+		maxIters:      opts.MaxIters,
+		memoryWindow:  memoryWindow,
+		bus:           opts.Bus,
+		sessions:      smgr,
+		transcript:    transcript,
+		skills:        sloader,
+		archiveDir:    archiveDir,
+		llm:           client,
+		onLLMAttempts: opts.OnLLMAttempts,
+		tools:         treg,
+		cron:          opts.Cron,
+		memIndex:      memMgr,
+		verbose:       opts.Verbose,
+		queueMode:     queueMode,
+	}
+	l.dispatch = newDispatcher(l, opts.Config.Agents.Defaults.MaxConcurrentTurnsValue())
+	return l, nil
 }
 
 func (l *Loop) SetSpawn(fn func(ctx context.Context, task, label, originChannel, originChatID string) (string, error)) {
@@ -142,50 +182,103 @@ func (l *Loop) SetSpawn(fn func(ctx context.Context, task, label, originChannel,
 	l.tools.Spawn = fn
 }
 
+// Close stops MCP servers started for this loop.
+func (l *Loop) Close() error {
+	if l == nil || l.tools == nil {
+		return nil
+	}
+	return l.tools.MCP.Close()
+}
+
 func (l *Loop) Run(ctx context.Context) error {
+	defer l.dispatch.wait()
+	if l.memIndex != nil && l.cfg.Agents.Defaults.MemorySearch.Sync.WatchValue() {
+		watchCtx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_ = l.memIndex.Watch(watchCtx)
+		}()
+		defer func() {
+			cancel()
+			<-done
+		}()
+	}
 	for {
 		msg, err := l.bus.ConsumeInbound(ctx)
 		if err != nil {
 			return err
 		}
-		out, omsg, err := l.processInbound(ctx, msg)
-		_ = out
-		if err != nil {
-			// Best-effort error reply
-			if omsg.Channel != "" && omsg.ChatID != "" {
-				omsg.Content = "error: " + err.Error()
-				_ = l.bus.PublishOutbound(ctx, omsg)
-			}
+		// /stop must not wait behind the turn it cancels.
+		if cmd, ok := l.command(msg); ok && cmd.name == "stop" {
+			_, _ = l.handleInbound(ctx, msg)
 			continue
 		}
-		if omsg.Channel != "" && omsg.ChatID != "" && strings.TrimSpace(omsg.Content) != "" {
+		key := inboundSessionKey(msg)
+		l.noteMemoryUser(key, msg)
+		l.dispatch.enqueue(ctx, key, msg)
+	}
+}
+
+// handleInbound answers msg on its channel. It returns the reply and the
+// error of the turn, after any error reply was sent.
+func (l *Loop) handleInbound(ctx context.Context, msg bus.InboundMessage) (string, error) {
+	reply, omsg, err := l.processInbound(ctx, msg)
+	if errors.Is(err, errTurnStopped) || errors.Is(err, errTurnInterrupted) {
+		// Nothing to answer; only close a partially streamed reply.
+		if omsg.StreamID != "" {
+			omsg.Content = "(stopped)"
+			_ = l.bus.PublishOutbound(ctx, omsg)
+		}
+		return "", err
+	}
+	if err != nil {
+		// Best-effort error reply
+		if omsg.Channel != "" && omsg.ChatID != "" {
+			omsg.Content = "error: " + err.Error()
 			_ = l.bus.PublishOutbound(ctx, omsg)
 		}
+		return "", err
+	}
+	if omsg.Channel != "" && omsg.ChatID != "" && strings.TrimSpace(omsg.Content) != "" {
+		_ = l.bus.PublishOutbound(ctx, omsg)
 	}
+	return reply, nil
 }
 
 func (l *Loop) ProcessDirect(ctx context.Context, content, sessionKey, channel, chatID string) (string, error) {
+	return l.ProcessDirectStream(ctx, content, sessionKey, channel, chatID, nil)
+}
+
+// ProcessDirectStream is like ProcessDirect but reports assistant text deltas
+// to onDelta as they are generated. The turn waits for any other turn of the
+// session, and for a worker slot, like messages from the bus do.
+func (l *Loop) ProcessDirectStream(ctx context.Context, content, sessionKey, channel, chatID string, onDelta func(string)) (string, error) {
+	release, err := l.dispatch.acquire(ctx, sessionKey)
+	if err != nil {
+		return "", err
+	}
+	defer release()
 	userText := strings.TrimSpace(content)
-	return l.processDirect(ctx, llm.Message{Role: "user", Content: content}, userText, sessionKey, channel, chatID)
+	return l.processDirect(ctx, llm.Message{Role: "user", Content: content}, userText, sessionKey, channel, chatID, onDelta)
 }
 
 func (l *Loop) processInbound(ctx context.Context, msg bus.InboundMessage) (string, bus.OutboundMessage, error) {
+	sessionKey := inboundSessionKey(msg)
 	// System message is used by subagents to announce back to origin.
 	if msg.Channel == "system" {
-		originCh, originChat := parseOrigin(msg.ChatID)
-		if originCh == "" || originChat == "" {
-			originCh = "cli"
-			originChat = msg.ChatID
-		}
 		// Route response back to origin session.
-		sk := originCh + ":" + originChat
-		res, err := l.processDirect(ctx, llm.Message{Role: "user", Content: msg.Content}, msg.Content, sk, originCh, originChat)
-		return res, bus.OutboundMessage{Channel: originCh, ChatID: originChat, Content: res}, err
+		originCh, originChat := parseOrigin(sessionKey)
+		omsg := bus.OutboundMessage{Channel: originCh, ChatID: originChat}
+		onDelta := l.streamTo(ctx, &omsg)
+		res, err := l.processDirect(ctx, llm.Message{Role: "user", Content: msg.Content}, msg.Content, sessionKey, originCh, originChat, onDelta)
+		omsg.Content = res
+		return res, omsg, err
 	}
 
-	sessionKey := msg.SessionKey
-	if strings.TrimSpace(sessionKey) == "" {
-		sessionKey = msg.Channel + ":" + msg.ChatID
+	if cmd, ok := l.command(msg); ok {
+		reply := l.runCommand(ctx, msg, sessionKey, cmd)
+		return reply, bus.OutboundMessage{Channel: msg.Channel, ChatID: msg.ChatID, Content: reply, Delivery: msg.Delivery}, nil
 	}
 	userInput, err := media.PrepareInbound(ctx, l.llm, l.cfg.Tools.Media, msg)
 	if err != nil {
@@ -195,49 +288,72 @@ func (l *Loop) processInbound(ctx context.Context, msg bus.InboundMessage) (stri
 	if sessionText == "" {
 		sessionText = strings.TrimSpace(msg.Content)
 	}
-	res, err := l.processDirect(ctx, userInput.UserMessage, sessionText, sessionKey, msg.Channel, msg.ChatID)
-	return res, bus.OutboundMessage{
+	omsg := bus.OutboundMessage{
 		Channel:  msg.Channel,
 		ChatID:   msg.ChatID,
-		Content:  res,
 		Delivery: msg.Delivery,
-	}, err
+	}
+	onDelta := l.streamTo(ctx, &omsg)
+	res, err := l.processDirect(ctx, userInput.UserMessage, sessionText, sessionKey, msg.Channel, msg.ChatID, onDelta)
+	omsg.Content = res
+	return res, omsg, err
+}
+
+// streamTo sets up progressive delivery of the reply described by omsg and
+// returns the delta callback, or nil when streaming is disabled.
+func (l *Loop) streamTo(ctx context.Context, omsg *bus.OutboundMessage) func(string) {
+	if !l.cfg.Agents.Defaults.StreamingValue() || omsg.Channel == "" || omsg.ChatID == "" {
+		return nil
+	}
+	pub := newStreamPublisher(ctx, l.bus, *omsg)
+	omsg.StreamID = pub.StreamID()
+	return pub.Delta
 }
 
-func (l *Loop) processDirect(ctx context.Context, userMessage llm.Message, sessionUserText, sessionKey, channel, chatID string) (string, error) {
+func (l *Loop) processDirect(ctx context.Context, userMessage llm.Message, sessionUserText, sessionKey, channel, chatID string, onDelta func(string)) (string, error) {
 	sess, err := l.sessions.GetOrCreate(sessionKey)
 	if err != nil {
 		return "", err
 	}
-	l.scheduleConsolidation(sessionKey, sess)
+	mem := l.memoryView(sess, channel)
+	l.scheduleConsolidation(sessionKey, sess, mem)
+	client, model := l.clientFor(sess)
+
+	ctx, endTurn := l.startTurn(ctx, sessionKey)
+	defer endTurn()
 
 	history := sess.History(l.memoryWindow)
 	messages := make([]llm.Message, 0, 1+len(history)+1)
-	system := l.buildSystemPrompt(channel, chatID)
+	system := l.buildSystemPrompt(channel, chatID, mem)
 	messages = append(messages, llm.Message{Role: "system", Content: system})
-	for _, m := range history {
-		messages = append(messages, llm.Message{Role: m.Role, Content: m.Content})
-	}
+	messages = append(messages, historyMessages(history)...)
 	messages = append(messages, userMessage)
 
 	toolsDefs := l.tools.Definitions()
 
+	stream := newTurnStream(onDelta)
+	window := newContextWindow(contextBudget(l.cfg, model), len(messages)-1, toolsDefs)
 	var final string
 	toolsUsed := make([]string, 0, 8)
 	for iter := 0; iter < l.maxIters; iter++ {
-		res, err := l.llm.Chat(ctx, messages, toolsDefs)
+		res, fitted, err := window.chat(ctx, stream, client, messages, toolsDefs)
+		messages = fitted
 		if err != nil {
+			if cause := context.Cause(ctx); errors.Is(cause, errTurnStopped) || errors.Is(cause, errTurnInterrupted) {
+				return "", cause
+			}
 			return "", err
 		}
 		if res.HasToolCalls() {
 			for _, tc := range res.ToolCalls {
 				toolsUsed = append(toolsUsed, tc.Name)
 			}
-			messages = appendToolRound(messages, res.Content, res.ToolCalls, func(tc llm.ToolCall) string {
+			messages = appendToolRound(messages, res.Content, res.ToolCalls, newToolRoundOptions(l.cfg, l.tools), func(tc llm.ToolCall) string {
 				out, err := l.tools.Execute(ctx, tools.Context{
 					Channel:    channel,
 					ChatID:     chatID,
 					SessionKey: sessionKey,
+					Memory:     &mem,
 				}, tc.Name, tc.Arguments)
 				if err != nil {
 					return "error: " + err.Error()
@@ -253,13 +369,12 @@ func (l *Loop) processDirect(ctx context.Context, userMessage llm.Message, sessi
 		final = "(no response)"
 	}
 
-	sess.Add("user", sessionUserText)
-	sess.AddWithTools("assistant", final, toolsUsed)
+	sess.AddMessages(turnTranscript(sessionUserText, window.turn(messages), final, toolsUsed, l.transcript))
 	_ = l.sessions.Save(sess)
 	return final, nil
 }
 
-func (l *Loop) scheduleConsolidation(sessionKey string, sess *session.Session) {
+func (l *Loop) scheduleConsolidation(sessionKey string, sess *session.Session, mem memory.View) {
 	if l == nil || sess == nil {
 		return
 	}
@@ -275,7 +390,7 @@ func (l *Loop) scheduleConsolidation(sessionKey string, sess *session.Session) {
 		cctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 		defer cancel()
 
-		done, err := maybeConsolidateSession(cctx, l.workspace, sess, l.memoryWindow, func(ctx context.Context, currentMemory, conversation string) (string, string, error) {
+		done, err := maybeConsolidateSession(cctx, mem, sess, l.memoryWindow, func(ctx context.Context, currentMemory, conversation string) (string, []memory.FactChange, error) {
 			return summarizeConsolidationWithLLM(ctx, l.llm, currentMemory, conversation)
 		})
 		if err != nil {
@@ -293,7 +408,16 @@ func (l *Loop) scheduleConsolidation(sessionKey string, sess *session.Session) {
 	}()
 }
 
-func (l *Loop) buildSystemPrompt(channel, chatID string) string {
+// now is the current time in cron.defaultTimezone, the user's zone, when set.
+func (l *Loop) now() time.Time {
+	loc, err := cron.LoadLocation(l.cfg.Cron.DefaultTimezone)
+	if err != nil {
+		loc = time.Local
+	}
+	return time.Now().In(loc)
+}
+
+func (l *Loop) buildSystemPrompt(channel, chatID string, mem memory.View) string {
 	// Keep it simple and deterministic. Add progressive skill summary.
 	var b strings.Builder
 	b.WriteString("# clawlet\n\n")
@@ -302,7 +426,7 @@ func (l *Loop) buildSystemPrompt(channel, chatID string) string {
 	b.WriteString("IMPORTANT: When replying to the current conversation, respond with plain text. Do not call the message tool.\n")
 	b.WriteString("Only use the message tool when you must send to a different channel/chat_id.\n\n")
 	b.WriteString("## Current Time\n")
-	b.WriteString(time.Now().Format("2006-01-02 15:04 (Mon)") + "\n\n")
+	b.WriteString(l.now().Format("2006-01-02 15:04 (Mon) MST") + "\n\n")
 	b.WriteString("## Workspace\n")
 	b.WriteString(l.workspace + "\n\n")
 	if l.cfg.Tools.RestrictToWorkspaceValue() {
@@ -327,10 +451,9 @@ func (l *Loop) buildSystemPrompt(channel, chatID string) string {
 	}
 
 	// Memory (long-term + today's notes)
-	mem := memory.New(l.workspace).GetContext()
-	if strings.TrimSpace(mem) != "" {
+	if memText := mem.Context(); strings.TrimSpace(memText) != "" {
 		b.WriteString("# Memory\n\n")
-		b.WriteString(mem)
+		b.WriteString(memText)
 		b.WriteString("\n\n")
 	}
 
@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"strings"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/memory"
+	"github.com/mosaxiv/clawlet/session"
+)
+
+// memoryUserMetaKey is the session metadata key holding the person a session
+// talks to ("channel:senderID"), or multipleMemoryUsers once a second person
+// has written in it.
+const (
+	memoryUserMetaKey   = "memoryUser"
+	multipleMemoryUsers = "*"
+)
+
+// noteMemoryUser records the sender of msg on its session. Only direct
+// messages name a user: a group chat or group DM, or a session with several
+// senders, gets no user memory scope, so facts about one member are never
+// shown to the others. Messages clawlet sends itself (subagent
+// announcements, cron turns) say nothing about who the chat is with.
+func (l *Loop) noteMemoryUser(sessionKey string, msg bus.InboundMessage) {
+	if msg.Channel == "system" || msg.OnDone != nil || strings.HasPrefix(msg.SenderID, "cron:") {
+		return
+	}
+	if !l.cfg.Agents.Defaults.MemoryScopes.EnabledValue() {
+		return
+	}
+	sess, err := l.sessions.GetOrCreate(sessionKey)
+	if err != nil {
+		return
+	}
+	id, _, _ := strings.Cut(msg.SenderID, "|")
+	id = strings.TrimSpace(id)
+	if !msg.Delivery.IsDirect || msg.Delivery.IsGroupDM {
+		// The user key is the same in a DM and a group, so a group must
+		// never take the scope of whoever spoke first.
+		if sess.MetaString(memoryUserMetaKey) != multipleMemoryUsers {
+			sess.SetMeta(memoryUserMetaKey, multipleMemoryUsers)
+		}
+		return
+	}
+	if id == "" {
+		return
+	}
+	user := msg.Channel + ":" + id
+	switch sess.MetaString(memoryUserMetaKey) {
+	case user, multipleMemoryUsers:
+	case "":
+		sess.SetMeta(memoryUserMetaKey, user)
+	default:
+		sess.SetMeta(memoryUserMetaKey, multipleMemoryUsers)
+	}
+}
+
+// memoryView is the long-term memory sess sees on channel.
+func (l *Loop) memoryView(sess *session.Session, channel string) memory.View {
+	user := sess.MetaString(memoryUserMetaKey)
+	if user == multipleMemoryUsers {
+		user = ""
+	}
+	return memory.ViewFor(l.cfg.Agents.Defaults.MemoryScopes, l.workspace, channel, user, sess.Key)
+}
@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/memory"
+)
+
+func TestLoopMemoryView_GroupChatsGetNoUserScope(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	l := newTestLoop(t, srv, func(cfg *config.Config) {
+		enabled := true
+		cfg.Agents.Defaults.MemoryScopes.Enabled = &enabled
+	})
+	view := func(key string) memory.View {
+		sess, err := l.sessions.GetOrCreate(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return l.memoryView(sess, "telegram")
+	}
+
+	direct := bus.Delivery{IsDirect: true}
+	l.noteMemoryUser("telegram:1", bus.InboundMessage{Channel: "telegram", ChatID: "1", SenderID: "1|alice", Delivery: direct})
+	l.noteMemoryUser("telegram:1", bus.InboundMessage{Channel: "telegram", ChatID: "1", SenderID: "1", Delivery: direct})
+	dm := view("telegram:1")
+	if len(dm.Scopes) != 3 || dm.Scopes[1] != (memory.Scope{Kind: memory.ScopeUser, Key: "telegram:1"}) {
+		t.Fatalf("dm view=%+v", dm)
+	}
+
+	// The first message in a group already gives it no user scope.
+	l.noteMemoryUser("telegram:-100", bus.InboundMessage{Channel: "telegram", ChatID: "-100", SenderID: "1|alice"})
+	group := view("telegram:-100")
+	if len(group.Scopes) != 2 || group.Default != memory.ScopeChat {
+		t.Fatalf("group view=%+v", group)
+	}
+
+	// Cron turns delivered to the DM don't change whom it is with.
+	l.noteMemoryUser("telegram:1", bus.InboundMessage{Channel: "telegram", ChatID: "1", SenderID: "cron:job1", OnDone: func(string, error) {}})
+	if got := view("telegram:1"); len(got.Scopes) != 3 || got.Scopes[1].Kind != memory.ScopeUser {
+		t.Fatalf("dm view after cron=%+v", got)
+	}
+
+	// A Slack group DM gets no user scope from its first message either.
+	l.noteMemoryUser("slack:G2", bus.InboundMessage{Channel: "slack", ChatID: "G2", SenderID: "U1", Delivery: bus.Delivery{IsDirect: true, IsGroupDM: true}})
+	if sess, _ := l.sessions.GetOrCreate("slack:G2"); l.memoryView(sess, "slack").Scopes[1].Kind != memory.ScopeChat {
+		t.Fatal("group DM has a user scope")
+	}
+
+	// Several senders in a direct session drop it too.
+	l.noteMemoryUser("slack:G1", bus.InboundMessage{Channel: "slack", ChatID: "G1", SenderID: "U1", Delivery: direct})
+	l.noteMemoryUser("slack:G1", bus.InboundMessage{Channel: "slack", ChatID: "G1", SenderID: "U2", Delivery: direct})
+	if sess, _ := l.sessions.GetOrCreate("slack:G1"); l.memoryView(sess, "slack").Scopes[1].Kind != memory.ScopeChat {
+		t.Fatal("group DM has a user scope")
+	}
+
+	// Consolidating the DM writes facts about Alice to her scope only.
+	sess, _ := l.sessions.GetOrCreate("telegram:1")
+	for range 15 {
+		sess.Add("user", "question")
+		sess.Add("assistant", "answer")
+	}
+	summarize := func(ctx context.Context, currentMemory, conversation string) (string, []memory.FactChange, error) {
+		if !strings.Contains(currentMemory, "## Scope: user (telegram:1)") {
+			t.Fatalf("currentMemory=%s", currentMemory)
+		}
+		return "", []memory.FactChange{{Text: "Alice is allergic to nuts"}}, nil
+	}
+	if done, err := maybeConsolidateSession(context.Background(), dm, sess, 20, summarize); err != nil || !done {
+		t.Fatalf("done=%v err=%v", done, err)
+	}
+	if !strings.Contains(l.buildSystemPrompt("telegram", "1", dm), "allergic to nuts") {
+		t.Fatal("DM prompt misses Alice's memory")
+	}
+	if strings.Contains(l.buildSystemPrompt("telegram", "-100", group), "allergic to nuts") {
+		t.Fatal("group prompt shows Alice's memory")
+	}
+	if !strings.Contains(memory.ForScope(l.workspace, dm.Scopes[1]).ReadLongTerm(), "[u1] Alice is allergic to nuts") {
+		t.Fatal("fact not in user scope")
+	}
+}
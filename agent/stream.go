@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/llm"
+)
+
+// streamEditInterval throttles partial updates so chat platforms aren't hit
+// with one edit per token.
+const streamEditInterval = time.Second
+
+// turnStream forwards assistant text deltas of a turn to onDelta. Text from
+// consecutive LLM rounds (e.g. before and after tool calls) is separated by a
+// blank line.
+type turnStream struct {
+	onDelta func(string)
+	wrote   bool
+	pending bool
+}
+
+func newTurnStream(onDelta func(string)) *turnStream {
+	if onDelta == nil {
+		return nil
+	}
+	return &turnStream{onDelta: onDelta}
+}
+
+func (s *turnStream) chat(ctx context.Context, client *llm.Client, messages []llm.Message, defs []llm.ToolDefinition) (*llm.ChatResult, error) {
+	if s == nil {
+		return client.Chat(ctx, messages, defs)
+	}
+	if s.wrote {
+		s.pending = true
+		s.wrote = false
+	}
+	return client.ChatStream(ctx, messages, defs, func(ev llm.StreamEvent) {
+		if ev.Delta == "" {
+			return
+		}
+		if s.pending {
+			s.onDelta("\n\n")
+			s.pending = false
+		}
+		s.wrote = true
+		s.onDelta(ev.Delta)
+	})
+}
+
+// streamPublisher accumulates deltas and publishes them as partial outbound
+// messages. The final reply is published separately with the same StreamID.
+type streamPublisher struct {
+	ctx      context.Context
+	bus      *bus.Bus
+	base     bus.OutboundMessage
+	interval time.Duration
+
+	mu       sync.Mutex
+	text     strings.Builder
+	lastSent time.Time
+}
+
+func newStreamPublisher(ctx context.Context, b *bus.Bus, base bus.OutboundMessage) *streamPublisher {
+	base.StreamID = randID()
+	return &streamPublisher{
+		ctx:      ctx,
+		bus:      b,
+		base:     base,
+		interval: streamEditInterval,
+	}
+}
+
+func (p *streamPublisher) Delta(s string) {
+	p.mu.Lock()
+	p.text.WriteString(s)
+	if time.Since(p.lastSent) < p.interval {
+		p.mu.Unlock()
+		return
+	}
+	content := p.text.String()
+	p.lastSent = time.Now()
+	p.mu.Unlock()
+
+	if strings.TrimSpace(content) == "" {
+		return
+	}
+	msg := p.base
+	msg.Content = content
+	msg.Partial = true
+	_ = p.bus.PublishOutbound(p.ctx, msg)
+}
+
+func (p *streamPublisher) StreamID() string {
+	if p == nil {
+		return ""
+	}
+	return p.base.StreamID
+}
@@ -91,13 +91,15 @@ func (m *SubagentManager) runSubagent(ctx context.Context, task string) (string,
 
 	const maxIters = 15
 	var final string
+	window := newContextWindow(contextBudget(l.cfg, l.model), len(messages)-1, toolsDefs)
 	for range maxIters {
-		res, err := l.llm.Chat(ctx, messages, toolsDefs)
+		res, fitted, err := window.chat(ctx, nil, l.llm, messages, toolsDefs)
+		messages = fitted
 		if err != nil {
 			return "", err
 		}
 		if res.HasToolCalls() {
-			messages = appendToolRound(messages, res.Content, res.ToolCalls, func(tc llm.ToolCall) string {
+			messages = appendToolRound(messages, res.Content, res.ToolCalls, newToolRoundOptions(l.cfg, treg), func(tc llm.ToolCall) string {
 				out, err := treg.Execute(ctx, tools.Context{
 					Channel:    "cli",
 					ChatID:     "subagent",
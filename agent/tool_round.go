@@ -1,11 +1,36 @@
 package agent
 
-import "github.com/mosaxiv/clawlet/llm"
+import (
+	"slices"
+	"sync"
+
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/llm"
+	"github.com/mosaxiv/clawlet/tools"
+)
+
+// toolRoundOptions controls how the tool calls of one round are executed.
+type toolRoundOptions struct {
+	// concurrency caps parallel calls; <= 1 runs them sequentially.
+	concurrency int
+	// serialKey groups calls that must not overlap (see tools.SerialTool).
+	serialKey func(tc llm.ToolCall) string
+}
+
+func newToolRoundOptions(cfg *config.Config, reg *tools.Registry) toolRoundOptions {
+	return toolRoundOptions{
+		concurrency: cfg.Agents.Defaults.ToolConcurrencyValue(),
+		serialKey: func(tc llm.ToolCall) string {
+			return reg.SerialKey(tc.Name, tc.Arguments)
+		},
+	}
+}
 
 func appendToolRound(
 	messages []llm.Message,
 	assistantContent string,
 	toolCalls []llm.ToolCall,
+	opts toolRoundOptions,
 	exec func(tc llm.ToolCall) string,
 ) []llm.Message {
 	if len(toolCalls) == 0 {
@@ -25,15 +50,64 @@ func appendToolRound(
 	}
 	messages = append(messages, llm.Message{Role: "assistant", Content: assistantContent, ToolCalls: tcs})
 
-	for _, tc := range toolCalls {
-		out := exec(tc)
+	outs := runToolCalls(toolCalls, opts, exec)
+	for i, tc := range toolCalls {
 		messages = append(messages, llm.Message{
 			Role:       "tool",
 			ToolCallID: tc.ID,
 			Name:       tc.Name,
-			Content:    out,
+			Content:    outs[i],
 		})
 	}
 
 	return append(messages, llm.Message{Role: "user", Content: "Reflect on the results and decide next steps."})
 }
+
+// runToolCalls executes calls with up to opts.concurrency in flight and returns
+// their outputs in call order. Calls sharing a serial key form a chain that
+// runs sequentially in call order; a tools.SerialAll call makes the whole
+// round sequential.
+func runToolCalls(toolCalls []llm.ToolCall, opts toolRoundOptions, exec func(tc llm.ToolCall) string) []string {
+	outs := make([]string, len(toolCalls))
+	keys := make([]string, len(toolCalls))
+	if opts.serialKey != nil && opts.concurrency > 1 {
+		for i, tc := range toolCalls {
+			keys[i] = opts.serialKey(tc)
+		}
+	}
+	if opts.concurrency <= 1 || len(toolCalls) == 1 || slices.Contains(keys, tools.SerialAll) {
+		for i, tc := range toolCalls {
+			outs[i] = exec(tc)
+		}
+		return outs
+	}
+
+	chains := make([][]int, 0, len(toolCalls))
+	byKey := map[string]int{}
+	for i, key := range keys {
+		if key == "" {
+			chains = append(chains, []int{i})
+			continue
+		}
+		if c, ok := byKey[key]; ok {
+			chains[c] = append(chains[c], i)
+			continue
+		}
+		byKey[key] = len(chains)
+		chains = append(chains, []int{i})
+	}
+
+	sem := make(chan struct{}, opts.concurrency)
+	var wg sync.WaitGroup
+	for _, chain := range chains {
+		wg.Go(func() {
+			for _, i := range chain {
+				sem <- struct{}{}
+				outs[i] = exec(toolCalls[i])
+				<-sem
+			}
+		})
+	}
+	wg.Wait()
+	return outs
+}
@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/llm"
+	"github.com/mosaxiv/clawlet/tools"
+)
+
+func TestAppendToolRound_ParallelKeepsCallOrder(t *testing.T) {
+	calls := []llm.ToolCall{
+		{ID: "1", Name: "web_fetch", Arguments: json.RawMessage(`{"delay":30}`)},
+		{ID: "2", Name: "web_fetch", Arguments: json.RawMessage(`{"delay":0}`)},
+		{ID: "3", Name: "web_fetch", Arguments: json.RawMessage(`{"delay":10}`)},
+	}
+	var inFlight, peak atomic.Int32
+	msgs := appendToolRound(nil, "", calls, toolRoundOptions{concurrency: 4}, func(tc llm.ToolCall) string {
+		n := inFlight.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		var a struct{ Delay int }
+		_ = json.Unmarshal(tc.Arguments, &a)
+		time.Sleep(time.Duration(a.Delay) * time.Millisecond)
+		inFlight.Add(-1)
+		return "out" + tc.ID
+	})
+
+	if len(msgs) != 5 {
+		t.Fatalf("messages=%d", len(msgs))
+	}
+	for i, id := range []string{"1", "2", "3"} {
+		m := msgs[1+i]
+		if m.ToolCallID != id || m.Content != "out"+id {
+			t.Fatalf("message %d = %+v", i, m)
+		}
+	}
+	if peak.Load() < 2 {
+		t.Fatalf("expected parallel execution, peak=%d", peak.Load())
+	}
+}
+
+func TestRunToolCalls_RespectsLimitAndSerialKeys(t *testing.T) {
+	calls := []llm.ToolCall{
+		{ID: "1", Name: "write_file", Arguments: json.RawMessage(`{"path":"a"}`)},
+		{ID: "2", Name: "read_file"},
+		{ID: "3", Name: "write_file", Arguments: json.RawMessage(`{"path":"a"}`)},
+		{ID: "4", Name: "read_file"},
+		{ID: "5", Name: "write_file", Arguments: json.RawMessage(`{"path":"a"}`)},
+	}
+	var mu sync.Mutex
+	var writes []string
+	var inFlight, peak atomic.Int32
+	outs := runToolCalls(calls, toolRoundOptions{
+		concurrency: 2,
+		serialKey: func(tc llm.ToolCall) string {
+			if tc.Name == "write_file" {
+				return "file:a"
+			}
+			return ""
+		},
+	}, func(tc llm.ToolCall) string {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		if tc.Name == "write_file" {
+			mu.Lock()
+			writes = append(writes, tc.ID)
+			mu.Unlock()
+		}
+		return tc.ID
+	})
+
+	for i, out := range outs {
+		if out != calls[i].ID {
+			t.Fatalf("outs=%v", outs)
+		}
+	}
+	if peak.Load() > 2 {
+		t.Fatalf("concurrency limit exceeded: %d", peak.Load())
+	}
+	if len(writes) != 3 || writes[0] != "1" || writes[1] != "3" || writes[2] != "5" {
+		t.Fatalf("serialized writes out of order: %v", writes)
+	}
+}
+
+func TestRunToolCalls_SerialAllRunsRoundInOrder(t *testing.T) {
+	calls := []llm.ToolCall{
+		{ID: "1", Name: "write_file", Arguments: json.RawMessage(`{"path":"a"}`)},
+		{ID: "2", Name: "exec"},
+		{ID: "3", Name: "read_file", Arguments: json.RawMessage(`{"path":"b"}`)},
+	}
+	var mu sync.Mutex
+	var order []string
+	var inFlight, peak atomic.Int32
+	runToolCalls(calls, toolRoundOptions{
+		concurrency: 4,
+		serialKey: func(tc llm.ToolCall) string {
+			if tc.Name == "exec" {
+				return tools.SerialAll
+			}
+			return "file:" + string(tc.Arguments)
+		},
+	}, func(tc llm.ToolCall) string {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		if n > peak.Load() {
+			peak.Store(n)
+		}
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		order = append(order, tc.ID)
+		mu.Unlock()
+		return tc.ID
+	})
+
+	if peak.Load() != 1 {
+		t.Fatalf("expected sequential execution, peak=%d", peak.Load())
+	}
+	if len(order) != 3 || order[0] != "1" || order[1] != "2" || order[2] != "3" {
+		t.Fatalf("order=%v", order)
+	}
+}
@@ -0,0 +1,75 @@
+package agent
+
+import (
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/llm"
+	"github.com/mosaxiv/clawlet/session"
+)
+
+// newTranscriptPolicy returns the policy for persisting tool rounds, or nil
+// when only user and final assistant messages are kept.
+func newTranscriptPolicy(cfg *config.Config) (*session.TranscriptPolicy, error) {
+	tc := cfg.Sessions.Transcript
+	if !tc.ToolCallsValue() {
+		return nil, nil
+	}
+	return session.NewTranscriptPolicy(tc.MaxArgsCharsValue(), tc.MaxResultCharsValue(), tc.Redact)
+}
+
+// historyMessages converts session history into prompt messages, replaying
+// persisted tool rounds as assistant tool calls and tool results.
+func historyMessages(history []session.Message) []llm.Message {
+	out := make([]llm.Message, 0, len(history))
+	for _, m := range history {
+		msg := llm.Message{
+			Role:       m.Role,
+			Content:    m.Content,
+			ToolCallID: m.ToolCallID,
+			Name:       m.Name,
+		}
+		for _, tc := range m.ToolCalls {
+			msg.ToolCalls = append(msg.ToolCalls, llm.ToolCallPayload{
+				ID:   tc.ID,
+				Type: "function",
+				Function: llm.ToolCallPayloadFunc{
+					Name:      tc.Name,
+					Arguments: tc.Arguments,
+				},
+			})
+		}
+		out = append(out, msg)
+	}
+	return out
+}
+
+// turnTranscript returns the session messages recorded for one turn. turn
+// holds the prompt messages that followed the user message; its tool rounds
+// are only kept when policy is non-nil.
+func turnTranscript(userText string, turn []llm.Message, final string, toolsUsed []string, policy *session.TranscriptPolicy) []session.Message {
+	out := []session.Message{{Role: "user", Content: userText}}
+	if policy != nil {
+		for _, m := range turn {
+			switch {
+			case m.Role == "assistant" && len(m.ToolCalls) > 0:
+				calls := make([]session.ToolCall, 0, len(m.ToolCalls))
+				for _, tc := range m.ToolCalls {
+					calls = append(calls, session.ToolCall{
+						ID:        tc.ID,
+						Name:      tc.Function.Name,
+						Arguments: tc.Function.Arguments,
+					})
+				}
+				out = append(out, policy.Apply(session.Message{Role: "assistant", Content: m.Content, ToolCalls: calls}))
+			case m.Role == "tool":
+				out = append(out, policy.Apply(session.Message{Role: "tool", Content: m.Content, ToolCallID: m.ToolCallID, Name: m.Name}))
+			}
+		}
+	}
+	last := session.Message{Role: "assistant", Content: final}
+	for _, name := range toolsUsed {
+		if name != "" {
+			last.ToolsUsed = append(last.ToolsUsed, name)
+		}
+	}
+	return append(out, last)
+}
@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mosaxiv/clawlet/llm"
+	"github.com/mosaxiv/clawlet/session"
+)
+
+func TestTurnTranscript_RoundTripsToolRounds(t *testing.T) {
+	turn := appendToolRound(nil, "checking", []llm.ToolCall{
+		{ID: "c1", Name: "read_file", Arguments: []byte(`{"path":"a.txt"}`)},
+	}, toolRoundOptions{concurrency: 1}, func(tc llm.ToolCall) string {
+		return "token=supersecretvalue"
+	})
+
+	policy, err := session.NewTranscriptPolicy(2000, 4000, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := turnTranscript("read a.txt", turn, "done", []string{"read_file"}, policy)
+	if len(got) != 4 {
+		t.Fatalf("transcript=%+v", got)
+	}
+	if got[1].Role != "assistant" || got[1].ToolCalls[0].Arguments != `{"path":"a.txt"}` {
+		t.Fatalf("assistant call=%+v", got[1])
+	}
+	if got[2].Role != "tool" || got[2].ToolCallID != "c1" || got[2].Content != "token=[REDACTED]" {
+		t.Fatalf("tool result=%+v", got[2])
+	}
+	if got[3].Content != "done" || strings.Join(got[3].ToolsUsed, ",") != "read_file" {
+		t.Fatalf("final=%+v", got[3])
+	}
+
+	msgs := historyMessages(got)
+	if len(msgs) != 4 || len(msgs[1].ToolCalls) != 1 {
+		t.Fatalf("history=%+v", msgs)
+	}
+	if tc := msgs[1].ToolCalls[0]; tc.ID != "c1" || tc.Type != "function" || tc.Function.Name != "read_file" {
+		t.Fatalf("tool call=%+v", tc)
+	}
+	if msgs[2].ToolCallID != "c1" || msgs[2].Name != "read_file" {
+		t.Fatalf("tool message=%+v", msgs[2])
+	}
+}
+
+func TestTurnTranscript_WithoutPolicyKeepsOnlyFinal(t *testing.T) {
+	turn := appendToolRound(nil, "", []llm.ToolCall{{ID: "c1", Name: "exec"}}, toolRoundOptions{concurrency: 1}, func(llm.ToolCall) string {
+		return "out"
+	})
+	got := turnTranscript("run it", turn, "ok", []string{"exec"}, nil)
+	if len(got) != 2 || got[0].Content != "run it" || got[1].Content != "ok" || len(got[1].ToolCalls) != 0 {
+		t.Fatalf("transcript=%+v", got)
+	}
+}
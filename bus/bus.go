@@ -10,6 +10,9 @@ type Delivery struct {
 	ReplyToID string
 	ThreadID  string
 	IsDirect  bool
+	// IsGroupDM marks a direct conversation with several people (Slack
+	// group DMs): threaded like a DM, but not private to one user.
+	IsGroupDM bool
 }
 
 type Attachment struct {
@@ -46,6 +49,11 @@ type InboundMessage struct {
 	Attachments []Attachment
 	SessionKey  string // usually "channel:chat_id"
 	Delivery    Delivery
+
+	// OnDone, if set, is called with the reply once the message has been
+	// answered, or with an error if it failed or was dropped. Such messages
+	// are always answered on their own, never merged with others.
+	OnDone func(reply string, err error)
 }
 
 type OutboundMessage struct {
@@ -54,6 +62,11 @@ type OutboundMessage struct {
 	Content  string
 	ReplyTo  string
 	Delivery Delivery
+
+	// StreamID groups progressive updates of a single reply. Each Partial
+	// message carries the full text so far; the final message has Partial=false.
+	StreamID string
+	Partial  bool
 }
 
 type Bus struct {
@@ -29,8 +29,13 @@ type Channel struct {
 	dg  *discordgo.Session
 	hc  *http.Client
 	ctx context.Context
+
+	streams channels.StreamTracker
 }
 
+// discordMaxMessageRunes is the API limit for a single message content.
+const discordMaxMessageRunes = 2000
+
 func New(cfg config.DiscordConfig, b *bus.Bus) *Channel {
 	return &Channel{
 		cfg:   cfg,
@@ -44,6 +49,7 @@ func New(cfg config.DiscordConfig, b *bus.Bus) *Channel {
 
 func (c *Channel) Name() string    { return "discord" }
 func (c *Channel) IsRunning() bool { return c.running.Load() }
+func (c *Channel) Streaming() bool { return true }
 
 func (c *Channel) Start(ctx context.Context) error {
 	if strings.TrimSpace(c.cfg.Token) == "" {
@@ -123,6 +129,33 @@ func (c *Channel) Send(ctx context.Context, msg bus.OutboundMessage) error {
 	}
 
 	replyToID := resolveDiscordReplyTarget(msg)
+	if msg.StreamID != "" && msg.Partial {
+		return c.sendPartial(dg, chID, msg.StreamID, content, replyToID)
+	}
+
+	chunks := channels.SplitText(content, discordMaxMessageRunes)
+	if msg.StreamID != "" {
+		if id, ok := c.streams.Take(msg.StreamID); ok {
+			if _, err := dg.ChannelMessageEdit(chID, id, chunks[0]); err == nil {
+				chunks, replyToID = chunks[1:], ""
+			} else {
+				// Send the reply afresh, without leaving the partial
+				// placeholder behind as a truncated duplicate.
+				_ = dg.ChannelMessageDelete(chID, id)
+			}
+		}
+	}
+	for _, chunk := range chunks {
+		if err := c.sendWithRetry(ctx, dg, chID, chunk, replyToID); err != nil {
+			return err
+		}
+		replyToID = ""
+	}
+	return nil
+}
+
+// sendWithRetry posts one message, retrying rate limits and server errors.
+func (c *Channel) sendWithRetry(ctx context.Context, dg *discordgo.Session, chID, content, replyToID string) error {
 	const maxAttempts = 3
 	for attempt := 1; attempt <= maxAttempts; attempt++ {
 		err := sendDiscordMessage(dg, chID, content, replyToID)
@@ -233,12 +266,31 @@ func buildDiscordDelivery(m *discordgo.MessageCreate) bus.Delivery {
 	return d
 }
 
+// sendPartial posts the placeholder for a stream or edits it with the text so far.
+func (c *Channel) sendPartial(dg *discordgo.Session, chID, streamID, content, replyToID string) error {
+	content = channels.ClampStreamText(content, discordMaxMessageRunes)
+	if id, ok := c.streams.Get(streamID); ok {
+		_, err := dg.ChannelMessageEdit(chID, id, content)
+		return err
+	}
+	m, err := postDiscordMessage(dg, chID, content, replyToID)
+	if err != nil {
+		return err
+	}
+	c.streams.Set(streamID, m.ID)
+	return nil
+}
+
 func sendDiscordMessage(dg *discordgo.Session, chID, content, replyToID string) error {
+	_, err := postDiscordMessage(dg, chID, content, replyToID)
+	return err
+}
+
+func postDiscordMessage(dg *discordgo.Session, chID, content, replyToID string) (*discordgo.Message, error) {
 	if replyToID == "" {
-		_, err := dg.ChannelMessageSend(chID, content)
-		return err
+		return dg.ChannelMessageSend(chID, content)
 	}
-	_, err := dg.ChannelMessageSendComplex(chID, &discordgo.MessageSend{
+	return dg.ChannelMessageSendComplex(chID, &discordgo.MessageSend{
 		Content: content,
 		Reference: &discordgo.MessageReference{
 			MessageID: replyToID,
@@ -248,7 +300,6 @@ func sendDiscordMessage(dg *discordgo.Session, chID, content, replyToID string)
 			RepliedUser: false,
 		},
 	})
-	return err
 }
 
 func shouldRetryDiscordSend(err error, attempt int) (bool, time.Duration) {
@@ -119,6 +119,10 @@ func (m *Manager) dispatchOutbound(ctx context.Context) {
 			// Unknown channel; drop.
 			continue
 		}
+		if msg.Partial && !supportsStreaming(ch) {
+			// Only the final message is delivered to channels that can't edit.
+			continue
+		}
 		if err := ch.Send(ctx, msg); err != nil && !errors.Is(err, context.Canceled) {
 			m.setChannelError(msg.Channel, err.Error())
 			log.Printf("channels: outbound send failed via %s: %v", msg.Channel, err)
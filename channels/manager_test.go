@@ -3,6 +3,8 @@ package channels
 import (
 	"context"
 	"errors"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -94,3 +96,88 @@ func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
 	}
 	t.Fatal("condition not met in time")
 }
+
+type recordingChannel struct {
+	stubChannel
+	streaming bool
+
+	mu   sync.Mutex
+	sent []bus.OutboundMessage
+}
+
+func (r *recordingChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, msg)
+	return nil
+}
+
+func (r *recordingChannel) Streaming() bool { return r.streaming }
+
+func (r *recordingChannel) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.sent)
+}
+
+func TestManagerDispatchOutbound_PartialOnlyForStreamingChannels(t *testing.T) {
+	b := bus.New(16)
+	m := NewManager(b)
+	plain := &recordingChannel{stubChannel: stubChannel{name: "plain"}}
+	live := &recordingChannel{stubChannel: stubChannel{name: "live"}, streaming: true}
+	m.Add(plain)
+	m.Add(live)
+
+	ctx := t.Context()
+	if err := m.StartAll(ctx); err != nil {
+		t.Fatalf("StartAll returned error: %v", err)
+	}
+	for _, name := range []string{"plain", "live"} {
+		_ = b.PublishOutbound(ctx, bus.OutboundMessage{Channel: name, ChatID: "c1", Content: "hel", StreamID: "s1", Partial: true})
+		_ = b.PublishOutbound(ctx, bus.OutboundMessage{Channel: name, ChatID: "c1", Content: "hello", StreamID: "s1"})
+	}
+
+	waitFor(t, 600*time.Millisecond, func() bool {
+		return plain.count() == 1 && live.count() == 2
+	})
+}
+
+func TestStreamTracker(t *testing.T) {
+	var tr StreamTracker
+	if _, ok := tr.Get("s1"); ok {
+		t.Fatal("expected empty tracker")
+	}
+	tr.Set("s1", "m1")
+	if id, ok := tr.Get("s1"); !ok || id != "m1" {
+		t.Fatalf("get=%q ok=%v", id, ok)
+	}
+	if id, ok := tr.Take("s1"); !ok || id != "m1" {
+		t.Fatalf("take=%q ok=%v", id, ok)
+	}
+	if _, ok := tr.Get("s1"); ok {
+		t.Fatal("expected stream to be forgotten after Take")
+	}
+}
+
+func TestClampStreamText(t *testing.T) {
+	if got := ClampStreamText("hello", 10); got != "hello" {
+		t.Fatalf("got=%q", got)
+	}
+	if got := ClampStreamText("hello world", 6); got != "…world" {
+		t.Fatalf("got=%q", got)
+	}
+}
+
+func TestSplitText(t *testing.T) {
+	if got := SplitText("short", 10); len(got) != 1 || got[0] != "short" {
+		t.Fatalf("got %q", got)
+	}
+	got := SplitText("first line\nsecond line here", 16)
+	if len(got) != 2 || got[0] != "first line" || got[1] != "second line here" {
+		t.Fatalf("got %q", got)
+	}
+	got = SplitText(strings.Repeat("é", 25), 10)
+	if len(got) != 3 || got[0] != strings.Repeat("é", 10) || got[2] != strings.Repeat("é", 5) {
+		t.Fatalf("got %q", got)
+	}
+}
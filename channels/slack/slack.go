@@ -31,6 +31,8 @@ type Channel struct {
 
 	botUserID string
 	cancel    context.CancelFunc
+
+	streams channels.StreamTracker
 }
 
 func New(cfg config.SlackConfig, b *bus.Bus) *Channel {
@@ -45,6 +47,7 @@ func New(cfg config.SlackConfig, b *bus.Bus) *Channel {
 
 func (c *Channel) Name() string    { return "slack" }
 func (c *Channel) IsRunning() bool { return c.running.Load() }
+func (c *Channel) Streaming() bool { return true }
 
 func (c *Channel) Start(ctx context.Context) error {
 	if strings.TrimSpace(c.cfg.BotToken) == "" {
@@ -175,6 +178,21 @@ func (c *Channel) Send(ctx context.Context, msg bus.OutboundMessage) error {
 		c.mu.Unlock()
 	}
 
+	if msg.StreamID != "" {
+		if ts, ok := c.streams.Get(msg.StreamID); ok {
+			if !msg.Partial {
+				c.streams.Take(msg.StreamID)
+			}
+			_, _, _, err := api.UpdateMessageContext(ctx, ch, ts, slack.MsgOptionText(text, false))
+			if err == nil || msg.Partial {
+				return err
+			}
+			// Send the reply afresh, without leaving the partial
+			// placeholder behind as a truncated duplicate.
+			_, _, _ = api.DeleteMessageContext(ctx, ch, ts)
+		}
+	}
+
 	threadTS, direct := slackThreadMeta(msg)
 	opts := []slack.MsgOption{
 		slack.MsgOptionText(text, false),
@@ -183,7 +201,10 @@ func (c *Channel) Send(ctx context.Context, msg bus.OutboundMessage) error {
 	if threadTS != "" && !direct {
 		opts = append(opts, slack.MsgOptionTS(threadTS))
 	}
-	_, _, err := api.PostMessageContext(ctx, ch, opts...)
+	_, ts, err := api.PostMessageContext(ctx, ch, opts...)
+	if err == nil && msg.Partial {
+		c.streams.Set(msg.StreamID, ts)
+	}
 	return err
 }
 
@@ -372,5 +393,6 @@ func buildSlackDelivery(ts, threadTS, channelType string) bus.Delivery {
 		MessageID: ts,
 		ThreadID:  threadTS,
 		IsDirect:  channelType == "im" || channelType == "mpim",
+		IsGroupDM: channelType == "mpim",
 	}
 }
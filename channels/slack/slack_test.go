@@ -107,7 +107,14 @@ func TestBuildSlackDelivery(t *testing.T) {
 
 	t.Run("direct_chat", func(t *testing.T) {
 		d := buildSlackDelivery("1740000000.400", "1740000000.401", "im")
-		if !d.IsDirect || d.ThreadID != "1740000000.401" {
+		if !d.IsDirect || d.IsGroupDM || d.ThreadID != "1740000000.401" {
+			t.Fatalf("unexpected delivery: %+v", d)
+		}
+	})
+
+	t.Run("group_dm", func(t *testing.T) {
+		d := buildSlackDelivery("1740000000.500", "", "mpim")
+		if !d.IsDirect || !d.IsGroupDM {
 			t.Fatalf("unexpected delivery: %+v", d)
 		}
 	})
@@ -0,0 +1,88 @@
+package channels
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// StreamingChannel is implemented by channels that can progressively edit a
+// placeholder message while a reply is generated. Partial outbound messages
+// are dropped for channels that don't implement it.
+type StreamingChannel interface {
+	Channel
+	Streaming() bool
+}
+
+func supportsStreaming(ch Channel) bool {
+	sc, ok := ch.(StreamingChannel)
+	return ok && sc.Streaming()
+}
+
+// StreamTracker remembers which platform message carries each in-flight
+// stream, so partial updates edit it instead of posting new messages.
+// The zero value is ready to use.
+type StreamTracker struct {
+	mu      sync.Mutex
+	streams map[string]string
+}
+
+func (t *StreamTracker) Get(streamID string) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	id, ok := t.streams[streamID]
+	return id, ok
+}
+
+func (t *StreamTracker) Set(streamID, messageID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.streams == nil {
+		t.streams = map[string]string{}
+	}
+	t.streams[streamID] = messageID
+}
+
+// Take returns the message for streamID and forgets it.
+func (t *StreamTracker) Take(streamID string) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	id, ok := t.streams[streamID]
+	delete(t.streams, streamID)
+	return id, ok
+}
+
+// ClampStreamText keeps the tail of a partial reply within a platform's
+// message length limit.
+func ClampStreamText(text string, limit int) string {
+	r := []rune(text)
+	if limit <= 0 || len(r) <= limit {
+		return text
+	}
+	return "…" + string(r[len(r)-limit+1:])
+}
+
+// SplitText splits a final reply into messages of at most limit runes,
+// preferring to break at a newline, then at a space.
+func SplitText(text string, limit int) []string {
+	var out []string
+	for {
+		runes := []rune(text)
+		if len(runes) <= limit {
+			return append(out, text)
+		}
+		cut := limit
+		head := string(runes[:limit])
+		if i := strings.LastIndex(head, "\n"); i > 0 {
+			cut = utf8.RuneCountInString(head[:i])
+		} else if i := strings.LastIndex(head, " "); i > 0 {
+			cut = utf8.RuneCountInString(head[:i])
+		}
+		out = append(out, strings.TrimRightFunc(string(runes[:cut]), unicode.IsSpace))
+		text = strings.TrimLeftFunc(string(runes[cut:]), unicode.IsSpace)
+		if text == "" {
+			return out
+		}
+	}
+}
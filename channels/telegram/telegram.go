@@ -19,6 +19,9 @@ import (
 	"github.com/mosaxiv/clawlet/config"
 )
 
+// telegramMaxMessageRunes is the Bot API limit for a single message text.
+const telegramMaxMessageRunes = 4096
+
 type Channel struct {
 	cfg   config.TelegramConfig
 	bus   *bus.Bus
@@ -32,6 +35,8 @@ type Channel struct {
 	mu     sync.Mutex
 	bot    *tgbot.Bot
 	cancel context.CancelFunc
+
+	streams channels.StreamTracker
 }
 
 func New(cfg config.TelegramConfig, b *bus.Bus) *Channel {
@@ -46,6 +51,7 @@ func New(cfg config.TelegramConfig, b *bus.Bus) *Channel {
 
 func (c *Channel) Name() string    { return "telegram" }
 func (c *Channel) IsRunning() bool { return c.running.Load() }
+func (c *Channel) Streaming() bool { return true }
 
 func (c *Channel) Start(ctx context.Context) error {
 	token := strings.TrimSpace(c.cfg.Token)
@@ -126,12 +132,43 @@ func (c *Channel) Send(ctx context.Context, msg bus.OutboundMessage) error {
 		return fmt.Errorf("telegram not connected")
 	}
 
+	if msg.StreamID != "" && msg.Partial {
+		return c.sendPartial(ctx, b, chatIDAny, msg, text)
+	}
+
+	chunks := channels.SplitText(text, telegramMaxMessageRunes)
+	reply := true
+	if msg.StreamID != "" {
+		if id, ok := c.streams.Take(msg.StreamID); ok {
+			if err := c.editFinal(ctx, b, chatIDAny, id, chunks[0]); err == nil {
+				chunks, reply = chunks[1:], false
+			} else {
+				// Send the reply afresh, without leaving the partial
+				// placeholder behind as a truncated duplicate.
+				if messageID, err := strconv.Atoi(id); err == nil {
+					_, _ = b.DeleteMessage(ctx, &tgbot.DeleteMessageParams{ChatID: chatIDAny, MessageID: messageID})
+				}
+			}
+		}
+	}
+	for _, chunk := range chunks {
+		if err := c.sendText(ctx, b, chatIDAny, msg, chunk, reply); err != nil {
+			return err
+		}
+		reply = false
+	}
+	return nil
+}
+
+// sendText sends one message of at most telegramMaxMessageRunes, as a reply
+// to msg's target when reply is set.
+func (c *Channel) sendText(ctx context.Context, b *tgbot.Bot, chatIDAny any, msg bus.OutboundMessage, text string, reply bool) error {
 	params := &tgbot.SendMessageParams{
 		ChatID:    chatIDAny,
 		Text:      markdownToTelegramHTML(text),
 		ParseMode: models.ParseModeHTML,
 	}
-	if replyTo := resolveTelegramReplyTarget(msg); replyTo > 0 {
+	if replyTo := resolveTelegramReplyTarget(msg); reply && replyTo > 0 {
 		params.ReplyParameters = &models.ReplyParameters{
 			MessageID:                int(replyTo),
 			AllowSendingWithoutReply: true,
@@ -209,6 +246,67 @@ func (c *Channel) sendMessageWithRetry(ctx context.Context, b *tgbot.Bot, params
 	return nil
 }
 
+// sendPartial posts the placeholder for a stream or edits it with the text so
+// far. Partial text is sent without formatting since markdown may be incomplete.
+func (c *Channel) sendPartial(ctx context.Context, b *tgbot.Bot, chatIDAny any, msg bus.OutboundMessage, text string) error {
+	text = channels.ClampStreamText(text, telegramMaxMessageRunes)
+	if id, ok := c.streams.Get(msg.StreamID); ok {
+		messageID, _ := strconv.Atoi(id)
+		_, err := b.EditMessageText(ctx, &tgbot.EditMessageTextParams{
+			ChatID:    chatIDAny,
+			MessageID: messageID,
+			Text:      text,
+		})
+		if isTelegramNotModifiedError(err) {
+			return nil
+		}
+		return err
+	}
+	params := &tgbot.SendMessageParams{
+		ChatID: chatIDAny,
+		Text:   text,
+	}
+	if replyTo := resolveTelegramReplyTarget(msg); replyTo > 0 {
+		params.ReplyParameters = &models.ReplyParameters{
+			MessageID:                int(replyTo),
+			AllowSendingWithoutReply: true,
+		}
+	}
+	sent, err := b.SendMessage(ctx, params)
+	if err != nil {
+		return err
+	}
+	c.streams.Set(msg.StreamID, strconv.Itoa(sent.ID))
+	return nil
+}
+
+func (c *Channel) editFinal(ctx context.Context, b *tgbot.Bot, chatIDAny any, id, text string) error {
+	messageID, err := strconv.Atoi(id)
+	if err != nil {
+		return err
+	}
+	params := &tgbot.EditMessageTextParams{
+		ChatID:    chatIDAny,
+		MessageID: messageID,
+		Text:      markdownToTelegramHTML(text),
+		ParseMode: models.ParseModeHTML,
+	}
+	_, err = b.EditMessageText(ctx, params)
+	if err == nil || isTelegramNotModifiedError(err) {
+		return nil
+	}
+	if !isTelegramParseError(err) {
+		return err
+	}
+	params.Text = text
+	params.ParseMode = ""
+	_, err = b.EditMessageText(ctx, params)
+	if isTelegramNotModifiedError(err) {
+		return nil
+	}
+	return err
+}
+
 func (c *Channel) sendTypingHint(chatID string) {
 	chatID = strings.TrimSpace(chatID)
 	if chatID == "" {
@@ -303,6 +401,13 @@ func isTelegramParseError(err error) bool {
 		(strings.Contains(msg, "parse entities") && strings.Contains(msg, " 400 "))
 }
 
+func isTelegramNotModifiedError(err error) bool {
+	if err == nil {
+		return false
+	}
+	return strings.Contains(strings.ToLower(err.Error()), "message is not modified")
+}
+
 func telegramSendBackoff(attempt int) time.Duration {
 	if attempt < 1 {
 		attempt = 1
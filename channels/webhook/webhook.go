@@ -0,0 +1,358 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/channels"
+	"github.com/mosaxiv/clawlet/config"
+)
+
+const (
+	// SignatureHeader carries "sha256=<hex>" of HMAC-SHA256(secret, timestamp + "." + body).
+	SignatureHeader = "X-Clawlet-Signature"
+	// TimestampHeader carries the unix time (seconds) the request was signed at.
+	TimestampHeader = "X-Clawlet-Timestamp"
+
+	// maxClockSkew bounds how old (or early) a signed inbound request may be.
+	maxClockSkew = 5 * time.Minute
+
+	maxInboundBytes = 8 << 20
+
+	// outboxSize bounds the replies waiting for the callback URL.
+	outboxSize = 64
+)
+
+// InboundPayload is the JSON body accepted on the webhook endpoint.
+type InboundPayload struct {
+	Sender      string              `json:"sender"`
+	ChatID      string              `json:"chat_id"`
+	Content     string              `json:"content"`
+	Attachments []InboundAttachment `json:"attachments,omitempty"`
+}
+
+// InboundAttachment references a file by URL, or inlines it as base64 data.
+type InboundAttachment struct {
+	Name     string `json:"name,omitempty"`
+	MIMEType string `json:"mime_type,omitempty"`
+	URL      string `json:"url,omitempty"`
+	Data     []byte `json:"data,omitempty"`
+}
+
+// OutboundPayload is the JSON body POSTed to the callback URL.
+type OutboundPayload struct {
+	Channel string `json:"channel"`
+	ChatID  string `json:"chat_id"`
+	Content string `json:"content"`
+	ReplyTo string `json:"reply_to,omitempty"`
+}
+
+type Channel struct {
+	cfg   config.WebhookConfig
+	bus   *bus.Bus
+	allow channels.AllowList
+	hc    *http.Client
+
+	maxRetries int
+	now        func() time.Time
+	// outbox feeds Start, which posts replies to the callback URL one at a
+	// time, so retries never hold up the delivery of other channels.
+	outbox chan []byte
+
+	running atomic.Bool
+}
+
+func New(cfg config.WebhookConfig, b *bus.Bus) *Channel {
+	return &Channel{
+		cfg:        cfg,
+		bus:        b,
+		allow:      channels.AllowList{AllowFrom: cfg.AllowFrom},
+		hc:         &http.Client{Timeout: time.Duration(clampWebhookTimeout(cfg.TimeoutSec)) * time.Second},
+		maxRetries: clampWebhookRetries(cfg.MaxRetries),
+		now:        time.Now,
+		outbox:     make(chan []byte, outboxSize),
+	}
+}
+
+func (c *Channel) Name() string    { return "webhook" }
+func (c *Channel) IsRunning() bool { return c.running.Load() }
+
+// Path is where the inbound handler should be mounted on the gateway server.
+func (c *Channel) Path() string {
+	p := strings.TrimSpace(c.cfg.Path)
+	if p == "" {
+		return "/webhook"
+	}
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	return p
+}
+
+// Start marks the channel as running and delivers queued replies to the
+// callback URL. Inbound requests are served by the gateway HTTP server via
+// ServeHTTP; they are refused while not running.
+func (c *Channel) Start(ctx context.Context) error {
+	if strings.TrimSpace(c.cfg.Secret) == "" {
+		return fmt.Errorf("webhook secret is empty")
+	}
+	c.running.Store(true)
+	defer c.running.Store(false)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case body := <-c.outbox:
+			if err := c.deliver(ctx, body); err != nil && !errors.Is(err, context.Canceled) {
+				log.Printf("webhook: callback delivery failed: %v", err)
+			}
+		}
+	}
+}
+
+func (c *Channel) Stop() error {
+	c.running.Store(false)
+	return nil
+}
+
+func (c *Channel) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !c.running.Load() {
+		http.Error(w, "webhook channel not running", http.StatusServiceUnavailable)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxInboundBytes))
+	if err != nil {
+		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err := verifySignature(c.cfg.Secret, r.Header.Get(TimestampHeader), r.Header.Get(SignatureHeader), body, c.now()); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var in InboundPayload
+	if err := json.Unmarshal(body, &in); err != nil {
+		http.Error(w, "invalid json body", http.StatusBadRequest)
+		return
+	}
+	in.Sender = strings.TrimSpace(in.Sender)
+	in.ChatID = strings.TrimSpace(in.ChatID)
+	in.Content = strings.TrimSpace(in.Content)
+	if in.Sender == "" || in.ChatID == "" {
+		http.Error(w, "sender and chat_id are required", http.StatusBadRequest)
+		return
+	}
+	attachments := webhookInboundAttachments(in.Attachments)
+	if in.Content == "" && len(attachments) == 0 {
+		http.Error(w, "content or attachments required", http.StatusBadRequest)
+		return
+	}
+	if !c.allow.Allowed(in.Sender) {
+		http.Error(w, "sender not allowed", http.StatusForbidden)
+		return
+	}
+
+	publishCtx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+	if err := c.bus.PublishInbound(publishCtx, bus.InboundMessage{
+		Channel:     "webhook",
+		SenderID:    in.Sender,
+		ChatID:      in.ChatID,
+		Content:     in.Content,
+		Attachments: attachments,
+		SessionKey:  "webhook:" + in.ChatID,
+	}); err != nil {
+		http.Error(w, "agent busy", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_, _ = io.WriteString(w, `{"ok":true}`+"\n")
+}
+
+func webhookInboundAttachments(in []InboundAttachment) []bus.Attachment {
+	if len(in) == 0 {
+		return nil
+	}
+	out := make([]bus.Attachment, 0, len(in))
+	for _, a := range in {
+		url := strings.TrimSpace(a.URL)
+		if url == "" && len(a.Data) == 0 {
+			continue
+		}
+		mimeType := strings.TrimSpace(a.MIMEType)
+		out = append(out, bus.Attachment{
+			Name:      strings.TrimSpace(a.Name),
+			MIMEType:  mimeType,
+			Kind:      bus.InferAttachmentKind(mimeType),
+			SizeBytes: int64(len(a.Data)),
+			URL:       url,
+			Data:      a.Data,
+		})
+	}
+	return out
+}
+
+// Send queues the reply for the callback URL; Start delivers it. Without a
+// callback URL the channel is inbound-only and replies are dropped.
+func (c *Channel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	if strings.TrimSpace(c.cfg.CallbackURL) == "" {
+		return nil
+	}
+	text := strings.TrimSpace(msg.Content)
+	if text == "" {
+		return nil
+	}
+	body, err := json.Marshal(OutboundPayload{
+		Channel: "webhook",
+		ChatID:  msg.ChatID,
+		Content: text,
+		ReplyTo: msg.ReplyTo,
+	})
+	if err != nil {
+		return err
+	}
+	select {
+	case c.outbox <- body:
+		return nil
+	default:
+		return errors.New("webhook callback queue is full")
+	}
+}
+
+// deliver posts body to the callback URL, retrying network errors, 429 and
+// 5xx responses.
+func (c *Channel) deliver(ctx context.Context, body []byte) error {
+	callback := strings.TrimSpace(c.cfg.CallbackURL)
+	for attempt := 1; ; attempt++ {
+		retry, wait, err := c.post(ctx, callback, body, attempt)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt > c.maxRetries {
+			return err
+		}
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+func (c *Channel) post(ctx context.Context, url string, body []byte, attempt int) (bool, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, 0, err
+	}
+	ts := strconv.FormatInt(c.now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, ts)
+	req.Header.Set(SignatureHeader, Sign(c.cfg.Secret, ts, body))
+
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return false, 0, err
+		}
+		var netErr net.Error
+		if errors.As(err, &netErr) {
+			return true, webhookSendBackoff(attempt), err
+		}
+		return false, 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, 0, nil
+	}
+	err = fmt.Errorf("webhook callback http %d", resp.StatusCode)
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		wait := webhookSendBackoff(attempt)
+		if secs, perr := strconv.Atoi(strings.TrimSpace(resp.Header.Get("Retry-After"))); perr == nil && secs > 0 {
+			wait = min(time.Duration(secs)*time.Second, 30*time.Second)
+		}
+		return true, wait, err
+	}
+	return false, 0, err
+}
+
+// Sign returns the signature header value for body signed at timestamp ts.
+func Sign(secret, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func verifySignature(secret, ts, sig string, body []byte, now time.Time) error {
+	if strings.TrimSpace(secret) == "" {
+		return fmt.Errorf("webhook secret is not configured")
+	}
+	ts = strings.TrimSpace(ts)
+	sig = strings.TrimSpace(sig)
+	if ts == "" || sig == "" {
+		return fmt.Errorf("missing signature")
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp")
+	}
+	if d := now.Sub(time.Unix(unix, 0)); d > maxClockSkew || d < -maxClockSkew {
+		return fmt.Errorf("timestamp outside allowed window")
+	}
+	if !hmac.Equal([]byte(sig), []byte(Sign(secret, ts, body))) {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
+
+func webhookSendBackoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	return min(time.Duration(1<<(attempt-1))*time.Second, 8*time.Second)
+}
+
+func clampWebhookRetries(v int) int {
+	if v <= 0 {
+		return 3
+	}
+	if v > 10 {
+		return 10
+	}
+	return v
+}
+
+func clampWebhookTimeout(v int) int {
+	if v <= 0 {
+		return 10
+	}
+	if v > 120 {
+		return 120
+	}
+	return v
+}
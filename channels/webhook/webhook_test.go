@@ -0,0 +1,181 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/config"
+)
+
+func signedRequest(t *testing.T, secret string, ts time.Time, body string) *http.Request {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+	unix := strconv.FormatInt(ts.Unix(), 10)
+	r.Header.Set(TimestampHeader, unix)
+	r.Header.Set(SignatureHeader, Sign(secret, unix, []byte(body)))
+	return r
+}
+
+func runningChannel(cfg config.WebhookConfig, b *bus.Bus) *Channel {
+	c := New(cfg, b)
+	c.running.Store(true)
+	return c
+}
+
+func TestServeHTTP_PublishesSignedMessage(t *testing.T) {
+	b := bus.New(1)
+	c := runningChannel(config.WebhookConfig{Secret: "s3cret"}, b)
+	body := `{"sender":"ci","chat_id":"build-42","content":"build failed","attachments":[{"name":"log.txt","mime_type":"text/plain","url":"https://example.com/log.txt"}]}`
+
+	w := httptest.NewRecorder()
+	c.ServeHTTP(w, signedRequest(t, "s3cret", time.Now(), body))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+	defer cancel()
+	msg, err := b.ConsumeInbound(ctx)
+	if err != nil {
+		t.Fatalf("consume: %v", err)
+	}
+	if msg.Channel != "webhook" || msg.SenderID != "ci" || msg.ChatID != "build-42" || msg.SessionKey != "webhook:build-42" {
+		t.Fatalf("msg=%+v", msg)
+	}
+	if msg.Content != "build failed" || len(msg.Attachments) != 1 || msg.Attachments[0].Kind != "file" {
+		t.Fatalf("content=%q attachments=%+v", msg.Content, msg.Attachments)
+	}
+}
+
+func TestServeHTTP_RejectsBadSignatureAndStaleTimestamp(t *testing.T) {
+	c := runningChannel(config.WebhookConfig{Secret: "s3cret"}, bus.New(1))
+	body := `{"sender":"ci","chat_id":"1","content":"hi"}`
+
+	cases := map[string]*http.Request{
+		"wrong secret": signedRequest(t, "other", time.Now(), body),
+		"stale":        signedRequest(t, "s3cret", time.Now().Add(-10*time.Minute), body),
+		"unsigned":     httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body)),
+	}
+	for name, r := range cases {
+		w := httptest.NewRecorder()
+		c.ServeHTTP(w, r)
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("%s: status=%d", name, w.Code)
+		}
+	}
+}
+
+func TestServeHTTP_AllowList(t *testing.T) {
+	c := runningChannel(config.WebhookConfig{Secret: "s3cret", AllowFrom: []string{"ci"}}, bus.New(1))
+	w := httptest.NewRecorder()
+	c.ServeHTTP(w, signedRequest(t, "s3cret", time.Now(), `{"sender":"intruder","chat_id":"1","content":"hi"}`))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status=%d", w.Code)
+	}
+}
+
+func TestSend_SignsAndRetries(t *testing.T) {
+	var calls atomic.Int32
+	var gotBody string
+	var gotSig, gotTS string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		gotSig = r.Header.Get(SignatureHeader)
+		gotTS = r.Header.Get(TimestampHeader)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	c := New(config.WebhookConfig{Secret: "s3cret", CallbackURL: srv.URL}, bus.New(1))
+	err := c.Send(t.Context(), bus.OutboundMessage{Channel: "webhook", ChatID: "build-42", Content: "on it"})
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if err := c.deliver(t.Context(), <-c.outbox); err != nil {
+		t.Fatalf("deliver: %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("calls=%d", calls.Load())
+	}
+	if !strings.Contains(gotBody, `"chat_id":"build-42"`) || !strings.Contains(gotBody, `"content":"on it"`) {
+		t.Fatalf("body=%s", gotBody)
+	}
+	if gotSig != Sign("s3cret", gotTS, []byte(gotBody)) {
+		t.Fatalf("signature mismatch: %s", gotSig)
+	}
+}
+
+func TestSend_DoesNotRetryClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	c := New(config.WebhookConfig{Secret: "s3cret", CallbackURL: srv.URL}, bus.New(1))
+	if err := c.deliver(t.Context(), []byte(`{}`)); err == nil {
+		t.Fatal("expected error")
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("calls=%d", calls.Load())
+	}
+}
+
+func TestSend_DoesNotWaitForTheCallback(t *testing.T) {
+	release := make(chan struct{})
+	got := make(chan string, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		<-release
+		got <- string(b)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	c := New(config.WebhookConfig{Secret: "s3cret", CallbackURL: srv.URL}, bus.New(1))
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	go func() { _ = c.Start(ctx) }()
+
+	// Both replies are accepted while the callback is still stuck on the first.
+	for _, text := range []string{"one", "two"} {
+		done := make(chan error, 1)
+		go func() { done <- c.Send(ctx, bus.OutboundMessage{ChatID: "1", Content: text}) }()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("send %q blocked on the callback", text)
+		}
+	}
+	release <- struct{}{}
+	release <- struct{}{}
+	for _, want := range []string{`"content":"one"`, `"content":"two"`} {
+		select {
+		case body := <-got:
+			if !strings.Contains(body, want) {
+				t.Fatalf("body=%s want %s", body, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("reply not delivered")
+		}
+	}
+}
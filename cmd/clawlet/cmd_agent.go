@@ -34,25 +34,29 @@ func cmdAgent() *cli.Command {
 				return err
 			}
 
+			store, err := openSessionStore(cfg)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
 			a, err := agent.New(agent.Options{
-				Config:       cfg,
-				WorkspaceDir: wsAbs,
-				SessionKey:   cmd.String("session"),
-				MaxIters:     cmd.Int("max-iters"),
-				Verbose:      cmd.Bool("verbose"),
+				Config:        cfg,
+				WorkspaceDir:  wsAbs,
+				SessionKey:    cmd.String("session"),
+				SessionStore:  store,
+				MaxIters:      cmd.Int("max-iters"),
+				Verbose:       cmd.Bool("verbose"),
+				OnLLMAttempts: recordLLMAttempts,
 			})
 			if err != nil {
 				return err
 			}
+			defer a.Close()
 
 			msg := cmd.String("message")
 			if msg != "" {
-				out, err := a.Process(ctx, msg)
-				if err != nil {
-					return err
-				}
-				fmt.Println(out)
-				return nil
+				return runAgentTurn(ctx, a, msg)
 			}
 
 			in := bufio.NewScanner(os.Stdin)
@@ -70,12 +74,10 @@ func cmdAgent() *cli.Command {
 					break
 				}
 				start := time.Now()
-				out, err := a.Process(ctx, line)
-				if err != nil {
+				if err := runAgentTurn(ctx, a, line); err != nil {
 					fmt.Fprintln(os.Stderr, "error:", err)
 					continue
 				}
-				fmt.Println(out)
 				if cmd.Bool("verbose") {
 					fmt.Fprintf(os.Stderr, "(took %s)\n", time.Since(start).Truncate(time.Millisecond))
 				}
@@ -84,3 +86,23 @@ func cmdAgent() *cli.Command {
 		},
 	}
 }
+
+// runAgentTurn prints the reply as it is generated. If the final reply was not
+// (fully) streamed, e.g. "(no response)", it is printed afterwards.
+func runAgentTurn(ctx context.Context, a *agent.Agent, input string) error {
+	var streamed strings.Builder
+	out, err := a.ProcessStream(ctx, input, func(delta string) {
+		streamed.WriteString(delta)
+		fmt.Print(delta)
+	})
+	if streamed.Len() > 0 {
+		fmt.Println()
+	}
+	if err != nil {
+		return err
+	}
+	if !strings.HasSuffix(strings.TrimSpace(streamed.String()), strings.TrimSpace(out)) {
+		fmt.Println(out)
+	}
+	return nil
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -21,6 +23,7 @@ func cmdCron() *cli.Command {
 			cronRemoveCmd(),
 			cronToggleCmd(),
 			cronRunCmd(),
+			cronHistoryCmd(),
 		},
 	}
 }
@@ -41,7 +44,11 @@ func cronListCmd() *cli.Command {
 				return nil
 			}
 			for _, j := range jobs {
-				fmt.Printf("- %s id=%s enabled=%v kind=%s next=%d\n", j.Name, j.ID, j.Enabled, j.Schedule.Kind, j.State.NextRunAtMS)
+				fmt.Printf("- %s id=%s enabled=%v kind=%s%s next=%s", j.Name, j.ID, j.Enabled, j.Schedule.Kind, cronTZLabel(j.Schedule), cronNextLabel(j))
+				if j.State.LastStatus != "" {
+					fmt.Printf(" last=%s", j.State.LastStatus)
+				}
+				fmt.Println()
 			}
 			return nil
 		},
@@ -54,16 +61,23 @@ func cronAddCmd() *cli.Command {
 		Usage: "add a job",
 		Flags: []cli.Flag{
 			&cli.StringFlag{Name: "name", Usage: "job name"},
-			&cli.StringFlag{Name: "message", Usage: "message for agent", Required: true},
+			&cli.StringFlag{Name: "kind", Value: cron.PayloadAgentTurn, Usage: "agent_turn, message (post text without an LLM call), exec or webhook"},
+			&cli.StringFlag{Name: "message", Usage: "message for the agent, or the text to post"},
+			&cli.StringFlag{Name: "command", Usage: "workspace command to run (--kind exec)"},
+			&cli.StringFlag{Name: "url", Usage: "URL to POST the job to (--kind webhook)"},
 			&cli.IntFlag{Name: "every", Usage: "run every N seconds"},
-			&cli.StringFlag{Name: "cron", Usage: "cron expression (5-field)"},
-			&cli.StringFlag{Name: "at", Usage: "run once at time (RFC3339)"},
-			&cli.BoolFlag{Name: "deliver", Value: true, Usage: "deliver response to a channel"},
+			&cli.StringFlag{Name: "cron", Usage: "cron expression (5 or 6 fields, or @daily, @every 90m, ...)"},
+			&cli.StringFlag{Name: "at", Usage: "run once at time (RFC3339, or 2006-01-02T15:04 in --tz)"},
+			&cli.StringFlag{Name: "tz", Usage: "IANA timezone for --cron and --at (default: cron.defaultTimezone, else local)"},
+			&cli.BoolFlag{Name: "deliver", Value: true, Usage: "deliver the agent reply or command output to a channel"},
 			&cli.StringFlag{Name: "channel", Usage: "delivery channel (e.g. discord, slack)"},
 			&cli.StringFlag{Name: "to", Usage: "delivery chat/user id"},
+			&cli.StringFlag{Name: "misfire", Usage: "runs missed while the gateway was down: skip, run-once or run-all (default: cron.misfire)"},
+			&cli.IntFlag{Name: "retries", Value: -1, Usage: "retries after a failed run (default: cron.maxRetries)"},
+			&cli.DurationFlag{Name: "retry-backoff", Usage: "delay before the first retry, doubling after (default: cron.retryBackoffSec)"},
 		},
 		Action: func(ctx context.Context, cmd *cli.Command) error {
-			_, _, err := loadConfig()
+			cfg, _, err := loadConfig()
 			if err != nil {
 				return err
 			}
@@ -71,7 +85,7 @@ func cronAddCmd() *cli.Command {
 			message := strings.TrimSpace(cmd.String("message"))
 			jname := strings.TrimSpace(cmd.String("name"))
 			if jname == "" {
-				jname = message
+				jname = cmp.Or(message, strings.TrimSpace(cmd.String("command")), strings.TrimSpace(cmd.String("url")))
 			}
 
 			every := cmd.Int("every")
@@ -91,6 +105,14 @@ func cronAddCmd() *cli.Command {
 			if scheduleFlags != 1 {
 				return cli.Exit("exactly one of --every/--cron/--at must be set", 2)
 			}
+			tz := strings.TrimSpace(cmd.String("tz"))
+			if tz == "" {
+				tz = cfg.Cron.DefaultTimezone
+			}
+			loc, err := cron.LoadLocation(tz)
+			if err != nil {
+				return cli.Exit(err.Error(), 2)
+			}
 
 			var sched cron.Schedule
 			switch {
@@ -100,11 +122,11 @@ func cronAddCmd() *cli.Command {
 				}
 				sched = cron.Schedule{Kind: "every", EveryMS: int64(every) * 1000}
 			case cronExpr != "":
-				sched = cron.Schedule{Kind: "cron", Expr: cronExpr}
+				sched = cron.Schedule{Kind: "cron", Expr: cronExpr, TZ: tz}
 			case at != "":
-				t, err := time.Parse(time.RFC3339, at)
+				t, err := cron.ParseTime(at, loc)
 				if err != nil {
-					return err
+					return cli.Exit("invalid --at: "+err.Error(), 2)
 				}
 				sched = cron.Schedule{Kind: "at", AtMS: t.UnixMilli()}
 			}
@@ -116,15 +138,33 @@ func cronAddCmd() *cli.Command {
 			}
 
 			payload := cron.Payload{
-				Kind:    "agent_turn",
+				Kind:    strings.ToLower(strings.TrimSpace(cmd.String("kind"))),
 				Message: message,
 				Deliver: cmd.Bool("deliver"),
 				Channel: channel,
 				To:      to,
+				Command: strings.TrimSpace(cmd.String("command")),
+				URL:     strings.TrimSpace(cmd.String("url")),
+			}
+
+			job := cron.Job{
+				Name:     jname,
+				Schedule: sched,
+				Payload:  payload,
+				Misfire:  strings.ToLower(strings.TrimSpace(cmd.String("misfire"))),
+			}
+			if retries, backoff := cmd.Int("retries"), cmd.Duration("retry-backoff"); retries >= 0 || backoff > 0 {
+				job.Retry = &cron.Retry{MaxAttempts: cfg.Cron.MaxRetries, BackoffMS: cfg.Cron.RetryBackoffValue().Milliseconds()}
+				if retries >= 0 {
+					job.Retry.MaxAttempts = int(retries)
+				}
+				if backoff > 0 {
+					job.Retry.BackoffMS = backoff.Milliseconds()
+				}
 			}
 
 			svc := cron.NewService(paths.CronStorePath(), nil)
-			j, err := svc.Add(jname, sched, payload)
+			j, err := svc.AddJob(job)
 			if err != nil {
 				return err
 			}
@@ -208,13 +248,78 @@ func cronRunCmd() *cli.Command {
 				return cli.Exit("usage: clawlet cron run [--force] <job_id>", 2)
 			}
 			id := cmd.Args().Get(0)
+			// The CLI has no agent or channels to run the job with; RunNow
+			// only checks that the job exists and may run.
 			svc := cron.NewService(paths.CronStorePath(), nil)
 			_, err = svc.RunNow(ctx, id, cmd.Bool("force"))
+			if errors.Is(err, cron.ErrNoHandler) {
+				return cli.Exit("jobs run inside `clawlet gateway`; ask the agent to run "+id+" (cron tool, action \"run\") while the gateway is up", 1)
+			}
+			return err
+		},
+	}
+}
+
+func cronHistoryCmd() *cli.Command {
+	return &cli.Command{
+		Name:      "history",
+		Usage:     "show the recent runs of a job",
+		ArgsUsage: "<job_id>",
+		Flags: []cli.Flag{
+			&cli.IntFlag{Name: "limit", Usage: "only the last N runs (0 = all kept)"},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			_, _, err := loadConfig()
 			if err != nil {
 				return err
 			}
-			fmt.Println("Triggered:", id)
+			if cmd.Args().Len() < 1 {
+				return cli.Exit("usage: clawlet cron history [--limit N] <job_id>", 2)
+			}
+			id := cmd.Args().Get(0)
+			svc := cron.NewService(paths.CronStorePath(), nil)
+			j, ok := svc.Get(id)
+			if !ok {
+				return cli.Exit("job not found: "+id, 1)
+			}
+			runs := j.State.History
+			if n := int(cmd.Int("limit")); n > 0 && len(runs) > n {
+				runs = runs[len(runs)-n:]
+			}
+			if len(runs) == 0 {
+				fmt.Printf("No runs of %s yet.\n", j.Name)
+				return nil
+			}
+			for _, r := range runs {
+				fmt.Printf("[%s] %s %s attempt=%d duration=%s\n", time.UnixMilli(r.StartedAtMS).Local().Format(time.DateTime),
+					r.Status, r.Trigger, r.Attempt, r.Duration().Round(time.Millisecond))
+				if r.Error != "" {
+					fmt.Printf("    error: %s\n", r.Error)
+				}
+				if r.Output != "" {
+					fmt.Printf("    %s\n", strings.ReplaceAll(r.Output, "\n", "\n    "))
+				}
+			}
 			return nil
 		},
 	}
 }
+
+func cronTZLabel(s cron.Schedule) string {
+	if s.Kind != "cron" || s.TZ == "" {
+		return ""
+	}
+	return " tz=" + s.TZ
+}
+
+// cronNextLabel formats the next run in the job's timezone.
+func cronNextLabel(j cron.Job) string {
+	if j.State.NextRunAtMS <= 0 {
+		return "-"
+	}
+	loc, err := j.Schedule.Location()
+	if err != nil {
+		loc = time.Local
+	}
+	return time.UnixMilli(j.State.NextRunAtMS).In(loc).Format(time.RFC3339)
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"strings"
+	"time"
 
 	"github.com/mosaxiv/clawlet/agent"
 	"github.com/mosaxiv/clawlet/bus"
@@ -14,12 +16,15 @@ import (
 	"github.com/mosaxiv/clawlet/channels/discord"
 	"github.com/mosaxiv/clawlet/channels/slack"
 	"github.com/mosaxiv/clawlet/channels/telegram"
+	"github.com/mosaxiv/clawlet/channels/webhook"
 	"github.com/mosaxiv/clawlet/channels/whatsapp"
 	"github.com/mosaxiv/clawlet/config"
 	"github.com/mosaxiv/clawlet/cron"
+	"github.com/mosaxiv/clawlet/gateway"
 	"github.com/mosaxiv/clawlet/heartbeat"
 	"github.com/mosaxiv/clawlet/paths"
 	"github.com/mosaxiv/clawlet/session"
+	"github.com/mosaxiv/clawlet/tools"
 	"github.com/urfave/cli/v3"
 )
 
@@ -40,6 +45,9 @@ func cmdGateway() *cli.Command {
 			if err := validateGatewayBindPolicy(cfg.Gateway); err != nil {
 				return err
 			}
+			if err := validateGatewayAPI(cfg.Gateway); err != nil {
+				return err
+			}
 
 			wsAbs, err := resolveWorkspace(cmd.String("workspace"))
 			if err != nil {
@@ -50,44 +58,49 @@ func cmdGateway() *cli.Command {
 			defer stop()
 
 			b := bus.New(256)
-			smgr := session.NewManager(paths.SessionsDir())
+			store, err := openSessionStore(cfg)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+			smgr := session.NewManager(store, cfg.Sessions.MaxCachedValue())
 
 			var cronSvc *cron.Service
+			cronJobs := &cronRunner{
+				bus: b,
+				exec: &tools.Registry{
+					WorkspaceDir:        wsAbs,
+					RestrictToWorkspace: cfg.Tools.RestrictToWorkspaceValue(),
+					ExecTimeout:         time.Duration(cfg.Tools.Exec.TimeoutSec) * time.Second,
+				},
+				http: &http.Client{},
+			}
 			if cfg.Cron.EnabledValue() {
-				cronSvc = cron.NewService(paths.CronStorePath(), func(ctx context.Context, job cron.Job) (string, error) {
-					if job.Payload.Kind != "" && job.Payload.Kind != "agent_turn" {
-						return "", nil
-					}
-					ch := job.Payload.Channel
-					to := job.Payload.To
-					if !job.Payload.Deliver || strings.TrimSpace(ch) == "" || strings.TrimSpace(to) == "" {
-						return "", nil
-					}
-					_ = b.PublishInbound(ctx, bus.InboundMessage{
-						Channel:    ch,
-						SenderID:   "cron:" + job.ID,
-						ChatID:     to,
-						Content:    job.Payload.Message,
-						SessionKey: ch + ":" + to,
-					})
-					return "", nil
-				})
+				cronSvc = cron.NewService(paths.CronStorePath(), cronJobs.run)
+				cronSvc.Retry = cron.Retry{
+					MaxAttempts: cfg.Cron.MaxRetries,
+					BackoffMS:   cfg.Cron.RetryBackoffValue().Milliseconds(),
+				}
+				cronSvc.Misfire = cfg.Cron.MisfireValue()
 			}
 
 			loop, err := agent.NewLoop(agent.LoopOptions{
-				Config:       cfg,
-				WorkspaceDir: wsAbs,
-				Model:        cfg.LLM.Model,
-				MaxIters:     cmd.Int("max-iters"),
-				Bus:          b,
-				Sessions:     smgr,
-				Cron:         cronSvc,
-				Spawn:        nil,
-				Verbose:      cmd.Bool("verbose"),
+				Config:        cfg,
+				WorkspaceDir:  wsAbs,
+				Model:         cfg.LLM.Model,
+				MaxIters:      cmd.Int("max-iters"),
+				Bus:           b,
+				Sessions:      smgr,
+				Cron:          cronSvc,
+				Spawn:         nil,
+				Verbose:       cmd.Bool("verbose"),
+				OnLLMAttempts: recordLLMAttempts,
 			})
 			if err != nil {
 				return err
 			}
+			defer loop.Close()
+			cronJobs.loop = loop
 
 			sa := agent.NewSubagentManager(loop)
 			loop.SetSpawn(sa.Spawn)
@@ -107,6 +120,7 @@ func cmdGateway() *cli.Command {
 			})
 			hb.Start(ctx)
 
+			srv := gateway.NewServer(cfg.Gateway.Listen)
 			cm := channels.NewManager(b)
 			if cfg.Channels.Discord.Enabled {
 				cm.Add(discord.New(cfg.Channels.Discord, b))
@@ -138,14 +152,38 @@ func cmdGateway() *cli.Command {
 				}
 				cm.Add(whatsapp.New(cfg.Channels.WhatsApp, b))
 			}
+			if cfg.Channels.Webhook.Enabled {
+				if strings.TrimSpace(cfg.Channels.Webhook.Secret) == "" {
+					return fmt.Errorf("webhook enabled but secret is empty")
+				}
+				wh := webhook.New(cfg.Channels.Webhook, b)
+				cm.Add(wh)
+				srv.Handle(wh.Path(), wh)
+			}
 
 			if err := cm.StartAll(ctx); err != nil {
 				return err
 			}
 
+			if cfg.Gateway.API.Enabled {
+				api := &gateway.OpenAIAPI{
+					Tokens:  cfg.Gateway.API.Tokens,
+					Model:   cfg.LLM.Model,
+					Process: loop.ProcessDirectStream,
+				}
+				api.Mount(srv)
+			}
+			if err := srv.Start(ctx); err != nil {
+				_ = cm.StopAll()
+				return err
+			}
+
 			go func() { _ = loop.Run(ctx) }()
 
 			fmt.Printf("gateway running\n- workspace: %s\n- sessions: %s\n", wsAbs, paths.SessionsDir())
+			if srv.Enabled() {
+				fmt.Printf("- http: %s\n", srv.Addr())
+			}
 			fmt.Println("stop: Ctrl+C")
 			<-ctx.Done()
 
@@ -174,6 +212,21 @@ func validateGatewayBindPolicy(cfg config.GatewayConfig) error {
 	)
 }
 
+func validateGatewayAPI(cfg config.GatewayConfig) error {
+	if !cfg.API.Enabled {
+		return nil
+	}
+	if strings.TrimSpace(cfg.Listen) == "" {
+		return fmt.Errorf("gateway.api enabled but gateway.listen is empty")
+	}
+	for _, tok := range cfg.API.Tokens {
+		if strings.TrimSpace(tok) != "" {
+			return nil
+		}
+	}
+	return fmt.Errorf("gateway.api enabled but gateway.api.tokens is empty")
+}
+
 func gatewayListenHost(listen string) string {
 	if strings.HasPrefix(listen, ":") {
 		return ""
@@ -29,3 +29,17 @@ func TestValidateGatewayBindPolicy_PublicBindAllowedWhenExplicitlyEnabled(t *tes
 		t.Fatalf("expected explicit public bind allow, got: %v", err)
 	}
 }
+
+func TestValidateGatewayAPI_RequiresTokens(t *testing.T) {
+	cfg := config.GatewayConfig{
+		Listen: "127.0.0.1:18790",
+		API:    config.GatewayAPIConfig{Enabled: true},
+	}
+	if err := validateGatewayAPI(cfg); err == nil {
+		t.Fatalf("expected api without tokens to be rejected")
+	}
+	cfg.API.Tokens = []string{"secret"}
+	if err := validateGatewayAPI(cfg); err != nil {
+		t.Fatalf("expected api with tokens allowed, got: %v", err)
+	}
+}
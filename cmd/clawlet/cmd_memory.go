@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mosaxiv/clawlet/memory"
+	"github.com/urfave/cli/v3"
+)
+
+func cmdMemory() *cli.Command {
+	workspaceFlag := &cli.StringFlag{Name: "workspace", Usage: "workspace directory (default: ~/.clawlet/workspace or CLAWLET_WORKSPACE)"}
+	return &cli.Command{
+		Name:  "memory",
+		Usage: "inspect and maintain the memory search index",
+		Flags: []cli.Flag{workspaceFlag},
+		Commands: []*cli.Command{
+			memoryStatusCmd(),
+			memoryReindexCmd(),
+			memorySearchCmd(),
+			{
+				Name:  "cache",
+				Usage: "manage the embedding cache",
+				Commands: []*cli.Command{
+					memoryCachePruneCmd(),
+				},
+			},
+		},
+	}
+}
+
+// withMemoryIndex opens the memory index of the workspace for a subcommand.
+func withMemoryIndex(cmd *cli.Command, fn func(m *memory.IndexManager) error) error {
+	cfg, _, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	ws, err := resolveWorkspace(cmd.String("workspace"))
+	if err != nil {
+		return err
+	}
+	m, err := memory.NewIndexManager(cfg, ws)
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		return cli.Exit("memory search is disabled (set agents.defaults.memorySearch.enabled)", 1)
+	}
+	defer m.Close()
+	return fn(m)
+}
+
+func memoryStatusCmd() *cli.Command {
+	return &cli.Command{
+		Name:  "status",
+		Usage: "show the state of the memory search index",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "json", Usage: "print as JSON"},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			return withMemoryIndex(cmd, func(m *memory.IndexManager) error {
+				st := m.Status(ctx)
+				if cmd.Bool("json") {
+					return printJSON(os.Stdout, st)
+				}
+				printMemoryStatus(os.Stdout, st)
+				return nil
+			})
+		},
+	}
+}
+
+func printMemoryStatus(w io.Writer, st memory.SearchStatus) {
+	fmt.Fprintf(w, "provider: %s\n", st.Provider)
+	fmt.Fprintf(w, "model: %s\n", st.Model)
+	fmt.Fprintf(w, "db: %s\n", st.DBPath)
+	fmt.Fprintf(w, "files: %d\n", st.Files)
+	fmt.Fprintf(w, "chunks: %d\n", st.Chunks)
+	fmt.Fprintf(w, "vector: enabled=%v ready=%v dims=%d\n", st.VectorEnabled, st.VectorReady, st.VectorDims)
+	fmt.Fprintf(w, "fts: enabled=%v ready=%v\n", st.FTSEnabled, st.FTSReady)
+	fmt.Fprintf(w, "cacheEntries: %d\n", st.CacheEntries)
+	fmt.Fprintf(w, "minScore: %.2f\n", st.MinScore)
+	fmt.Fprintf(w, "maxResults: %d\n", st.MaxResults)
+	if st.LastSyncAtMS > 0 {
+		fmt.Fprintf(w, "lastSync: %s\n", time.UnixMilli(st.LastSyncAtMS).Local().Format(time.DateTime))
+	} else {
+		fmt.Fprintln(w, "lastSync: never")
+	}
+	if st.LastError != "" {
+		fmt.Fprintf(w, "lastError: %s\n", st.LastError)
+	}
+}
+
+func memoryReindexCmd() *cli.Command {
+	return &cli.Command{
+		Name:  "reindex",
+		Usage: "index new and changed memory files",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "force", Usage: "rebuild the index from scratch"},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			return withMemoryIndex(cmd, func(m *memory.IndexManager) error {
+				start := time.Now()
+				if err := m.Sync(ctx, cmd.Bool("force")); err != nil {
+					return err
+				}
+				st := m.Status(ctx)
+				fmt.Printf("Indexed %d files (%d chunks) in %s\n", st.Files, st.Chunks, time.Since(start).Round(time.Millisecond))
+				return nil
+			})
+		},
+	}
+}
+
+func memorySearchCmd() *cli.Command {
+	return &cli.Command{
+		Name:      "search",
+		Usage:     "search memory the way the memory_search tool does",
+		ArgsUsage: "<query>",
+		Flags: []cli.Flag{
+			&cli.IntFlag{Name: "max-results", Usage: "max results (default: memorySearch.query.maxResults)"},
+			&cli.FloatFlag{Name: "min-score", Usage: "minimum score (default: memorySearch.query.minScore)"},
+			&cli.BoolFlag{Name: "json", Usage: "print results as JSON"},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			query := strings.TrimSpace(strings.Join(cmd.Args().Slice(), " "))
+			if query == "" {
+				return cli.Exit("usage: clawlet memory search [--json] <query>", 2)
+			}
+			return withMemoryIndex(cmd, func(m *memory.IndexManager) error {
+				results, err := m.Search(ctx, query, memory.SearchOptions{
+					MaxResults: int(cmd.Int("max-results")),
+					MinScore:   cmd.Float("min-score"),
+				})
+				if err != nil {
+					return err
+				}
+				if cmd.Bool("json") {
+					return printJSON(os.Stdout, results)
+				}
+				printSearchResults(os.Stdout, results)
+				return nil
+			})
+		},
+	}
+}
+
+func printSearchResults(w io.Writer, results []memory.SearchResult) {
+	if len(results) == 0 {
+		fmt.Fprintln(w, "No results.")
+		return
+	}
+	for _, r := range results {
+		fmt.Fprintf(w, "%.3f %s:%d-%d [%s]\n", r.Score, r.Path, r.StartLine, r.EndLine, r.Source)
+		for line := range strings.SplitSeq(strings.TrimSpace(r.Snippet), "\n") {
+			fmt.Fprintf(w, "    %s\n", line)
+		}
+	}
+}
+
+func memoryCachePruneCmd() *cli.Command {
+	return &cli.Command{
+		Name:  "prune",
+		Usage: "drop embeddings cached for other models and trim the cache to memorySearch.cache.maxEntries",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "all", Usage: "empty the cache"},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			return withMemoryIndex(cmd, func(m *memory.IndexManager) error {
+				n, err := m.PruneCache(ctx, cmd.Bool("all"))
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Removed %d cached embeddings (%d left)\n", n, m.Status(ctx).CacheEntries)
+				return nil
+			})
+		},
+	}
+}
+
+func printJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
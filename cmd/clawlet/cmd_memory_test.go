@@ -0,0 +1,24 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mosaxiv/clawlet/memory"
+)
+
+func TestPrintSearchResults(t *testing.T) {
+	var b bytes.Buffer
+	printSearchResults(&b, []memory.SearchResult{
+		{Path: "memory/2026-01-10.md", StartLine: 3, EndLine: 4, Score: 0.8123, Snippet: "Adopted a cat.\nNamed her Miso.\n", Source: memory.SourceMemory},
+	})
+	want := "0.812 memory/2026-01-10.md:3-4 [memory]\n    Adopted a cat.\n    Named her Miso.\n"
+	if b.String() != want {
+		t.Fatalf("got %q", b.String())
+	}
+	b.Reset()
+	printSearchResults(&b, nil)
+	if b.String() != "No results.\n" {
+		t.Fatalf("got %q", b.String())
+	}
+}
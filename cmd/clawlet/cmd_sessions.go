@@ -0,0 +1,364 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mosaxiv/clawlet/paths"
+	"github.com/mosaxiv/clawlet/session"
+	"github.com/urfave/cli/v3"
+)
+
+func cmdSessions() *cli.Command {
+	return &cli.Command{
+		Name:  "sessions",
+		Usage: "manage conversation sessions",
+		Commands: []*cli.Command{
+			sessionsListCmd(),
+			sessionsShowCmd(),
+			sessionsExportCmd(),
+			sessionsResetCmd(),
+			sessionsDeleteCmd(),
+			sessionsSearchCmd(),
+			sessionsMigrateCmd(),
+		},
+	}
+}
+
+func sessionsQueryFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{Name: "prefix", Usage: "only sessions whose key starts with this (e.g. telegram:)"},
+		&cli.StringFlag{Name: "since", Usage: "only sessions active since (e.g. 7d, 12h, 2026-02-01, RFC3339)"},
+		&cli.StringFlag{Name: "until", Usage: "only sessions created before (same formats as --since)"},
+	}
+}
+
+// sessionsQuery builds a session.Query from the --prefix/--since/--until flags.
+func sessionsQuery(cmd *cli.Command, now time.Time) (session.Query, error) {
+	q := session.Query{Prefix: strings.TrimSpace(cmd.String("prefix"))}
+	var err error
+	if q.Since, err = parseSessionTime(cmd.String("since"), now); err != nil {
+		return q, cli.Exit(fmt.Sprintf("invalid --since: %v", err), 2)
+	}
+	if q.Until, err = parseSessionTime(cmd.String("until"), now); err != nil {
+		return q, cli.Exit(fmt.Sprintf("invalid --until: %v", err), 2)
+	}
+	return q, nil
+}
+
+// parseSessionTime accepts a duration before now ("7d", "36h"), a date
+// (2006-01-02, local time) or an RFC3339 timestamp.
+func parseSessionTime(v string, now time.Time) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if days, ok := strings.CutSuffix(v, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", v, time.Local); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a duration, date or RFC3339 time", v)
+	}
+	return t, nil
+}
+
+// withSessionStore opens the configured session store for a subcommand.
+func withSessionStore(fn func(st session.Store) error) error {
+	cfg, _, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	st, err := openSessionStore(cfg)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	return fn(st)
+}
+
+func loadSessionArg(cmd *cli.Command, st session.Store, usage string) (*session.Session, error) {
+	if cmd.Args().Len() < 1 {
+		return nil, cli.Exit("usage: "+usage, 2)
+	}
+	key := cmd.Args().Get(0)
+	s, err := st.Load(key)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, cli.Exit("session not found: "+key, 1)
+	}
+	return s, nil
+}
+
+func sessionsListCmd() *cli.Command {
+	return &cli.Command{
+		Name:  "list",
+		Usage: "list sessions, most recently active first",
+		Flags: append([]cli.Flag{
+			&cli.IntFlag{Name: "limit", Usage: "max sessions to list (0 = all)"},
+		}, sessionsQueryFlags()...),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			q, err := sessionsQuery(cmd, time.Now())
+			if err != nil {
+				return err
+			}
+			q.Limit = int(cmd.Int("limit"))
+			return withSessionStore(func(st session.Store) error {
+				infos, err := st.List(q)
+				if err != nil {
+					return err
+				}
+				if len(infos) == 0 {
+					fmt.Println("No sessions.")
+					return nil
+				}
+				for _, info := range infos {
+					fmt.Printf("- %s messages=%d updated=%s created=%s\n", info.Key, info.Messages,
+						info.UpdatedAt.Local().Format(time.DateTime), info.CreatedAt.Local().Format(time.DateTime))
+				}
+				return nil
+			})
+		},
+	}
+}
+
+func sessionsShowCmd() *cli.Command {
+	return &cli.Command{
+		Name:      "show",
+		Usage:     "print the messages of a session",
+		ArgsUsage: "<session_key>",
+		Flags: []cli.Flag{
+			&cli.IntFlag{Name: "last", Usage: "only the last N messages (0 = all)"},
+			&cli.BoolFlag{Name: "full", Usage: "print tool results and arguments in full"},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			return withSessionStore(func(st session.Store) error {
+				s, err := loadSessionArg(cmd, st, "clawlet sessions show [--last N] <session_key>")
+				if err != nil {
+					return err
+				}
+				msgs := s.Messages
+				if n := int(cmd.Int("last")); n > 0 && len(msgs) > n {
+					msgs = msgs[len(msgs)-n:]
+				}
+				printSessionMessages(os.Stdout, msgs, cmd.Bool("full"))
+				return nil
+			})
+		},
+	}
+}
+
+func printSessionMessages(w io.Writer, msgs []session.Message, full bool) {
+	limit := 300
+	if full {
+		limit = 0
+	}
+	for _, m := range msgs {
+		prefix := strings.ToUpper(m.Role)
+		if m.Role == "tool" && m.Name != "" {
+			prefix += " (" + m.Name + ")"
+		}
+		if len(m.ToolsUsed) > 0 {
+			prefix += " [tools: " + strings.Join(m.ToolsUsed, ", ") + "]"
+		}
+		if t, err := time.Parse(time.RFC3339Nano, m.Timestamp); err == nil {
+			prefix = "[" + t.Local().Format(time.DateTime) + "] " + prefix
+		}
+		content := strings.TrimSpace(m.Content)
+		if m.Role == "tool" {
+			content = clip(content, limit)
+		}
+		fmt.Fprintf(w, "%s: %s\n", prefix, content)
+		for _, tc := range m.ToolCalls {
+			fmt.Fprintf(w, "    -> %s %s\n", tc.Name, clip(tc.Arguments, limit))
+		}
+	}
+}
+
+func clip(s string, n int) string {
+	if n <= 0 || len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + "…"
+}
+
+func sessionsExportCmd() *cli.Command {
+	return &cli.Command{
+		Name:      "export",
+		Usage:     "export a session as Markdown, JSON or HTML",
+		ArgsUsage: "<session_key>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "format", Aliases: []string{"f"}, Value: session.FormatMarkdown, Usage: "md, json or html"},
+			&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Usage: "output file (default: stdout)"},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			return withSessionStore(func(st session.Store) error {
+				s, err := loadSessionArg(cmd, st, "clawlet sessions export [--format md|json|html] [-o file] <session_key>")
+				if err != nil {
+					return err
+				}
+				out := strings.TrimSpace(cmd.String("output"))
+				if out == "" {
+					return session.Export(os.Stdout, s, cmd.String("format"))
+				}
+				f, err := os.OpenFile(out, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+				if err != nil {
+					return err
+				}
+				if err := session.Export(f, s, cmd.String("format")); err != nil {
+					_ = f.Close()
+					return err
+				}
+				if err := f.Close(); err != nil {
+					return err
+				}
+				fmt.Printf("Exported %s (%d messages) to %s\n", s.Key, len(s.Messages), out)
+				return nil
+			})
+		},
+	}
+}
+
+func sessionsResetCmd() *cli.Command {
+	return &cli.Command{
+		Name:      "reset",
+		Usage:     "archive a session and start it over",
+		ArgsUsage: "<session_key>",
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if cmd.Args().Len() < 1 {
+				return cli.Exit("usage: clawlet sessions reset <session_key>", 2)
+			}
+			key := cmd.Args().Get(0)
+			return withSessionStore(func(st session.Store) error {
+				path, err := session.Archive(st, key, paths.SessionsArchiveDir())
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Reset %s (archived to %s)\n", key, path)
+				return nil
+			})
+		},
+	}
+}
+
+func sessionsDeleteCmd() *cli.Command {
+	return &cli.Command{
+		Name:      "delete",
+		Usage:     "permanently delete a session (see reset to archive instead)",
+		ArgsUsage: "<session_key>",
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			return withSessionStore(func(st session.Store) error {
+				s, err := loadSessionArg(cmd, st, "clawlet sessions delete <session_key>")
+				if err != nil {
+					return err
+				}
+				if err := st.Delete(s.Key); err != nil {
+					return err
+				}
+				fmt.Println("Deleted:", s.Key)
+				return nil
+			})
+		},
+	}
+}
+
+func sessionsSearchCmd() *cli.Command {
+	return &cli.Command{
+		Name:      "search",
+		Usage:     "find messages containing text",
+		ArgsUsage: "<text>",
+		Flags: append([]cli.Flag{
+			&cli.IntFlag{Name: "limit", Value: 50, Usage: "max matches (0 = all)"},
+		}, sessionsQueryFlags()...),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			text := strings.TrimSpace(strings.Join(cmd.Args().Slice(), " "))
+			if text == "" {
+				return cli.Exit("usage: clawlet sessions search [--prefix p] [--since 7d] <text>", 2)
+			}
+			q, err := sessionsQuery(cmd, time.Now())
+			if err != nil {
+				return err
+			}
+			return withSessionStore(func(st session.Store) error {
+				hits, err := session.Search(st, q, text, int(cmd.Int("limit")))
+				if err != nil {
+					return err
+				}
+				if len(hits) == 0 {
+					fmt.Println("No matches.")
+					return nil
+				}
+				for _, h := range hits {
+					fmt.Printf("%s #%d ", h.Key, h.Index)
+					printSessionMessages(os.Stdout, []session.Message{h.Message}, false)
+				}
+				return nil
+			})
+		},
+	}
+}
+
+func sessionsMigrateCmd() *cli.Command {
+	return &cli.Command{
+		Name:  "migrate",
+		Usage: "copy all sessions from one store to another",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "from", Value: session.StoreJSONL, Usage: "source store (jsonl or sqlite)"},
+			&cli.StringFlag{Name: "to", Value: session.StoreSQLite, Usage: "destination store (jsonl or sqlite)"},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			cfg, _, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			from := strings.ToLower(strings.TrimSpace(cmd.String("from")))
+			to := strings.ToLower(strings.TrimSpace(cmd.String("to")))
+			if from == to {
+				return cli.Exit("--from and --to must differ", 2)
+			}
+			if err := paths.EnsureStateDirs(); err != nil {
+				return err
+			}
+
+			src, err := session.OpenStore(from, paths.SessionsDir(), paths.SessionsDBPath())
+			if err != nil {
+				return err
+			}
+			defer src.Close()
+			dst, err := session.OpenStore(to, paths.SessionsDir(), paths.SessionsDBPath())
+			if err != nil {
+				return err
+			}
+			defer dst.Close()
+
+			n, err := session.Migrate(src, dst)
+			if err != nil {
+				return fmt.Errorf("migrated %d sessions before failing: %w", n, err)
+			}
+			fmt.Printf("Migrated %d sessions from %s to %s (the %s data was left in place).\n", n, from, to, from)
+			if cfg.Sessions.StoreValue() != to {
+				fmt.Printf("Set \"sessions\": {\"store\": %q} in the config to use it.\n", to)
+			}
+			return nil
+		},
+	}
+}
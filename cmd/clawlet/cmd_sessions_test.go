@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSessionTime(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	cases := map[string]time.Time{
+		"":                     {},
+		"7d":                   now.AddDate(0, 0, -7),
+		"36h":                  now.Add(-36 * time.Hour),
+		"2026-03-01T08:00:00Z": time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC),
+		"2026-03-01":           time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local),
+	}
+	for in, want := range cases {
+		got, err := parseSessionTime(in, now)
+		if err != nil {
+			t.Fatalf("parseSessionTime(%q): %v", in, err)
+		}
+		if !got.Equal(want) {
+			t.Fatalf("parseSessionTime(%q)=%v want %v", in, got, want)
+		}
+	}
+	if _, err := parseSessionTime("last week", now); err == nil {
+		t.Fatal("expected error")
+	}
+}
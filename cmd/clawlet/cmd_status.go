@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/mosaxiv/clawlet/llm"
 	"github.com/mosaxiv/clawlet/paths"
 	"github.com/urfave/cli/v3"
 )
@@ -26,21 +28,68 @@ func cmdStatus() *cli.Command {
 			if strings.TrimSpace(cfg.Agents.Defaults.Model) != "" {
 				fmt.Printf("agents.defaults.model: %s\n", cfg.Agents.Defaults.Model)
 			}
+			if len(cfg.Agents.Defaults.FallbackModels) > 0 {
+				fmt.Printf("agents.defaults.fallbackModels: %s\n", strings.Join(cfg.Agents.Defaults.FallbackModels, ", "))
+			}
+			fmt.Printf("agents.defaults.retry.maxAttempts: %d\n", cfg.Agents.Defaults.Retry.MaxAttemptsValue())
 			fmt.Printf("agents.defaults.maxTokens: %d\n", cfg.Agents.Defaults.MaxTokensValue())
+			if cfg.Agents.Defaults.ContextTokens > 0 {
+				fmt.Printf("agents.defaults.contextTokens: %d\n", cfg.Agents.Defaults.ContextTokens)
+			}
 			fmt.Printf("agents.defaults.temperature: %.2f\n", cfg.Agents.Defaults.TemperatureValue())
+			fmt.Printf("agents.defaults.maxConcurrentTurns: %d\n", cfg.Agents.Defaults.MaxConcurrentTurnsValue())
+			fmt.Printf("agents.defaults.queueMode: %s\n", cfg.Agents.Defaults.QueueModeValue())
+			fmt.Printf("sessions.store: %s\n", cfg.Sessions.StoreValue())
+			fmt.Printf("sessions.transcript.toolCalls: %v\n", cfg.Sessions.Transcript.ToolCallsValue())
+			fmt.Printf("commands.enabled: %v\n", cfg.Commands.EnabledValue())
+			fmt.Printf("commands.admins: %d\n", len(cfg.Commands.Admins))
 			fmt.Printf("tools.restrictToWorkspace: %v\n", cfg.Tools.RestrictToWorkspaceValue())
 			fmt.Printf("tools.exec.timeoutSec: %d\n", cfg.Tools.Exec.TimeoutSec)
 			fmt.Printf("tools.web.braveApiKey: %v\n", cfg.Tools.Web.BraveAPIKey != "")
+			fmt.Printf("tools.mcp.servers: %d\n", len(cfg.Tools.MCP.Servers))
 			fmt.Printf("cron.enabled: %v\n", cfg.Cron.EnabledValue())
+			if tz := cfg.Cron.DefaultTimezone; tz != "" {
+				fmt.Printf("cron.defaultTimezone: %s\n", tz)
+			}
+			fmt.Printf("cron.maxRetries: %d (backoff %s)\n", cfg.Cron.MaxRetries, cfg.Cron.RetryBackoffValue())
+			fmt.Printf("cron.misfire: %s\n", cfg.Cron.MisfireValue())
 			fmt.Printf("heartbeat.enabled: %v\n", cfg.Heartbeat.EnabledValue())
 			fmt.Printf("heartbeat.intervalSec: %d\n", cfg.Heartbeat.IntervalSec)
 			fmt.Printf("gateway.listen: %s\n", cfg.Gateway.Listen)
 			fmt.Printf("gateway.allowPublicBind: %v\n", cfg.Gateway.AllowPublicBind)
+			fmt.Printf("gateway.api.enabled: %v (tokens: %d)\n", cfg.Gateway.API.Enabled, len(cfg.Gateway.API.Tokens))
 			fmt.Printf("channels.discord.enabled: %v\n", cfg.Channels.Discord.Enabled)
 			fmt.Printf("channels.slack.enabled: %v\n", cfg.Channels.Slack.Enabled)
 			fmt.Printf("channels.telegram.enabled: %v\n", cfg.Channels.Telegram.Enabled)
 			fmt.Printf("channels.whatsapp.enabled: %v\n", cfg.Channels.WhatsApp.Enabled)
+			fmt.Printf("channels.webhook.enabled: %v\n", cfg.Channels.Webhook.Enabled)
+			printLastLLMAttempts()
 			return nil
 		},
 	}
 }
+
+// recordLLMAttempts persists the attempts of the latest LLM request so that
+// `clawlet status` can show which provider actually answered.
+func recordLLMAttempts(attempts []llm.Attempt) {
+	_ = llm.SaveAttemptLog(paths.LLMStatusPath(), attempts)
+}
+
+func printLastLLMAttempts() {
+	log, err := llm.LoadAttemptLog(paths.LLMStatusPath())
+	if err != nil {
+		return
+	}
+	if a, ok := log.Answered(); ok {
+		fmt.Printf("llm.lastAnswered: %s/%s (%s)\n", a.Provider, a.Model, log.UpdatedAt.Local().Format(time.RFC3339))
+	} else {
+		fmt.Printf("llm.lastAnswered: none (%s)\n", log.UpdatedAt.Local().Format(time.RFC3339))
+	}
+	for i, a := range log.Attempts {
+		status := "ok"
+		if !a.OK() {
+			status = "error: " + a.Error
+		}
+		fmt.Printf("llm.lastAttempts[%d]: %s/%s %dms %s\n", i, a.Provider, a.Model, a.DurationMS, status)
+	}
+}
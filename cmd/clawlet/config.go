@@ -8,6 +8,7 @@ import (
 
 	"github.com/mosaxiv/clawlet/config"
 	"github.com/mosaxiv/clawlet/paths"
+	"github.com/mosaxiv/clawlet/session"
 )
 
 func loadConfig() (*config.Config, string, error) {
@@ -30,6 +31,14 @@ func loadConfig() (*config.Config, string, error) {
 	return cfg, cfgPath, nil
 }
 
+// openSessionStore opens the session store selected by sessions.store.
+func openSessionStore(cfg *config.Config) (session.Store, error) {
+	if err := paths.EnsureStateDirs(); err != nil {
+		return nil, err
+	}
+	return session.OpenStore(cfg.Sessions.StoreValue(), paths.SessionsDir(), paths.SessionsDBPath())
+}
+
 func applyEnvOverrides(cfg *config.Config) {
 	if v := os.Getenv("CLAWLET_API_KEY"); v != "" {
 		cfg.LLM.APIKey = v
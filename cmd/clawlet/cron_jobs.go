@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mosaxiv/clawlet/agent"
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/cron"
+	"github.com/mosaxiv/clawlet/tools"
+)
+
+// cronWebhookTimeout bounds a webhook job's request.
+const cronWebhookTimeout = 30 * time.Second
+
+// cronRunner runs the payloads of cron jobs for the gateway.
+type cronRunner struct {
+	bus  *bus.Bus
+	exec *tools.Registry // workspace and guard policy of exec jobs
+	loop *agent.Loop     // set once the loop exists
+	http *http.Client
+}
+
+func (r *cronRunner) run(ctx context.Context, job cron.Job) (string, error) {
+	p := job.Payload
+	switch p.Kind {
+	case "", cron.PayloadAgentTurn:
+		return r.agentTurn(ctx, job)
+	case cron.PayloadMessage:
+		return p.Message, r.deliver(ctx, p, p.Message)
+	case cron.PayloadExec:
+		out, err := r.exec.Exec(ctx, p.Command)
+		if p.Deliver {
+			text := out
+			if err != nil {
+				text = strings.TrimSpace(fmt.Sprintf("%s failed: %v\n%s", job.Name, err, out))
+			}
+			if derr := r.deliver(ctx, p, text); derr != nil && err == nil {
+				err = derr
+			}
+		}
+		return out, err
+	case cron.PayloadWebhook:
+		return r.webhook(ctx, job)
+	default:
+		return "", fmt.Errorf("unknown payload kind: %s", p.Kind)
+	}
+}
+
+// agentTurn answers the job's message in the target chat, or in a session of
+// its own when the reply is not delivered.
+func (r *cronRunner) agentTurn(ctx context.Context, job cron.Job) (string, error) {
+	p := job.Payload
+	if !p.Deliver || strings.TrimSpace(p.Channel) == "" || strings.TrimSpace(p.To) == "" {
+		if r.loop == nil {
+			return "", fmt.Errorf("agent loop not running")
+		}
+		return r.loop.ProcessDirect(ctx, p.Message, "cron:"+job.ID, "cron", job.ID)
+	}
+	// Queue the turn behind the chat's other messages and wait for the reply,
+	// so the run is recorded with its outcome.
+	type result struct {
+		reply string
+		err   error
+	}
+	done := make(chan result, 1)
+	err := r.bus.PublishInbound(ctx, bus.InboundMessage{
+		Channel:    p.Channel,
+		SenderID:   "cron:" + job.ID,
+		ChatID:     p.To,
+		Content:    p.Message,
+		SessionKey: p.Channel + ":" + p.To,
+		OnDone: func(reply string, err error) {
+			done <- result{reply, err}
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	select {
+	case res := <-done:
+		return res.reply, res.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func (r *cronRunner) deliver(ctx context.Context, p cron.Payload, text string) error {
+	if strings.TrimSpace(p.Channel) == "" || strings.TrimSpace(p.To) == "" || strings.TrimSpace(text) == "" {
+		return nil
+	}
+	return r.bus.PublishOutbound(ctx, bus.OutboundMessage{Channel: p.Channel, ChatID: p.To, Content: text})
+}
+
+func (r *cronRunner) webhook(ctx context.Context, job cron.Job) (string, error) {
+	body, err := json.Marshal(map[string]string{
+		"jobId":   job.ID,
+		"name":    job.Name,
+		"message": job.Payload.Message,
+		"firedAt": time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, cronWebhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.Payload.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "clawlet-cron")
+	resp, err := r.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+	out := strings.TrimSpace(fmt.Sprintf("HTTP %d\n%s", resp.StatusCode, b))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return out, fmt.Errorf("webhook returned HTTP %d", resp.StatusCode)
+	}
+	return out, nil
+}
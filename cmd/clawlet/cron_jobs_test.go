@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/bus"
+	"github.com/mosaxiv/clawlet/cron"
+	"github.com/mosaxiv/clawlet/tools"
+)
+
+func TestCronRunner_MessageAndExecAreDelivered(t *testing.T) {
+	b := bus.New(4)
+	r := &cronRunner{bus: b, exec: &tools.Registry{WorkspaceDir: t.TempDir(), RestrictToWorkspace: true, ExecTimeout: 5 * time.Second}}
+
+	jobs := []cron.Job{
+		{ID: "1", Name: "stretch", Payload: cron.Payload{Kind: cron.PayloadMessage, Message: "Stand up!", Channel: "telegram", To: "9"}},
+		{ID: "2", Name: "disk", Payload: cron.Payload{Kind: cron.PayloadExec, Command: "echo 42% used", Deliver: true, Channel: "telegram", To: "9"}},
+	}
+	for _, j := range jobs {
+		if _, err := r.run(t.Context(), j); err != nil {
+			t.Fatalf("%s: %v", j.Name, err)
+		}
+	}
+	if m, _ := b.ConsumeOutbound(t.Context()); m.ChatID != "9" || m.Content != "Stand up!" {
+		t.Fatalf("message job sent %+v", m)
+	}
+	if m, _ := b.ConsumeOutbound(t.Context()); !strings.Contains(m.Content, "42% used") {
+		t.Fatalf("exec job sent %+v", m)
+	}
+}
+
+func TestCronRunner_Webhook(t *testing.T) {
+	var got map[string]string
+	status := http.StatusNoContent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_ = json.NewDecoder(req.Body).Decode(&got)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+	r := &cronRunner{http: srv.Client()}
+	job := cron.Job{ID: "j1", Name: "nightly", Payload: cron.Payload{Kind: cron.PayloadWebhook, URL: srv.URL, Message: "backup"}}
+
+	if _, err := r.run(t.Context(), job); err != nil {
+		t.Fatal(err)
+	}
+	if got["jobId"] != "j1" || got["message"] != "backup" || got["firedAt"] == "" {
+		t.Fatalf("body=%v", got)
+	}
+	status = http.StatusBadGateway
+	if _, err := r.run(t.Context(), job); err == nil || !strings.Contains(err.Error(), "502") {
+		t.Fatalf("err=%v", err)
+	}
+}
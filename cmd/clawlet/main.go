@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"os"
+	// Embedded zoneinfo so cron timezones work on hosts without /usr/share/zoneinfo.
+	_ "time/tzdata"
 
 	"github.com/urfave/cli/v3"
 )
@@ -22,6 +24,8 @@ func main() {
 			cmdProvider(),
 			cmdChannels(),
 			cmdCron(),
+			cmdSessions(),
+			cmdMemory(),
 		},
 	}
 
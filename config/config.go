@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 type Config struct {
@@ -18,6 +19,8 @@ type Config struct {
 	Cron      CronConfig      `json:"cron"`
 	Heartbeat HeartbeatConfig `json:"heartbeat"`
 	Gateway   GatewayConfig   `json:"gateway"`
+	Sessions  SessionsConfig  `json:"sessions"`
+	Commands  CommandsConfig  `json:"commands"`
 	// Channels are optional; enable what you need.
 	Channels ChannelsConfig `json:"channels"`
 }
@@ -35,11 +38,34 @@ type AgentsConfig struct {
 }
 
 type AgentDefaultsConfig struct {
-	Model        string             `json:"model"`
-	MaxTokens    int                `json:"maxTokens,omitempty"`
-	Temperature  *float64           `json:"temperature,omitempty"`
-	MemoryWindow int                `json:"memoryWindow,omitempty"`
+	Model string `json:"model"`
+	// FallbackModels are routed like Model and tried in order when the
+	// primary model keeps failing (rate limits, outages, auth errors).
+	FallbackModels []string       `json:"fallbackModels,omitempty"`
+	Retry          LLMRetryConfig `json:"retry"`
+	MaxTokens      int            `json:"maxTokens,omitempty"`
+	Temperature    *float64       `json:"temperature,omitempty"`
+	MemoryWindow   int            `json:"memoryWindow,omitempty"`
+	// ContextTokens is the model's context window in tokens. When 0 it is
+	// guessed from the model name. The prompt is trimmed to fit it, leaving
+	// MaxTokens for the reply.
+	ContextTokens int `json:"contextTokens,omitempty"`
+	// ToolConcurrency caps how many tool calls of one round run in parallel.
+	// 1 runs them sequentially. Default: 4.
+	ToolConcurrency int `json:"toolConcurrency,omitempty"`
+	// Streaming progressively edits a placeholder message on channels that
+	// support it (Telegram, Discord, Slack). Default: true.
+	Streaming *bool `json:"streaming,omitempty"`
+	// MaxConcurrentTurns caps how many sessions the gateway answers at once.
+	// Messages of one session are always handled in order. Default: 4.
+	MaxConcurrentTurns int `json:"maxConcurrentTurns,omitempty"`
+	// QueueMode decides what happens to messages that arrive while their
+	// session is busy: "collect" (default) answers them together once the
+	// current turn is done; "interrupt" cancels the current turn and starts
+	// over with all pending messages.
+	QueueMode    string             `json:"queueMode,omitempty"`
 	MemorySearch MemorySearchConfig `json:"memorySearch"`
+	MemoryScopes MemoryScopesConfig `json:"memoryScopes"`
 }
 
 func (c AgentDefaultsConfig) MaxTokensValue() int {
@@ -56,6 +82,64 @@ func (c AgentDefaultsConfig) TemperatureValue() float64 {
 	return *c.Temperature
 }
 
+// LLMRetryConfig controls retries of transient LLM errors (429, 5xx, network)
+// against one model before failing over to the next fallback model.
+type LLMRetryConfig struct {
+	MaxAttempts      int `json:"maxAttempts,omitempty"`
+	InitialBackoffMs int `json:"initialBackoffMs,omitempty"`
+	MaxBackoffMs     int `json:"maxBackoffMs,omitempty"`
+}
+
+func (c LLMRetryConfig) MaxAttemptsValue() int {
+	if c.MaxAttempts <= 0 {
+		return DefaultLLMRetryMaxAttempts
+	}
+	return c.MaxAttempts
+}
+
+func (c LLMRetryConfig) InitialBackoffValue() time.Duration {
+	if c.InitialBackoffMs <= 0 {
+		return DefaultLLMRetryInitialBackoffMs * time.Millisecond
+	}
+	return time.Duration(c.InitialBackoffMs) * time.Millisecond
+}
+
+func (c LLMRetryConfig) MaxBackoffValue() time.Duration {
+	if c.MaxBackoffMs <= 0 {
+		return DefaultLLMRetryMaxBackoffMs * time.Millisecond
+	}
+	return time.Duration(c.MaxBackoffMs) * time.Millisecond
+}
+
+func (c AgentDefaultsConfig) StreamingValue() bool {
+	if c.Streaming == nil {
+		return true
+	}
+	return *c.Streaming
+}
+
+func (c AgentDefaultsConfig) ToolConcurrencyValue() int {
+	if c.ToolConcurrency <= 0 {
+		return DefaultAgentToolConcurrency
+	}
+	return c.ToolConcurrency
+}
+
+func (c AgentDefaultsConfig) MaxConcurrentTurnsValue() int {
+	if c.MaxConcurrentTurns <= 0 {
+		return DefaultAgentMaxConcurrentTurns
+	}
+	return c.MaxConcurrentTurns
+}
+
+func (c AgentDefaultsConfig) QueueModeValue() string {
+	v := strings.ToLower(strings.TrimSpace(c.QueueMode))
+	if v == "" {
+		return DefaultAgentQueueMode
+	}
+	return v
+}
+
 func (c AgentDefaultsConfig) MemoryWindowValue() int {
 	if c.MemoryWindow <= 0 {
 		return DefaultAgentMemoryWindow
@@ -63,15 +147,58 @@ func (c AgentDefaultsConfig) MemoryWindowValue() int {
 	return c.MemoryWindow
 }
 
+// MemoryScopesConfig splits long-term memory into a global store, one per
+// user and one per chat, so facts learned from one person are not shown in
+// another person's chats.
+type MemoryScopesConfig struct {
+	Enabled *bool `json:"enabled,omitempty"`
+	// Default is the scope new facts go to unless one is picked: "global",
+	// "user" or "chat". Default: "user"
+	Default string `json:"default,omitempty"`
+	// Inject maps a channel ("*" for the others) to the scopes put into its
+	// system prompt; memory_search and memory writes use the same scopes.
+	// Default: every scope on every channel.
+	Inject map[string][]string `json:"inject,omitempty"`
+}
+
+func (c MemoryScopesConfig) EnabledValue() bool {
+	if c.Enabled == nil {
+		return false
+	}
+	return *c.Enabled
+}
+
+func (c MemoryScopesConfig) DefaultValue() string {
+	if d := strings.ToLower(strings.TrimSpace(c.Default)); d != "" {
+		return d
+	}
+	return DefaultMemoryScope
+}
+
+// InjectValue returns the scopes of channel.
+func (c MemoryScopesConfig) InjectValue(channel string) []string {
+	if scopes, ok := c.Inject[channel]; ok {
+		return scopes
+	}
+	if scopes, ok := c.Inject["*"]; ok {
+		return scopes
+	}
+	return []string{"global", "user", "chat"}
+}
+
 type MemorySearchConfig struct {
 	Enabled *bool `json:"enabled,omitempty"`
 
-	Provider string `json:"provider,omitempty"` // currently openai-compatible
+	Provider string `json:"provider,omitempty"` // openai (compatible), ollama, llamacpp or hash
 	Model    string `json:"model,omitempty"`
+	// Dimensions is the vector size of the hash provider, or the size
+	// requested from openai/ollama models that can shorten their output.
+	Dimensions int `json:"dimensions,omitempty"`
 
 	Remote MemorySearchRemoteConfig `json:"remote"`
 	Store  MemorySearchStoreConfig  `json:"store"`
 
+	Sources  MemorySearchSourcesConfig  `json:"sources"`
 	Chunking MemorySearchChunkingConfig `json:"chunking"`
 	Query    MemorySearchQueryConfig    `json:"query"`
 	Cache    MemorySearchCacheConfig    `json:"cache"`
@@ -107,15 +234,134 @@ func (c MemorySearchVectorStoreConfig) EnabledValue() bool {
 	return *c.Enabled
 }
 
+// MemorySearchSourcesConfig adds content to index besides MEMORY.md and
+// memory/**/*.md.
+type MemorySearchSourcesConfig struct {
+	// Paths are glob patterns relative to the workspace, e.g. "notes/**/*.md"
+	// or "docs/*.txt"; "**" matches any number of directories.
+	Paths    []string                        `json:"paths,omitempty"`
+	Sessions MemorySearchSessionSourceConfig `json:"sessions"`
+}
+
+// MemorySearchSessionSourceConfig indexes JSONL chat transcripts, one chunk
+// per turn. It requires the jsonl session store.
+type MemorySearchSessionSourceConfig struct {
+	Enabled *bool `json:"enabled,omitempty"`
+	// Dir holds the transcripts, including its archive/ subdirectory.
+	// Default: ~/.clawlet/sessions
+	Dir string `json:"dir,omitempty"`
+}
+
+func (c MemorySearchSessionSourceConfig) EnabledValue() bool {
+	if c.Enabled == nil {
+		return false
+	}
+	return *c.Enabled
+}
+
 type MemorySearchChunkingConfig struct {
 	Tokens  int `json:"tokens,omitempty"`
 	Overlap int `json:"overlap,omitempty"`
 }
 
 type MemorySearchQueryConfig struct {
-	MaxResults int                      `json:"maxResults,omitempty"`
-	MinScore   *float64                 `json:"minScore,omitempty"`
-	Hybrid     MemorySearchHybridConfig `json:"hybrid"`
+	MaxResults int                       `json:"maxResults,omitempty"`
+	MinScore   *float64                  `json:"minScore,omitempty"`
+	Hybrid     MemorySearchHybridConfig  `json:"hybrid"`
+	Rerank     MemorySearchRerankConfig  `json:"rerank"`
+	MMR        MemorySearchMMRConfig     `json:"mmr"`
+	Recency    MemorySearchRecencyConfig `json:"recency"`
+}
+
+// MemorySearchRerankConfig rescores the best candidates with a cross-encoder
+// served by a Jina/Cohere-style POST /rerank endpoint (llama.cpp
+// llama-server --reranking, vLLM, Jina, Cohere) or by
+// text-embeddings-inference (provider "tei").
+type MemorySearchRerankConfig struct {
+	Enabled  *bool             `json:"enabled,omitempty"`
+	Provider string            `json:"provider,omitempty"`
+	BaseURL  string            `json:"baseURL,omitempty"`
+	APIKey   string            `json:"apiKey,omitempty"`
+	Model    string            `json:"model,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	// Candidates is how many of the best hybrid results are reranked.
+	// Default: 20
+	Candidates int `json:"candidates,omitempty"`
+	// Weight of the rerank score against the hybrid score. Default: 0.5
+	Weight *float64 `json:"weight,omitempty"`
+}
+
+func (c MemorySearchRerankConfig) EnabledValue() bool {
+	if c.Enabled == nil {
+		return false
+	}
+	return *c.Enabled
+}
+
+func (c MemorySearchRerankConfig) CandidatesValue() int {
+	if c.Candidates <= 0 {
+		return DefaultMemorySearchRerankCandidates
+	}
+	return c.Candidates
+}
+
+func (c MemorySearchRerankConfig) WeightValue() float64 {
+	if c.Weight == nil {
+		return DefaultMemorySearchRerankWeight
+	}
+	return min(max(*c.Weight, 0), 1)
+}
+
+// MemorySearchMMRConfig diversifies results with Maximal Marginal Relevance,
+// so overlapping chunks of one file do not crowd out everything else.
+type MemorySearchMMRConfig struct {
+	Enabled *bool `json:"enabled,omitempty"`
+	// Lambda trades relevance (1) against diversity (0). Default: 0.7
+	Lambda *float64 `json:"lambda,omitempty"`
+}
+
+func (c MemorySearchMMRConfig) EnabledValue() bool {
+	if c.Enabled == nil {
+		return true
+	}
+	return *c.Enabled
+}
+
+func (c MemorySearchMMRConfig) LambdaValue() float64 {
+	if c.Lambda == nil {
+		return DefaultMemorySearchMMRLambda
+	}
+	return min(max(*c.Lambda, 0), 1)
+}
+
+// MemorySearchRecencyConfig boosts daily notes (memory/YYYY-MM-DD.md) by
+// age: a note from today scores up to Weight higher, one HalfLifeDays old
+// half as much.
+type MemorySearchRecencyConfig struct {
+	Enabled      *bool    `json:"enabled,omitempty"`
+	HalfLifeDays int      `json:"halfLifeDays,omitempty"`
+	Weight       *float64 `json:"weight,omitempty"`
+}
+
+func (c MemorySearchRecencyConfig) EnabledValue() bool {
+	if c.Enabled == nil {
+		return false
+	}
+	return *c.Enabled
+}
+
+func (c MemorySearchRecencyConfig) HalfLifeDaysValue() int {
+	if c.HalfLifeDays <= 0 {
+		return DefaultMemorySearchRecencyHalfLifeDays
+	}
+	return c.HalfLifeDays
+}
+
+func (c MemorySearchRecencyConfig) WeightValue() float64 {
+	if c.Weight == nil {
+		return DefaultMemorySearchRecencyWeight
+	}
+	return max(*c.Weight, 0)
 }
 
 type MemorySearchHybridConfig struct {
@@ -138,6 +384,13 @@ func (c MemorySearchCacheConfig) EnabledValue() bool {
 
 type MemorySearchSyncConfig struct {
 	OnSearch *bool `json:"onSearch,omitempty"`
+	// Watch re-indexes changed files in the background while the gateway
+	// runs (inotify on Linux, polling elsewhere). Default: true
+	Watch *bool `json:"watch,omitempty"`
+	// WatchDebounceMs waits for edits to settle before re-indexing. Default: 1500
+	WatchDebounceMs int `json:"watchDebounceMs,omitempty"`
+	// PollIntervalSec is the scan interval without inotify. Default: 5
+	PollIntervalSec int `json:"pollIntervalSec,omitempty"`
 }
 
 func (c MemorySearchSyncConfig) OnSearchValue() bool {
@@ -147,11 +400,33 @@ func (c MemorySearchSyncConfig) OnSearchValue() bool {
 	return *c.OnSearch
 }
 
+func (c MemorySearchSyncConfig) WatchValue() bool {
+	if c.Watch == nil {
+		return true
+	}
+	return *c.Watch
+}
+
+func (c MemorySearchSyncConfig) WatchDebounceValue() time.Duration {
+	if c.WatchDebounceMs <= 0 {
+		return DefaultMemorySearchWatchDebounceMs * time.Millisecond
+	}
+	return time.Duration(c.WatchDebounceMs) * time.Millisecond
+}
+
+func (c MemorySearchSyncConfig) PollIntervalValue() time.Duration {
+	if c.PollIntervalSec <= 0 {
+		return DefaultMemorySearchPollIntervalSec * time.Second
+	}
+	return time.Duration(c.PollIntervalSec) * time.Second
+}
+
 type ToolsConfig struct {
 	RestrictToWorkspace *bool            `json:"restrictToWorkspace"`
 	Exec                ExecToolConfig   `json:"exec"`
 	Web                 WebToolsConfig   `json:"web"`
 	Media               MediaToolsConfig `json:"media"`
+	MCP                 MCPToolsConfig   `json:"mcp"`
 }
 
 func (c ToolsConfig) RestrictToWorkspaceValue() bool {
@@ -169,6 +444,42 @@ type WebToolsConfig struct {
 	BraveAPIKey string `json:"braveApiKey"`
 }
 
+// MCPToolsConfig declares Model Context Protocol servers whose tools are
+// exposed to the agent as mcp_<server>__<tool>.
+type MCPToolsConfig struct {
+	Servers map[string]MCPServerConfig `json:"servers,omitempty"`
+}
+
+// MCPServerConfig configures one MCP server. Set Command for a stdio server
+// or URL for a streamable HTTP server.
+type MCPServerConfig struct {
+	Enabled *bool `json:"enabled,omitempty"`
+	// stdio
+	Command string            `json:"command,omitempty"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	Dir     string            `json:"dir,omitempty"`
+	// streamable HTTP
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// TimeoutSec bounds startup and each tool call. Default: 60
+	TimeoutSec int `json:"timeoutSec,omitempty"`
+}
+
+func (c MCPServerConfig) EnabledValue() bool {
+	if c.Enabled == nil {
+		return true
+	}
+	return *c.Enabled
+}
+
+func (c MCPServerConfig) TimeoutValue() time.Duration {
+	if c.TimeoutSec <= 0 {
+		return DefaultMCPTimeoutSec * time.Second
+	}
+	return time.Duration(c.TimeoutSec) * time.Second
+}
+
 type MediaToolsConfig struct {
 	Enabled             *bool `json:"enabled,omitempty"`
 	AudioEnabled        *bool `json:"audioEnabled,omitempty"`
@@ -211,6 +522,33 @@ func (c MediaToolsConfig) AttachmentEnabledValue() bool {
 
 type CronConfig struct {
 	Enabled *bool `json:"enabled"`
+	// DefaultTimezone is the IANA zone (e.g. "America/New_York") new cron
+	// expressions are evaluated in when none is given. Default: the server's
+	// local zone.
+	DefaultTimezone string `json:"defaultTimezone,omitempty"`
+	// MaxRetries is how often a failed run is retried. Default: 0.
+	MaxRetries int `json:"maxRetries,omitempty"`
+	// RetryBackoffSec is the delay before the first retry; it doubles with
+	// each further retry. Default: 30.
+	RetryBackoffSec int `json:"retryBackoffSec,omitempty"`
+	// Misfire decides what happens to runs missed while the gateway was
+	// down: "skip", "run-once" or "run-all". Default: "skip".
+	Misfire string `json:"misfire,omitempty"`
+}
+
+func (c CronConfig) RetryBackoffValue() time.Duration {
+	if c.RetryBackoffSec <= 0 {
+		return DefaultCronRetryBackoffSec * time.Second
+	}
+	return time.Duration(c.RetryBackoffSec) * time.Second
+}
+
+func (c CronConfig) MisfireValue() string {
+	v := strings.ToLower(strings.TrimSpace(c.Misfire))
+	if v == "" {
+		return DefaultCronMisfire
+	}
+	return v
 }
 
 func (c CronConfig) EnabledValue() bool {
@@ -220,6 +558,21 @@ func (c CronConfig) EnabledValue() bool {
 	return *c.Enabled
 }
 
+// CommandsConfig controls in-chat slash commands (/new, /model, /status, ...).
+type CommandsConfig struct {
+	Enabled *bool `json:"enabled"`
+	// Admins may use /model and /memory. Entries match a sender ID
+	// ("123456") or a channel-qualified one ("telegram:123456").
+	Admins []string `json:"admins,omitempty"`
+}
+
+func (c CommandsConfig) EnabledValue() bool {
+	if c.Enabled == nil {
+		return true
+	}
+	return *c.Enabled
+}
+
 type HeartbeatConfig struct {
 	Enabled     *bool `json:"enabled"`
 	IntervalSec int   `json:"intervalSec"`
@@ -232,13 +585,84 @@ func (c HeartbeatConfig) EnabledValue() bool {
 	return *c.Enabled
 }
 
+type SessionsConfig struct {
+	// Store is where sessions are persisted: "jsonl" (default, one file per
+	// session under ~/.clawlet/sessions) or "sqlite" (~/.clawlet/sessions.sqlite).
+	Store string `json:"store,omitempty"`
+	// MaxCached bounds how many sessions the gateway keeps in memory. Default: 256.
+	MaxCached  int                     `json:"maxCached,omitempty"`
+	Transcript SessionTranscriptConfig `json:"transcript"`
+}
+
+func (c SessionsConfig) StoreValue() string {
+	v := strings.ToLower(strings.TrimSpace(c.Store))
+	if v == "" {
+		return DefaultSessionsStore
+	}
+	return v
+}
+
+func (c SessionsConfig) MaxCachedValue() int {
+	if c.MaxCached <= 0 {
+		return DefaultSessionsMaxCached
+	}
+	return c.MaxCached
+}
+
+// SessionTranscriptConfig controls how much of each turn is persisted to the
+// session file. By default only user and final assistant messages are kept.
+type SessionTranscriptConfig struct {
+	// ToolCalls also persists assistant tool calls and tool results, so later
+	// turns (and restarts) replay them to the model. Default: false.
+	ToolCalls *bool `json:"toolCalls,omitempty"`
+	// MaxArgsChars caps persisted tool call arguments. Default: 2000.
+	MaxArgsChars int `json:"maxArgsChars,omitempty"`
+	// MaxResultChars caps persisted tool results. Default: 4000.
+	MaxResultChars int `json:"maxResultChars,omitempty"`
+	// Redact lists extra regular expressions masked in persisted tool calls
+	// and results, on top of built-in patterns for common API keys and tokens.
+	Redact []string `json:"redact,omitempty"`
+}
+
+func (c SessionTranscriptConfig) ToolCallsValue() bool {
+	if c.ToolCalls == nil {
+		return false
+	}
+	return *c.ToolCalls
+}
+
+func (c SessionTranscriptConfig) MaxArgsCharsValue() int {
+	if c.MaxArgsChars <= 0 {
+		return DefaultSessionTranscriptMaxArgsChars
+	}
+	return c.MaxArgsChars
+}
+
+func (c SessionTranscriptConfig) MaxResultCharsValue() int {
+	if c.MaxResultChars <= 0 {
+		return DefaultSessionTranscriptMaxResultChars
+	}
+	return c.MaxResultChars
+}
+
 type GatewayConfig struct {
-	// Listen address for HTTP endpoints needed by channels (reserved for future use).
+	// Listen address for the gateway HTTP server (OpenAI-compatible API, webhook channel).
+	// The listener is only opened when an HTTP feature is enabled.
 	// Default: "127.0.0.1:18790"
 	Listen string `json:"listen"`
 	// Allow binding gateway to non-localhost addresses.
 	// Keep false unless you intentionally expose it behind a trusted tunnel/proxy.
 	AllowPublicBind bool `json:"allowPublicBind,omitempty"`
+	// API serves /v1/chat/completions and /v1/models on Listen.
+	API GatewayAPIConfig `json:"api"`
+}
+
+// GatewayAPIConfig configures the OpenAI-compatible HTTP API.
+// Requests pick a session via the X-Clawlet-Session header or the `user` field.
+type GatewayAPIConfig struct {
+	Enabled bool `json:"enabled"`
+	// Tokens accepted as "Authorization: Bearer <token>". Required when enabled.
+	Tokens []string `json:"tokens,omitempty"`
 }
 
 type ChannelsConfig struct {
@@ -246,6 +670,7 @@ type ChannelsConfig struct {
 	Slack    SlackConfig    `json:"slack"`
 	Telegram TelegramConfig `json:"telegram"`
 	WhatsApp WhatsAppConfig `json:"whatsapp"`
+	Webhook  WebhookConfig  `json:"webhook"`
 }
 
 type DiscordConfig struct {
@@ -291,10 +716,31 @@ type WhatsAppConfig struct {
 	SessionStorePath string   `json:"sessionStorePath,omitempty"` // optional: sqlite store path for persistent login
 }
 
+// Webhook (generic signed JSON over HTTP).
+// Inbound via POST on gateway.listen, outbound via POST to CallbackURL.
+// Both directions are signed with HMAC-SHA256 using Secret.
+type WebhookConfig struct {
+	Enabled   bool     `json:"enabled"`
+	AllowFrom []string `json:"allowFrom"`
+	Secret    string   `json:"secret"`
+	// Path of the inbound endpoint. Default: "/webhook"
+	Path string `json:"path,omitempty"`
+	// CallbackURL receives replies; replies are dropped when empty.
+	CallbackURL string `json:"callbackURL,omitempty"`
+	MaxRetries  int    `json:"maxRetries,omitempty"`
+	TimeoutSec  int    `json:"timeoutSec,omitempty"`
+}
+
 const (
 	DefaultAgentMaxTokens                  = 8192
 	DefaultAgentTemperature                = 0.7
+	DefaultAgentToolConcurrency            = 4
 	DefaultAgentMemoryWindow               = 50
+	DefaultAgentMaxConcurrentTurns         = 4
+	DefaultAgentQueueMode                  = "collect"
+	DefaultLLMRetryMaxAttempts             = 3
+	DefaultLLMRetryInitialBackoffMs        = 500
+	DefaultLLMRetryMaxBackoffMs            = 8000
 	DefaultMemorySearchChunkTokens         = 400
 	DefaultMemorySearchChunkOverlap        = 80
 	DefaultMemorySearchMaxResults          = 6
@@ -302,12 +748,31 @@ const (
 	DefaultMemorySearchHybridVectorWeight  = 0.7
 	DefaultMemorySearchHybridTextWeight    = 0.3
 	DefaultMemorySearchCandidateMultiplier = 4
+	DefaultMemorySearchHashDimensions      = 256
+	DefaultMemorySearchWatchDebounceMs     = 1500
+	DefaultMemorySearchPollIntervalSec     = 5
+	DefaultMemorySearchRerankCandidates    = 20
+	DefaultMemorySearchRerankWeight        = 0.5
+	DefaultMemorySearchMMRLambda           = 0.7
+	DefaultMemorySearchRecencyHalfLifeDays = 30
+	DefaultMemorySearchRecencyWeight       = 0.2
+	DefaultMemoryScope                     = "user"
+	DefaultMCPTimeoutSec                   = 60
+	DefaultCronRetryBackoffSec             = 30
+	DefaultCronMisfire                     = "skip"
+	DefaultSessionsStore                   = "jsonl"
+	DefaultSessionsMaxCached               = 256
+	DefaultSessionTranscriptMaxArgsChars   = 2000
+	DefaultSessionTranscriptMaxResultChars = 4000
 	DefaultOpenAIBaseURL                   = "https://api.openai.com/v1"
 	DefaultOpenAICodexBaseURL              = "https://chatgpt.com/backend-api"
 	DefaultOpenRouterBaseURL               = "https://openrouter.ai/api/v1"
 	DefaultAnthropicBaseURL                = "https://api.anthropic.com"
 	DefaultGeminiBaseURL                   = "https://generativelanguage.googleapis.com/v1beta"
 	DefaultOllamaBaseURL                   = "http://localhost:11434/v1"
+	DefaultOllamaEmbedBaseURL              = "http://localhost:11434"
+	DefaultLlamaCppBaseURL                 = "http://localhost:8080"
+	DefaultRerankBaseURL                   = "http://localhost:8080"
 	DefaultMediaMaxAttachments             = 4
 	DefaultMediaMaxFileBytes               = int64(20 << 20)
 	DefaultMediaMaxInlineImageBytes        = int64(5 << 20)
@@ -333,7 +798,12 @@ func Default() *Config {
 	return &Config{
 		Env: map[string]string{},
 		Agents: AgentsConfig{Defaults: AgentDefaultsConfig{
-			Model:        "openrouter/openai/gpt-4o-mini",
+			Model: "openrouter/openai/gpt-4o-mini",
+			Retry: LLMRetryConfig{
+				MaxAttempts:      DefaultLLMRetryMaxAttempts,
+				InitialBackoffMs: DefaultLLMRetryInitialBackoffMs,
+				MaxBackoffMs:     DefaultLLMRetryMaxBackoffMs,
+			},
 			MemoryWindow: DefaultAgentMemoryWindow,
 			MemorySearch: MemorySearchConfig{
 				Enabled:  &memSearchEnabled,
@@ -437,6 +907,10 @@ func Default() *Config {
 				Enabled:   false,
 				AllowFrom: nil,
 			},
+			Webhook: WebhookConfig{
+				Enabled: false,
+				Path:    "/webhook",
+			},
 		},
 	}
 }
@@ -587,6 +1061,9 @@ func Load(path string) (*Config, error) {
 	if cfg.Channels.Telegram.Workers <= 0 {
 		cfg.Channels.Telegram.Workers = 2
 	}
+	if strings.TrimSpace(cfg.Channels.Webhook.Path) == "" {
+		cfg.Channels.Webhook.Path = "/webhook"
+	}
 	cfg.Channels.WhatsApp.SessionStorePath = strings.TrimSpace(cfg.Channels.WhatsApp.SessionStorePath)
 
 	// Apply model routing to populate cfg.LLM for runtime use.
@@ -702,6 +1179,40 @@ func (cfg *Config) ApplyLLMRouting() (provider string, configuredModel string) {
 	return provider, configuredModel
 }
 
+// FallbackLLMs resolves agents.defaults.fallbackModels with the same routing
+// rules as ApplyLLMRouting. A fallback on the primary's provider inherits the
+// explicit llm.baseURL/apiKey/headers; other providers use their defaults and
+// API keys from env.
+func (cfg *Config) FallbackLLMs() []LLMConfig {
+	out := make([]LLMConfig, 0, len(cfg.Agents.Defaults.FallbackModels))
+	for _, m := range cfg.Agents.Defaults.FallbackModels {
+		m = strings.TrimSpace(m)
+		if m == "" {
+			continue
+		}
+		out = append(out, cfg.RoutedLLM(m))
+	}
+	return out
+}
+
+// RoutedLLM resolves a model name such as "anthropic/claude-sonnet-4-5" to
+// an endpoint, following the same rules as FallbackLLMs.
+func (cfg *Config) RoutedLLM(model string) LLMConfig {
+	model = strings.TrimSpace(model)
+	r := Config{Env: cfg.Env}
+	r.Agents.Defaults.Model = model
+	if p, _ := parseRoutedModel(model); p == "" || p == canonicalProvider(cfg.LLM.Provider) {
+		r.LLM = LLMConfig{
+			Provider: cfg.LLM.Provider,
+			APIKey:   cfg.LLM.APIKey,
+			BaseURL:  cfg.LLM.BaseURL,
+			Headers:  cfg.LLM.Headers,
+		}
+	}
+	r.ApplyLLMRouting()
+	return r.LLM
+}
+
 func parseRoutedModel(s string) (provider string, model string) {
 	s = strings.TrimSpace(s)
 	if after, ok := strings.CutPrefix(s, "openai-codex/"); ok {
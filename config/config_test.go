@@ -290,3 +290,24 @@ func TestGatewayDefaults_LocalhostAndNoPublicBind(t *testing.T) {
 		t.Fatalf("loaded gateway.allowPublicBind must be false")
 	}
 }
+
+func TestFallbackLLMs_RoutesLikePrimary(t *testing.T) {
+	cfg := Default()
+	cfg.Env["OPENROUTER_API_KEY"] = "or-key"
+	cfg.Env["ANTHROPIC_API_KEY"] = "ant-key"
+	cfg.Agents.Defaults.Model = "anthropic/claude-primary"
+	cfg.LLM.APIKey = "explicit-key"
+	cfg.Agents.Defaults.FallbackModels = []string{"anthropic/claude-backup", "openrouter/openai/gpt-4o-mini", " "}
+	cfg.ApplyLLMRouting()
+
+	fbs := cfg.FallbackLLMs()
+	if len(fbs) != 2 {
+		t.Fatalf("fallbacks=%d", len(fbs))
+	}
+	if fbs[0].Provider != "anthropic" || fbs[0].Model != "claude-backup" || fbs[0].APIKey != "explicit-key" {
+		t.Fatalf("same-provider fallback=%+v", fbs[0])
+	}
+	if fbs[1].Provider != "openrouter" || fbs[1].Model != "openai/gpt-4o-mini" || fbs[1].APIKey != "or-key" || fbs[1].BaseURL != DefaultOpenRouterBaseURL {
+		t.Fatalf("openrouter fallback=%+v", fbs[1])
+	}
+}
@@ -5,12 +5,15 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 )
 
 type Schedule struct {
@@ -18,15 +21,73 @@ type Schedule struct {
 	AtMS    int64  `json:"atMs,omitempty"`
 	EveryMS int64  `json:"everyMs,omitempty"`
 	Expr    string `json:"expr,omitempty"`
-	TZ      string `json:"tz,omitempty"`
+	// TZ is the IANA zone (e.g. "Europe/Berlin") cron expressions are
+	// evaluated in. Empty means the server's local zone.
+	TZ string `json:"tz,omitempty"`
 }
 
+var locationCache sync.Map // map[string]*time.Location
+
+// Location returns the zone of TZ, or time.Local when TZ is empty.
+func (s Schedule) Location() (*time.Location, error) {
+	return LoadLocation(s.TZ)
+}
+
+// LoadLocation is time.LoadLocation with caching; an empty name is the
+// server's local zone.
+func LoadLocation(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return time.Local, nil
+	}
+	if loc, ok := locationCache.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", name)
+	}
+	locationCache.Store(name, loc)
+	return loc, nil
+}
+
+// ParseTime parses an RFC3339 timestamp, or a wall-clock time
+// ("2006-01-02T15:04", "2006-01-02 15:04", optionally with seconds) in loc.
+func ParseTime(v string, loc *time.Location) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02T15:04:05", "2006-01-02 15:04:05"} {
+		if t, err := time.ParseInLocation(layout, v, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (expected RFC3339 or 2006-01-02T15:04)", v)
+}
+
+// Payload kinds.
+const (
+	// PayloadAgentTurn sends Message to the agent, delivering the reply to
+	// Channel/To when Deliver is set.
+	PayloadAgentTurn = "agent_turn"
+	// PayloadMessage posts Message to Channel/To as is, without an LLM call.
+	PayloadMessage = "message"
+	// PayloadExec runs Command in the workspace under the exec tool's guard,
+	// delivering its output to Channel/To when Deliver is set.
+	PayloadExec = "exec"
+	// PayloadWebhook POSTs the job as JSON to URL.
+	PayloadWebhook = "webhook"
+)
+
 type Payload struct {
-	Kind    string `json:"kind"` // "agent_turn"
+	Kind    string `json:"kind"` // one of the Payload* kinds; empty means agent_turn
 	Message string `json:"message"`
 	Deliver bool   `json:"deliver"`
 	Channel string `json:"channel,omitempty"`
 	To      string `json:"to,omitempty"`
+	Command string `json:"command,omitempty"` // exec
+	URL     string `json:"url,omitempty"`     // webhook
 }
 
 type State struct {
@@ -34,6 +95,72 @@ type State struct {
 	LastRunAtMS int64  `json:"lastRunAtMs,omitempty"`
 	LastStatus  string `json:"lastStatus,omitempty"`
 	LastError   string `json:"lastError,omitempty"`
+	// Attempts counts failed attempts of the current run; the next run is
+	// a retry while it is non-zero.
+	Attempts int `json:"attempts,omitempty"`
+	// CatchUp is the number of missed runs still to be made up for.
+	CatchUp int `json:"catchUp,omitempty"`
+	// History holds the most recent runs, oldest first.
+	History []Run `json:"history,omitempty"`
+}
+
+// Run triggers.
+const (
+	TriggerSchedule = "schedule"
+	TriggerRetry    = "retry"
+	TriggerCatchUp  = "catch-up"
+	TriggerManual   = "manual"
+)
+
+// Run is one execution of a job.
+type Run struct {
+	StartedAtMS  int64  `json:"startedAtMs"`
+	FinishedAtMS int64  `json:"finishedAtMs"`
+	Trigger      string `json:"trigger,omitempty"`
+	Attempt      int    `json:"attempt,omitempty"` // 1 for the first try, 2+ for retries
+	Status       string `json:"status"`            // "ok" | "error"
+	Output       string `json:"output,omitempty"`  // excerpt
+	Error        string `json:"error,omitempty"`
+}
+
+func (r Run) Duration() time.Duration {
+	return time.Duration(r.FinishedAtMS-r.StartedAtMS) * time.Millisecond
+}
+
+const (
+	// maxRunHistory is how many runs are kept per job.
+	maxRunHistory = 20
+	// maxRunOutput caps the output excerpt stored with a run.
+	maxRunOutput = 500
+	// maxCatchUp caps the runs made up for under MisfireRunAll.
+	maxCatchUp = 100
+	// maxRetryBackoff caps the doubling retry delay.
+	maxRetryBackoff = time.Hour
+)
+
+// Misfire policies decide what Start does about runs missed while the
+// service was not running.
+const (
+	MisfireSkip    = "skip"     // resume with the next run after now
+	MisfireRunOnce = "run-once" // run once now for all missed runs
+	MisfireRunAll  = "run-all"  // run once for every missed run, up to 100
+)
+
+// Retry re-runs a job whose run failed.
+type Retry struct {
+	// MaxAttempts is the number of retries after a failed run.
+	MaxAttempts int `json:"maxAttempts"`
+	// BackoffMS is the delay before the first retry; it doubles with each
+	// further retry, up to an hour.
+	BackoffMS int64 `json:"backoffMs,omitempty"`
+}
+
+func (r Retry) delayMS(attempt int) int64 {
+	d := time.Duration(max64(r.BackoffMS, 1000)) * time.Millisecond
+	for i := 1; i < attempt && d < maxRetryBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxRetryBackoff).Milliseconds()
 }
 
 type Job struct {
@@ -46,6 +173,10 @@ type Job struct {
 	CreatedAtMS    int64    `json:"createdAtMs"`
 	UpdatedAtMS    int64    `json:"updatedAtMs"`
 	DeleteAfterRun bool     `json:"deleteAfterRun,omitempty"`
+	// Misfire overrides the service's misfire policy when set.
+	Misfire string `json:"misfire,omitempty"`
+	// Retry overrides the service's retry policy when set.
+	Retry *Retry `json:"retry,omitempty"`
 }
 
 type Store struct {
@@ -57,17 +188,31 @@ type Service struct {
 	storePath string
 	onJob     func(ctx context.Context, job Job) (string, error)
 
+	// Retry and Misfire apply to jobs that do not set their own. Set them
+	// before Start.
+	Retry   Retry
+	Misfire string
+
 	mu      sync.Mutex
 	store   Store
 	running bool
+	runCtx  context.Context // of Start, for re-arming after changes
 	timer   *time.Timer
+	// inFlight holds the IDs of jobs the timer started that have not
+	// finished; they are neither started again nor waited for.
+	inFlight map[string]bool
 }
 
+// ErrNoHandler is returned when running jobs on a Service created without a
+// job handler, such as the one the CLI uses to edit the job store.
+var ErrNoHandler = errors.New("cron: no job handler; jobs run in the gateway")
+
 func NewService(storePath string, onJob func(ctx context.Context, job Job) (string, error)) *Service {
 	return &Service{
 		storePath: storePath,
 		onJob:     onJob,
 		store:     Store{Version: 1, Jobs: nil},
+		inFlight:  map[string]bool{},
 	}
 }
 
@@ -77,14 +222,21 @@ func (s *Service) Start(ctx context.Context) error {
 	if s.running {
 		return nil
 	}
+	if s.onJob == nil {
+		return ErrNoHandler
+	}
+	if err := validateMisfire(s.Misfire); err != nil {
+		return err
+	}
 	if err := s.loadLocked(); err != nil {
 		return err
 	}
-	s.recomputeNextRunsLocked()
+	s.catchUpLocked(nowMS())
 	if err := s.saveLocked(); err != nil {
 		return err
 	}
 	s.running = true
+	s.runCtx = ctx
 	s.armLocked(ctx)
 	return nil
 }
@@ -112,35 +264,58 @@ func (s *Service) List(includeDisabled bool) []Job {
 	return jobs
 }
 
+// Get returns the job with the given ID.
+func (s *Service) Get(id string) (Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_ = s.loadLocked()
+	for _, j := range s.store.Jobs {
+		if j.ID == id {
+			return j, true
+		}
+	}
+	return Job{}, false
+}
+
 func (s *Service) Add(name string, sched Schedule, payload Payload) (Job, error) {
+	return s.AddJob(Job{Name: name, Schedule: sched, Payload: payload})
+}
+
+// AddJob adds an enabled job from the Name, Schedule, Payload, Misfire,
+// Retry and DeleteAfterRun of j.
+func (s *Service) AddJob(j Job) (Job, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if err := s.loadLocked(); err != nil {
 		return Job{}, err
 	}
 	now := nowMS()
-	if err := validateSchedule(sched, now); err != nil {
+	if err := validateSchedule(j.Schedule, now); err != nil {
 		return Job{}, err
 	}
-	nextRun := computeNextRunMS(sched, now)
+	if err := validateMisfire(j.Misfire); err != nil {
+		return Job{}, err
+	}
+	if err := validatePayload(j.Payload); err != nil {
+		return Job{}, err
+	}
+	if j.Retry != nil && (j.Retry.MaxAttempts < 0 || j.Retry.BackoffMS < 0) {
+		return Job{}, fmt.Errorf("retry attempts and backoff must not be negative")
+	}
+	nextRun := computeNextRunMS(j.Schedule, now)
 	if nextRun <= 0 {
-		return Job{}, fmt.Errorf("failed to compute next run for schedule kind: %s", sched.Kind)
-	}
-	j := Job{
-		ID:          newID(),
-		Name:        name,
-		Enabled:     true,
-		Schedule:    sched,
-		Payload:     payload,
-		State:       State{},
-		CreatedAtMS: now,
-		UpdatedAtMS: now,
-	}
-	j.State.NextRunAtMS = nextRun
+		return Job{}, fmt.Errorf("failed to compute next run for schedule kind: %s", j.Schedule.Kind)
+	}
+	j.ID = newID()
+	j.Enabled = true
+	j.State = State{NextRunAtMS: nextRun}
+	j.CreatedAtMS = now
+	j.UpdatedAtMS = now
 	s.store.Jobs = append(s.store.Jobs, j)
 	if err := s.saveLocked(); err != nil {
 		return Job{}, err
 	}
+	s.rearmLocked()
 	return j, nil
 }
 
@@ -162,6 +337,62 @@ func (s *Service) Remove(id string) bool {
 	return removed
 }
 
+// Update applies fn to the job with the given ID and saves it. The schedule
+// and misfire policy are validated again, and an enabled job's next run is
+// recomputed when its schedule changed.
+func (s *Service) Update(id string, fn func(j *Job) error) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.loadLocked(); err != nil {
+		return Job{}, err
+	}
+	for i := range s.store.Jobs {
+		if s.store.Jobs[i].ID != id {
+			continue
+		}
+		j := s.store.Jobs[i]
+		j.Retry = cloneRetry(j.Retry)
+		if err := fn(&j); err != nil {
+			return Job{}, err
+		}
+		now := nowMS()
+		if j.Schedule != s.store.Jobs[i].Schedule {
+			if err := validateSchedule(j.Schedule, now); err != nil {
+				return Job{}, err
+			}
+			j.State.Attempts = 0
+			j.State.CatchUp = 0
+			j.State.NextRunAtMS = 0
+			if j.Enabled {
+				j.State.NextRunAtMS = computeNextRunMS(j.Schedule, now)
+			}
+		}
+		if err := validateMisfire(j.Misfire); err != nil {
+			return Job{}, err
+		}
+		if err := validatePayload(j.Payload); err != nil {
+			return Job{}, err
+		}
+		j.ID = id
+		j.UpdatedAtMS = now
+		s.store.Jobs[i] = j
+		if err := s.saveLocked(); err != nil {
+			return Job{}, err
+		}
+		s.rearmLocked()
+		return j, nil
+	}
+	return Job{}, fmt.Errorf("job not found: %s", id)
+}
+
+func cloneRetry(r *Retry) *Retry {
+	if r == nil {
+		return nil
+	}
+	c := *r
+	return &c
+}
+
 func (s *Service) Toggle(id string, disable bool) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -172,6 +403,8 @@ func (s *Service) Toggle(id string, disable bool) bool {
 			continue
 		}
 		s.store.Jobs[i].Enabled = !disable
+		s.store.Jobs[i].State.Attempts = 0
+		s.store.Jobs[i].State.CatchUp = 0
 		if s.store.Jobs[i].Enabled {
 			s.store.Jobs[i].State.NextRunAtMS = computeNextRunMS(s.store.Jobs[i].Schedule, now)
 		} else {
@@ -179,6 +412,7 @@ func (s *Service) Toggle(id string, disable bool) bool {
 		}
 		s.store.Jobs[i].UpdatedAtMS = now
 		_ = s.saveLocked()
+		s.rearmLocked()
 		return true
 	}
 	return false
@@ -201,7 +435,14 @@ func (s *Service) RunNow(ctx context.Context, id string, force bool) (string, er
 	if !job.Enabled && !force {
 		return "", fmt.Errorf("job disabled: %s (use force)", id)
 	}
-	return s.execute(ctx, *job)
+	return s.execute(ctx, *job, TriggerManual)
+}
+
+// rearmLocked re-arms the timer of a running service after jobs changed.
+func (s *Service) rearmLocked() {
+	if s.running {
+		s.armLocked(s.runCtx)
+	}
 }
 
 func (s *Service) armLocked(ctx context.Context) {
@@ -221,46 +462,61 @@ func (s *Service) armLocked(ctx context.Context) {
 	})
 }
 
+// onTimer starts the due jobs and re-arms the timer right away. Each job
+// runs on its own, so a slow agent turn doesn't hold back the others; the
+// timer is re-armed again when it finishes.
 func (s *Service) onTimer(ctx context.Context) error {
-	// Run due jobs and re-arm. Keep lock short.
 	var due []Job
 	now := nowMS()
 	s.mu.Lock()
+	defer s.mu.Unlock()
 	if !s.running {
-		s.mu.Unlock()
 		return nil
 	}
 	_ = s.loadLocked()
 	for _, j := range s.store.Jobs {
-		if !j.Enabled || j.State.NextRunAtMS <= 0 {
+		if !j.Enabled || j.State.NextRunAtMS <= 0 || s.inFlight[j.ID] {
 			continue
 		}
 		if now >= j.State.NextRunAtMS {
 			due = append(due, j)
+			s.inFlight[j.ID] = true
 		}
 	}
-	s.mu.Unlock()
+	s.fillNextRunsLocked(now)
+	_ = s.saveLocked()
+	s.armLocked(ctx)
 
 	for _, j := range due {
-		_, _ = s.execute(ctx, j)
+		trigger := TriggerSchedule
+		switch {
+		case j.State.Attempts > 0:
+			trigger = TriggerRetry
+		case j.State.CatchUp > 0:
+			trigger = TriggerCatchUp
+		}
+		go func() {
+			_, _ = s.execute(ctx, j, trigger)
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			delete(s.inFlight, j.ID)
+			_ = s.loadLocked()
+			s.fillNextRunsLocked(nowMS())
+			_ = s.saveLocked()
+			s.armLocked(ctx)
+		}()
 	}
-
-	s.mu.Lock()
-	_ = s.loadLocked()
-	s.recomputeNextRunsLocked()
-	_ = s.saveLocked()
-	s.armLocked(ctx)
-	s.mu.Unlock()
 	return nil
 }
 
-func (s *Service) execute(ctx context.Context, job Job) (string, error) {
-	start := nowMS()
-	var resp string
-	var err error
-	if s.onJob != nil {
-		resp, err = s.onJob(ctx, job)
+// execute runs job and records the run. Nothing is recorded without a
+// handler, since nothing ran.
+func (s *Service) execute(ctx context.Context, job Job, trigger string) (string, error) {
+	if s.onJob == nil {
+		return "", ErrNoHandler
 	}
+	start := nowMS()
+	resp, err := s.onJob(ctx, job)
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -271,16 +527,48 @@ func (s *Service) execute(ctx context.Context, job Job) (string, error) {
 		}
 		j := &s.store.Jobs[i]
 		updated := nowMS()
+		run := Run{
+			StartedAtMS:  start,
+			FinishedAtMS: updated,
+			Trigger:      trigger,
+			Attempt:      1,
+			Status:       "ok",
+			Output:       excerpt(resp, maxRunOutput),
+		}
+		if trigger != TriggerManual {
+			run.Attempt = j.State.Attempts + 1
+		}
 		j.State.LastRunAtMS = start
 		if err != nil {
+			run.Status = "error"
+			run.Error = err.Error()
 			j.State.LastStatus = "error"
 			j.State.LastError = err.Error()
 		} else {
 			j.State.LastStatus = "ok"
 			j.State.LastError = ""
 		}
+		j.State.History = append(j.State.History, run)
+		if n := len(j.State.History); n > maxRunHistory {
+			j.State.History = append([]Run(nil), j.State.History[n-maxRunHistory:]...)
+		}
 		j.UpdatedAtMS = updated
 
+		if trigger != TriggerManual && err != nil && s.scheduleRetryLocked(j, updated) {
+			break
+		}
+		if trigger != TriggerManual {
+			j.State.Attempts = 0
+			if j.State.CatchUp > 0 {
+				j.State.CatchUp--
+			}
+			if j.State.CatchUp > 0 {
+				// Make up for the next missed run right away.
+				j.State.NextRunAtMS = updated
+				break
+			}
+		}
+
 		// One-shot at: disable or delete
 		if j.Schedule.Kind == "at" {
 			if j.DeleteAfterRun {
@@ -334,21 +622,132 @@ func (s *Service) saveLocked() error {
 	return os.Rename(tmp, s.storePath)
 }
 
-func (s *Service) recomputeNextRunsLocked() {
-	now := nowMS()
+// scheduleRetryLocked schedules a retry of the failed run of j, unless its
+// retries are used up or its next regular run comes first.
+func (s *Service) scheduleRetryLocked(j *Job, now int64) bool {
+	retry := s.Retry
+	if j.Retry != nil {
+		retry = *j.Retry
+	}
+	if j.State.Attempts >= retry.MaxAttempts {
+		return false
+	}
+	at := now + retry.delayMS(j.State.Attempts+1)
+	if j.Schedule.Kind != "at" && j.State.CatchUp <= 1 {
+		if next := computeNextRunMS(j.Schedule, now); next > 0 && next <= at {
+			return false
+		}
+	}
+	j.State.Attempts++
+	j.State.NextRunAtMS = at
+	return true
+}
+
+// fillNextRunsLocked schedules enabled jobs that have no next run yet, such
+// as jobs added by another process, and clears disabled ones.
+func (s *Service) fillNextRunsLocked(now int64) {
 	for i := range s.store.Jobs {
-		if !s.store.Jobs[i].Enabled {
-			s.store.Jobs[i].State.NextRunAtMS = 0
+		j := &s.store.Jobs[i]
+		switch {
+		case !j.Enabled:
+			j.State.NextRunAtMS = 0
+		case j.State.NextRunAtMS <= 0:
+			j.State.NextRunAtMS = computeNextRunMS(j.Schedule, now)
+		}
+	}
+}
+
+// catchUpLocked applies the misfire policy to jobs whose next run passed
+// while the service was not running.
+func (s *Service) catchUpLocked(now int64) {
+	s.fillNextRunsLocked(now)
+	for i := range s.store.Jobs {
+		j := &s.store.Jobs[i]
+		if !j.Enabled || j.State.NextRunAtMS <= 0 || j.State.NextRunAtMS > now {
 			continue
 		}
-		s.store.Jobs[i].State.NextRunAtMS = computeNextRunMS(s.store.Jobs[i].Schedule, now)
+		policy := s.Misfire
+		if j.Misfire != "" {
+			policy = j.Misfire
+		}
+		switch policy {
+		case MisfireRunOnce:
+			j.State.CatchUp = 1
+		case MisfireRunAll:
+			j.State.CatchUp = countRuns(j.Schedule, j.State.NextRunAtMS, now, maxCatchUp)
+		default:
+			j.State.Attempts = 0
+			j.State.CatchUp = 0
+			j.State.NextRunAtMS = computeNextRunMS(j.Schedule, now)
+		}
+	}
+}
+
+// countRuns counts the runs of sched from first up to now, at most limit.
+func countRuns(sched Schedule, first, now int64, limit int) int {
+	n := 0
+	for at := first; at > 0 && at <= now && n < limit; at = computeNextRunMS(sched, at) {
+		n++
 	}
+	return n
+}
+
+func validatePayload(p Payload) error {
+	if (strings.TrimSpace(p.Channel) == "") != (strings.TrimSpace(p.To) == "") {
+		return fmt.Errorf("payload channel and to must be set together")
+	}
+	switch p.Kind {
+	case "", PayloadAgentTurn:
+		if strings.TrimSpace(p.Message) == "" {
+			return fmt.Errorf("agent_turn payload requires a message")
+		}
+	case PayloadMessage:
+		if strings.TrimSpace(p.Message) == "" {
+			return fmt.Errorf("message payload requires a message")
+		}
+		if strings.TrimSpace(p.Channel) == "" {
+			return fmt.Errorf("message payload requires channel and to")
+		}
+	case PayloadExec:
+		if strings.TrimSpace(p.Command) == "" {
+			return fmt.Errorf("exec payload requires a command")
+		}
+	case PayloadWebhook:
+		u, err := url.Parse(strings.TrimSpace(p.URL))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhook payload requires an http(s) url")
+		}
+	default:
+		return fmt.Errorf("unknown payload kind: %s", p.Kind)
+	}
+	return nil
+}
+
+func validateMisfire(policy string) error {
+	switch policy {
+	case "", MisfireSkip, MisfireRunOnce, MisfireRunAll:
+		return nil
+	default:
+		return fmt.Errorf("unknown misfire policy: %s (expected %s, %s or %s)", policy, MisfireSkip, MisfireRunOnce, MisfireRunAll)
+	}
+}
+
+// excerpt shortens s to at most n bytes on a rune boundary.
+func excerpt(s string, n int) string {
+	s = strings.TrimSpace(s)
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + "…"
 }
 
 func (s *Service) nextWakeMSLocked() int64 {
 	var best int64
 	for _, j := range s.store.Jobs {
-		if !j.Enabled || j.State.NextRunAtMS <= 0 {
+		if !j.Enabled || j.State.NextRunAtMS <= 0 || s.inFlight[j.ID] {
 			continue
 		}
 		if best == 0 || j.State.NextRunAtMS < best {
@@ -374,11 +773,18 @@ func computeNextRunMS(s Schedule, now int64) int64 {
 		if strings.TrimSpace(s.Expr) == "" {
 			return 0
 		}
-		sched, err := parseCron5(strings.TrimSpace(s.Expr))
+		sched, err := parseCron(strings.TrimSpace(s.Expr))
+		if err != nil {
+			return 0
+		}
+		loc, err := s.Location()
 		if err != nil {
 			return 0
 		}
-		next := sched.Next(time.UnixMilli(now))
+		next := sched.Next(time.UnixMilli(now).In(loc))
+		if next.IsZero() {
+			return 0
+		}
 		return next.UnixMilli()
 	default:
 		return 0
@@ -388,6 +794,9 @@ func computeNextRunMS(s Schedule, now int64) int64 {
 func nowMS() int64 { return time.Now().UnixMilli() }
 
 func validateSchedule(s Schedule, now int64) error {
+	if _, err := s.Location(); err != nil {
+		return err
+	}
 	switch s.Kind {
 	case "at":
 		if s.AtMS <= 0 {
@@ -407,7 +816,7 @@ func validateSchedule(s Schedule, now int64) error {
 		if expr == "" {
 			return fmt.Errorf("cron schedule requires expr")
 		}
-		if _, err := parseCron5(expr); err != nil {
+		if _, err := parseCron(expr); err != nil {
 			return fmt.Errorf("invalid cron expression: %w", err)
 		}
 		return nil
@@ -9,14 +9,38 @@ import (
 )
 
 type cronSchedule struct {
+	second uint64
 	minute uint64
 	hour   uint64
 	dom    uint64
 	month  uint64
 	dow    uint64
 
-	domStar bool
-	dowStar bool
+	// Quartz-style day modifiers, matched in addition to the dom/dow bits.
+	domRules []domRule
+	dowRules []dowRule
+
+	domStar  bool
+	dowStar  bool
+	hourStar bool
+
+	// every is set for "@every <duration>" schedules, which ignore the fields.
+	every time.Duration
+}
+
+// domRule is a day-of-month modifier: "L", "L-n", "LW" or "nW".
+type domRule struct {
+	last     bool // count from the last day of the month
+	fromLast int  // days before the last day ("L-n")
+	day      int  // target day of "nW"
+	weekday  bool // move to the nearest weekday in the same month ("W")
+}
+
+// dowRule is a day-of-week modifier: "dL" (last d of the month) or "d#n"
+// (nth d of the month).
+type dowRule struct {
+	weekday time.Weekday
+	nth     int // 1-5, or -1 for the last
 }
 
 type cronBounds struct {
@@ -25,6 +49,7 @@ type cronBounds struct {
 }
 
 var (
+	cronSecondBounds = cronBounds{min: 0, max: 59}
 	cronMinuteBounds = cronBounds{min: 0, max: 59}
 	cronHourBounds   = cronBounds{min: 0, max: 23}
 	cronDomBounds    = cronBounds{min: 1, max: 31}
@@ -43,9 +68,28 @@ var (
 	}
 )
 
+// cronMacros are the predefined schedules accepted besides "@every".
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
 var cronExprCache sync.Map // map[string]*cronSchedule
 
-func parseCron5(expr string) (*cronSchedule, error) {
+// parseCron parses a cron expression:
+//   - 5 fields: minute hour day-of-month month day-of-week;
+//   - 6 fields: a leading seconds field, then the 5 above;
+//   - a macro: @yearly, @monthly, @weekly, @daily, @hourly or @every <duration>.
+//
+// Day-of-month also accepts L (last day), L-n, LW (last weekday) and nW
+// (weekday nearest the nth); day-of-week accepts dL (last d of the month)
+// and d#n (nth d of the month), with 0 or 7 for Sunday.
+func parseCron(expr string) (*cronSchedule, error) {
 	expr = strings.TrimSpace(expr)
 	if expr == "" {
 		return nil, fmt.Errorf("empty cron expression")
@@ -56,69 +100,130 @@ func parseCron5(expr string) (*cronSchedule, error) {
 		}
 	}
 
-	fields := strings.Fields(expr)
-	if len(fields) != 5 {
-		return nil, fmt.Errorf("expected 5 fields, found %d", len(fields))
+	var (
+		s   *cronSchedule
+		err error
+	)
+	if strings.HasPrefix(expr, "@") {
+		s, err = parseCronMacro(expr)
+	} else {
+		s, err = parseCronFields(strings.Fields(expr))
 	}
-
-	minute, _, err := parseCronField(fields[0], cronMinuteBounds, false)
 	if err != nil {
-		return nil, fmt.Errorf("invalid minute field: %w", err)
+		return nil, err
 	}
-	hour, _, err := parseCronField(fields[1], cronHourBounds, false)
-	if err != nil {
-		return nil, fmt.Errorf("invalid hour field: %w", err)
+	cronExprCache.Store(expr, s)
+	return s, nil
+}
+
+func parseCronMacro(expr string) (*cronSchedule, error) {
+	name, arg, _ := strings.Cut(expr, " ")
+	name, arg = strings.ToLower(name), strings.TrimSpace(arg)
+	if name == "@every" {
+		if arg == "" {
+			return nil, fmt.Errorf("@every requires a duration (e.g. @every 90m)")
+		}
+		d, err := time.ParseDuration(arg)
+		if err != nil {
+			return nil, fmt.Errorf("@every: bad duration %q", arg)
+		}
+		if d < time.Second {
+			return nil, fmt.Errorf("@every: duration must be at least 1s, got %s", d)
+		}
+		return &cronSchedule{every: d}, nil
 	}
-	dom, domStar, err := parseCronField(fields[2], cronDomBounds, false)
-	if err != nil {
-		return nil, fmt.Errorf("invalid day-of-month field: %w", err)
+	fields, ok := cronMacros[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown macro %q (expected @yearly, @monthly, @weekly, @daily, @hourly or @every <duration>)", name)
 	}
-	month, _, err := parseCronField(fields[3], cronMonthBounds, false)
-	if err != nil {
-		return nil, fmt.Errorf("invalid month field: %w", err)
+	if arg != "" {
+		return nil, fmt.Errorf("%s takes no arguments", name)
 	}
-	dow, dowStar, err := parseCronField(fields[4], cronDowBounds, true)
-	if err != nil {
-		return nil, fmt.Errorf("invalid day-of-week field: %w", err)
+	return parseCronFields(strings.Fields(fields))
+}
+
+func parseCronFields(fields []string) (*cronSchedule, error) {
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("expected 5 fields (minute hour day-of-month month day-of-week) or 6 with leading seconds, found %d", len(fields))
 	}
 
-	s := &cronSchedule{
-		minute:  minute,
-		hour:    hour,
-		dom:     dom,
-		month:   month,
-		dow:     dow,
-		domStar: domStar,
-		dowStar: dowStar,
+	s := &cronSchedule{}
+	var err error
+	if s.second, _, err = parseCronField(fields[0], cronSecondBounds, false, nil); err != nil {
+		return nil, fmt.Errorf("invalid second field %q: %w", fields[0], err)
+	}
+	if s.minute, _, err = parseCronField(fields[1], cronMinuteBounds, false, nil); err != nil {
+		return nil, fmt.Errorf("invalid minute field %q: %w", fields[1], err)
+	}
+	if s.hour, s.hourStar, err = parseCronField(fields[2], cronHourBounds, false, nil); err != nil {
+		return nil, fmt.Errorf("invalid hour field %q: %w", fields[2], err)
+	}
+	s.dom, s.domStar, err = parseCronField(fields[3], cronDomBounds, false, func(seg string) (bool, error) {
+		r, ok, err := parseDomRule(seg)
+		if ok {
+			s.domRules = append(s.domRules, r)
+		}
+		return ok, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid day-of-month field %q: %w", fields[3], err)
+	}
+	if s.month, _, err = parseCronField(fields[4], cronMonthBounds, false, nil); err != nil {
+		return nil, fmt.Errorf("invalid month field %q: %w", fields[4], err)
+	}
+	s.dow, s.dowStar, err = parseCronField(fields[5], cronDowBounds, true, func(seg string) (bool, error) {
+		r, ok, err := parseDowRule(seg)
+		if ok {
+			s.dowRules = append(s.dowRules, r)
+		}
+		return ok, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid day-of-week field %q: %w", fields[5], err)
 	}
-	cronExprCache.Store(expr, s)
 	return s, nil
 }
 
-func parseCronField(field string, bounds cronBounds, isDow bool) (uint64, bool, error) {
+// parseCronField parses a comma-separated field. rule, if set, is offered
+// each segment first and reports whether it consumed it.
+func parseCronField(field string, bounds cronBounds, isDow bool, rule func(seg string) (bool, error)) (uint64, bool, error) {
 	var bits uint64
-	hasStar := false
+	hasStar, hasRule := false, false
 	for {
 		part, rest, hasMore := strings.Cut(field, ",")
 		part = strings.TrimSpace(part)
 		if part == "" {
 			return 0, false, fmt.Errorf("empty segment")
 		}
-		b, star, err := parseCronSegment(part, bounds, isDow)
-		if err != nil {
-			return 0, false, err
+		ok := false
+		if rule != nil {
+			var err error
+			if ok, err = rule(part); err != nil {
+				return 0, false, err
+			}
+			hasRule = hasRule || ok
+		}
+		if !ok {
+			b, star, err := parseCronSegment(part, bounds, isDow)
+			if err != nil {
+				return 0, false, err
+			}
+			bits |= b
+			hasStar = hasStar || star
 		}
-		bits |= b
-		hasStar = hasStar || star
 		if !hasMore {
 			break
 		}
 		field = rest
 	}
-	if bits == 0 {
+	if bits == 0 && !hasRule {
 		return 0, false, fmt.Errorf("no values")
 	}
-	return bits, hasStar, nil
+	return bits, hasStar && !hasRule, nil
 }
 
 func parseCronSegment(seg string, bounds cronBounds, isDow bool) (uint64, bool, error) {
@@ -172,13 +277,13 @@ func parseCronSegment(seg string, bounds cronBounds, isDow bool) (uint64, bool,
 	}
 
 	if start < bounds.min || start > bounds.max {
-		return 0, false, fmt.Errorf("value %d out of range", start)
+		return 0, false, fmt.Errorf("value %d out of range %d-%d", start, bounds.min, bounds.max)
 	}
 	if end < bounds.min || end > bounds.max {
-		return 0, false, fmt.Errorf("value %d out of range", end)
+		return 0, false, fmt.Errorf("value %d out of range %d-%d", end, bounds.min, bounds.max)
 	}
 	if end < start {
-		return 0, false, fmt.Errorf("range start > end")
+		return 0, false, fmt.Errorf("range start > end in %q", base)
 	}
 
 	return buildBits(start, end, step, isDow), star, nil
@@ -194,9 +299,81 @@ func parseCronValue(s string, bounds cronBounds) (int, error) {
 			return v, nil
 		}
 	}
+	if strings.ContainsAny(s, "LW#") {
+		return 0, fmt.Errorf("%q: L, W and # are only allowed in the day-of-month and day-of-week fields", s)
+	}
 	return 0, fmt.Errorf("bad value %q", s)
 }
 
+func parseDomRule(seg string) (domRule, bool, error) {
+	upper := strings.ToUpper(seg)
+	switch {
+	case upper == "L":
+		return domRule{last: true}, true, nil
+	case upper == "LW":
+		return domRule{last: true, weekday: true}, true, nil
+	case strings.HasPrefix(upper, "L-"):
+		n, err := strconv.Atoi(upper[2:])
+		if err != nil || n < 0 || n > 30 {
+			return domRule{}, false, fmt.Errorf("bad offset in %q (expected L-0 to L-30)", seg)
+		}
+		return domRule{last: true, fromLast: n}, true, nil
+	case strings.HasSuffix(upper, "W"):
+		n, err := strconv.Atoi(upper[:len(upper)-1])
+		if err != nil || n < 1 || n > 31 {
+			return domRule{}, false, fmt.Errorf("bad day in %q (expected 1W to 31W)", seg)
+		}
+		return domRule{day: n, weekday: true}, true, nil
+	case strings.Contains(upper, "#"):
+		return domRule{}, false, fmt.Errorf("%q: # is only allowed in the day-of-week field", seg)
+	case strings.ContainsAny(upper, "LW"):
+		return domRule{}, false, fmt.Errorf("bad modifier %q (expected L, L-n, LW or nW)", seg)
+	}
+	return domRule{}, false, nil
+}
+
+func parseDowRule(seg string) (dowRule, bool, error) {
+	if day, n, ok := strings.Cut(seg, "#"); ok {
+		wd, err := parseCronWeekday(day)
+		if err != nil {
+			return dowRule{}, false, err
+		}
+		nth, err := strconv.Atoi(n)
+		if err != nil || nth < 1 || nth > 5 {
+			return dowRule{}, false, fmt.Errorf("bad occurrence in %q (expected #1 to #5)", seg)
+		}
+		return dowRule{weekday: wd, nth: nth}, true, nil
+	}
+	upper := strings.ToUpper(seg)
+	if upper == "L" {
+		return dowRule{}, false, fmt.Errorf("L needs a weekday in the day-of-week field (e.g. 5L for the last Friday)")
+	}
+	if day, ok := strings.CutSuffix(upper, "L"); ok {
+		wd, err := parseCronWeekday(day)
+		if err != nil {
+			return dowRule{}, false, err
+		}
+		return dowRule{weekday: wd, nth: -1}, true, nil
+	}
+	if day, ok := strings.CutSuffix(upper, "W"); ok {
+		if _, err := strconv.Atoi(day); err == nil {
+			return dowRule{}, false, fmt.Errorf("%q: W is only allowed in the day-of-month field", seg)
+		}
+	}
+	return dowRule{}, false, nil
+}
+
+func parseCronWeekday(s string) (time.Weekday, error) {
+	v, err := parseCronValue(s, cronDowBounds)
+	if err != nil {
+		return 0, err
+	}
+	if v < cronDowBounds.min || v > cronDowBounds.max {
+		return 0, fmt.Errorf("value %d out of range %d-%d", v, cronDowBounds.min, cronDowBounds.max)
+	}
+	return time.Weekday(v % 7), nil
+}
+
 func buildBits(start, end, step int, isDow bool) uint64 {
 	var bits uint64
 	for v := start; v <= end; v += step {
@@ -209,84 +386,175 @@ func buildBits(start, end, step int, isDow bool) uint64 {
 	return bits
 }
 
+// Next returns the first activation strictly after t, matching the wall clock
+// of t's location. Around DST changes:
+//   - a time skipped by a gap fires once, shifted forward by the gap
+//     (02:30 becomes 03:30 when clocks jump from 02:00 to 03:00);
+//   - a time repeated by an overlap fires on its first occurrence only,
+//     unless the hour field is "*", in which case it fires on both.
+//
+// "@every" schedules simply return t plus the interval.
 func (s *cronSchedule) Next(t time.Time) time.Time {
-	origLocation := t.Location()
-	loc := origLocation
-
-	// 5-field cron is minute granularity.
-	t = t.Add(time.Minute - time.Duration(t.Second())*time.Second - time.Duration(t.Nanosecond()))
-	added := false
-	yearLimit := t.Year() + 5
-
-WRAP:
-	if t.Year() > yearLimit {
-		return time.Time{}
+	if s.every > 0 {
+		return t.Add(s.every - time.Duration(t.Nanosecond()))
 	}
-
-	for !hasBit(s.month, int(t.Month())) {
-		if !added {
-			added = true
-			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
-		}
-		t = t.AddDate(0, 1, 0)
-		if t.Month() == time.January {
-			goto WRAP
-		}
+	loc := t.Location()
+	// Wall times are walked in UTC fields, starting a few hours early near a
+	// DST change: after an overlap the wall clock repeats.
+	from := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+	if nearTransition(t) {
+		from = from.Add(-3 * time.Hour)
 	}
-
-	for !s.dayMatches(t) {
-		if !added {
-			added = true
-			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	// Once a run is found, later wall times can only be earlier instants
+	// across a DST gap, so near one keep looking a few hours past it.
+	var best, bestWall time.Time
+	done := func(wall time.Time) bool {
+		return !best.IsZero() && wall.After(bestWall.Add(3*time.Hour))
+	}
+	y, m, d := from.Date()
+	for i := 0; i < 5*366+2; i++ {
+		day := time.Date(y, m, d+i, 0, 0, 0, 0, time.UTC)
+		if done(day) {
+			break
 		}
-		t = t.AddDate(0, 0, 1)
-		// Keep behavior around DST transitions near midnight.
-		if t.Hour() != 0 {
-			if t.Hour() > 12 {
-				t = t.Add(time.Duration(24-t.Hour()) * time.Hour)
-			} else {
-				t = t.Add(time.Duration(-t.Hour()) * time.Hour)
-			}
+		if !hasBit(s.month, int(day.Month())) || !s.dayMatches(day) {
+			continue
 		}
-		if t.Day() == 1 {
-			goto WRAP
+		for h := 0; h < 24; h++ {
+			hourStart := day.Add(time.Duration(h) * time.Hour)
+			if !hasBit(s.hour, h) || !hourStart.Add(time.Hour).After(from) {
+				continue
+			}
+			for mi := 0; mi < 60; mi++ {
+				minStart := hourStart.Add(time.Duration(mi) * time.Minute)
+				if !hasBit(s.minute, mi) || !minStart.Add(time.Minute).After(from) {
+					continue
+				}
+				for sec := 0; sec < 60; sec++ {
+					wall := minStart.Add(time.Duration(sec) * time.Second)
+					if !hasBit(s.second, sec) || wall.Before(from) {
+						continue
+					}
+					if done(wall) {
+						return best
+					}
+					for _, at := range wallInstants(wall, loc, s.hourStar) {
+						if at.After(t) && (best.IsZero() || at.Before(best)) {
+							best, bestWall = at, wall
+						}
+					}
+					if !best.IsZero() && !nearTransition(best) {
+						return best
+					}
+				}
+			}
 		}
 	}
+	return best
+}
 
-	for !hasBit(s.hour, t.Hour()) {
-		if !added {
-			added = true
-			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
-		}
-		t = t.Add(1 * time.Hour)
-		if t.Hour() == 0 {
-			goto WRAP
-		}
+// wallInstants returns the instants at which clocks in loc show the wall
+// time given in UTC fields. A wall time in a DST gap maps to one instant,
+// using the offset from before the gap. One repeated by an overlap maps to
+// its first occurrence, or to both when both is set.
+func wallInstants(wall time.Time, loc *time.Location, both bool) []time.Time {
+	guess := time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), 0, loc)
+	_, before := guess.Add(-3 * time.Hour).Zone()
+	_, after := guess.Add(3 * time.Hour).Zone()
+	if before == after {
+		return []time.Time{guess}
 	}
-
-	for !hasBit(s.minute, t.Minute()) {
-		if !added {
-			added = true
-			t = t.Truncate(time.Minute)
-		}
-		t = t.Add(1 * time.Minute)
-		if t.Minute() == 0 {
-			goto WRAP
+	var out []time.Time
+	for _, off := range []int{before, after} {
+		at := wall.Add(-time.Duration(off) * time.Second).In(loc)
+		if sameWall(at, wall) {
+			out = append(out, at)
 		}
 	}
+	switch {
+	case len(out) == 0:
+		return []time.Time{wall.Add(-time.Duration(before) * time.Second).In(loc)}
+	case len(out) == 2 && out[1].Before(out[0]):
+		out[0], out[1] = out[1], out[0]
+	}
+	if !both {
+		out = out[:1]
+	}
+	return out
+}
 
-	return t.In(origLocation)
+// nearTransition reports whether loc changes its UTC offset within three
+// hours of t.
+func nearTransition(t time.Time) bool {
+	_, before := t.Add(-3 * time.Hour).Zone()
+	_, after := t.Add(3 * time.Hour).Zone()
+	return before != after
+}
+
+func sameWall(t, wall time.Time) bool {
+	y, m, d := t.Date()
+	wy, wm, wd := wall.Date()
+	return y == wy && m == wm && d == wd && t.Hour() == wall.Hour() && t.Minute() == wall.Minute() && t.Second() == wall.Second()
 }
 
 func (s *cronSchedule) dayMatches(t time.Time) bool {
 	domMatch := hasBit(s.dom, t.Day())
+	for _, r := range s.domRules {
+		domMatch = domMatch || r.matches(t)
+	}
 	dowMatch := hasBit(s.dow, int(t.Weekday()))
+	for _, r := range s.dowRules {
+		dowMatch = dowMatch || r.matches(t)
+	}
 	if s.domStar || s.dowStar {
 		return domMatch && dowMatch
 	}
 	return domMatch || dowMatch
 }
 
+func (r domRule) matches(t time.Time) bool {
+	last := daysIn(t.Year(), t.Month())
+	target := r.day
+	if r.last {
+		target = last - r.fromLast
+	}
+	// "31W" in a 30-day month or "L-30" in February never fire.
+	if target < 1 || target > last {
+		return false
+	}
+	if r.weekday {
+		switch time.Date(t.Year(), t.Month(), target, 0, 0, 0, 0, time.UTC).Weekday() {
+		case time.Saturday:
+			if target == 1 {
+				target += 2
+			} else {
+				target--
+			}
+		case time.Sunday:
+			if target == last {
+				target -= 2
+			} else {
+				target++
+			}
+		}
+	}
+	return t.Day() == target
+}
+
+func (r dowRule) matches(t time.Time) bool {
+	if t.Weekday() != r.weekday {
+		return false
+	}
+	if r.nth < 0 {
+		return t.Day()+7 > daysIn(t.Year(), t.Month())
+	}
+	return (t.Day()-1)/7+1 == r.nth
+}
+
+func daysIn(y int, m time.Month) int {
+	return time.Date(y, m+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
 func hasBit(bits uint64, value int) bool {
 	if value < 0 || value > 63 {
 		return false
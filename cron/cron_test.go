@@ -1,9 +1,13 @@
 package cron
 
 import (
+	"context"
+	"errors"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+	_ "time/tzdata"
 )
 
 func TestServiceAdd_RejectsInvalidSchedule(t *testing.T) {
@@ -101,3 +105,453 @@ func TestComputeNextRunMS_CronWeekday(t *testing.T) {
 		t.Fatalf("expected %v, got %v", want, got)
 	}
 }
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func TestComputeNextRunMS_CronInScheduleTimezone(t *testing.T) {
+	t.Parallel()
+
+	tokyo := mustLoad(t, "Asia/Tokyo")
+	// 2026-02-16 08:30 UTC is 17:30 in Tokyo: today's 09:00 there has passed.
+	now := time.Date(2026, time.February, 16, 8, 30, 0, 0, time.UTC)
+	next := computeNextRunMS(Schedule{Kind: "cron", Expr: "0 9 * * 1-5", TZ: "Asia/Tokyo"}, now.UnixMilli())
+	want := time.Date(2026, time.February, 17, 9, 0, 0, 0, tokyo)
+	if got := time.UnixMilli(next); !got.Equal(want) {
+		t.Fatalf("expected %v, got %v", want, got.In(tokyo))
+	}
+}
+
+func TestCronNext_DSTGapAndOverlap(t *testing.T) {
+	t.Parallel()
+
+	ny := mustLoad(t, "America/New_York")
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want []time.Time
+	}{
+		{
+			// Clocks jump from 02:00 EST to 03:00 EDT on 2026-03-08.
+			name: "gap runs shifted forward once",
+			expr: "30 2 * * *",
+			from: time.Date(2026, time.March, 7, 12, 0, 0, 0, ny),
+			want: []time.Time{
+				time.Date(2026, time.March, 8, 7, 30, 0, 0, time.UTC), // 03:30 EDT
+				time.Date(2026, time.March, 9, 6, 30, 0, 0, time.UTC), // 02:30 EDT
+			},
+		},
+		{
+			name: "gap does not delay later runs",
+			expr: "30 2,3 * * *",
+			from: time.Date(2026, time.March, 8, 1, 0, 0, 0, ny),
+			want: []time.Time{
+				time.Date(2026, time.March, 8, 7, 30, 0, 0, time.UTC), // 02:30 -> 03:30 EDT
+				time.Date(2026, time.March, 9, 6, 30, 0, 0, time.UTC),
+			},
+		},
+		{
+			// Clocks fall back from 02:00 EDT to 01:00 EST on 2026-11-01.
+			name: "overlap runs fixed hour once",
+			expr: "30 1 * * *",
+			from: time.Date(2026, time.November, 1, 0, 0, 0, 0, ny),
+			want: []time.Time{
+				time.Date(2026, time.November, 1, 5, 30, 0, 0, time.UTC), // 01:30 EDT
+				time.Date(2026, time.November, 2, 6, 30, 0, 0, time.UTC), // 01:30 EST
+			},
+		},
+		{
+			name: "overlap runs hourly jobs in both hours",
+			expr: "30 * * * *",
+			from: time.Date(2026, time.November, 1, 0, 45, 0, 0, ny),
+			want: []time.Time{
+				time.Date(2026, time.November, 1, 5, 30, 0, 0, time.UTC), // 01:30 EDT
+				time.Date(2026, time.November, 1, 6, 30, 0, 0, time.UTC), // 01:30 EST
+				time.Date(2026, time.November, 1, 7, 30, 0, 0, time.UTC), // 02:30 EST
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			sched, err := parseCron(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			at := tt.from
+			for i, want := range tt.want {
+				at = sched.Next(at)
+				if !at.Equal(want) {
+					t.Fatalf("run %d: expected %v, got %v", i, want.In(ny), at)
+				}
+			}
+		})
+	}
+}
+
+func TestServiceAdd_RejectsUnknownTimezone(t *testing.T) {
+	t.Parallel()
+
+	svc := NewService(filepath.Join(t.TempDir(), "cron.json"), nil)
+	if _, err := svc.Add("tz", Schedule{Kind: "cron", Expr: "0 8 * * *", TZ: "Mars/Olympus"}, Payload{Kind: "agent_turn", Message: "hi"}); err == nil {
+		t.Fatal("expected error for unknown timezone")
+	}
+}
+
+func TestCronNext_ExtendedSyntax(t *testing.T) {
+	t.Parallel()
+
+	utc := time.UTC
+	tests := []struct {
+		expr string
+		from time.Time
+		want []time.Time
+	}{
+		{
+			expr: "@daily",
+			from: time.Date(2026, time.May, 4, 10, 0, 0, 0, utc),
+			want: []time.Time{time.Date(2026, time.May, 5, 0, 0, 0, 0, utc)},
+		},
+		{
+			expr: "@every 90m",
+			from: time.Date(2026, time.May, 4, 10, 0, 0, 0, utc),
+			want: []time.Time{time.Date(2026, time.May, 4, 11, 30, 0, 0, utc), time.Date(2026, time.May, 4, 13, 0, 0, 0, utc)},
+		},
+		{
+			expr: "*/15 * * * * *",
+			from: time.Date(2026, time.May, 4, 10, 0, 50, 0, utc),
+			want: []time.Time{time.Date(2026, time.May, 4, 10, 1, 0, 0, utc), time.Date(2026, time.May, 4, 10, 1, 15, 0, utc)},
+		},
+		{
+			// Last Friday of the month.
+			expr: "0 9 * * 5L",
+			from: time.Date(2026, time.May, 1, 0, 0, 0, 0, utc),
+			want: []time.Time{time.Date(2026, time.May, 29, 9, 0, 0, 0, utc), time.Date(2026, time.June, 26, 9, 0, 0, 0, utc)},
+		},
+		{
+			expr: "0 9 ? * MON#2",
+			from: time.Date(2026, time.May, 1, 0, 0, 0, 0, utc),
+			want: []time.Time{time.Date(2026, time.May, 11, 9, 0, 0, 0, utc), time.Date(2026, time.June, 8, 9, 0, 0, 0, utc)},
+		},
+		{
+			expr: "0 18 L * *",
+			from: time.Date(2026, time.February, 1, 0, 0, 0, 0, utc),
+			want: []time.Time{time.Date(2026, time.February, 28, 18, 0, 0, 0, utc), time.Date(2026, time.March, 31, 18, 0, 0, 0, utc)},
+		},
+		{
+			// Last weekday: 2026-05-31 is a Sunday.
+			expr: "0 12 LW * *",
+			from: time.Date(2026, time.May, 1, 0, 0, 0, 0, utc),
+			want: []time.Time{time.Date(2026, time.May, 29, 12, 0, 0, 0, utc), time.Date(2026, time.June, 30, 12, 0, 0, 0, utc)},
+		},
+		{
+			// 2026-08-01 is a Saturday: the nearest weekday stays in August.
+			expr: "0 8 1W * *",
+			from: time.Date(2026, time.July, 15, 0, 0, 0, 0, utc),
+			want: []time.Time{time.Date(2026, time.August, 3, 8, 0, 0, 0, utc), time.Date(2026, time.September, 1, 8, 0, 0, 0, utc)},
+		},
+		{
+			expr: "0 0 L-2 * *",
+			from: time.Date(2026, time.April, 1, 0, 0, 0, 0, utc),
+			want: []time.Time{time.Date(2026, time.April, 28, 0, 0, 0, 0, utc)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			t.Parallel()
+			sched, err := parseCron(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			at := tt.from
+			for i, want := range tt.want {
+				at = sched.Next(at)
+				if !at.Equal(want) {
+					t.Fatalf("run %d: expected %v, got %v", i, want, at)
+				}
+			}
+		})
+	}
+}
+
+func TestParseCron_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"* * * *", "expected 5 fields"},
+		{"61 * * * *", `invalid minute field "61": value 61 out of range 0-59`},
+		{"0 0 * * 8", "value 8 out of range 0-7"},
+		{"0 L * * *", "only allowed in the day-of-month and day-of-week fields"},
+		{"0 0 32W * *", "expected 1W to 31W"},
+		{"0 0 1#2 * *", "# is only allowed in the day-of-week field"},
+		{"0 0 * * 5#6", "expected #1 to #5"},
+		{"0 0 * * L", "needs a weekday"},
+		{"0 0 * * 5W", "W is only allowed in the day-of-month field"},
+		{"@fortnightly", "unknown macro"},
+		{"@every 10ms", "at least 1s"},
+		{"@daily 9", "takes no arguments"},
+	}
+	for _, tt := range tests {
+		if _, err := parseCron(tt.expr); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("parseCron(%q) error = %v, want %q", tt.expr, err, tt.want)
+		}
+	}
+	for _, ok := range []string{"0 9 * * mon-wed", "0 9 * * WED", "@hourly", "@every 1h30m", "0 0 0 1 jan ?"} {
+		if _, err := parseCron(ok); err != nil {
+			t.Errorf("parseCron(%q): %v", ok, err)
+		}
+	}
+}
+
+func TestServiceExecute_RecordsHistoryAndRetries(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	svc := NewService(filepath.Join(t.TempDir(), "cron.json"), func(ctx context.Context, job Job) (string, error) {
+		calls++
+		if calls < 3 {
+			return "", errors.New("provider unavailable")
+		}
+		return "done", nil
+	})
+	svc.Retry = Retry{MaxAttempts: 2, BackoffMS: 60_000}
+	job, err := svc.Add("retry", Schedule{Kind: "every", EveryMS: 3_600_000}, Payload{Kind: "agent_turn", Message: "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, wantDelay := range []int64{60_000, 120_000} {
+		before := nowMS()
+		j, _ := svc.Get(job.ID)
+		trigger := TriggerSchedule
+		if i > 0 {
+			trigger = TriggerRetry
+		}
+		if _, err := svc.execute(t.Context(), j, trigger); err == nil {
+			t.Fatal("expected error")
+		}
+		j, _ = svc.Get(job.ID)
+		if j.State.Attempts != i+1 || j.State.NextRunAtMS < before+wantDelay || j.State.NextRunAtMS > nowMS()+wantDelay {
+			t.Fatalf("attempt %d: state=%+v", i+1, j.State)
+		}
+	}
+
+	j, _ := svc.Get(job.ID)
+	if _, err := svc.execute(t.Context(), j, TriggerRetry); err != nil {
+		t.Fatal(err)
+	}
+	j, _ = svc.Get(job.ID)
+	if j.State.Attempts != 0 || j.State.NextRunAtMS < nowMS()+3_500_000 {
+		t.Fatalf("state after success=%+v", j.State)
+	}
+	h := j.State.History
+	if len(h) != 3 || h[0].Status != "error" || h[0].Error != "provider unavailable" || h[2].Status != "ok" || h[2].Attempt != 3 || h[2].Trigger != TriggerRetry || h[2].Output != "done" {
+		t.Fatalf("history=%+v", h)
+	}
+}
+
+func TestRetryDelayDoublesUpToAnHour(t *testing.T) {
+	t.Parallel()
+
+	r := Retry{BackoffMS: 1000}
+	for attempt, want := range map[int]int64{1: 1000, 2: 2000, 3: 4000, 20: time.Hour.Milliseconds()} {
+		if got := r.delayMS(attempt); got != want {
+			t.Errorf("delayMS(%d) = %d, want %d", attempt, got, want)
+		}
+	}
+}
+
+func TestServiceStart_MisfirePolicies(t *testing.T) {
+	t.Parallel()
+
+	svc := NewService(filepath.Join(t.TempDir(), "cron.json"), nil)
+	for _, policy := range []string{MisfireSkip, MisfireRunOnce, MisfireRunAll} {
+		_, err := svc.AddJob(Job{Name: policy, Misfire: policy, Schedule: Schedule{Kind: "every", EveryMS: 600_000}, Payload: Payload{Kind: "agent_turn", Message: "hi"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := svc.AddJob(Job{Name: "bad", Misfire: "sometimes", Schedule: Schedule{Kind: "every", EveryMS: 600_000}}); err == nil {
+		t.Fatal("expected error for unknown misfire policy")
+	}
+
+	// Runs at -25m, -15m and -5m were missed.
+	now := nowMS()
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	for i := range svc.store.Jobs {
+		svc.store.Jobs[i].State.NextRunAtMS = now - 25*60_000
+	}
+	svc.catchUpLocked(now)
+
+	want := map[string]int{MisfireSkip: 0, MisfireRunOnce: 1, MisfireRunAll: 3}
+	for _, j := range svc.store.Jobs {
+		policy := j.Misfire
+		if j.State.CatchUp != want[policy] {
+			t.Errorf("%s: catchUp=%d, want %d", policy, j.State.CatchUp, want[policy])
+		}
+		if due := j.State.NextRunAtMS <= now; due != (policy != MisfireSkip) {
+			t.Errorf("%s: nextRunAtMs=%d now=%d", policy, j.State.NextRunAtMS, now)
+		}
+	}
+}
+
+func TestServiceUpdate(t *testing.T) {
+	t.Parallel()
+
+	svc := NewService(filepath.Join(t.TempDir(), "cron.json"), nil)
+	job, err := svc.Add("standup", Schedule{Kind: "every", EveryMS: 60_000}, Payload{Kind: "agent_turn", Message: "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := svc.Update(job.ID, func(j *Job) error {
+		j.Payload.Message = "hello"
+		j.Schedule = Schedule{Kind: "every", EveryMS: 3_600_000}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Payload.Message != "hello" || updated.State.NextRunAtMS < nowMS()+3_500_000 {
+		t.Fatalf("updated=%+v", updated)
+	}
+	if got, _ := svc.Get(job.ID); got.Payload.Message != "hello" {
+		t.Fatalf("stored=%+v", got)
+	}
+
+	if _, err := svc.Update(job.ID, func(j *Job) error {
+		j.Schedule = Schedule{Kind: "cron", Expr: "0 25 * * *"}
+		return nil
+	}); err == nil {
+		t.Fatal("expected error for invalid schedule")
+	}
+	if got, _ := svc.Get(job.ID); got.Schedule.Kind != "every" {
+		t.Fatalf("invalid update was stored: %+v", got.Schedule)
+	}
+	if _, err := svc.Update("missing", func(j *Job) error { return nil }); err == nil {
+		t.Fatal("expected error for unknown job")
+	}
+}
+
+func TestServiceAdd_ValidatesPayload(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		payload Payload
+		wantErr string
+	}{
+		{"agent turn", Payload{Kind: PayloadAgentTurn, Message: "hi"}, ""},
+		{"agent turn without message", Payload{Kind: PayloadAgentTurn}, "requires a message"},
+		{"message", Payload{Kind: PayloadMessage, Message: "stretch", Channel: "telegram", To: "1"}, ""},
+		{"message without chat", Payload{Kind: PayloadMessage, Message: "stretch"}, "requires channel and to"},
+		{"exec", Payload{Kind: PayloadExec, Command: "df -h"}, ""},
+		{"exec without command", Payload{Kind: PayloadExec, Message: "df -h"}, "requires a command"},
+		{"webhook", Payload{Kind: PayloadWebhook, URL: "https://example.com/hook"}, ""},
+		{"webhook with bad url", Payload{Kind: PayloadWebhook, URL: "file:///etc/passwd"}, "http(s) url"},
+		{"channel without to", Payload{Kind: PayloadAgentTurn, Message: "hi", Channel: "slack"}, "set together"},
+		{"unknown kind", Payload{Kind: "email", Message: "hi"}, "unknown payload kind"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			svc := NewService(filepath.Join(t.TempDir(), "cron.json"), nil)
+			_, err := svc.Add("test", Schedule{Kind: "every", EveryMS: 60_000}, tt.payload)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Add returned error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("err=%v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestServiceRunNow_WithoutHandlerRecordsNothing(t *testing.T) {
+	t.Parallel()
+
+	svc := NewService(filepath.Join(t.TempDir(), "cron.json"), nil)
+	j, err := svc.AddJob(Job{Name: "ping", Schedule: Schedule{Kind: "every", EveryMS: 60_000}, Payload: Payload{Kind: PayloadAgentTurn, Message: "hi"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.RunNow(t.Context(), j.ID, false); !errors.Is(err, ErrNoHandler) {
+		t.Fatalf("err=%v", err)
+	}
+	got, _ := svc.Get(j.ID)
+	if len(got.State.History) != 0 || got.State.LastStatus != "" {
+		t.Fatalf("state=%+v", got.State)
+	}
+	if err := svc.Start(t.Context()); !errors.Is(err, ErrNoHandler) {
+		t.Fatalf("start err=%v", err)
+	}
+}
+
+func TestServiceTimer_SlowJobDoesNotHoldBackOthers(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	ran := make(chan string, 8)
+	svc := NewService(filepath.Join(t.TempDir(), "cron.json"), func(ctx context.Context, job Job) (string, error) {
+		ran <- job.Name
+		if job.Name == "slow" {
+			<-release
+		}
+		return "", nil
+	})
+	svc.Misfire = MisfireRunOnce
+	for _, name := range []string{"slow", "fast"} {
+		if _, err := svc.Add(name, Schedule{Kind: "every", EveryMS: 3_600_000}, Payload{Kind: "agent_turn", Message: "hi"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	svc.mu.Lock()
+	for i := range svc.store.Jobs {
+		svc.store.Jobs[i].State.NextRunAtMS = nowMS() - 1
+	}
+	_ = svc.saveLocked()
+	svc.mu.Unlock()
+
+	if err := svc.Start(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(svc.Stop)
+	got := map[string]int{}
+	for len(got) < 2 {
+		select {
+		case name := <-ran:
+			got[name]++
+		case <-time.After(5 * time.Second):
+			t.Fatalf("ran=%v while slow was still running", got)
+		}
+	}
+	// The slow job is not started again while it runs.
+	select {
+	case name := <-ran:
+		t.Fatalf("%s ran again", name)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(5 * time.Millisecond) {
+		svc.mu.Lock()
+		idle := len(svc.inFlight) == 0
+		svc.mu.Unlock()
+		if idle {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("jobs never finished")
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package gateway
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ProcessFunc runs one agent turn. It matches agent.Loop.ProcessDirectStream;
+// onDelta is nil for non-streaming requests.
+type ProcessFunc func(ctx context.Context, content, sessionKey, channel, chatID string, onDelta func(string)) (string, error)
+
+// SessionHeader selects the clawlet session for an API request. When absent,
+// the OpenAI `user` field is used, then "default".
+const SessionHeader = "X-Clawlet-Session"
+
+// APIChannel is the channel name API turns are recorded under.
+const APIChannel = "api"
+
+const maxAPIRequestBytes = 4 << 20
+
+// OpenAIAPI serves an OpenAI-compatible subset (/v1/chat/completions and
+// /v1/models) backed by the agent loop. The loop keeps its own session history,
+// so only the last user message of each request is used as the turn input.
+type OpenAIAPI struct {
+	Tokens  []string
+	Model   string
+	Process ProcessFunc
+}
+
+// Mount registers the API routes on s.
+func (a *OpenAIAPI) Mount(s *Server) {
+	s.Handle("/v1/chat/completions", a.auth(http.HandlerFunc(a.chatCompletions)))
+	s.Handle("/v1/models", a.auth(http.HandlerFunc(a.models)))
+}
+
+func (a *OpenAIAPI) auth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.authorized(r) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeAPIError(w, http.StatusUnauthorized, "invalid_api_key", "invalid or missing bearer token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (a *OpenAIAPI) authorized(r *http.Request) bool {
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	got = strings.TrimSpace(got)
+	if !ok || got == "" {
+		return false
+	}
+	match := false
+	for _, tok := range a.Tokens {
+		tok = strings.TrimSpace(tok)
+		if tok == "" {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(got), []byte(tok)) == 1 {
+			match = true
+		}
+	}
+	return match
+}
+
+type chatCompletionRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+	Stream   bool          `json:"stream"`
+	User     string        `json:"user"`
+}
+
+type chatMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+// text returns the message content, accepting either a string or an array of
+// content parts (only text parts are kept).
+func (m chatMessage) text() string {
+	var s string
+	if err := json.Unmarshal(m.Content, &s); err == nil {
+		return s
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(m.Content, &parts); err != nil {
+		return ""
+	}
+	var out []string
+	for _, p := range parts {
+		if p.Type == "text" && p.Text != "" {
+			out = append(out, p.Text)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+func (a *OpenAIAPI) chatCompletions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeAPIError(w, http.StatusMethodNotAllowed, "invalid_request_error", "method not allowed")
+		return
+	}
+	var req chatCompletionRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAPIRequestBytes)).Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_request_error", "invalid json body: "+err.Error())
+		return
+	}
+	content := ""
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		if req.Messages[i].Role == "user" {
+			content = strings.TrimSpace(req.Messages[i].text())
+			break
+		}
+	}
+	if content == "" {
+		writeAPIError(w, http.StatusBadRequest, "invalid_request_error", "messages must include a non-empty user message")
+		return
+	}
+
+	chatID := apiSessionID(r, req.User)
+	sessionKey := APIChannel + ":" + chatID
+	model := a.Model
+	if strings.TrimSpace(model) == "" {
+		model = "clawlet"
+	}
+	id := "chatcmpl-" + randHex(12)
+	created := time.Now().Unix()
+
+	if req.Stream {
+		a.streamCompletion(w, r, content, sessionKey, chatID, id, model, created)
+		return
+	}
+
+	out, err := a.Process(r.Context(), content, sessionKey, APIChannel, chatID, nil)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"id":      id,
+		"object":  "chat.completion",
+		"created": created,
+		"model":   model,
+		"choices": []map[string]any{{
+			"index":         0,
+			"message":       map[string]string{"role": "assistant", "content": out},
+			"finish_reason": "stop",
+		}},
+		"usage": map[string]int{"prompt_tokens": 0, "completion_tokens": 0, "total_tokens": 0},
+	})
+}
+
+func (a *OpenAIAPI) streamCompletion(w http.ResponseWriter, r *http.Request, content, sessionKey, chatID, id, model string, created int64) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeAPIError(w, http.StatusInternalServerError, "server_error", "streaming unsupported")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(v any) {
+		b, _ := json.Marshal(v)
+		_, _ = fmt.Fprintf(w, "data: %s\n\n", b)
+		flusher.Flush()
+	}
+	chunk := func(delta map[string]string, finish any) map[string]any {
+		return map[string]any{
+			"id":      id,
+			"object":  "chat.completion.chunk",
+			"created": created,
+			"model":   model,
+			"choices": []map[string]any{{
+				"index":         0,
+				"delta":         delta,
+				"finish_reason": finish,
+			}},
+		}
+	}
+
+	send(chunk(map[string]string{"role": "assistant"}, nil))
+	streamed := false
+	out, err := a.Process(r.Context(), content, sessionKey, APIChannel, chatID, func(s string) {
+		streamed = true
+		send(chunk(map[string]string{"content": s}, nil))
+	})
+	if err != nil {
+		send(map[string]any{"error": map[string]string{"message": err.Error(), "type": "server_error"}})
+	} else {
+		// Fallback replies such as "(no response)" aren't streamed by the
+		// model; deliver them in one piece.
+		if !streamed && out != "" {
+			send(chunk(map[string]string{"content": out}, nil))
+		}
+		send(chunk(map[string]string{}, "stop"))
+	}
+	_, _ = fmt.Fprint(w, "data: [DONE]\n\n")
+	flusher.Flush()
+}
+
+func (a *OpenAIAPI) models(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeAPIError(w, http.StatusMethodNotAllowed, "invalid_request_error", "method not allowed")
+		return
+	}
+	model := a.Model
+	if strings.TrimSpace(model) == "" {
+		model = "clawlet"
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"object": "list",
+		"data": []map[string]any{{
+			"id":       model,
+			"object":   "model",
+			"created":  0,
+			"owned_by": "clawlet",
+		}},
+	})
+}
+
+func apiSessionID(r *http.Request, user string) string {
+	if v := strings.TrimSpace(r.Header.Get(SessionHeader)); v != "" {
+		return v
+	}
+	if v := strings.TrimSpace(user); v != "" {
+		return v
+	}
+	return "default"
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, typ, msg string) {
+	writeJSON(w, status, map[string]any{
+		"error": map[string]string{"message": msg, "type": typ},
+	})
+}
+
+func randHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,205 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type processCall struct {
+	content, sessionKey, channel, chatID string
+	streaming                            bool
+}
+
+func newTestAPI(t *testing.T, calls *[]processCall) *httptest.Server {
+	t.Helper()
+	api := &OpenAIAPI{
+		Tokens: []string{"secret"},
+		Model:  "test-model",
+		Process: func(ctx context.Context, content, sessionKey, channel, chatID string, onDelta func(string)) (string, error) {
+			*calls = append(*calls, processCall{content, sessionKey, channel, chatID, onDelta != nil})
+			if onDelta != nil {
+				onDelta("Hel")
+				onDelta("lo")
+			}
+			return "Hello", nil
+		},
+	}
+	s := NewServer("127.0.0.1:0")
+	api.Mount(s)
+	srv := httptest.NewServer(s.mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func postChat(t *testing.T, srv *httptest.Server, token, body string, header map[string]string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/v1/chat/completions", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestChatCompletions_RejectsBadToken(t *testing.T) {
+	var calls []processCall
+	srv := newTestAPI(t, &calls)
+	body := `{"messages":[{"role":"user","content":"hi"}]}`
+	for _, tok := range []string{"", "wrong"} {
+		resp := postChat(t, srv, tok, body, nil)
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("token=%q status=%d", tok, resp.StatusCode)
+		}
+	}
+	if len(calls) != 0 {
+		t.Fatalf("calls=%d", len(calls))
+	}
+}
+
+func TestChatCompletions_NonStreaming(t *testing.T) {
+	var calls []processCall
+	srv := newTestAPI(t, &calls)
+	body := `{"model":"x","user":"alice","messages":[{"role":"system","content":"be nice"},{"role":"user","content":"first"},{"role":"assistant","content":"ok"},{"role":"user","content":[{"type":"text","text":"second"}]}]}`
+	resp := postChat(t, srv, "secret", body, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d", resp.StatusCode)
+	}
+	var out struct {
+		Object  string `json:"object"`
+		Model   string `json:"model"`
+		Choices []struct {
+			Message struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if out.Object != "chat.completion" || out.Model != "test-model" || len(out.Choices) != 1 {
+		t.Fatalf("out=%+v", out)
+	}
+	if out.Choices[0].Message.Content != "Hello" || out.Choices[0].FinishReason != "stop" {
+		t.Fatalf("choice=%+v", out.Choices[0])
+	}
+	if len(calls) != 1 {
+		t.Fatalf("calls=%d", len(calls))
+	}
+	want := processCall{content: "second", sessionKey: "api:alice", channel: "api", chatID: "alice"}
+	if calls[0] != want {
+		t.Fatalf("call=%+v", calls[0])
+	}
+}
+
+func TestChatCompletions_SessionHeaderWins(t *testing.T) {
+	var calls []processCall
+	srv := newTestAPI(t, &calls)
+	body := `{"user":"alice","messages":[{"role":"user","content":"hi"}]}`
+	resp := postChat(t, srv, "secret", body, map[string]string{SessionHeader: "editor-1"})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d", resp.StatusCode)
+	}
+	if len(calls) != 1 || calls[0].sessionKey != "api:editor-1" {
+		t.Fatalf("calls=%+v", calls)
+	}
+}
+
+func TestChatCompletions_Streaming(t *testing.T) {
+	var calls []processCall
+	srv := newTestAPI(t, &calls)
+	resp := postChat(t, srv, "secret", `{"stream":true,"messages":[{"role":"user","content":"hi"}]}`, nil)
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("content-type=%q", ct)
+	}
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var text strings.Builder
+	finish := ""
+	done := false
+	for _, line := range strings.Split(string(raw), "\n") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			done = true
+			continue
+		}
+		var chunk struct {
+			Object  string `json:"object"`
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+				FinishReason *string `json:"finish_reason"`
+			} `json:"choices"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatalf("chunk %q: %v", data, err)
+		}
+		if chunk.Object != "chat.completion.chunk" || len(chunk.Choices) != 1 {
+			t.Fatalf("chunk=%s", data)
+		}
+		text.WriteString(chunk.Choices[0].Delta.Content)
+		if chunk.Choices[0].FinishReason != nil {
+			finish = *chunk.Choices[0].FinishReason
+		}
+	}
+	if text.String() != "Hello" || finish != "stop" || !done {
+		t.Fatalf("text=%q finish=%q done=%v", text.String(), finish, done)
+	}
+	if len(calls) != 1 || !calls[0].streaming || calls[0].sessionKey != "api:default" {
+		t.Fatalf("calls=%+v", calls)
+	}
+}
+
+func TestChatCompletions_RequiresUserMessage(t *testing.T) {
+	var calls []processCall
+	srv := newTestAPI(t, &calls)
+	resp := postChat(t, srv, "secret", `{"messages":[{"role":"system","content":"x"}]}`, nil)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status=%d", resp.StatusCode)
+	}
+}
+
+func TestModels(t *testing.T) {
+	var calls []processCall
+	srv := newTestAPI(t, &calls)
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/v1/models", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var out struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Data) != 1 || out.Data[0].ID != "test-model" {
+		t.Fatalf("out=%+v", out)
+	}
+}
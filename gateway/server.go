@@ -0,0 +1,77 @@
+// Package gateway serves the gateway's HTTP endpoints on gateway.listen.
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Server is the gateway HTTP listener. Features mount their handlers on it
+// before Start; the listener is only opened when something is mounted.
+type Server struct {
+	addr string
+	mux  *http.ServeMux
+
+	mu      sync.Mutex
+	mounted int
+	srv     *http.Server
+}
+
+func NewServer(addr string) *Server {
+	return &Server{addr: addr, mux: http.NewServeMux()}
+}
+
+func (s *Server) Handle(pattern string, h http.Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mux.Handle(pattern, h)
+	s.mounted++
+}
+
+// Enabled reports whether any handler has been mounted.
+func (s *Server) Enabled() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.mounted > 0
+}
+
+// Start opens the listener and serves until ctx is cancelled.
+// It is a no-op when no handler is mounted.
+func (s *Server) Start(ctx context.Context) error {
+	if !s.Enabled() {
+		return nil
+	}
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("gateway listen %s: %w", s.addr, err)
+	}
+	srv := &http.Server{
+		Handler:           s.mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	s.mu.Lock()
+	s.srv = srv
+	s.mu.Unlock()
+
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("gateway: http server stopped: %v", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	return nil
+}
+
+// Addr returns the configured listen address.
+func (s *Server) Addr() string { return s.addr }
@@ -13,59 +13,19 @@ import (
 const anthropicVersion = "2023-06-01"
 
 func (c *Client) chatAnthropic(ctx context.Context, messages []Message, tools []ToolDefinition) (*ChatResult, error) {
-	endpoint := anthropicMessagesEndpoint(c.BaseURL)
-
-	anthropicMessages, systemText := toAnthropicMessages(messages)
-	reqBody := struct {
-		Model       string          `json:"model"`
-		Messages    []anthropicMsg  `json:"messages"`
-		System      string          `json:"system,omitempty"`
-		Tools       []anthropicTool `json:"tools,omitempty"`
-		MaxTokens   int             `json:"max_tokens"`
-		Temperature *float64        `json:"temperature,omitempty"`
-	}{
-		Model:       c.Model,
-		Messages:    anthropicMessages,
-		System:      systemText,
-		MaxTokens:   c.maxTokensValue(),
-		Temperature: c.temperatureValue(),
-	}
-	if len(tools) > 0 {
-		converted, err := toAnthropicTools(tools)
-		if err != nil {
-			return nil, err
-		}
-		reqBody.Tools = converted
-	}
-
-	b, err := json.Marshal(reqBody)
+	req, err := c.newAnthropicRequest(ctx, messages, tools, false)
 	if err != nil {
 		return nil, err
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(b))
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Content-Type", "application/json")
-	if strings.TrimSpace(c.APIKey) != "" {
-		req.Header.Set("x-api-key", c.APIKey)
-	}
-	req.Header.Set("anthropic-version", anthropicVersion)
-	for k, v := range c.Headers {
-		if strings.TrimSpace(k) == "" {
-			continue
-		}
-		req.Header.Set(k, v)
-	}
 
-	resp, err := c.HTTP.Do(req)
+	resp, err := c.httpDoer().Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	body, _ := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("llm http %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+		return nil, newHTTPError("llm", resp, string(body))
 	}
 
 	var parsed struct {
@@ -112,6 +72,162 @@ func (c *Client) chatAnthropic(ctx context.Context, messages []Message, tools []
 	return out, nil
 }
 
+func (c *Client) streamAnthropic(ctx context.Context, messages []Message, tools []ToolDefinition, onEvent StreamHandler) (*ChatResult, error) {
+	req, err := c.newAnthropicRequest(ctx, messages, tools, true)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.doStream(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+		return nil, newHTTPError("llm", resp, string(body))
+	}
+	return consumeAnthropicStream(resp.Body, onEvent)
+}
+
+func (c *Client) newAnthropicRequest(ctx context.Context, messages []Message, tools []ToolDefinition, stream bool) (*http.Request, error) {
+	endpoint := anthropicMessagesEndpoint(c.BaseURL)
+
+	anthropicMessages, systemText := toAnthropicMessages(messages)
+	reqBody := struct {
+		Model       string          `json:"model"`
+		Messages    []anthropicMsg  `json:"messages"`
+		System      string          `json:"system,omitempty"`
+		Tools       []anthropicTool `json:"tools,omitempty"`
+		MaxTokens   int             `json:"max_tokens"`
+		Temperature *float64        `json:"temperature,omitempty"`
+		Stream      bool            `json:"stream,omitempty"`
+	}{
+		Model:       c.Model,
+		Messages:    anthropicMessages,
+		System:      systemText,
+		MaxTokens:   c.maxTokensValue(),
+		Temperature: c.temperatureValue(),
+		Stream:      stream,
+	}
+	if len(tools) > 0 {
+		converted, err := toAnthropicTools(tools)
+		if err != nil {
+			return nil, err
+		}
+		reqBody.Tools = converted
+	}
+
+	b, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if strings.TrimSpace(c.APIKey) != "" {
+		req.Header.Set("x-api-key", c.APIKey)
+	}
+	req.Header.Set("anthropic-version", anthropicVersion)
+	for k, v := range c.Headers {
+		if strings.TrimSpace(k) == "" {
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+	return req, nil
+}
+
+type anthropicBlockBuffer struct {
+	Type  string
+	ID    string
+	Name  string
+	Input strings.Builder
+}
+
+func consumeAnthropicStream(r io.Reader, onEvent StreamHandler) (*ChatResult, error) {
+	out := &ChatResult{}
+	var content strings.Builder
+	blocks := map[int]*anthropicBlockBuffer{}
+	toolCount := 0
+
+	err := readSSE(r, func(data string) error {
+		var evt struct {
+			Type         string `json:"type"`
+			Index        int    `json:"index"`
+			ContentBlock struct {
+				Type string `json:"type"`
+				ID   string `json:"id"`
+				Name string `json:"name"`
+			} `json:"content_block"`
+			Delta struct {
+				Type        string `json:"type"`
+				Text        string `json:"text"`
+				PartialJSON string `json:"partial_json"`
+			} `json:"delta"`
+			Error struct {
+				Type    string `json:"type"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(data), &evt); err != nil {
+			return nil
+		}
+		switch evt.Type {
+		case "content_block_start":
+			blocks[evt.Index] = &anthropicBlockBuffer{
+				Type: evt.ContentBlock.Type,
+				ID:   evt.ContentBlock.ID,
+				Name: evt.ContentBlock.Name,
+			}
+			if evt.ContentBlock.Type == "text" && content.Len() > 0 {
+				// Separate text blocks the same way Chat joins them.
+				content.WriteString("\n")
+				onEvent.delta("\n")
+			}
+		case "content_block_delta":
+			switch evt.Delta.Type {
+			case "text_delta":
+				content.WriteString(evt.Delta.Text)
+				onEvent.delta(evt.Delta.Text)
+			case "input_json_delta":
+				if buf := blocks[evt.Index]; buf != nil {
+					buf.Input.WriteString(evt.Delta.PartialJSON)
+				}
+			}
+		case "content_block_stop":
+			buf := blocks[evt.Index]
+			delete(blocks, evt.Index)
+			if buf == nil || buf.Type != "tool_use" {
+				return nil
+			}
+			toolCount++
+			toolID := strings.TrimSpace(buf.ID)
+			if toolID == "" {
+				toolID = fmt.Sprintf("toolu_%d", toolCount)
+			}
+			args := strings.TrimSpace(buf.Input.String())
+			if args == "" {
+				args = "{}"
+			}
+			tc := ToolCall{ID: toolID, Name: buf.Name, Arguments: json.RawMessage(args)}
+			out.ToolCalls = append(out.ToolCalls, tc)
+			onEvent.toolCall(tc)
+		case "error":
+			return fmt.Errorf("anthropic stream error: %s: %s", evt.Error.Type, evt.Error.Message)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	out.Content = content.String()
+	return out, nil
+}
+
 type anthropicMsg struct {
 	Role    string                 `json:"role"`
 	Content []anthropicContentPart `json:"content"`
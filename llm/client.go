@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 	"strings"
 	"time"
@@ -18,12 +19,55 @@ type Client struct {
 	Temperature *float64
 	Headers     map[string]string
 	HTTP        HTTPDoer
+
+	// Fallbacks are tried in order once this client gives up on a request.
+	Fallbacks []*Client
+	// Retry applies to this client and each fallback.
+	Retry RetryPolicy
+	// OnAttempts, if set, receives every provider call made for a request.
+	OnAttempts func([]Attempt)
 }
 
 type HTTPDoer interface {
 	Do(req *http.Request) (*http.Response, error)
 }
 
+// defaultHTTP serves clients without an HTTP doer of their own. Clients are
+// shared by concurrent turns, so it is never stored on the client.
+var defaultHTTP = &http.Client{Timeout: 120 * time.Second}
+
+// defaultStreamHTTP serves streaming requests, which may run for many
+// minutes: only connecting and waiting for the response headers are bounded
+// here, the body by streamIdleTimeout between reads.
+var defaultStreamHTTP = &http.Client{Transport: newStreamTransport()}
+
+const (
+	streamHeaderTimeout = 120 * time.Second
+	streamIdleTimeout   = 120 * time.Second
+)
+
+func newStreamTransport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	t.TLSHandshakeTimeout = 10 * time.Second
+	t.ResponseHeaderTimeout = streamHeaderTimeout
+	return t
+}
+
+func (c *Client) httpDoer() HTTPDoer {
+	if c.HTTP != nil {
+		return c.HTTP
+	}
+	return defaultHTTP
+}
+
+func (c *Client) streamDoer() HTTPDoer {
+	if c.HTTP != nil {
+		return c.HTTP
+	}
+	return defaultStreamHTTP
+}
+
 type ToolCall struct {
 	ID        string
 	Name      string
@@ -38,9 +82,12 @@ type ChatResult struct {
 func (r ChatResult) HasToolCalls() bool { return len(r.ToolCalls) > 0 }
 
 func (c *Client) Chat(ctx context.Context, messages []Message, tools []ToolDefinition) (*ChatResult, error) {
-	if c.HTTP == nil {
-		c.HTTP = &http.Client{Timeout: 120 * time.Second}
-	}
+	return c.withFailover(ctx, func(cc *Client) (*ChatResult, error) {
+		return cc.chatOnce(ctx, messages, tools)
+	}, nil)
+}
+
+func (c *Client) chatOnce(ctx context.Context, messages []Message, tools []ToolDefinition) (*ChatResult, error) {
 	switch normalizeProvider(c.Provider) {
 	case "", "openai", "openrouter", "ollama":
 		return c.chatOpenAICompatible(ctx, messages, tools)
@@ -49,7 +96,7 @@ func (c *Client) Chat(ctx context.Context, messages []Message, tools []ToolDefin
 	case "gemini":
 		return c.chatGemini(ctx, messages, tools)
 	case "openai-codex":
-		return c.chatOpenAICodex(ctx, messages, tools)
+		return c.chatOpenAICodex(ctx, messages, tools, nil)
 	default:
 		return nil, fmt.Errorf("unsupported llm provider: %s", strings.TrimSpace(c.Provider))
 	}
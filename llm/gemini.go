@@ -12,8 +12,68 @@ import (
 )
 
 func (c *Client) chatGemini(ctx context.Context, messages []Message, tools []ToolDefinition) (*ChatResult, error) {
-	endpoint := geminiGenerateContentEndpoint(c.BaseURL, c.Model)
+	req, err := c.newGeminiRequest(ctx, geminiGenerateContentEndpoint(c.BaseURL, c.Model), messages, tools)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpDoer().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, newHTTPError("llm", resp, string(body))
+	}
+
+	var parsed geminiResponse
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, fmt.Errorf("parse gemini response: %w", err)
+	}
+	if len(parsed.Candidates) == 0 {
+		if strings.TrimSpace(parsed.PromptFeedback.BlockReason) != "" {
+			return nil, fmt.Errorf("gemini blocked: %s", parsed.PromptFeedback.BlockReason)
+		}
+		return nil, fmt.Errorf("gemini response: no candidates")
+	}
+
+	out := &ChatResult{}
+	var textParts []string
+	callCount := 0
+	for _, part := range parsed.Candidates[0].Content.Parts {
+		if strings.TrimSpace(part.Text) != "" {
+			textParts = append(textParts, part.Text)
+		}
+		if part.FunctionCall != nil {
+			callCount++
+			out.ToolCalls = append(out.ToolCalls, geminiToolCall(part.FunctionCall, callCount))
+		}
+	}
+	out.Content = strings.Join(textParts, "\n")
+	return out, nil
+}
+
+func (c *Client) streamGemini(ctx context.Context, messages []Message, tools []ToolDefinition, onEvent StreamHandler) (*ChatResult, error) {
+	req, err := c.newGeminiRequest(ctx, geminiStreamGenerateContentEndpoint(c.BaseURL, c.Model), messages, tools)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.doStream(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+		return nil, newHTTPError("llm", resp, string(body))
+	}
+	return consumeGeminiStream(resp.Body, onEvent)
+}
 
+func (c *Client) newGeminiRequest(ctx context.Context, endpoint string, messages []Message, tools []ToolDefinition) (*http.Request, error) {
 	contents, systemText := toGeminiMessages(messages)
 	reqBody := struct {
 		Contents          []geminiContent `json:"contents,omitempty"`
@@ -59,64 +119,69 @@ func (c *Client) chatGemini(ctx context.Context, messages []Message, tools []Too
 		}
 		req.Header.Set(k, v)
 	}
+	return req, nil
+}
 
-	resp, err := c.HTTP.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	body, _ := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("llm http %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
-	}
+type geminiResponse struct {
+	Candidates []struct {
+		Content struct {
+			Parts []struct {
+				Text         string              `json:"text,omitempty"`
+				FunctionCall *geminiFunctionCall `json:"functionCall,omitempty"`
+			} `json:"parts"`
+		} `json:"content"`
+	} `json:"candidates"`
+	PromptFeedback struct {
+		BlockReason string `json:"blockReason,omitempty"`
+	} `json:"promptFeedback"`
+}
 
-	var parsed struct {
-		Candidates []struct {
-			Content struct {
-				Parts []struct {
-					Text         string `json:"text,omitempty"`
-					FunctionCall *struct {
-						Name string          `json:"name"`
-						Args json.RawMessage `json:"args"`
-					} `json:"functionCall,omitempty"`
-				} `json:"parts"`
-			} `json:"content"`
-		} `json:"candidates"`
-		PromptFeedback struct {
-			BlockReason string `json:"blockReason,omitempty"`
-		} `json:"promptFeedback"`
-	}
-	if err := json.Unmarshal(body, &parsed); err != nil {
-		return nil, fmt.Errorf("parse gemini response: %w", err)
+func geminiToolCall(fc *geminiFunctionCall, n int) ToolCall {
+	args := fc.Args
+	if len(args) == 0 {
+		args = json.RawMessage(`{}`)
 	}
-	if len(parsed.Candidates) == 0 {
-		if strings.TrimSpace(parsed.PromptFeedback.BlockReason) != "" {
-			return nil, fmt.Errorf("gemini blocked: %s", parsed.PromptFeedback.BlockReason)
-		}
-		return nil, fmt.Errorf("gemini response: no candidates")
+	return ToolCall{
+		ID:        fmt.Sprintf("call_%d", n),
+		Name:      fc.Name,
+		Arguments: args,
 	}
+}
 
+func consumeGeminiStream(r io.Reader, onEvent StreamHandler) (*ChatResult, error) {
 	out := &ChatResult{}
-	var textParts []string
+	var content strings.Builder
 	callCount := 0
-	for _, part := range parsed.Candidates[0].Content.Parts {
-		if strings.TrimSpace(part.Text) != "" {
-			textParts = append(textParts, part.Text)
+
+	err := readSSE(r, func(data string) error {
+		var chunk geminiResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil
 		}
-		if part.FunctionCall != nil {
-			callCount++
-			args := part.FunctionCall.Args
-			if len(args) == 0 {
-				args = json.RawMessage(`{}`)
+		if len(chunk.Candidates) == 0 {
+			if strings.TrimSpace(chunk.PromptFeedback.BlockReason) != "" {
+				return fmt.Errorf("gemini blocked: %s", chunk.PromptFeedback.BlockReason)
+			}
+			return nil
+		}
+		for _, part := range chunk.Candidates[0].Content.Parts {
+			if part.Text != "" {
+				content.WriteString(part.Text)
+				onEvent.delta(part.Text)
+			}
+			if part.FunctionCall != nil {
+				callCount++
+				tc := geminiToolCall(part.FunctionCall, callCount)
+				out.ToolCalls = append(out.ToolCalls, tc)
+				onEvent.toolCall(tc)
 			}
-			out.ToolCalls = append(out.ToolCalls, ToolCall{
-				ID:        fmt.Sprintf("call_%d", callCount),
-				Name:      part.FunctionCall.Name,
-				Arguments: args,
-			})
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	out.Content = strings.Join(textParts, "\n")
+	out.Content = content.String()
 	return out, nil
 }
 
@@ -290,6 +355,11 @@ func parseToolResponseValue(s string) json.RawMessage {
 	return json.RawMessage(fallback)
 }
 
+func geminiStreamGenerateContentEndpoint(baseURL, model string) string {
+	endpoint := strings.TrimSuffix(geminiGenerateContentEndpoint(baseURL, model), ":generateContent")
+	return endpoint + ":streamGenerateContent?alt=sse"
+}
+
 func geminiGenerateContentEndpoint(baseURL, model string) string {
 	base := strings.TrimRight(baseURL, "/")
 	m := strings.TrimPrefix(strings.TrimSpace(model), "models/")
@@ -8,10 +8,77 @@ import (
 	"io"
 	"net/http"
 	"strings"
-	"time"
 )
 
 func (c *Client) chatOpenAICompatible(ctx context.Context, messages []Message, tools []ToolDefinition) (*ChatResult, error) {
+	req, err := c.newOpenAICompatibleRequest(ctx, messages, tools, false)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpDoer().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, newHTTPError("llm", resp, string(body))
+	}
+
+	var parsed struct {
+		Choices []struct {
+			Message struct {
+				Content   string `json:"content"`
+				ToolCalls []struct {
+					ID       string `json:"id"`
+					Type     string `json:"type"`
+					Function struct {
+						Name      string          `json:"name"`
+						Arguments json.RawMessage `json:"arguments"`
+					} `json:"function"`
+				} `json:"tool_calls"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, fmt.Errorf("parse llm response: %w", err)
+	}
+	if len(parsed.Choices) == 0 {
+		return nil, fmt.Errorf("llm response: no choices")
+	}
+	m := parsed.Choices[0].Message
+	out := &ChatResult{Content: m.Content}
+	for _, tc := range m.ToolCalls {
+		out.ToolCalls = append(out.ToolCalls, ToolCall{
+			ID:        tc.ID,
+			Name:      tc.Function.Name,
+			Arguments: unwrapOpenAIArguments(tc.Function.Arguments),
+		})
+	}
+	return out, nil
+}
+
+func (c *Client) streamOpenAICompatible(ctx context.Context, messages []Message, tools []ToolDefinition, onEvent StreamHandler) (*ChatResult, error) {
+	req, err := c.newOpenAICompatibleRequest(ctx, messages, tools, true)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.doStream(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+		return nil, newHTTPError("llm", resp, string(body))
+	}
+	return consumeOpenAIStream(resp.Body, onEvent)
+}
+
+func (c *Client) newOpenAICompatibleRequest(ctx context.Context, messages []Message, tools []ToolDefinition, stream bool) (*http.Request, error) {
 	endpoint := strings.TrimRight(c.BaseURL, "/") + "/chat/completions"
 
 	type chatRequest struct {
@@ -21,12 +88,14 @@ func (c *Client) chatOpenAICompatible(ctx context.Context, messages []Message, t
 		Temperature *float64         `json:"temperature,omitempty"`
 		Tools       []ToolDefinition `json:"tools,omitempty"`
 		ToolChoice  string           `json:"tool_choice,omitempty"`
+		Stream      bool             `json:"stream,omitempty"`
 	}
 	reqBody := chatRequest{
 		Model:       c.Model,
 		Messages:    toOpenAIMessages(messages),
 		MaxTokens:   c.maxTokensValue(),
 		Temperature: c.temperatureValue(),
+		Stream:      stream,
 	}
 	if len(tools) > 0 {
 		reqBody.Tools = tools
@@ -50,63 +119,106 @@ func (c *Client) chatOpenAICompatible(ctx context.Context, messages []Message, t
 		}
 		req.Header.Set(k, v)
 	}
+	return req, nil
+}
 
-	hc := c.HTTP
-	if hc == nil {
-		hc = &http.Client{Timeout: 120 * time.Second}
-	}
-	resp, err := hc.Do(req)
+type openAIToolCallBuffer struct {
+	ID        string
+	Name      string
+	Arguments strings.Builder
+}
+
+func consumeOpenAIStream(r io.Reader, onEvent StreamHandler) (*ChatResult, error) {
+	out := &ChatResult{}
+	var content strings.Builder
+	var calls []*openAIToolCallBuffer
+
+	err := readSSE(r, func(data string) error {
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content   string `json:"content"`
+					ToolCalls []struct {
+						Index    int    `json:"index"`
+						ID       string `json:"id"`
+						Function struct {
+							Name      string `json:"name"`
+							Arguments string `json:"arguments"`
+						} `json:"function"`
+					} `json:"tool_calls"`
+				} `json:"delta"`
+			} `json:"choices"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			// Ignore non-JSON chunks (comments, keep-alives).
+			return nil
+		}
+		if chunk.Error != nil {
+			return fmt.Errorf("llm stream error: %s", strings.TrimSpace(chunk.Error.Message))
+		}
+		if len(chunk.Choices) == 0 {
+			return nil
+		}
+		delta := chunk.Choices[0].Delta
+		if delta.Content != "" {
+			content.WriteString(delta.Content)
+			onEvent.delta(delta.Content)
+		}
+		for _, tc := range delta.ToolCalls {
+			for len(calls) <= tc.Index {
+				calls = append(calls, &openAIToolCallBuffer{})
+			}
+			buf := calls[tc.Index]
+			if tc.ID != "" {
+				buf.ID = tc.ID
+			}
+			if tc.Function.Name != "" {
+				buf.Name = tc.Function.Name
+			}
+			buf.Arguments.WriteString(tc.Function.Arguments)
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-	body, _ := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("llm http %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
-	}
 
-	var parsed struct {
-		Choices []struct {
-			Message struct {
-				Content   string `json:"content"`
-				ToolCalls []struct {
-					ID       string `json:"id"`
-					Type     string `json:"type"`
-					Function struct {
-						Name      string          `json:"name"`
-						Arguments json.RawMessage `json:"arguments"`
-					} `json:"function"`
-				} `json:"tool_calls"`
-			} `json:"message"`
-		} `json:"choices"`
-	}
-	if err := json.Unmarshal(body, &parsed); err != nil {
-		return nil, fmt.Errorf("parse llm response: %w", err)
-	}
-	if len(parsed.Choices) == 0 {
-		return nil, fmt.Errorf("llm response: no choices")
-	}
-	m := parsed.Choices[0].Message
-	out := &ChatResult{Content: m.Content}
-	for _, tc := range m.ToolCalls {
-		args := tc.Function.Arguments
-		// OpenAI-compatible servers typically return arguments as a JSON string.
-		// Convert it to raw JSON bytes so downstream tools can unmarshal into structs.
-		if len(args) > 0 && args[0] == '"' {
-			var s string
-			if err := json.Unmarshal(args, &s); err == nil {
-				args = []byte(s)
-			}
+	out.Content = content.String()
+	for i, buf := range calls {
+		if strings.TrimSpace(buf.Name) == "" {
+			continue
 		}
-		out.ToolCalls = append(out.ToolCalls, ToolCall{
-			ID:        tc.ID,
-			Name:      tc.Function.Name,
-			Arguments: args,
-		})
+		id := buf.ID
+		if id == "" {
+			id = fmt.Sprintf("call_%d", i+1)
+		}
+		args := strings.TrimSpace(buf.Arguments.String())
+		if args == "" {
+			args = "{}"
+		}
+		tc := ToolCall{ID: id, Name: buf.Name, Arguments: json.RawMessage(args)}
+		out.ToolCalls = append(out.ToolCalls, tc)
+		onEvent.toolCall(tc)
 	}
 	return out, nil
 }
 
+// unwrapOpenAIArguments converts string-encoded tool arguments to raw JSON.
+// OpenAI-compatible servers typically return arguments as a JSON string;
+// downstream tools expect raw JSON bytes they can unmarshal into structs.
+func unwrapOpenAIArguments(args json.RawMessage) json.RawMessage {
+	if len(args) > 0 && args[0] == '"' {
+		var s string
+		if err := json.Unmarshal(args, &s); err == nil {
+			return []byte(s)
+		}
+	}
+	return args
+}
+
 type openAIMessage struct {
 	Role       string            `json:"role"`
 	Content    *openAIContent    `json:"content,omitempty"`
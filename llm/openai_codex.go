@@ -1,7 +1,6 @@
 package llm
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"crypto/sha256"
@@ -61,7 +60,7 @@ type codexInputContent struct {
 	Text string `json:"text,omitempty"`
 }
 
-func (c *Client) chatOpenAICodex(ctx context.Context, messages []Message, tools []ToolDefinition) (*ChatResult, error) {
+func (c *Client) chatOpenAICodex(ctx context.Context, messages []Message, tools []ToolDefinition, onEvent StreamHandler) (*ChatResult, error) {
 	tok, err := LoadCodexOAuthToken()
 	if err != nil {
 		return nil, err
@@ -119,7 +118,7 @@ func (c *Client) chatOpenAICodex(ctx context.Context, messages []Message, tools
 		req.Header.Set(k, v)
 	}
 
-	resp, err := c.HTTP.Do(req)
+	resp, err := c.doStream(req)
 	if err != nil {
 		return nil, err
 	}
@@ -127,10 +126,10 @@ func (c *Client) chatOpenAICodex(ctx context.Context, messages []Message, tools
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
-		return nil, fmt.Errorf("codex http %d: %s", resp.StatusCode, codexFriendlyError(resp.StatusCode, strings.TrimSpace(string(raw))))
+		return nil, newHTTPError("codex", resp, codexFriendlyError(resp.StatusCode, strings.TrimSpace(string(raw))))
 	}
 
-	return consumeCodexSSE(resp.Body)
+	return consumeCodexStream(resp.Body, onEvent)
 }
 
 type codexSSEEvent struct {
@@ -154,48 +153,22 @@ type codexToolCallBuffer struct {
 }
 
 func consumeCodexSSE(r io.Reader) (*ChatResult, error) {
+	return consumeCodexStream(r, nil)
+}
+
+func consumeCodexStream(r io.Reader, onEvent StreamHandler) (*ChatResult, error) {
 	out := &ChatResult{}
 	buffers := map[string]*codexToolCallBuffer{}
-
-	scanner := bufio.NewScanner(r)
-	scanner.Buffer(make([]byte, 0, 64*1024), 2<<20)
-	dataLines := make([]string, 0, 2)
-
-	flush := func() error {
-		if len(dataLines) == 0 {
-			return nil
-		}
-		data := strings.TrimSpace(strings.Join(dataLines, "\n"))
-		dataLines = dataLines[:0]
-		if data == "" || data == "[DONE]" {
-			return nil
-		}
-		return handleCodexSSEData(data, out, buffers)
-	}
-
-	for scanner.Scan() {
-		line := scanner.Text()
-		if line == "" {
-			if err := flush(); err != nil {
-				return nil, err
-			}
-			continue
-		}
-		if after, ok := strings.CutPrefix(line, "data:"); ok {
-			dataLines = append(dataLines, strings.TrimSpace(after))
-		}
-	}
-	if err := scanner.Err(); err != nil {
-		return nil, err
-	}
-	if err := flush(); err != nil {
+	err := readSSE(r, func(data string) error {
+		return handleCodexSSEData(data, out, buffers, onEvent)
+	})
+	if err != nil {
 		return nil, err
 	}
-
 	return out, nil
 }
 
-func handleCodexSSEData(data string, out *ChatResult, buffers map[string]*codexToolCallBuffer) error {
+func handleCodexSSEData(data string, out *ChatResult, buffers map[string]*codexToolCallBuffer, onEvent StreamHandler) error {
 	var evt codexSSEEvent
 	if err := json.Unmarshal([]byte(data), &evt); err != nil {
 		// Ignore non-JSON chunks.
@@ -204,6 +177,7 @@ func handleCodexSSEData(data string, out *ChatResult, buffers map[string]*codexT
 	switch evt.Type {
 	case "response.output_text.delta":
 		out.Content += evt.Delta
+		onEvent.delta(evt.Delta)
 	case "response.output_item.added":
 		if evt.Item.Type != "function_call" {
 			return nil
@@ -267,11 +241,13 @@ func handleCodexSSEData(data string, out *ChatResult, buffers map[string]*codexT
 		if itemID == "" {
 			itemID = "fc_0"
 		}
-		out.ToolCalls = append(out.ToolCalls, ToolCall{
+		tc := ToolCall{
 			ID:        callID + "|" + itemID,
 			Name:      strings.TrimSpace(buf.Name),
 			Arguments: codexArgumentsToJSON(buf.Arguments),
-		})
+		}
+		out.ToolCalls = append(out.ToolCalls, tc)
+		onEvent.toolCall(tc)
 		delete(buffers, callID)
 	case "error", "response.failed":
 		return fmt.Errorf("codex response failed")
@@ -0,0 +1,295 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTPError is returned when a provider answers with a non-2xx status.
+type HTTPError struct {
+	Label      string // "llm" or "codex"
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration
+}
+
+func (e *HTTPError) Error() string {
+	label := e.Label
+	if label == "" {
+		label = "llm"
+	}
+	return fmt.Sprintf("%s http %d: %s", label, e.StatusCode, e.Body)
+}
+
+func newHTTPError(label string, resp *http.Response, body string) *HTTPError {
+	return &HTTPError{
+		Label:      label,
+		StatusCode: resp.StatusCode,
+		Body:       strings.TrimSpace(body),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// IsRetryable reports whether err is transient (rate limits, overload, server
+// errors, dropped connections) and the same request may succeed if repeated.
+// Other errors are fatal for the current model.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var he *HTTPError
+	if errors.As(err, &he) {
+		switch he.StatusCode {
+		case http.StatusRequestTimeout, http.StatusConflict, http.StatusTooEarly, http.StatusTooManyRequests:
+			return true
+		}
+		return he.StatusCode >= 500
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var ne net.Error
+	if errors.As(err, &ne) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "overloaded") || strings.Contains(msg, "rate limit")
+}
+
+// IsContextLengthError reports whether the provider rejected the request
+// because the prompt doesn't fit the model's context window.
+func IsContextLengthError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var he *HTTPError
+	if errors.As(err, &he) {
+		switch he.StatusCode {
+		case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		default:
+			return false
+		}
+	}
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{
+		"context_length_exceeded",
+		"context length",
+		"context window",
+		"maximum context",
+		"prompt is too long",
+		"input is too long",
+		"too many tokens",
+		"exceeds the maximum number of tokens",
+		"input token count",
+	} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// RetryPolicy controls how often a single model is retried on retryable
+// errors before falling back to the next model. The zero value makes a
+// single attempt.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+func (p RetryPolicy) maxAttempts() int {
+	if p.MaxAttempts <= 0 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// backoff returns the wait before attempt n+1. A Retry-After hint from the
+// provider wins over the exponential schedule, capped at MaxBackoff.
+func (p RetryPolicy) backoff(n int, err error) time.Duration {
+	initial := p.InitialBackoff
+	if initial <= 0 {
+		initial = 500 * time.Millisecond
+	}
+	maxWait := p.MaxBackoff
+	if maxWait <= 0 {
+		maxWait = 30 * time.Second
+	}
+	wait := initial << (n - 1)
+	var he *HTTPError
+	if errors.As(err, &he) && he.RetryAfter > 0 {
+		wait = he.RetryAfter
+	}
+	if wait <= 0 || wait > maxWait {
+		wait = maxWait
+	}
+	return wait
+}
+
+// Attempt records one provider call made on behalf of Chat or ChatStream.
+type Attempt struct {
+	Provider   string    `json:"provider"`
+	Model      string    `json:"model"`
+	StartedAt  time.Time `json:"startedAt"`
+	DurationMS int64     `json:"durationMs"`
+	Error      string    `json:"error,omitempty"`
+}
+
+func (a Attempt) OK() bool { return a.Error == "" }
+
+func (c *Client) candidates() []*Client {
+	out := make([]*Client, 0, 1+len(c.Fallbacks))
+	out = append(out, c)
+	for _, fb := range c.Fallbacks {
+		if fb != nil {
+			out = append(out, fb)
+		}
+	}
+	return out
+}
+
+// withFailover runs call against this client and then each fallback, retrying
+// retryable errors per c.Retry. emitted reports whether output was already
+// streamed to the caller, in which case the call can't be transparently
+// repeated.
+func (c *Client) withFailover(ctx context.Context, call func(cc *Client) (*ChatResult, error), emitted func() bool) (*ChatResult, error) {
+	var attempts []Attempt
+	defer func() {
+		if c.OnAttempts != nil && len(attempts) > 0 {
+			c.OnAttempts(attempts)
+		}
+	}()
+
+	candidates := c.candidates()
+	var lastErr error
+	for _, cand := range candidates {
+		for n := 1; ; n++ {
+			start := time.Now()
+			res, err := call(cand)
+			a := Attempt{
+				Provider:   providerLabel(cand.Provider),
+				Model:      cand.Model,
+				StartedAt:  start,
+				DurationMS: time.Since(start).Milliseconds(),
+			}
+			if err != nil {
+				a.Error = err.Error()
+			}
+			attempts = append(attempts, a)
+			if err == nil {
+				return res, nil
+			}
+			lastErr = err
+			if ctx.Err() != nil || (emitted != nil && emitted()) {
+				return nil, err
+			}
+			if !IsRetryable(err) || n >= c.Retry.maxAttempts() {
+				break
+			}
+			t := time.NewTimer(c.Retry.backoff(n, err))
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return nil, ctx.Err()
+			case <-t.C:
+			}
+		}
+	}
+	if len(candidates) > 1 {
+		return nil, fmt.Errorf("all %d models failed: %w", len(candidates), lastErr)
+	}
+	return nil, lastErr
+}
+
+func providerLabel(p string) string {
+	p = normalizeProvider(p)
+	if p == "" {
+		return "openai"
+	}
+	return p
+}
+
+// AttemptLog is the last set of attempts persisted for `clawlet status`.
+type AttemptLog struct {
+	UpdatedAt time.Time `json:"updatedAt"`
+	Attempts  []Attempt `json:"attempts"`
+}
+
+// Answered returns the attempt that produced the response, if any.
+func (l *AttemptLog) Answered() (Attempt, bool) {
+	if l == nil || len(l.Attempts) == 0 {
+		return Attempt{}, false
+	}
+	last := l.Attempts[len(l.Attempts)-1]
+	return last, last.OK()
+}
+
+func SaveAttemptLog(path string, attempts []Attempt) error {
+	b, err := json.MarshalIndent(AttemptLog{UpdatedAt: time.Now(), Attempts: attempts}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".llm-status-*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func LoadAttemptLog(path string) (*AttemptLog, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var l AttemptLog
+	if err := json.Unmarshal(b, &l); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
@@ -0,0 +1,192 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	if got := parseRetryAfter("3", now); got != 3*time.Second {
+		t.Fatalf("seconds=%s", got)
+	}
+	if got := parseRetryAfter(now.Add(10*time.Second).Format(http.TimeFormat), now); got != 10*time.Second {
+		t.Fatalf("date=%s", got)
+	}
+	if got := parseRetryAfter("soon", now); got != 0 {
+		t.Fatalf("invalid=%s", got)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{&HTTPError{StatusCode: 429}, true},
+		{&HTTPError{StatusCode: 529}, true},
+		{&HTTPError{StatusCode: 503}, true},
+		{&HTTPError{StatusCode: 400}, false},
+		{&HTTPError{StatusCode: 401}, false},
+		{io.ErrUnexpectedEOF, true},
+		{errors.New("parse llm response: bad json"), false},
+	}
+	for _, tc := range cases {
+		if got := IsRetryable(tc.err); got != tc.want {
+			t.Fatalf("IsRetryable(%v)=%v want %v", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestRetryPolicyBackoff_HonorsRetryAfterAndCap(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	if got := p.backoff(3, errors.New("x")); got != 400*time.Millisecond {
+		t.Fatalf("exp=%s", got)
+	}
+	if got := p.backoff(1, &HTTPError{StatusCode: 429, RetryAfter: 700 * time.Millisecond}); got != 700*time.Millisecond {
+		t.Fatalf("retry-after=%s", got)
+	}
+	if got := p.backoff(1, &HTTPError{StatusCode: 429, RetryAfter: time.Minute}); got != time.Second {
+		t.Fatalf("cap=%s", got)
+	}
+}
+
+func okChatServer(t *testing.T, content string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"choices":[{"message":{"content":"`+content+`"}}]}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestChat_RetriesRetryableErrors(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+			return
+		}
+		_, _ = io.WriteString(w, `{"choices":[{"message":{"content":"ok"}}]}`)
+	}))
+	defer srv.Close()
+
+	var attempts []Attempt
+	c := &Client{
+		Provider:   "openai",
+		BaseURL:    srv.URL,
+		Model:      "m1",
+		Retry:      RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+		OnAttempts: func(a []Attempt) { attempts = a },
+	}
+	out, err := c.Chat(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil)
+	if err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	if out.Content != "ok" || calls.Load() != 2 {
+		t.Fatalf("content=%q calls=%d", out.Content, calls.Load())
+	}
+	if len(attempts) != 2 || attempts[0].OK() || !attempts[1].OK() {
+		t.Fatalf("attempts=%+v", attempts)
+	}
+}
+
+func TestChat_FailsOverOnFatalError(t *testing.T) {
+	var primaryCalls atomic.Int32
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		primaryCalls.Add(1)
+		http.Error(w, "bad key", http.StatusUnauthorized)
+	}))
+	defer primary.Close()
+	fallback := okChatServer(t, "from fallback")
+
+	var attempts []Attempt
+	c := &Client{
+		Provider:   "openai",
+		BaseURL:    primary.URL,
+		Model:      "primary",
+		Retry:      RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+		Fallbacks:  []*Client{{Provider: "openrouter", BaseURL: fallback.URL, Model: "backup"}},
+		OnAttempts: func(a []Attempt) { attempts = a },
+	}
+	out, err := c.Chat(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil)
+	if err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	if out.Content != "from fallback" {
+		t.Fatalf("content=%q", out.Content)
+	}
+	if primaryCalls.Load() != 1 {
+		t.Fatalf("fatal error should not be retried, calls=%d", primaryCalls.Load())
+	}
+	if len(attempts) != 2 || attempts[1].Provider != "openrouter" || attempts[1].Model != "backup" {
+		t.Fatalf("attempts=%+v", attempts)
+	}
+}
+
+func TestChat_AllModelsFail(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	c := &Client{
+		Provider:  "openai",
+		BaseURL:   srv.URL,
+		Model:     "a",
+		Fallbacks: []*Client{{Provider: "openai", BaseURL: srv.URL, Model: "b"}},
+	}
+	_, err := c.Chat(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil)
+	var he *HTTPError
+	if !errors.As(err, &he) || he.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("err=%v", err)
+	}
+	if !strings.Contains(err.Error(), "all 2 models failed") {
+		t.Fatalf("err=%v", err)
+	}
+}
+
+func TestChatStream_NoRetryAfterOutputStarted(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		_, _ = io.WriteString(w, "data: {\"choices\":[{\"delta\":{\"content\":\"par\"}}]}\n\ndata: {\"error\":{\"message\":\"overloaded\"}}\n\n")
+	}))
+	defer srv.Close()
+
+	c := &Client{Provider: "openai", BaseURL: srv.URL, Model: "m", Retry: RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}}
+	if _, err := c.ChatStream(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, nil); err == nil {
+		t.Fatal("expected error")
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("calls=%d", calls.Load())
+	}
+}
+
+func TestIsContextLengthError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{&HTTPError{StatusCode: 400, Body: `{"error":{"code":"context_length_exceeded","message":"This model's maximum context length is 128000 tokens."}}`}, true},
+		{&HTTPError{StatusCode: 400, Body: `{"type":"error","error":{"type":"invalid_request_error","message":"prompt is too long: 210000 tokens > 200000 maximum"}}`}, true},
+		{fmt.Errorf("all 2 models failed: %w", &HTTPError{StatusCode: 400, Body: "The input token count (1200000) exceeds the maximum number of tokens allowed"}), true},
+		{&HTTPError{StatusCode: 400, Body: "invalid tool schema"}, false},
+		{&HTTPError{StatusCode: 429, Body: "too many tokens per minute"}, false},
+		{nil, false},
+	}
+	for i, tc := range cases {
+		if got := IsContextLengthError(tc.err); got != tc.want {
+			t.Fatalf("case %d: got %v want %v (%v)", i, got, tc.want, tc.err)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package llm
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// StreamEvent is an incremental update emitted by ChatStream.
+// Exactly one of Delta or ToolCall is set.
+type StreamEvent struct {
+	// Delta is the next chunk of assistant text.
+	Delta string
+	// ToolCall is a fully assembled tool call (arguments complete).
+	ToolCall *ToolCall
+}
+
+type StreamHandler func(StreamEvent)
+
+func (h StreamHandler) delta(s string) {
+	if h == nil || s == "" {
+		return
+	}
+	h(StreamEvent{Delta: s})
+}
+
+func (h StreamHandler) toolCall(tc ToolCall) {
+	if h == nil {
+		return
+	}
+	h(StreamEvent{ToolCall: &tc})
+}
+
+// ChatStream behaves like Chat but reports text deltas and assembled tool calls
+// to onEvent while the response is being generated. The returned ChatResult is
+// the same as Chat would have returned.
+//
+// Retries and fallbacks only happen until the first event has been emitted;
+// after that, a failure is returned to the caller as is.
+func (c *Client) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, onEvent StreamHandler) (*ChatResult, error) {
+	emitted := false
+	tracked := func(ev StreamEvent) {
+		emitted = true
+		if onEvent != nil {
+			onEvent(ev)
+		}
+	}
+	return c.withFailover(ctx, func(cc *Client) (*ChatResult, error) {
+		return cc.streamOnce(ctx, messages, tools, tracked)
+	}, func() bool { return emitted })
+}
+
+func (c *Client) streamOnce(ctx context.Context, messages []Message, tools []ToolDefinition, onEvent StreamHandler) (*ChatResult, error) {
+	switch normalizeProvider(c.Provider) {
+	case "", "openai", "openrouter", "ollama":
+		return c.streamOpenAICompatible(ctx, messages, tools, onEvent)
+	case "anthropic":
+		return c.streamAnthropic(ctx, messages, tools, onEvent)
+	case "gemini":
+		return c.streamGemini(ctx, messages, tools, onEvent)
+	case "openai-codex":
+		return c.chatOpenAICodex(ctx, messages, tools, onEvent)
+	default:
+		return nil, fmt.Errorf("unsupported llm provider: %s", strings.TrimSpace(c.Provider))
+	}
+}
+
+// doStream sends a streaming request. The request is cancelled when its
+// body yields nothing for streamIdleTimeout, so a stalled stream fails
+// instead of hanging the turn.
+func (c *Client) doStream(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	resp, err := c.streamDoer().Do(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = newIdleBody(resp.Body, streamIdleTimeout, cancel)
+	return resp, nil
+}
+
+// idleBody calls stop when a Read waits longer than idle, and reports the
+// stall instead of the error the stopped read fails with. Time spent by the
+// caller between reads is not counted.
+type idleBody struct {
+	rc       io.ReadCloser
+	idle     time.Duration
+	stop     func()
+	timer    *time.Timer
+	timedOut atomic.Bool
+}
+
+func newIdleBody(rc io.ReadCloser, idle time.Duration, stop func()) *idleBody {
+	b := &idleBody{rc: rc, idle: idle, stop: stop}
+	b.timer = time.AfterFunc(idle, func() {
+		b.timedOut.Store(true)
+		stop()
+	})
+	b.timer.Stop()
+	return b
+}
+
+func (b *idleBody) Read(p []byte) (int, error) {
+	b.timer.Reset(b.idle)
+	n, err := b.rc.Read(p)
+	b.timer.Stop()
+	if b.timedOut.Load() {
+		return n, fmt.Errorf("llm stream: no data for %s", b.idle)
+	}
+	return n, err
+}
+
+func (b *idleBody) Close() error {
+	b.timer.Stop()
+	err := b.rc.Close()
+	b.stop()
+	return err
+}
+
+// readSSE calls fn with the data payload of every server-sent event in r.
+// Empty payloads and the OpenAI-style "[DONE]" terminator are skipped.
+func readSSE(r io.Reader, fn func(data string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 2<<20)
+	dataLines := make([]string, 0, 2)
+
+	flush := func() error {
+		if len(dataLines) == 0 {
+			return nil
+		}
+		data := strings.TrimSpace(strings.Join(dataLines, "\n"))
+		dataLines = dataLines[:0]
+		if data == "" || data == "[DONE]" {
+			return nil
+		}
+		return fn(data)
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if err := flush(); err != nil {
+				return err
+			}
+			continue
+		}
+		if after, ok := strings.CutPrefix(line, "data:"); ok {
+			dataLines = append(dataLines, strings.TrimSpace(after))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return flush()
+}
@@ -0,0 +1,218 @@
+package llm
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConsumeOpenAIStream_TextAndToolCall(t *testing.T) {
+	stream := strings.Join([]string{
+		`data: {"choices":[{"delta":{"role":"assistant","content":"Hel"}}]}`,
+		"",
+		`data: {"choices":[{"delta":{"content":"lo"}}]}`,
+		"",
+		`data: {"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"read_file","arguments":""}}]}}]}`,
+		"",
+		`data: {"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"path\":"}}]}}]}`,
+		"",
+		`data: {"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"README.md\"}"}}]}}]}`,
+		"",
+		"data: [DONE]",
+		"",
+	}, "\n")
+
+	var deltas []string
+	var calls []ToolCall
+	out, err := consumeOpenAIStream(strings.NewReader(stream), func(ev StreamEvent) {
+		if ev.Delta != "" {
+			deltas = append(deltas, ev.Delta)
+		}
+		if ev.ToolCall != nil {
+			calls = append(calls, *ev.ToolCall)
+		}
+	})
+	if err != nil {
+		t.Fatalf("consume: %v", err)
+	}
+	if out.Content != "Hello" {
+		t.Fatalf("content=%q", out.Content)
+	}
+	if strings.Join(deltas, "|") != "Hel|lo" {
+		t.Fatalf("deltas=%v", deltas)
+	}
+	if len(out.ToolCalls) != 1 || len(calls) != 1 {
+		t.Fatalf("tool_calls=%d events=%d", len(out.ToolCalls), len(calls))
+	}
+	var args map[string]string
+	if err := json.Unmarshal(out.ToolCalls[0].Arguments, &args); err != nil {
+		t.Fatalf("args json: %v", err)
+	}
+	if out.ToolCalls[0].ID != "call_1" || args["path"] != "README.md" {
+		t.Fatalf("tool_call=%+v", out.ToolCalls[0])
+	}
+}
+
+func TestConsumeOpenAIStream_Error(t *testing.T) {
+	stream := "data: {\"error\":{\"message\":\"overloaded\"}}\n\n"
+	if _, err := consumeOpenAIStream(strings.NewReader(stream), nil); err == nil || !strings.Contains(err.Error(), "overloaded") {
+		t.Fatalf("err=%v", err)
+	}
+}
+
+func TestConsumeAnthropicStream_TextAndToolUse(t *testing.T) {
+	stream := strings.Join([]string{
+		"event: message_start",
+		`data: {"type":"message_start","message":{"id":"msg_1"}}`,
+		"",
+		`data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		"",
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Let me look"}}`,
+		"",
+		`data: {"type":"content_block_stop","index":0}`,
+		"",
+		`data: {"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_9","name":"list_dir","input":{}}}`,
+		"",
+		`data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"path\":"}}`,
+		"",
+		`data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\".\"}"}}`,
+		"",
+		`data: {"type":"content_block_stop","index":1}`,
+		"",
+		`data: {"type":"message_stop"}`,
+		"",
+	}, "\n")
+
+	var text strings.Builder
+	out, err := consumeAnthropicStream(strings.NewReader(stream), func(ev StreamEvent) {
+		text.WriteString(ev.Delta)
+	})
+	if err != nil {
+		t.Fatalf("consume: %v", err)
+	}
+	if out.Content != "Let me look" || text.String() != "Let me look" {
+		t.Fatalf("content=%q streamed=%q", out.Content, text.String())
+	}
+	if len(out.ToolCalls) != 1 {
+		t.Fatalf("tool_calls=%d", len(out.ToolCalls))
+	}
+	if out.ToolCalls[0].ID != "toolu_9" || out.ToolCalls[0].Name != "list_dir" || string(out.ToolCalls[0].Arguments) != `{"path":"."}` {
+		t.Fatalf("tool_call=%+v args=%s", out.ToolCalls[0], out.ToolCalls[0].Arguments)
+	}
+}
+
+func TestConsumeGeminiStream_TextAndFunctionCall(t *testing.T) {
+	stream := strings.Join([]string{
+		`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"Hi "}]}}]}`,
+		"",
+		`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"there"},{"functionCall":{"name":"read_file","args":{"path":"a.txt"}}}]}}]}`,
+		"",
+	}, "\n")
+
+	out, err := consumeGeminiStream(strings.NewReader(stream), nil)
+	if err != nil {
+		t.Fatalf("consume: %v", err)
+	}
+	if out.Content != "Hi there" {
+		t.Fatalf("content=%q", out.Content)
+	}
+	if len(out.ToolCalls) != 1 || out.ToolCalls[0].ID != "call_1" || out.ToolCalls[0].Name != "read_file" {
+		t.Fatalf("tool_calls=%+v", out.ToolCalls)
+	}
+}
+
+func TestGeminiStreamGenerateContentEndpoint(t *testing.T) {
+	got := geminiStreamGenerateContentEndpoint("https://generativelanguage.googleapis.com", "gemini-2.5-flash")
+	if got != "https://generativelanguage.googleapis.com/v1beta/models/gemini-2.5-flash:streamGenerateContent?alt=sse" {
+		t.Fatalf("endpoint=%q", got)
+	}
+}
+
+func TestChatStream_OpenAICompatibleRequestsStream(t *testing.T) {
+	var gotStream bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req struct {
+			Stream bool `json:"stream"`
+		}
+		_ = json.Unmarshal(body, &req)
+		gotStream = req.Stream
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "data: {\"choices\":[{\"delta\":{\"content\":\"ok\"}}]}\n\ndata: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	c := &Client{Provider: "openai", BaseURL: srv.URL, Model: "gpt-test"}
+	var streamed string
+	out, err := c.ChatStream(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, func(ev StreamEvent) {
+		streamed += ev.Delta
+	})
+	if err != nil {
+		t.Fatalf("chat stream: %v", err)
+	}
+	if !gotStream {
+		t.Fatal("expected stream=true in request")
+	}
+	if out.Content != "ok" || streamed != "ok" {
+		t.Fatalf("content=%q streamed=%q", out.Content, streamed)
+	}
+}
+
+func TestChatStream_SharedClientIsSafeForConcurrentUse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "data: {\"choices\":[{\"delta\":{\"content\":\"ok\"}}]}\n\ndata: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	// No HTTP doer set: concurrent calls must not race to install one.
+	c := &Client{Provider: "openai", BaseURL: srv.URL, Model: "gpt-test"}
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Go(func() {
+			if _, err := c.ChatStream(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, nil); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+	if c.HTTP != nil {
+		t.Fatal("client was modified")
+	}
+}
+
+func TestIdleBody_StopsStalledRead(t *testing.T) {
+	if defaultStreamHTTP.Timeout != 0 {
+		t.Fatalf("streaming client has an overall timeout of %s", defaultStreamHTTP.Timeout)
+	}
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	b := newIdleBody(pr, 50*time.Millisecond, func() { _ = pr.CloseWithError(io.ErrUnexpectedEOF) })
+	defer b.Close()
+
+	go func() { _, _ = pw.Write([]byte("data: 1\n\n")) }()
+	buf := make([]byte, 64)
+	if n, err := b.Read(buf); err != nil || string(buf[:n]) != "data: 1\n\n" {
+		t.Fatalf("first read=%q err=%v", buf[:n], err)
+	}
+	// Time spent between reads does not count as idle.
+	time.Sleep(100 * time.Millisecond)
+	go func() { _, _ = pw.Write([]byte("data: 2\n\n")) }()
+	if _, err := b.Read(buf); err != nil {
+		t.Fatalf("second read err=%v", err)
+	}
+
+	start := time.Now()
+	_, err := b.Read(buf)
+	if err == nil || !strings.Contains(err.Error(), "no data for") {
+		t.Fatalf("err=%v", err)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Fatalf("stalled read not stopped: %s", time.Since(start))
+	}
+}
@@ -0,0 +1,185 @@
+// Package mcp is a minimal Model Context Protocol client. It speaks JSON-RPC
+// over stdio or streamable HTTP and supports the tools capability only.
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+const protocolVersion = "2025-03-26"
+
+type rpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      *int64 `json:"id,omitempty"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+func (m rpcMessage) isResponse() bool { return len(m.ID) > 0 && m.Method == "" }
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("mcp error %d: %s", e.Code, e.Message)
+}
+
+// errClosed is returned by a transport whose server is gone; the caller
+// reconnects on the next call.
+var errClosed = errors.New("mcp server connection closed")
+
+type transport interface {
+	// call sends a request and returns the matching response.
+	call(ctx context.Context, req rpcRequest) (rpcMessage, error)
+	notify(ctx context.Context, method string, params any) error
+	alive() bool
+	close() error
+}
+
+// Tool is a tool advertised by an MCP server.
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"inputSchema,omitempty"`
+}
+
+// Client is an initialized session with one MCP server.
+type Client struct {
+	t      transport
+	nextID atomic.Int64
+}
+
+func newClient(ctx context.Context, t transport) (*Client, error) {
+	c := &Client{t: t}
+	var res struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	if err := c.request(ctx, "initialize", map[string]any{
+		"protocolVersion": protocolVersion,
+		"capabilities":    map[string]any{},
+		"clientInfo":      map[string]string{"name": "clawlet", "version": "1"},
+	}, &res); err != nil {
+		_ = t.close()
+		return nil, fmt.Errorf("initialize: %w", err)
+	}
+	if err := t.notify(ctx, "notifications/initialized", nil); err != nil {
+		_ = t.close()
+		return nil, fmt.Errorf("initialized: %w", err)
+	}
+	return c, nil
+}
+
+func (c *Client) request(ctx context.Context, method string, params any, out any) error {
+	id := c.nextID.Add(1)
+	msg, err := c.t.call(ctx, rpcRequest{JSONRPC: "2.0", ID: &id, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	if msg.Error != nil {
+		return msg.Error
+	}
+	if out == nil || len(msg.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(msg.Result, out)
+}
+
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	var out []Tool
+	cursor := ""
+	for range 100 {
+		var params map[string]string
+		if cursor != "" {
+			params = map[string]string{"cursor": cursor}
+		}
+		var res struct {
+			Tools      []Tool `json:"tools"`
+			NextCursor string `json:"nextCursor"`
+		}
+		if err := c.request(ctx, "tools/list", params, &res); err != nil {
+			return nil, err
+		}
+		out = append(out, res.Tools...)
+		if res.NextCursor == "" {
+			break
+		}
+		cursor = res.NextCursor
+	}
+	return out, nil
+}
+
+// CallTool invokes a tool and renders its result as text. Tool-level failures
+// (isError) are returned as text prefixed with "error:" so the model can react.
+func (c *Client) CallTool(ctx context.Context, name string, args json.RawMessage) (string, error) {
+	if len(args) == 0 || string(args) == "null" {
+		args = json.RawMessage(`{}`)
+	}
+	var res callToolResult
+	if err := c.request(ctx, "tools/call", map[string]any{"name": name, "arguments": args}, &res); err != nil {
+		return "", err
+	}
+	return res.text(), nil
+}
+
+func (c *Client) alive() bool { return c.t.alive() }
+
+func (c *Client) Close() error { return c.t.close() }
+
+type callToolResult struct {
+	Content []struct {
+		Type     string `json:"type"`
+		Text     string `json:"text"`
+		MIMEType string `json:"mimeType"`
+		Resource *struct {
+			URI  string `json:"uri"`
+			Text string `json:"text"`
+		} `json:"resource"`
+	} `json:"content"`
+	StructuredContent json.RawMessage `json:"structuredContent"`
+	IsError           bool            `json:"isError"`
+}
+
+func (r callToolResult) text() string {
+	parts := make([]string, 0, len(r.Content))
+	for _, c := range r.Content {
+		switch c.Type {
+		case "text":
+			parts = append(parts, c.Text)
+		case "resource":
+			if c.Resource == nil {
+				continue
+			}
+			if c.Resource.Text != "" {
+				parts = append(parts, c.Resource.Text)
+			} else {
+				parts = append(parts, "[resource: "+c.Resource.URI+"]")
+			}
+		default:
+			parts = append(parts, fmt.Sprintf("[%s: %s]", c.Type, c.MIMEType))
+		}
+	}
+	out := strings.Join(parts, "\n")
+	if out == "" && len(r.StructuredContent) > 0 {
+		out = string(r.StructuredContent)
+	}
+	if r.IsError {
+		return "error: " + out
+	}
+	return out
+}
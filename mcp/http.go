@@ -0,0 +1,174 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const sessionHeader = "Mcp-Session-Id"
+
+// httpTransport implements the streamable HTTP transport: every message is a
+// POST, answered either with a JSON body or an SSE stream carrying the response.
+type httpTransport struct {
+	url     string
+	headers map[string]string
+	hc      *http.Client
+
+	mu        sync.Mutex
+	sessionID string
+	dead      atomic.Bool
+}
+
+func newHTTPTransport(url string, headers map[string]string, hc *http.Client) *httpTransport {
+	if hc == nil {
+		hc = &http.Client{}
+	}
+	return &httpTransport{url: url, headers: headers, hc: hc}
+}
+
+func (t *httpTransport) post(ctx context.Context, v any) (*http.Response, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	t.mu.Lock()
+	sid := t.sessionID
+	t.mu.Unlock()
+	if sid != "" {
+		req.Header.Set(sessionHeader, sid)
+		req.Header.Set("MCP-Protocol-Version", protocolVersion)
+	}
+	resp, err := t.hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if id := resp.Header.Get(sessionHeader); id != "" {
+		t.mu.Lock()
+		t.sessionID = id
+		t.mu.Unlock()
+	}
+	if resp.StatusCode == http.StatusNotFound && sid != "" {
+		// The server dropped our session; a new one must be initialized.
+		resp.Body.Close()
+		t.dead.Store(true)
+		return nil, fmt.Errorf("%w: session expired", errClosed)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+		return nil, fmt.Errorf("mcp http %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+	return resp, nil
+}
+
+func (t *httpTransport) call(ctx context.Context, req rpcRequest) (rpcMessage, error) {
+	resp, err := t.post(ctx, req)
+	if err != nil {
+		return rpcMessage{}, err
+	}
+	defer resp.Body.Close()
+
+	want := fmt.Sprint(*req.ID)
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "text/event-stream" {
+		return readSSEResponse(resp.Body, want)
+	}
+	var msg rpcMessage
+	if err := json.NewDecoder(resp.Body).Decode(&msg); err != nil {
+		return rpcMessage{}, fmt.Errorf("mcp decode response: %w", err)
+	}
+	return msg, nil
+}
+
+// readSSEResponse reads events until the response for id arrives. Other
+// messages on the stream (notifications, server requests) are skipped.
+func readSSEResponse(r io.Reader, id string) (rpcMessage, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 8<<20)
+	var data []string
+	flush := func() (rpcMessage, bool) {
+		defer func() { data = data[:0] }()
+		if len(data) == 0 {
+			return rpcMessage{}, false
+		}
+		var msg rpcMessage
+		if err := json.Unmarshal([]byte(strings.Join(data, "\n")), &msg); err != nil {
+			return rpcMessage{}, false
+		}
+		return msg, msg.isResponse() && string(msg.ID) == id
+	}
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if msg, ok := flush(); ok {
+				return msg, nil
+			}
+			continue
+		}
+		if after, ok := strings.CutPrefix(line, "data:"); ok {
+			data = append(data, strings.TrimPrefix(after, " "))
+		}
+	}
+	if msg, ok := flush(); ok {
+		return msg, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return rpcMessage{}, err
+	}
+	return rpcMessage{}, fmt.Errorf("mcp stream ended without a response")
+}
+
+func (t *httpTransport) notify(ctx context.Context, method string, params any) error {
+	resp, err := t.post(ctx, rpcRequest{JSONRPC: "2.0", Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	return resp.Body.Close()
+}
+
+func (t *httpTransport) alive() bool { return !t.dead.Load() }
+
+// close ends the session on the server (best effort).
+func (t *httpTransport) close() error {
+	t.dead.Store(true)
+	t.mu.Lock()
+	sid := t.sessionID
+	t.mu.Unlock()
+	if sid == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, t.url, nil)
+	if err != nil {
+		return nil
+	}
+	req.Header.Set(sessionHeader, sid)
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	if resp, err := t.hc.Do(req); err == nil {
+		resp.Body.Close()
+	}
+	return nil
+}
@@ -0,0 +1,327 @@
+package mcp
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/llm"
+)
+
+// ToolPrefix starts the name of every tool proxied from an MCP server.
+const ToolPrefix = "mcp_"
+
+// restartBackoff limits how often a failed server is reconnected.
+const restartBackoff = 5 * time.Second
+
+const maxToolOutputBytes = 64 << 10
+
+// Manager connects to the configured MCP servers and exposes their tools as
+// mcp_<server>__<tool>. Servers are started lazily on first use and restarted
+// on the next call after they crash. Servers that never listed their tools
+// are retried in the background.
+type Manager struct {
+	servers []*server
+
+	startOnce sync.Once
+
+	mu    sync.RWMutex
+	tools map[string]toolRef
+}
+
+type toolRef struct {
+	server *server
+	tool   string
+}
+
+type server struct {
+	name    string
+	cfg     config.MCPServerConfig
+	timeout time.Duration
+
+	connectMu sync.Mutex
+	retrying  atomic.Bool
+
+	mu      sync.Mutex
+	client  *Client
+	tools   []Tool
+	lastErr error
+	lastTry time.Time
+}
+
+// NewManager returns nil when no server is enabled.
+func NewManager(servers map[string]config.MCPServerConfig) *Manager {
+	names := make([]string, 0, len(servers))
+	for name, sc := range servers {
+		if strings.TrimSpace(name) == "" || !sc.EnabledValue() {
+			continue
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	slices.Sort(names)
+	m := &Manager{tools: map[string]toolRef{}}
+	for _, name := range names {
+		sc := servers[name]
+		m.servers = append(m.servers, &server{name: name, cfg: sc, timeout: sc.TimeoutValue()})
+	}
+	return m
+}
+
+func (m *Manager) start() {
+	m.startOnce.Do(func() {
+		var wg sync.WaitGroup
+		for _, s := range m.servers {
+			wg.Go(func() {
+				if _, err := s.ensure(context.Background()); err != nil {
+					log.Printf("mcp: %s: %v", s.name, err)
+				}
+			})
+		}
+		wg.Wait()
+	})
+}
+
+// Definitions lists the tools of all servers that have connected at least once.
+// The first call blocks until every server has been tried; later calls retry
+// servers without tools in the background, so their tools appear in a later
+// call once they come up.
+func (m *Manager) Definitions() []llm.ToolDefinition {
+	if m == nil {
+		return nil
+	}
+	m.start()
+
+	defs := make([]llm.ToolDefinition, 0, 16)
+	names := map[string]toolRef{}
+	for _, s := range m.servers {
+		tools := s.snapshot()
+		if tools == nil {
+			s.retryList()
+		}
+		for _, t := range tools {
+			name := ToolName(s.name, t.Name)
+			if _, dup := names[name]; dup {
+				continue
+			}
+			names[name] = toolRef{server: s, tool: t.Name}
+			defs = append(defs, llm.ToolDefinition{
+				Type: "function",
+				Function: llm.FunctionDefinition{
+					Name:        name,
+					Description: strings.TrimSpace(t.Description),
+					Parameters:  llm.JSONSchema{Raw: toolSchema(t.InputSchema)},
+				},
+			})
+		}
+	}
+	m.mu.Lock()
+	m.tools = names
+	m.mu.Unlock()
+	return defs
+}
+
+// Has reports whether name is a tool listed by the last Definitions call.
+func (m *Manager) Has(name string) bool {
+	if m == nil || !strings.HasPrefix(name, ToolPrefix) {
+		return false
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.tools[name]
+	return ok
+}
+
+func (m *Manager) Call(ctx context.Context, name string, args json.RawMessage) (string, error) {
+	if m == nil {
+		return "", fmt.Errorf("unknown tool: %s", name)
+	}
+	m.mu.RLock()
+	ref, ok := m.tools[name]
+	m.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("unknown tool: %s", name)
+	}
+	return ref.server.call(ctx, ref.tool, args)
+}
+
+func (m *Manager) Close() error {
+	if m == nil {
+		return nil
+	}
+	for _, s := range m.servers {
+		s.mu.Lock()
+		c := s.client
+		s.client = nil
+		s.mu.Unlock()
+		if c != nil {
+			_ = c.Close()
+		}
+	}
+	return nil
+}
+
+func (s *server) snapshot() []Tool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tools
+}
+
+// retryList connects a server that has not listed its tools yet, at most
+// once per restartBackoff and never twice at a time.
+func (s *server) retryList() {
+	s.mu.Lock()
+	due := s.lastErr != nil && time.Since(s.lastTry) >= restartBackoff
+	s.mu.Unlock()
+	if !due || !s.retrying.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer s.retrying.Store(false)
+		if _, err := s.ensure(context.Background()); err != nil {
+			log.Printf("mcp: %s: %v", s.name, err)
+		}
+	}()
+}
+
+func (s *server) call(ctx context.Context, tool string, args json.RawMessage) (string, error) {
+	c, err := s.ensure(ctx)
+	if err != nil {
+		return "", fmt.Errorf("mcp server %s unavailable: %w", s.name, err)
+	}
+	cctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	out, err := c.CallTool(cctx, tool, args)
+	if err != nil {
+		if cctx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+			return "", fmt.Errorf("mcp %s/%s timed out after %s", s.name, tool, s.timeout)
+		}
+		return "", err
+	}
+	if len(out) > maxToolOutputBytes {
+		out = out[:maxToolOutputBytes] + "\n(truncated)"
+	}
+	return out, nil
+}
+
+// ensure returns a live client, (re)starting the server when needed.
+func (s *server) ensure(ctx context.Context) (*Client, error) {
+	s.connectMu.Lock()
+	defer s.connectMu.Unlock()
+
+	s.mu.Lock()
+	c := s.client
+	if c != nil && c.alive() {
+		s.mu.Unlock()
+		return c, nil
+	}
+	if s.lastErr != nil && time.Since(s.lastTry) < restartBackoff {
+		err := s.lastErr
+		s.mu.Unlock()
+		return nil, err
+	}
+	s.client = nil
+	s.mu.Unlock()
+	if c != nil {
+		log.Printf("mcp: %s: connection lost, restarting", s.name)
+		_ = c.Close()
+	}
+
+	cctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	c, tools, err := s.dial(cctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastTry = time.Now()
+	s.lastErr = err
+	if err != nil {
+		return nil, err
+	}
+	s.client = c
+	s.tools = tools
+	return c, nil
+}
+
+func (s *server) dial(ctx context.Context) (*Client, []Tool, error) {
+	var t transport
+	switch {
+	case strings.TrimSpace(s.cfg.Command) != "":
+		st, err := startStdio(s.cfg.Command, s.cfg.Args, s.cfg.Env, s.cfg.Dir)
+		if err != nil {
+			return nil, nil, err
+		}
+		t = st
+	case strings.TrimSpace(s.cfg.URL) != "":
+		t = newHTTPTransport(strings.TrimSpace(s.cfg.URL), s.cfg.Headers, &http.Client{})
+	default:
+		return nil, nil, fmt.Errorf("command or url is required")
+	}
+	c, err := newClient(ctx, t)
+	if err != nil {
+		return nil, nil, err
+	}
+	tools, err := c.ListTools(ctx)
+	if err != nil {
+		_ = c.Close()
+		return nil, nil, fmt.Errorf("tools/list: %w", err)
+	}
+	return c, tools, nil
+}
+
+// ToolName builds the namespaced tool name exposed to the model. Characters
+// providers reject are replaced, and overlong names are shortened with a hash.
+func ToolName(server, tool string) string {
+	name := ToolPrefix + sanitizeToolName(server) + "__" + sanitizeToolName(tool)
+	const maxLen = 64
+	if len(name) <= maxLen {
+		return name
+	}
+	sum := sha1.Sum([]byte(name))
+	return name[:maxLen-9] + "_" + hex.EncodeToString(sum[:4])
+}
+
+func sanitizeToolName(s string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(s) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// toolSchema normalizes an MCP input schema into a tool parameters schema.
+func toolSchema(raw json.RawMessage) json.RawMessage {
+	var m map[string]any
+	if len(raw) == 0 || json.Unmarshal(raw, &m) != nil || m == nil {
+		return json.RawMessage(`{"type":"object","properties":{}}`)
+	}
+	delete(m, "$schema")
+	if _, ok := m["type"]; !ok {
+		m["type"] = "object"
+	}
+	if _, ok := m["properties"]; !ok {
+		m["properties"] = map[string]any{}
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return json.RawMessage(`{"type":"object","properties":{}}`)
+	}
+	return b
+}
@@ -0,0 +1,233 @@
+package mcp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/config"
+)
+
+// TestHelperMCPServer is not a real test: it runs as a fake stdio MCP server
+// when invoked by helperServerConfig.
+func TestHelperMCPServer(t *testing.T) {
+	if os.Getenv("CLAWLET_MCP_HELPER") != "1" {
+		return
+	}
+	out := json.NewEncoder(os.Stdout)
+	in := bufio.NewScanner(os.Stdin)
+	for in.Scan() {
+		var msg rpcMessage
+		if err := json.Unmarshal(in.Bytes(), &msg); err != nil || len(msg.ID) == 0 {
+			continue
+		}
+		reply := map[string]any{"jsonrpc": "2.0", "id": msg.ID}
+		switch msg.Method {
+		case "initialize":
+			reply["result"] = map[string]any{"protocolVersion": protocolVersion, "capabilities": map[string]any{"tools": map[string]any{}}}
+		case "tools/list":
+			reply["result"] = map[string]any{"tools": []map[string]any{
+				{"name": "echo", "description": "Echo text.", "inputSchema": map[string]any{"$schema": "x", "type": "object", "properties": map[string]any{"text": map[string]any{"type": "string"}}}},
+				{"name": "crash"},
+				{"name": "hang"},
+			}}
+		case "tools/call":
+			var p struct {
+				Name      string            `json:"name"`
+				Arguments map[string]string `json:"arguments"`
+			}
+			_ = json.Unmarshal(msg.Params, &p)
+			switch p.Name {
+			case "crash":
+				fmt.Fprintln(os.Stderr, "boom")
+				os.Exit(3)
+			case "hang":
+				continue
+			}
+			reply["result"] = map[string]any{"content": []map[string]any{{"type": "text", "text": "echo: " + p.Arguments["text"]}}}
+		}
+		_ = out.Encode(reply)
+	}
+	os.Exit(0)
+}
+
+func helperServerConfig(timeoutSec int) config.MCPServerConfig {
+	return config.MCPServerConfig{
+		Command:    os.Args[0],
+		Args:       []string{"-test.run=^TestHelperMCPServer$"},
+		Env:        map[string]string{"CLAWLET_MCP_HELPER": "1"},
+		TimeoutSec: timeoutSec,
+	}
+}
+
+func TestManager_StdioListCallAndRestart(t *testing.T) {
+	m := NewManager(map[string]config.MCPServerConfig{"fake": helperServerConfig(5)})
+	defer m.Close()
+
+	defs := m.Definitions()
+	if len(defs) != 3 {
+		t.Fatalf("defs=%d", len(defs))
+	}
+	if defs[0].Function.Name != "mcp_fake__echo" || defs[0].Function.Description != "Echo text." {
+		t.Fatalf("def=%+v", defs[0].Function)
+	}
+	if strings.Contains(string(defs[0].Function.Parameters.Raw), "$schema") {
+		t.Fatalf("schema not sanitized: %s", defs[0].Function.Parameters.Raw)
+	}
+	if !m.Has("mcp_fake__echo") || m.Has("echo") {
+		t.Fatal("unexpected Has result")
+	}
+
+	out, err := m.Call(t.Context(), "mcp_fake__echo", json.RawMessage(`{"text":"hi"}`))
+	if err != nil || out != "echo: hi" {
+		t.Fatalf("out=%q err=%v", out, err)
+	}
+
+	if _, err := m.Call(t.Context(), "mcp_fake__crash", nil); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("expected crash error with stderr, got %v", err)
+	}
+
+	out, err = m.Call(t.Context(), "mcp_fake__echo", json.RawMessage(`{"text":"again"}`))
+	if err != nil || out != "echo: again" {
+		t.Fatalf("after restart out=%q err=%v", out, err)
+	}
+}
+
+func TestManager_CallTimeout(t *testing.T) {
+	m := NewManager(map[string]config.MCPServerConfig{"fake": helperServerConfig(1)})
+	defer m.Close()
+	m.Definitions()
+
+	start := time.Now()
+	_, err := m.Call(t.Context(), "mcp_fake__hang", nil)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("err=%v", err)
+	}
+	if time.Since(start) > 3*time.Second {
+		t.Fatalf("timeout not enforced: %s", time.Since(start))
+	}
+}
+
+func TestManager_StreamableHTTP(t *testing.T) {
+	var sawSession atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var msg rpcMessage
+		_ = json.Unmarshal(body, &msg)
+		if msg.Method != "initialize" && r.Header.Get(sessionHeader) == "sess-1" {
+			sawSession.Store(true)
+		}
+		if len(msg.ID) == 0 {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		switch msg.Method {
+		case "initialize":
+			w.Header().Set(sessionHeader, "sess-1")
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":{"protocolVersion":"%s"}}`, msg.ID, protocolVersion)
+		case "tools/list":
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/message\"}\n\n")
+			fmt.Fprintf(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"id\":%s,\"result\":{\"tools\":[{\"name\":\"create issue\"}]}}\n\n", msg.ID)
+		case "tools/call":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":{"isError":true,"content":[{"type":"text","text":"repo not found"}]}}`, msg.ID)
+		}
+	}))
+	defer srv.Close()
+
+	m := NewManager(map[string]config.MCPServerConfig{"github": {URL: srv.URL}})
+	defer m.Close()
+	defs := m.Definitions()
+	if len(defs) != 1 || defs[0].Function.Name != "mcp_github__create_issue" {
+		t.Fatalf("defs=%+v", defs)
+	}
+	out, err := m.Call(t.Context(), "mcp_github__create_issue", json.RawMessage(`{}`))
+	if err != nil || out != "error: repo not found" {
+		t.Fatalf("out=%q err=%v", out, err)
+	}
+	if !sawSession.Load() {
+		t.Fatal("expected Mcp-Session-Id on follow-up requests")
+	}
+}
+
+func TestManager_RetriesListingInBackground(t *testing.T) {
+	var up atomic.Bool
+	var inits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var msg rpcMessage
+		_ = json.Unmarshal(body, &msg)
+		if msg.Method == "initialize" {
+			inits.Add(1)
+		}
+		if !up.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if len(msg.ID) == 0 {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch msg.Method {
+		case "initialize":
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":{"protocolVersion":"%s"}}`, msg.ID, protocolVersion)
+		case "tools/list":
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":{"tools":[{"name":"search"}]}}`, msg.ID)
+		}
+	}))
+	defer srv.Close()
+
+	m := NewManager(map[string]config.MCPServerConfig{"docs": {URL: srv.URL}})
+	defer m.Close()
+	if defs := m.Definitions(); len(defs) != 0 {
+		t.Fatalf("defs=%+v", defs)
+	}
+	up.Store(true)
+	if defs := m.Definitions(); len(defs) != 0 || inits.Load() != 1 {
+		t.Fatalf("retried within backoff: defs=%d inits=%d", len(defs), inits.Load())
+	}
+
+	s := m.servers[0]
+	s.mu.Lock()
+	s.lastTry = time.Now().Add(-restartBackoff)
+	s.mu.Unlock()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		defs := m.Definitions()
+		if len(defs) == 1 && defs[0].Function.Name == "mcp_docs__search" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("tools not listed after retry: %+v", defs)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestNewManager_SkipsDisabled(t *testing.T) {
+	off := false
+	if m := NewManager(map[string]config.MCPServerConfig{"x": {Command: "true", Enabled: &off}}); m != nil {
+		t.Fatal("expected nil manager when all servers are disabled")
+	}
+}
+
+func TestToolName(t *testing.T) {
+	if got := ToolName("my.server", "do thing"); got != "mcp_my_server__do_thing" {
+		t.Fatalf("got=%q", got)
+	}
+	long := ToolName("server", strings.Repeat("x", 80))
+	if len(long) != 64 || !strings.HasPrefix(long, "mcp_server__xxx") {
+		t.Fatalf("long=%q (%d)", long, len(long))
+	}
+}
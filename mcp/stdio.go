@@ -0,0 +1,230 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// stdioTransport runs the server as a subprocess and exchanges
+// newline-delimited JSON-RPC messages over its stdin/stdout.
+type stdioTransport struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stderr *tailBuffer
+
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	pending map[string]chan rpcMessage
+	done    chan struct{}
+	err     error
+}
+
+func startStdio(command string, args []string, env map[string]string, dir string) (*stdioTransport, error) {
+	cmd := exec.Command(command, args...)
+	cmd.Dir = dir
+	cmd.Env = os.Environ()
+	for k, v := range env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	t := &stdioTransport{
+		cmd:     cmd,
+		stdin:   stdin,
+		stderr:  &tailBuffer{max: 1024},
+		pending: map[string]chan rpcMessage{},
+		done:    make(chan struct{}),
+	}
+	cmd.Stderr = t.stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	go t.readLoop(stdout)
+	return t, nil
+}
+
+func (t *stdioTransport) readLoop(stdout io.Reader) {
+	r := bufio.NewReaderSize(stdout, 64*1024)
+	var readErr error
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(strings.TrimSpace(string(line))) > 0 {
+			var msg rpcMessage
+			if json.Unmarshal(line, &msg) == nil {
+				t.dispatch(msg)
+			}
+		}
+		if err != nil {
+			readErr = err
+			break
+		}
+	}
+	waitErr := t.cmd.Wait()
+
+	t.mu.Lock()
+	t.err = errClosed
+	if tail := strings.TrimSpace(t.stderr.String()); tail != "" {
+		t.err = fmt.Errorf("%w: %s", errClosed, tail)
+	} else if waitErr != nil {
+		t.err = fmt.Errorf("%w: %v", errClosed, waitErr)
+	} else if readErr != nil && !errors.Is(readErr, io.EOF) {
+		t.err = fmt.Errorf("%w: %v", errClosed, readErr)
+	}
+	for id, ch := range t.pending {
+		close(ch)
+		delete(t.pending, id)
+	}
+	t.mu.Unlock()
+	close(t.done)
+}
+
+func (t *stdioTransport) dispatch(msg rpcMessage) {
+	if msg.isResponse() {
+		t.mu.Lock()
+		ch := t.pending[string(msg.ID)]
+		delete(t.pending, string(msg.ID))
+		t.mu.Unlock()
+		if ch != nil {
+			ch <- msg
+		}
+		return
+	}
+	if len(msg.ID) == 0 {
+		// Notifications (progress, logging, list_changed) are ignored.
+		return
+	}
+	// Server-initiated request: answer pings, decline everything else.
+	reply := map[string]any{"jsonrpc": "2.0", "id": msg.ID}
+	if msg.Method == "ping" {
+		reply["result"] = map[string]any{}
+	} else {
+		reply["error"] = rpcError{Code: -32601, Message: "method not found"}
+	}
+	_ = t.write(reply)
+}
+
+func (t *stdioTransport) write(v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	if _, err := t.stdin.Write(b); err != nil {
+		return fmt.Errorf("%w: %v", errClosed, err)
+	}
+	return nil
+}
+
+func (t *stdioTransport) call(ctx context.Context, req rpcRequest) (rpcMessage, error) {
+	key := fmt.Sprint(*req.ID)
+	ch := make(chan rpcMessage, 1)
+	t.mu.Lock()
+	if t.err != nil {
+		err := t.err
+		t.mu.Unlock()
+		return rpcMessage{}, err
+	}
+	t.pending[key] = ch
+	t.mu.Unlock()
+
+	if err := t.write(req); err != nil {
+		t.forget(key)
+		return rpcMessage{}, err
+	}
+	select {
+	case msg, ok := <-ch:
+		if !ok {
+			return rpcMessage{}, t.closedErr()
+		}
+		return msg, nil
+	case <-ctx.Done():
+		t.forget(key)
+		_ = t.notify(context.Background(), "notifications/cancelled", map[string]any{"requestId": *req.ID, "reason": "timeout"})
+		return rpcMessage{}, ctx.Err()
+	}
+}
+
+func (t *stdioTransport) forget(key string) {
+	t.mu.Lock()
+	delete(t.pending, key)
+	t.mu.Unlock()
+}
+
+func (t *stdioTransport) closedErr() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.err != nil {
+		return t.err
+	}
+	return errClosed
+}
+
+func (t *stdioTransport) notify(_ context.Context, method string, params any) error {
+	return t.write(rpcRequest{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func (t *stdioTransport) alive() bool {
+	select {
+	case <-t.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// close shuts stdin so the server can exit cleanly, then kills it if it
+// hasn't within a short grace period.
+func (t *stdioTransport) close() error {
+	_ = t.stdin.Close()
+	select {
+	case <-t.done:
+		return nil
+	case <-time.After(2 * time.Second):
+	}
+	if t.cmd.Process != nil {
+		_ = t.cmd.Process.Kill()
+	}
+	<-t.done
+	return nil
+}
+
+// tailBuffer keeps the last max bytes written, for crash diagnostics.
+type tailBuffer struct {
+	mu  sync.Mutex
+	max int
+	buf []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > b.max {
+		b.buf = b.buf[len(b.buf)-b.max:]
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.buf)
+}
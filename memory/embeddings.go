@@ -0,0 +1,456 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Embedding providers accepted in memorySearch.provider.
+const (
+	embeddingOpenAI   = "openai"
+	embeddingOllama   = "ollama"
+	embeddingLlamaCpp = "llamacpp"
+	embeddingHash     = "hash"
+)
+
+// dimensionProbeText is embedded to learn the vector size of a backend that
+// cannot report it.
+const dimensionProbeText = "dimension probe"
+
+// embeddingProvider turns texts into unit-length vectors.
+type embeddingProvider interface {
+	EmbedBatch(ctx context.Context, texts []string) ([][]float64, error)
+	// providerKey identifies the vector space. The index and the embedding
+	// cache are rebuilt when it changes.
+	providerKey() string
+	// dimensions reports the vector size, asking the backend the first time.
+	dimensions(ctx context.Context) (int, error)
+}
+
+func newEmbeddingProvider(cfg resolvedSearchConfig) embeddingProvider {
+	client := &http.Client{Timeout: 60 * time.Second}
+	baseURL := strings.TrimRight(cfg.baseURL, "/")
+	switch cfg.provider {
+	case embeddingOllama:
+		return &ollamaEmbeddingProvider{
+			// Accept the OpenAI-compatible base URL used for chat models too.
+			baseURL: strings.TrimSuffix(baseURL, "/v1"),
+			model:   cfg.model,
+			dims:    cfg.dimensions,
+			apiKey:  cfg.apiKey,
+			headers: copyHeaders(cfg.headers),
+			client:  client,
+		}
+	case embeddingLlamaCpp:
+		return &llamaCppEmbeddingProvider{
+			baseURL: baseURL,
+			model:   cfg.model,
+			apiKey:  cfg.apiKey,
+			headers: copyHeaders(cfg.headers),
+			client:  client,
+		}
+	case embeddingHash:
+		return &hashEmbeddingProvider{dims: cfg.dimensions}
+	default:
+		return &openAIEmbeddingProvider{
+			provider: cfg.provider,
+			baseURL:  baseURL,
+			apiKey:   cfg.apiKey,
+			model:    cfg.model,
+			dims:     cfg.dimensions,
+			headers:  copyHeaders(cfg.headers),
+			client:   client,
+		}
+	}
+}
+
+// detectedDims memoizes a vector size once a backend has reported it.
+type detectedDims struct {
+	mu sync.Mutex
+	n  int
+}
+
+func (d *detectedDims) get(ctx context.Context, detect func(context.Context) (int, error)) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.n > 0 {
+		return d.n, nil
+	}
+	n, err := detect(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if n <= 0 {
+		return 0, errors.New("embedding backend reported no dimensions")
+	}
+	d.n = n
+	return n, nil
+}
+
+// probeDimensions embeds a short text and returns the size of its vector.
+func probeDimensions(ctx context.Context, p embeddingProvider) (int, error) {
+	vecs, err := p.EmbedBatch(ctx, []string{dimensionProbeText})
+	if err != nil {
+		return 0, err
+	}
+	if len(vecs) == 0 {
+		return 0, errors.New("embeddings response has no data")
+	}
+	return len(vecs[0]), nil
+}
+
+// endpointKey hashes what determines the vectors of an HTTP backend.
+// Authorization headers are left out so rotating a key keeps the index.
+func endpointKey(provider, baseURL, model string, headers map[string]string, dims int) string {
+	headerPairs := make([]string, 0, len(headers))
+	for k, v := range headers {
+		if strings.EqualFold(strings.TrimSpace(k), "authorization") {
+			continue
+		}
+		headerPairs = append(headerPairs, strings.TrimSpace(k)+"="+v)
+	}
+	sort.Strings(headerPairs)
+	payload := fmt.Sprintf("%s|%s|%s|%s", provider, baseURL, model, strings.Join(headerPairs, "|"))
+	if dims > 0 {
+		payload += fmt.Sprintf("|dims=%d", dims)
+	}
+	return hashText(payload)
+}
+
+// requestJSON POSTs body to endpoint (or GETs it when body is nil) and
+// decodes the JSON reply into out.
+func requestJSON(ctx context.Context, client *http.Client, what, endpoint, apiKey string, headers map[string]string, body, out any) error {
+	method, payload := http.MethodGet, io.Reader(nil)
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		method, payload = http.MethodPost, bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, payload)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if strings.TrimSpace(apiKey) != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	for k, v := range headers {
+		k = strings.TrimSpace(k)
+		if k == "" {
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		buf, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s http %d: %s", what, resp.StatusCode, strings.TrimSpace(string(buf)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// openAIEmbeddingProvider calls an OpenAI-compatible /embeddings endpoint.
+type openAIEmbeddingProvider struct {
+	provider string
+	baseURL  string
+	apiKey   string
+	model    string
+	dims     int
+	headers  map[string]string
+	client   *http.Client
+	detected detectedDims
+}
+
+func (p *openAIEmbeddingProvider) EmbedBatch(ctx context.Context, texts []string) ([][]float64, error) {
+	if len(texts) == 0 {
+		return [][]float64{}, nil
+	}
+	if strings.TrimSpace(p.model) == "" {
+		return nil, errors.New("memory embedding model is empty")
+	}
+	reqBody := map[string]any{
+		"model": p.model,
+		"input": texts,
+	}
+	if p.dims > 0 {
+		reqBody["dimensions"] = p.dims
+	}
+	var parsed struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+	endpoint := strings.TrimRight(p.baseURL, "/") + "/embeddings"
+	if err := requestJSON(ctx, p.client, "embeddings", endpoint, p.apiKey, p.headers, reqBody, &parsed); err != nil {
+		return nil, err
+	}
+	if len(parsed.Data) == 0 {
+		return nil, errors.New("embeddings response has no data")
+	}
+	out := make([][]float64, len(texts))
+	for _, d := range parsed.Data {
+		if d.Index < 0 || d.Index >= len(out) {
+			continue
+		}
+		out[d.Index] = normalizeEmbedding(d.Embedding)
+	}
+	for i := range out {
+		if len(out[i]) == 0 {
+			return nil, fmt.Errorf("embedding index %d missing in response", i)
+		}
+	}
+	return out, nil
+}
+
+func (p *openAIEmbeddingProvider) providerKey() string {
+	return endpointKey(p.provider, p.baseURL, p.model, p.headers, p.dims)
+}
+
+// dimensions is the requested size, or else that of a probe embedding:
+// OpenAI-compatible servers have no endpoint describing a model's output.
+func (p *openAIEmbeddingProvider) dimensions(ctx context.Context) (int, error) {
+	if p.dims > 0 {
+		return p.dims, nil
+	}
+	return p.detected.get(ctx, func(ctx context.Context) (int, error) {
+		return probeDimensions(ctx, p)
+	})
+}
+
+// ollamaEmbeddingProvider calls Ollama's native /api/embed endpoint.
+type ollamaEmbeddingProvider struct {
+	baseURL  string
+	model    string
+	dims     int
+	apiKey   string
+	headers  map[string]string
+	client   *http.Client
+	detected detectedDims
+}
+
+func (p *ollamaEmbeddingProvider) EmbedBatch(ctx context.Context, texts []string) ([][]float64, error) {
+	if len(texts) == 0 {
+		return [][]float64{}, nil
+	}
+	reqBody := map[string]any{
+		"model": p.model,
+		"input": texts,
+	}
+	if p.dims > 0 {
+		reqBody["dimensions"] = p.dims
+	}
+	var parsed struct {
+		Embeddings [][]float64 `json:"embeddings"`
+	}
+	if err := requestJSON(ctx, p.client, "embeddings", p.baseURL+"/api/embed", p.apiKey, p.headers, reqBody, &parsed); err != nil {
+		return nil, fmt.Errorf("ollama: %w", err)
+	}
+	if len(parsed.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("ollama: got %d embeddings for %d texts", len(parsed.Embeddings), len(texts))
+	}
+	out := make([][]float64, len(texts))
+	for i, v := range parsed.Embeddings {
+		if len(v) == 0 {
+			return nil, fmt.Errorf("ollama: embedding index %d is empty", i)
+		}
+		out[i] = normalizeEmbedding(v)
+	}
+	return out, nil
+}
+
+func (p *ollamaEmbeddingProvider) providerKey() string {
+	return endpointKey(embeddingOllama, p.baseURL, p.model, p.headers, p.dims)
+}
+
+// dimensions reads "<architecture>.embedding_length" from /api/show, and
+// probes when the model info does not have it.
+func (p *ollamaEmbeddingProvider) dimensions(ctx context.Context) (int, error) {
+	if p.dims > 0 {
+		return p.dims, nil
+	}
+	return p.detected.get(ctx, func(ctx context.Context) (int, error) {
+		var show struct {
+			ModelInfo map[string]any `json:"model_info"`
+		}
+		err := requestJSON(ctx, p.client, "embeddings", p.baseURL+"/api/show", p.apiKey, p.headers, map[string]string{"model": p.model}, &show)
+		if err != nil {
+			return 0, fmt.Errorf("ollama: %w", err)
+		}
+		for k, v := range show.ModelInfo {
+			if n, ok := v.(float64); ok && strings.HasSuffix(k, ".embedding_length") && n > 0 {
+				return int(n), nil
+			}
+		}
+		return probeDimensions(ctx, p)
+	})
+}
+
+// llamaCppEmbeddingProvider calls the /embedding endpoint of a llama.cpp
+// server started with --embedding.
+type llamaCppEmbeddingProvider struct {
+	baseURL  string
+	model    string
+	apiKey   string
+	headers  map[string]string
+	client   *http.Client
+	detected detectedDims
+}
+
+func (p *llamaCppEmbeddingProvider) EmbedBatch(ctx context.Context, texts []string) ([][]float64, error) {
+	if len(texts) == 0 {
+		return [][]float64{}, nil
+	}
+	var raw json.RawMessage
+	if err := requestJSON(ctx, p.client, "embeddings", p.baseURL+"/embedding", p.apiKey, p.headers, map[string]any{"content": texts}, &raw); err != nil {
+		return nil, fmt.Errorf("llama.cpp: %w", err)
+	}
+	// Recent servers answer with a list of {index, embedding}, older ones
+	// with a single {embedding}.
+	type item struct {
+		Index     int             `json:"index"`
+		Embedding json.RawMessage `json:"embedding"`
+	}
+	var items []item
+	if err := json.Unmarshal(raw, &items); err != nil {
+		var one item
+		if err := json.Unmarshal(raw, &one); err != nil {
+			return nil, fmt.Errorf("llama.cpp: decode embeddings: %w", err)
+		}
+		items = []item{one}
+	}
+	out := make([][]float64, len(texts))
+	for _, it := range items {
+		if it.Index < 0 || it.Index >= len(out) {
+			continue
+		}
+		vec, err := poolLlamaCppEmbedding(it.Embedding)
+		if err != nil {
+			return nil, fmt.Errorf("llama.cpp: %w", err)
+		}
+		out[it.Index] = normalizeEmbedding(vec)
+	}
+	for i := range out {
+		if len(out[i]) == 0 {
+			return nil, fmt.Errorf("llama.cpp: embedding index %d missing in response", i)
+		}
+	}
+	return out, nil
+}
+
+// poolLlamaCppEmbedding accepts a vector, or one vector per token (servers
+// running with --pooling none) which are averaged.
+func poolLlamaCppEmbedding(raw json.RawMessage) ([]float64, error) {
+	var vec []float64
+	if err := json.Unmarshal(raw, &vec); err == nil {
+		return vec, nil
+	}
+	var rows [][]float64
+	if err := json.Unmarshal(raw, &rows); err != nil {
+		return nil, fmt.Errorf("decode embedding: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	mean := make([]float64, len(rows[0]))
+	for _, r := range rows {
+		if len(r) != len(mean) {
+			return nil, errors.New("token embeddings differ in size")
+		}
+		for i, v := range r {
+			mean[i] += v / float64(len(rows))
+		}
+	}
+	return mean, nil
+}
+
+// providerKey leaves out the model name unless one is configured: the server
+// embeds with whatever model it was started with.
+func (p *llamaCppEmbeddingProvider) providerKey() string {
+	return endpointKey(embeddingLlamaCpp, p.baseURL, p.model, p.headers, 0)
+}
+
+// dimensions reads n_embd from /v1/models, and probes on servers too old to
+// report it.
+func (p *llamaCppEmbeddingProvider) dimensions(ctx context.Context) (int, error) {
+	return p.detected.get(ctx, func(ctx context.Context) (int, error) {
+		var models struct {
+			Data []struct {
+				Meta struct {
+					NEmbd int `json:"n_embd"`
+				} `json:"meta"`
+			} `json:"data"`
+		}
+		err := requestJSON(ctx, p.client, "embeddings", p.baseURL+"/v1/models", p.apiKey, p.headers, nil, &models)
+		if err == nil && len(models.Data) > 0 && models.Data[0].Meta.NEmbd > 0 {
+			return models.Data[0].Meta.NEmbd, nil
+		}
+		return probeDimensions(ctx, p)
+	})
+}
+
+// hashEmbeddingProvider embeds offline by hashing words and word pairs into
+// a fixed number of buckets. It only matches shared vocabulary, but needs no
+// model and always gives the same vector for the same text.
+type hashEmbeddingProvider struct {
+	dims int
+}
+
+func (p *hashEmbeddingProvider) EmbedBatch(ctx context.Context, texts []string) ([][]float64, error) {
+	out := make([][]float64, len(texts))
+	for i, t := range texts {
+		out[i] = p.embed(t)
+	}
+	return out, nil
+}
+
+func (p *hashEmbeddingProvider) embed(text string) []float64 {
+	vec := make([]float64, p.dims)
+	words := tokenRe.FindAllString(strings.ToLower(text), -1)
+	add := func(feature string, weight float64) {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(feature))
+		sum := h.Sum64()
+		// The top bit picks the sign so that collisions tend to cancel out.
+		if sum>>63 == 1 {
+			weight = -weight
+		}
+		vec[sum%uint64(p.dims)] += weight
+	}
+	for i, w := range words {
+		add(w, 1)
+		if i > 0 {
+			add(words[i-1]+" "+w, 0.5)
+		}
+	}
+	if len(words) == 0 {
+		add(strings.TrimSpace(text), 1)
+	}
+	return normalizeEmbedding(vec)
+}
+
+func (p *hashEmbeddingProvider) providerKey() string {
+	return hashText(fmt.Sprintf("%s|v1|%d", embeddingHash, p.dims))
+}
+
+func (p *hashEmbeddingProvider) dimensions(context.Context) (int, error) {
+	return p.dims, nil
+}
@@ -0,0 +1,163 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mosaxiv/clawlet/config"
+)
+
+func TestOllamaEmbeddingProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		switch r.URL.Path {
+		case "/api/embed":
+			embs := make([][]float64, 0, len(req.Input))
+			for _, txt := range req.Input {
+				embs = append(embs, fakeEmbedding(txt))
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"model": req.Model, "embeddings": embs})
+		case "/api/show":
+			_ = json.NewEncoder(w).Encode(map[string]any{"model_info": map[string]any{
+				"general.architecture":        "nomic-bert",
+				"nomic-bert.embedding_length": 8,
+			}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	p := newEmbeddingProvider(resolvedSearchConfig{provider: embeddingOllama, model: "nomic-embed-text", baseURL: server.URL + "/v1"})
+	out, err := p.EmbedBatch(context.Background(), []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("EmbedBatch error: %v", err)
+	}
+	if len(out) != 2 || len(out[0]) != 8 {
+		t.Fatalf("embeddings=%v", out)
+	}
+	dims, err := p.dimensions(context.Background())
+	if err != nil || dims != 8 {
+		t.Fatalf("dimensions=%d err=%v", dims, err)
+	}
+}
+
+func TestLlamaCppEmbeddingProvider_ResponseShapes(t *testing.T) {
+	for _, shape := range []string{"list", "tokens", "single"} {
+		t.Run(shape, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/v1/models" {
+					http.NotFound(w, r)
+					return
+				}
+				var req struct {
+					Content []string `json:"content"`
+				}
+				_ = json.NewDecoder(r.Body).Decode(&req)
+				var resp any
+				switch shape {
+				case "list":
+					items := []map[string]any{}
+					for i, txt := range req.Content {
+						items = append(items, map[string]any{"index": i, "embedding": [][]float64{fakeEmbedding(txt)}})
+					}
+					resp = items
+				case "tokens":
+					resp = []map[string]any{{"index": 0, "embedding": [][]float64{{1, 0, 0}, {0, 1, 0}}}}
+				default:
+					resp = map[string]any{"embedding": []float64{3, 4, 0}}
+				}
+				_ = json.NewEncoder(w).Encode(resp)
+			}))
+			defer server.Close()
+
+			p := newEmbeddingProvider(resolvedSearchConfig{provider: embeddingLlamaCpp, baseURL: server.URL})
+			out, err := p.EmbedBatch(context.Background(), []string{"hello"})
+			if err != nil {
+				t.Fatalf("EmbedBatch error: %v", err)
+			}
+			want := map[string]int{"list": 8, "tokens": 3, "single": 3}[shape]
+			if len(out) != 1 || len(out[0]) != want {
+				t.Fatalf("embeddings=%v", out)
+			}
+			if dims, err := p.dimensions(context.Background()); err != nil || dims != want {
+				t.Fatalf("dimensions=%d err=%v", dims, err)
+			}
+		})
+	}
+}
+
+func TestHashEmbeddingProvider(t *testing.T) {
+	p := &hashEmbeddingProvider{dims: 64}
+	out, _ := p.EmbedBatch(context.Background(), []string{
+		"the project codename is Nebula",
+		"The project CODENAME is nebula!",
+		"we bought groceries on friday",
+	})
+	if len(out[0]) != 64 {
+		t.Fatalf("dims=%d", len(out[0]))
+	}
+	if cosine(out[0], out[1]) < 0.999 {
+		t.Fatalf("same words differ: %f", cosine(out[0], out[1]))
+	}
+	if cosine(out[0], out[2]) > 0.5 {
+		t.Fatalf("unrelated texts too close: %f", cosine(out[0], out[2]))
+	}
+	if (&hashEmbeddingProvider{dims: 64}).providerKey() == (&hashEmbeddingProvider{dims: 32}).providerKey() {
+		t.Fatal("providerKey ignores dimensions")
+	}
+}
+
+func TestIndexManager_SwitchingProviderRebuildsIndex(t *testing.T) {
+	ws := t.TempDir()
+	if err := os.WriteFile(filepath.Join(ws, "MEMORY.md"), []byte("- project codename is Nebula\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	open := func(dims int) *IndexManager {
+		t.Helper()
+		cfg := config.Default()
+		enabled := true
+		cfg.Agents.Defaults.MemorySearch.Enabled = &enabled
+		cfg.Agents.Defaults.MemorySearch.Provider = "hash"
+		cfg.Agents.Defaults.MemorySearch.Dimensions = dims
+		mgr, err := NewIndexManager(cfg, ws)
+		if err != nil {
+			t.Fatalf("NewIndexManager error: %v", err)
+		}
+		t.Cleanup(func() { _ = mgr.Close() })
+		return mgr
+	}
+
+	for _, dims := range []int{64, 32} {
+		mgr := open(dims)
+		results, err := mgr.Search(context.Background(), "project codename", SearchOptions{})
+		if err != nil {
+			t.Fatalf("Search error: %v", err)
+		}
+		if len(results) == 0 || !strings.Contains(results[0].Snippet, "Nebula") {
+			t.Fatalf("dims=%d results=%+v", dims, results)
+		}
+		if st := mgr.Status(context.Background()); st.VectorDims != dims || st.Model != "hash" {
+			t.Fatalf("status=%+v", st)
+		}
+		_ = mgr.Close()
+	}
+}
+
+func cosine(a, b []float64) float64 {
+	var dot float64
+	for i := range a {
+		dot += a[i] * b[i]
+	}
+	return dot
+}
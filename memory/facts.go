@@ -0,0 +1,361 @@
+package memory
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fact is one entry of long-term memory. MEMORY.md is rendered from the
+// facts, so a regenerated summary can no longer drop older ones.
+type Fact struct {
+	ID        string    `json:"id"`
+	Text      string    `json:"text"`
+	Tags      []string  `json:"tags,omitempty"`
+	Session   string    `json:"session,omitempty"` // session the fact was learned in
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// FactChange is one edit of the fact store: an add when ID is empty, a
+// forget when Forget is set, otherwise an update of the fact with ID (empty
+// Text or nil Tags keep the current value).
+type FactChange struct {
+	ID     string   `json:"id,omitempty"`
+	Text   string   `json:"text,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+	Forget bool     `json:"forget,omitempty"`
+	// Scope picks the store of an add in View.ApplyFacts.
+	Scope string `json:"scope,omitempty"`
+}
+
+// FactDiff reports what ApplyFacts changed.
+type FactDiff struct {
+	Added     []Fact `json:"added,omitempty"`
+	Updated   []Fact `json:"updated,omitempty"`
+	Forgotten []Fact `json:"forgotten,omitempty"`
+	// Unknown lists IDs of updates and forgets that matched no fact.
+	Unknown []string `json:"unknown,omitempty"`
+}
+
+type factFile struct {
+	NextID int `json:"nextId"`
+	// Rendered is the hash of the MEMORY.md last rendered; a different file
+	// was edited by hand and is read back before the next change.
+	Rendered string `json:"rendered,omitempty"`
+	Facts    []Fact `json:"facts"`
+
+	idPrefix string // "f", or "u"/"c" in user and chat scopes
+}
+
+const longTermHeader = "# Long-term Memory\n\n"
+
+// factsMu serializes read-modify-write cycles of the fact store.
+var factsMu sync.Mutex
+
+// LoadFacts returns the facts of long-term memory. Edits made to MEMORY.md
+// by hand are taken over first; a MEMORY.md without a fact store becomes
+// one fact per line.
+func (s *Store) LoadFacts() ([]Fact, error) {
+	factsMu.Lock()
+	defer factsMu.Unlock()
+	ff, err := s.loadFactsLocked()
+	if err != nil {
+		return nil, err
+	}
+	return ff.Facts, nil
+}
+
+// ApplyFacts applies changes, learned in session source, and renders
+// MEMORY.md. Adding a fact that already exists updates it instead.
+func (s *Store) ApplyFacts(changes []FactChange, source string) (FactDiff, error) {
+	factsMu.Lock()
+	defer factsMu.Unlock()
+	var diff FactDiff
+	ff, err := s.loadFactsLocked()
+	if err != nil {
+		return diff, err
+	}
+	now := time.Now().UTC()
+	for _, c := range changes {
+		id := strings.TrimSpace(c.ID)
+		text := normalizeFactText(c.Text)
+		if id == "" {
+			if text == "" {
+				continue
+			}
+			if i := ff.find(text); i >= 0 {
+				f := &ff.Facts[i]
+				f.Tags = mergeTags(f.Tags, c.Tags)
+				f.UpdatedAt = now
+				diff.Updated = append(diff.Updated, *f)
+				continue
+			}
+			f := ff.add(text, normalizeTags(c.Tags), source, now)
+			diff.Added = append(diff.Added, f)
+			continue
+		}
+		i := slices.IndexFunc(ff.Facts, func(f Fact) bool { return f.ID == id })
+		if i < 0 {
+			diff.Unknown = append(diff.Unknown, id)
+			continue
+		}
+		if c.Forget {
+			diff.Forgotten = append(diff.Forgotten, ff.Facts[i])
+			ff.Facts = slices.Delete(ff.Facts, i, i+1)
+			continue
+		}
+		f := &ff.Facts[i]
+		if text != "" {
+			f.Text = text
+		}
+		if c.Tags != nil {
+			f.Tags = normalizeTags(c.Tags)
+		}
+		f.UpdatedAt = now
+		diff.Updated = append(diff.Updated, *f)
+	}
+	if len(diff.Added)+len(diff.Updated)+len(diff.Forgotten) == 0 {
+		return diff, nil
+	}
+	return diff, s.saveFactsLocked(ff)
+}
+
+func (s *Store) loadFactsLocked() (*factFile, error) {
+	ff := &factFile{NextID: 1, idPrefix: s.Scope.idPrefix()}
+	b, err := os.ReadFile(s.Facts)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(b, ff); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", s.Facts, err)
+		}
+	case errors.Is(err, fs.ErrNotExist):
+	default:
+		return nil, err
+	}
+	md, err := os.ReadFile(s.LongTerm)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if hashText(string(md)) == ff.Rendered {
+		return ff, nil
+	}
+	ff.takeOver(string(md), time.Now().UTC())
+	if err := s.saveFactsLocked(ff); err != nil {
+		return nil, err
+	}
+	return ff, nil
+}
+
+// saveFactsLocked renders MEMORY.md before recording its hash, so a crash
+// in between only makes the next load read MEMORY.md back.
+func (s *Store) saveFactsLocked(ff *factFile) error {
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return err
+	}
+	md := renderFacts(ff.Facts)
+	if err := writeFileAtomic(s.LongTerm, []byte(md)); err != nil {
+		return err
+	}
+	ff.Rendered = hashText(md)
+	b, err := json.MarshalIndent(ff, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.Facts, append(b, '\n'))
+}
+
+func (ff *factFile) find(text string) int {
+	return slices.IndexFunc(ff.Facts, func(f Fact) bool { return strings.EqualFold(f.Text, text) })
+}
+
+func (ff *factFile) add(text string, tags []string, source string, now time.Time) Fact {
+	f := Fact{
+		ID:        ff.idPrefix + strconv.Itoa(ff.NextID),
+		Text:      text,
+		Tags:      tags,
+		Session:   source,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	ff.NextID++
+	ff.Facts = append(ff.Facts, f)
+	return f
+}
+
+// takeOver replaces the facts with the lines of a MEMORY.md edited by
+// hand: lines keeping their [id] update that fact, other lines are new
+// facts, and facts whose line was deleted are dropped.
+func (ff *factFile) takeOver(md string, now time.Time) {
+	lines := parseMemoryMarkdown(md)
+	kept := map[string]memoryLine{}
+	var added []memoryLine
+	for _, l := range lines {
+		if _, dup := kept[l.id]; l.id != "" && !dup && slices.ContainsFunc(ff.Facts, func(f Fact) bool { return f.ID == l.id }) {
+			kept[l.id] = l
+			continue
+		}
+		added = append(added, l)
+	}
+	old := ff.Facts
+	ff.Facts = nil
+	for _, f := range old {
+		l, ok := kept[f.ID]
+		if !ok {
+			continue
+		}
+		if f.Text != l.text || !slices.Equal(f.Tags, l.tags) {
+			f.Text, f.Tags, f.UpdatedAt = l.text, l.tags, now
+		}
+		ff.Facts = append(ff.Facts, f)
+	}
+	for _, l := range added {
+		if ff.find(l.text) < 0 {
+			ff.add(l.text, l.tags, "", now)
+		}
+	}
+}
+
+// renderFacts writes facts as Markdown: untagged facts first, then one
+// section per first tag. Further tags follow the text as "(#tag ...)".
+func renderFacts(facts []Fact) string {
+	var b strings.Builder
+	b.WriteString(longTermHeader)
+	b.WriteString("<!-- Generated from facts.json. Edit a line but keep its [id], delete a line to forget it, or add \"- new fact\" lines. -->\n")
+	sections := map[string][]Fact{}
+	for _, f := range facts {
+		tag := ""
+		if len(f.Tags) > 0 {
+			tag = f.Tags[0]
+		}
+		sections[tag] = append(sections[tag], f)
+	}
+	tags := make([]string, 0, len(sections))
+	for tag := range sections {
+		tags = append(tags, tag)
+	}
+	slices.Sort(tags) // "" sorts first
+	for _, tag := range tags {
+		b.WriteString("\n")
+		if tag != "" {
+			b.WriteString("## " + tag + "\n\n")
+		}
+		for _, f := range sections[tag] {
+			fmt.Fprintf(&b, "- [%s] %s", f.ID, f.Text)
+			if len(f.Tags) > 1 {
+				b.WriteString(" (#" + strings.Join(f.Tags[1:], " #") + ")")
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+type memoryLine struct {
+	id   string
+	text string
+	tags []string
+}
+
+var (
+	memoryBulletRe = regexp.MustCompile(`^(?:[-*+]|\d+\.)\s+`)
+	memoryIDRe     = regexp.MustCompile(`^\[([fuc]\d+)\]\s*`)
+	memoryTagsRe   = regexp.MustCompile(`\s+\((#[\w-]+(?:\s+#[\w-]+)*)\)$`)
+)
+
+// parseMemoryMarkdown reads the facts back from MEMORY.md. Headings below
+// the title become the first tag of the lines under them; any other
+// non-empty line is a fact.
+func parseMemoryMarkdown(md string) []memoryLine {
+	var out []memoryLine
+	section := ""
+	inComment := false
+	for raw := range strings.SplitSeq(md, "\n") {
+		line := strings.TrimSpace(raw)
+		if inComment {
+			inComment = !strings.Contains(line, "-->")
+			continue
+		}
+		if strings.HasPrefix(line, "<!--") {
+			inComment = !strings.Contains(line, "-->")
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		if title, ok := markdownHeading(line); ok {
+			section = ""
+			if level := len(line) - len(strings.TrimLeft(line, "#")); level > 1 {
+				section = normalizeTag(title)
+			}
+			continue
+		}
+		line = memoryBulletRe.ReplaceAllString(line, "")
+		var l memoryLine
+		if m := memoryIDRe.FindStringSubmatch(line); m != nil {
+			l.id = m[1]
+			line = line[len(m[0]):]
+		}
+		var tags []string
+		if section != "" {
+			tags = append(tags, section)
+		}
+		if m := memoryTagsRe.FindStringSubmatch(line); m != nil {
+			tags = append(tags, strings.Fields(m[1])...)
+			line = line[:len(line)-len(m[0])]
+		}
+		l.text = normalizeFactText(line)
+		l.tags = normalizeTags(tags)
+		if l.text != "" {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+func markdownHeading(line string) (string, bool) {
+	rest := strings.TrimLeft(line, "#")
+	if rest == line || (rest != "" && rest[0] != ' ') {
+		return "", false
+	}
+	return strings.TrimSpace(rest), true
+}
+
+func normalizeFactText(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func normalizeTag(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(tag), "#")))
+	return strings.Join(strings.Fields(tag), "-")
+}
+
+func normalizeTags(tags []string) []string {
+	var out []string
+	for _, t := range tags {
+		if t = normalizeTag(t); t != "" && !slices.Contains(out, t) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func mergeTags(cur, more []string) []string {
+	return normalizeTags(append(slices.Clone(cur), more...))
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
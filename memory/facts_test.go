@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestStoreApplyFacts(t *testing.T) {
+	s := New(t.TempDir())
+	diff, err := s.ApplyFacts([]FactChange{
+		{Text: "Prefers  concise answers", Tags: []string{"Preferences", "#style"}},
+		{Text: "Works at Acme"},
+		{ID: "f42", Text: "nope"},
+	}, "telegram:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Added) != 2 || len(diff.Unknown) != 1 || diff.Added[0].Session != "telegram:1" {
+		t.Fatalf("diff=%+v", diff)
+	}
+	md := s.ReadLongTerm()
+	for _, want := range []string{"- [f2] Works at Acme\n", "## preferences\n\n- [f1] Prefers concise answers (#style)\n"} {
+		if !strings.Contains(md, want) {
+			t.Fatalf("MEMORY.md missing %q:\n%s", want, md)
+		}
+	}
+
+	// Adding a known fact updates it; forgetting removes it.
+	diff, err = s.ApplyFacts([]FactChange{{Text: "works at acme", Tags: []string{"work"}}, {ID: "f1", Forget: true}}, "")
+	if err != nil || len(diff.Added) != 0 || len(diff.Updated) != 1 || len(diff.Forgotten) != 1 {
+		t.Fatalf("diff=%+v err=%v", diff, err)
+	}
+	facts, err := s.LoadFacts()
+	if err != nil || len(facts) != 1 || facts[0].ID != "f2" || strings.Join(facts[0].Tags, ",") != "work" {
+		t.Fatalf("facts=%+v err=%v", facts, err)
+	}
+}
+
+func TestStoreLoadFacts_TakesOverHandEdits(t *testing.T) {
+	s := New(t.TempDir())
+	// A MEMORY.md from before the fact store.
+	if err := s.WriteLongTerm("# Long-term Memory\n\n## User Profile\n\n- Lives in Osaka\n- Has a cat\n\nLikes green tea.\n"); err != nil {
+		t.Fatal(err)
+	}
+	facts, err := s.LoadFacts()
+	if err != nil || len(facts) != 3 || facts[0].Tags[0] != "user-profile" || facts[2].Text != "Likes green tea." {
+		t.Fatalf("imported=%+v err=%v", facts, err)
+	}
+
+	// Rendering and parsing round-trip without changes.
+	md := s.ReadLongTerm()
+	if again, _ := s.LoadFacts(); len(again) != 3 || s.ReadLongTerm() != md {
+		t.Fatalf("not stable:\n%s", s.ReadLongTerm())
+	}
+
+	// Edit f1, delete f2, add a line.
+	md = strings.Replace(md, "[f1] Lives in Osaka", "[f1] Lives in Kyoto", 1)
+	md = strings.Replace(md, "- [f2] Has a cat\n", "", 1)
+	md += "- Birthday is May 4\n"
+	if err := os.WriteFile(s.LongTerm, []byte(md), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	facts, err = s.LoadFacts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range facts {
+		got = append(got, f.ID+" "+f.Text)
+	}
+	if strings.Join(got, "; ") != "f1 Lives in Kyoto; f3 Likes green tea.; f4 Birthday is May 4" {
+		t.Fatalf("facts=%v", got)
+	}
+}
@@ -1,6 +1,8 @@
 package memory
 
 import (
+	"bytes"
+	"cmp"
 	"context"
 	"crypto/sha256"
 	"database/sql"
@@ -9,12 +11,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/fs"
 	"maps"
 	"math"
-	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -22,6 +26,8 @@ import (
 
 	"github.com/mosaxiv/clawlet/config"
 	_ "github.com/mosaxiv/clawlet/internal/sqlite3"
+	"github.com/mosaxiv/clawlet/paths"
+	"github.com/mosaxiv/clawlet/session"
 )
 
 const (
@@ -35,6 +41,9 @@ const (
 type SearchOptions struct {
 	MaxResults int
 	MinScore   float64
+	// Scopes, when non-nil, leaves out the memory of other users and chats
+	// (see View.SearchScopes).
+	Scopes []Scope
 }
 
 type SearchResult struct {
@@ -43,11 +52,17 @@ type SearchResult struct {
 	EndLine   int     `json:"endLine"`
 	Score     float64 `json:"score"`
 	Snippet   string  `json:"snippet"`
+	// Source is SourceMemory, SourceWorkspace or SourceSession. For sessions
+	// the lines are message numbers.
+	Source     string `json:"source"`
+	SessionKey string `json:"sessionKey,omitempty"`
 }
 
 type ReadFileOptions struct {
 	From  int
 	Lines int
+	// Scopes, when non-nil, refuses files of other users and chats.
+	Scopes []Scope
 }
 
 type SearchStatus struct {
@@ -65,6 +80,12 @@ type SearchStatus struct {
 	MinScore      float64 `json:"minScore"`
 	MaxResults    int     `json:"maxResults"`
 	LastError     string  `json:"lastError,omitempty"`
+	// Watching is set while Watch runs; WatchMode is "inotify" or "poll".
+	Watching     bool   `json:"watching"`
+	WatchMode    string `json:"watchMode,omitempty"`
+	QueuedFiles  int    `json:"queuedFiles"`
+	CacheEntries int    `json:"cacheEntries"`
+	LastSyncAtMS int64  `json:"lastSyncAtMs,omitempty"`
 }
 
 type SearchManager interface {
@@ -78,7 +99,8 @@ type SearchManager interface {
 type IndexManager struct {
 	workspaceDir string
 	cfg          resolvedSearchConfig
-	provider     *openAIEmbeddingProvider
+	provider     embeddingProvider
+	reranker     reranker // nil unless query.rerank is enabled
 	db           *sql.DB
 
 	// Serialized via dbMu for predictable index consistency.
@@ -88,13 +110,18 @@ type IndexManager struct {
 	vectorDims  int
 	ftsReady    bool
 	lastError   string
+	lastSyncAt  time.Time
+
+	watchMu sync.Mutex
+	watch   watchState
 }
 
 type resolvedSearchConfig struct {
 	enabled bool
 
-	provider string
-	model    string
+	provider   string
+	model      string
+	dimensions int
 
 	baseURL string
 	apiKey  string
@@ -102,6 +129,9 @@ type resolvedSearchConfig struct {
 
 	storePath string
 
+	sourcePaths []string
+	sessionsDir string // empty unless transcripts are indexed
+
 	vectorEnabled bool
 	chunkTokens   int
 	chunkOverlap  int
@@ -113,10 +143,19 @@ type resolvedSearchConfig struct {
 	hybridTextWeight   float64
 	candidateMul       int
 
+	rerank          resolvedRerankConfig
+	mmrEnabled      bool
+	mmrLambda       float64
+	recencyEnabled  bool
+	recencyHalfLife time.Duration
+	recencyWeight   float64
+
 	cacheEnabled bool
 	cacheMax     int
 
-	syncOnSearch bool
+	syncOnSearch  bool
+	watchDebounce time.Duration
+	pollInterval  time.Duration
 }
 
 type indexMeta struct {
@@ -126,15 +165,17 @@ type indexMeta struct {
 	ChunkTokens int    `json:"chunkTokens"`
 	ChunkOver   int    `json:"chunkOverlap"`
 	VectorDims  int    `json:"vectorDims,omitempty"`
+	SyncedAtMS  int64  `json:"syncedAtMs,omitempty"`
 }
 
 type memoryFileEntry struct {
 	AbsPath  string
 	RelPath  string
+	Source   string
 	Hash     string
 	Size     int64
 	Modified int64
-	Content  string
+	Content  string // not read for sessions
 }
 
 type chunkEntry struct {
@@ -156,15 +197,6 @@ type vectorResult struct {
 	VectorScore float64
 }
 
-type openAIEmbeddingProvider struct {
-	provider string
-	baseURL  string
-	apiKey   string
-	model    string
-	headers  map[string]string
-	client   *http.Client
-}
-
 func NewIndexManager(cfg *config.Config, workspace string) (*IndexManager, error) {
 	if cfg == nil {
 		return nil, errors.New("config is nil")
@@ -203,14 +235,8 @@ func NewIndexManager(cfg *config.Config, workspace string) (*IndexManager, error
 		workspaceDir: ws,
 		cfg:          resolved,
 		db:           db,
-		provider: &openAIEmbeddingProvider{
-			provider: resolved.provider,
-			baseURL:  strings.TrimRight(resolved.baseURL, "/"),
-			apiKey:   resolved.apiKey,
-			model:    resolved.model,
-			headers:  copyHeaders(resolved.headers),
-			client:   &http.Client{Timeout: 60 * time.Second},
-		},
+		provider:     newEmbeddingProvider(resolved),
+		reranker:     newReranker(resolved.rerank),
 	}
 	if err := m.ensureSchema(); err != nil {
 		_ = db.Close()
@@ -221,6 +247,9 @@ func NewIndexManager(cfg *config.Config, workspace string) (*IndexManager, error
 		m.vectorDims = meta.VectorDims
 		m.vectorReady = m.cfg.vectorEnabled
 	}
+	if meta != nil && meta.SyncedAtMS > 0 {
+		m.lastSyncAt = time.UnixMilli(meta.SyncedAtMS)
+	}
 	return m, nil
 }
 
@@ -256,9 +285,29 @@ func (m *IndexManager) Search(ctx context.Context, query string, opts SearchOpti
 		candidates = 200
 	}
 
+	m.dbMu.Lock()
+	results, err := m.searchCandidatesLocked(ctx, cleaned, candidates, opts)
+	m.dbMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	results, rerankErr := m.rerank(ctx, cleaned, results, maxResults)
+
 	m.dbMu.Lock()
 	defer m.dbMu.Unlock()
+	if rerankErr != nil {
+		m.lastError = rerankErr.Error()
+	}
+	if !m.cfg.mmrEnabled {
+		return searchResults(clampResults(results, maxResults, minScore)), nil
+	}
+	results = clampResults(results, len(results), minScore)
+	return searchResults(m.selectMMRLocked(results, maxResults)), nil
+}
 
+// searchCandidatesLocked returns the hybrid candidates for query, filtered
+// by scope and boosted by recency.
+func (m *IndexManager) searchCandidatesLocked(ctx context.Context, cleaned string, candidates int, opts SearchOptions) ([]rankedResult, error) {
 	if m.cfg.syncOnSearch {
 		if err := m.syncLocked(ctx, false); err != nil {
 			return nil, err
@@ -275,16 +324,28 @@ func (m *IndexManager) Search(ctx context.Context, query string, opts SearchOpti
 		qv = queryVec[0]
 	}
 
-	vectorRows, err := m.searchVectorLocked(qv, candidates)
+	filter, filterArgs := "1", []any(nil)
+	if opts.Scopes != nil {
+		filter, filterArgs = scopeFilterSQL(opts.Scopes)
+	}
+	vectorRows, err := m.searchVectorLocked(qv, candidates, filter, filterArgs)
 	if err != nil {
 		return nil, err
 	}
-	keywordRows, err := m.searchKeywordLocked(cleaned, candidates)
+	keywordRows, err := m.searchKeywordLocked(cleaned, candidates, filter, filterArgs)
 	if err != nil {
 		return nil, err
 	}
-	merged := mergeHybrid(vectorRows, keywordRows, m.cfg.hybridVectorWeight, m.cfg.hybridTextWeight)
-	return clampResults(merged, maxResults, minScore), nil
+	results := mergeHybrid(vectorRows, keywordRows, m.cfg.hybridVectorWeight, m.cfg.hybridTextWeight)
+	if opts.Scopes != nil {
+		results = slices.DeleteFunc(results, func(r rankedResult) bool {
+			return !allowsResult(opts.Scopes, r.Source, r.Path, r.SessionKey)
+		})
+	}
+	if m.cfg.recencyEnabled {
+		results = applyRecency(results, m.cfg.recencyHalfLife, m.cfg.recencyWeight, time.Now())
+	}
+	return results, nil
 }
 
 func (m *IndexManager) ReadFile(relPath string, opts ReadFileOptions) (string, string, error) {
@@ -295,6 +356,20 @@ func (m *IndexManager) ReadFile(relPath string, opts ReadFileOptions) (string, s
 	if raw == "" {
 		return "", "", errors.New("path required")
 	}
+	if strings.HasPrefix(raw, sessionPathPrefix) {
+		file, err := sessionFile(m.cfg.sessionsDir, raw)
+		if err != nil {
+			return "", "", err
+		}
+		s, err := session.ReadFile(file)
+		if err != nil {
+			return "", "", err
+		}
+		if s == nil || (opts.Scopes != nil && !allowsResult(opts.Scopes, SourceSession, raw, s.Key)) {
+			return "", "", errors.New("path required")
+		}
+		return renderSessionMessages(s, opts), raw, nil
+	}
 	abs := raw
 	if !filepath.IsAbs(abs) {
 		abs = filepath.Join(m.workspaceDir, raw)
@@ -306,11 +381,11 @@ func (m *IndexManager) ReadFile(relPath string, opts ReadFileOptions) (string, s
 		return "", "", errors.New("path required")
 	}
 	rp = filepath.ToSlash(rp)
-	if strings.HasPrefix(rp, "../") || rp == ".." || !isMemoryPath(rp) {
+	if strings.HasPrefix(rp, "../") || rp == ".." || !m.isSourcePath(rp) {
 		return "", "", errors.New("path required")
 	}
-	if !strings.HasSuffix(strings.ToLower(rp), ".md") {
-		return "", "", errors.New("path required")
+	if opts.Scopes != nil && !allowsResult(opts.Scopes, "", rp, "") {
+		return "", "", errors.New("path is outside the memory scopes of this chat")
 	}
 	info, err := os.Lstat(abs)
 	if err != nil || !info.Mode().IsRegular() || (info.Mode()&os.ModeSymlink) != 0 {
@@ -351,6 +426,8 @@ func (m *IndexManager) Status(ctx context.Context) SearchStatus {
 	if m == nil {
 		return SearchStatus{Enabled: false}
 	}
+	m.dbMu.Lock()
+	defer m.dbMu.Unlock()
 	out := SearchStatus{
 		Enabled:       m != nil,
 		Provider:      m.cfg.provider,
@@ -365,31 +442,25 @@ func (m *IndexManager) Status(ctx context.Context) SearchStatus {
 		MaxResults:    m.cfg.maxResults,
 		LastError:     m.lastError,
 	}
-	m.dbMu.Lock()
-	defer m.dbMu.Unlock()
 	out.Files = queryCount(m.db, `SELECT COUNT(*) FROM files`)
 	out.Chunks = queryCount(m.db, `SELECT COUNT(*) FROM chunks`)
+	out.CacheEntries = queryCount(m.db, fmt.Sprintf(`SELECT COUNT(*) FROM %s`, cacheTableName))
+	if !m.lastSyncAt.IsZero() {
+		out.LastSyncAtMS = m.lastSyncAt.UnixMilli()
+	}
+	m.watchMu.Lock()
+	out.Watching = m.watch.mode != ""
+	out.WatchMode = m.watch.mode
+	out.QueuedFiles = len(m.watch.pending) + m.watch.processing
+	m.watchMu.Unlock()
 	return out
 }
 
 func (m *IndexManager) syncLocked(ctx context.Context, force bool) error {
-	if err := ctx.Err(); err != nil {
-		return err
-	}
-	if err := m.ensureSchema(); err != nil {
-		return err
-	}
-	meta, err := m.readMeta()
+	needFull, err := m.needFullSyncLocked(ctx, force)
 	if err != nil {
 		return err
 	}
-	providerKey := m.provider.providerKey()
-	needFull := force || meta == nil ||
-		meta.Model != m.cfg.model ||
-		meta.Provider != m.cfg.provider ||
-		meta.ProviderKey != providerKey ||
-		meta.ChunkTokens != m.cfg.chunkTokens ||
-		meta.ChunkOver != m.cfg.chunkOverlap
 	if needFull {
 		if err := m.resetIndexLocked(); err != nil {
 			return err
@@ -445,14 +516,89 @@ func (m *IndexManager) syncLocked(ctx context.Context, force bool) error {
 			return err
 		}
 	}
+	return m.finishSyncLocked()
+}
 
+// syncFilesLocked re-indexes only the given absolute paths: changed files
+// are indexed again and vanished ones dropped. Paths outside the sources
+// are ignored. It falls back to syncLocked when the index must be rebuilt.
+func (m *IndexManager) syncFilesLocked(ctx context.Context, absPaths []string) error {
+	needFull, err := m.needFullSyncLocked(ctx, false)
+	if err != nil {
+		return err
+	}
+	if needFull {
+		return m.syncLocked(ctx, false)
+	}
+	for _, abs := range absPaths {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		f, ok := m.classifyPath(abs)
+		if !ok {
+			continue
+		}
+		entry, ok, err := readSourceFile(f)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if err := m.deletePathLocked(f.RelPath); err != nil {
+				return err
+			}
+			continue
+		}
+		var oldHash string
+		err = m.db.QueryRow(`SELECT hash FROM files WHERE path = ?`, entry.RelPath).Scan(&oldHash)
+		if err == nil && oldHash == entry.Hash {
+			continue
+		}
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if err := m.indexFileLocked(ctx, entry); err != nil {
+			return err
+		}
+	}
+	return m.finishSyncLocked()
+}
+
+// needFullSyncLocked prepares the schema and reports whether the index was
+// built with different settings and must be rebuilt from scratch.
+func (m *IndexManager) needFullSyncLocked(ctx context.Context, force bool) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	if err := m.ensureSchema(); err != nil {
+		return false, err
+	}
+	meta, err := m.readMeta()
+	if err != nil {
+		return false, err
+	}
+	dims, err := m.provider.dimensions(ctx)
+	if err != nil {
+		m.lastError = err.Error()
+		return false, err
+	}
+	return force || meta == nil ||
+		(meta.VectorDims > 0 && meta.VectorDims != dims) ||
+		meta.Model != m.cfg.model ||
+		meta.Provider != m.cfg.provider ||
+		meta.ProviderKey != m.provider.providerKey() ||
+		meta.ChunkTokens != m.cfg.chunkTokens ||
+		meta.ChunkOver != m.cfg.chunkOverlap, nil
+}
+
+func (m *IndexManager) finishSyncLocked() error {
 	next := &indexMeta{
 		Model:       m.cfg.model,
 		Provider:    m.cfg.provider,
-		ProviderKey: providerKey,
+		ProviderKey: m.provider.providerKey(),
 		ChunkTokens: m.cfg.chunkTokens,
 		ChunkOver:   m.cfg.chunkOverlap,
 		VectorDims:  m.vectorDims,
+		SyncedAtMS:  time.Now().UnixMilli(),
 	}
 	if err := m.writeMeta(next); err != nil {
 		return err
@@ -460,6 +606,7 @@ func (m *IndexManager) syncLocked(ctx context.Context, force bool) error {
 	if err := m.pruneEmbeddingCacheLocked(); err != nil {
 		return err
 	}
+	m.lastSyncAt = time.UnixMilli(next.SyncedAtMS)
 	return nil
 }
 
@@ -484,7 +631,9 @@ func (m *IndexManager) ensureSchema() error {
 			model TEXT NOT NULL,
 			text TEXT NOT NULL,
 			embedding TEXT NOT NULL,
-			updated_at INTEGER NOT NULL
+			updated_at INTEGER NOT NULL,
+			source TEXT NOT NULL DEFAULT 'memory',
+			session_key TEXT NOT NULL DEFAULT ''
 		)`,
 		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
 			provider TEXT NOT NULL,
@@ -504,6 +653,15 @@ func (m *IndexManager) ensureSchema() error {
 			return err
 		}
 	}
+	// Indexes created before sources existed hold memory files only.
+	for _, col := range []string{
+		`source TEXT NOT NULL DEFAULT 'memory'`,
+		`session_key TEXT NOT NULL DEFAULT ''`,
+	} {
+		if err := m.addColumnIfMissing("chunks", col); err != nil {
+			return err
+		}
+	}
 
 	m.ftsReady = false
 	if _, err := m.db.Exec(
@@ -523,6 +681,31 @@ func (m *IndexManager) ensureSchema() error {
 	return nil
 }
 
+// addColumnIfMissing adds the column declared by def ("name TYPE ...") to
+// table unless it exists.
+func (m *IndexManager) addColumnIfMissing(table, def string) error {
+	name, _, _ := strings.Cut(def, " ")
+	rows, err := m.db.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var col string
+		if err := rows.Scan(&col); err != nil {
+			return err
+		}
+		if col == name {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	_, err = m.db.Exec(`ALTER TABLE ` + table + ` ADD COLUMN ` + def)
+	return err
+}
+
 func (m *IndexManager) readMeta() (*indexMeta, error) {
 	var raw string
 	err := m.db.QueryRow(`SELECT value FROM meta WHERE key = ?`, metaKeyMemoryIndex).Scan(&raw)
@@ -625,7 +808,20 @@ func (m *IndexManager) deletePathLocked(relPath string) error {
 }
 
 func (m *IndexManager) indexFileLocked(ctx context.Context, entry memoryFileEntry) error {
-	chunks := chunkMarkdown(entry.Content, m.cfg.chunkTokens, m.cfg.chunkOverlap)
+	var chunks []chunkEntry
+	sessionKey := ""
+	if entry.Source == SourceSession {
+		s, err := session.ReadFile(entry.AbsPath)
+		if err != nil {
+			return err
+		}
+		if s != nil {
+			sessionKey = s.Key
+			chunks = chunkSession(s, m.cfg.chunkTokens)
+		}
+	} else {
+		chunks = chunkMarkdown(entry.Content, m.cfg.chunkTokens, m.cfg.chunkOverlap)
+	}
 	filtered := make([]chunkEntry, 0, len(chunks))
 	for _, c := range chunks {
 		if strings.TrimSpace(c.Text) == "" {
@@ -683,14 +879,16 @@ func (m *IndexManager) indexFileLocked(ctx context.Context, entry memoryFileEntr
 		id := hashText(fmt.Sprintf("%s:%d:%d:%s:%s", entry.RelPath, c.StartLine, c.EndLine, c.Hash, m.cfg.model))
 		embJSON, _ := json.Marshal(emb)
 		if _, err := tx.Exec(
-			`INSERT INTO chunks (id,path,start_line,end_line,hash,model,text,embedding,updated_at)
-			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			`INSERT INTO chunks (id,path,start_line,end_line,hash,model,text,embedding,updated_at,source,session_key)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 			 ON CONFLICT(id) DO UPDATE SET
 			 	hash=excluded.hash,
 				model=excluded.model,
 				text=excluded.text,
 				embedding=excluded.embedding,
-				updated_at=excluded.updated_at`,
+				updated_at=excluded.updated_at,
+				source=excluded.source,
+				session_key=excluded.session_key`,
 			id,
 			entry.RelPath,
 			c.StartLine,
@@ -700,6 +898,8 @@ func (m *IndexManager) indexFileLocked(ctx context.Context, entry memoryFileEntr
 			c.Text,
 			string(embJSON),
 			now,
+			entry.Source,
+			sessionKey,
 		); err != nil {
 			return err
 		}
@@ -750,23 +950,24 @@ func (m *IndexManager) indexFileLocked(ctx context.Context, entry memoryFileEntr
 	return nil
 }
 
-func (m *IndexManager) searchVectorLocked(queryVec []float64, limit int) ([]vectorResult, error) {
+// searchVectorLocked returns the limit chunks nearest to queryVec among
+// those matching the SQL condition filter on chunks c.
+func (m *IndexManager) searchVectorLocked(queryVec []float64, limit int, filter string, filterArgs []any) ([]vectorResult, error) {
 	if len(queryVec) == 0 || limit <= 0 {
 		return []vectorResult{}, nil
 	}
 	if err := m.ensureVectorTableLocked(len(queryVec)); err != nil {
 		return nil, err
 	}
+	args := append([]any{vectorToBlob(queryVec), m.cfg.model}, filterArgs...)
 	rows, err := m.db.Query(
-		`SELECT c.id, c.path, c.start_line, c.end_line, c.text, vec_distance_cosine(v.embedding, ?) AS dist
+		`SELECT c.id, c.path, c.start_line, c.end_line, c.text, c.source, c.session_key, vec_distance_cosine(v.embedding, ?) AS dist
 		   FROM `+vectorTableName+` v
 		   JOIN chunks c ON c.id = v.id
-		  WHERE c.model = ?
+		  WHERE c.model = ? AND `+filter+`
 		  ORDER BY dist ASC
 		  LIMIT ?`,
-		vectorToBlob(queryVec),
-		m.cfg.model,
-		limit,
+		append(args, limit)...,
 	)
 	if err != nil {
 		return nil, err
@@ -774,21 +975,23 @@ func (m *IndexManager) searchVectorLocked(queryVec []float64, limit int) ([]vect
 	defer rows.Close()
 	out := make([]vectorResult, 0, limit)
 	for rows.Next() {
-		var id, p, text string
+		var id, p, text, source, sessionKey string
 		var startLine, endLine int
 		var dist float64
-		if err := rows.Scan(&id, &p, &startLine, &endLine, &text, &dist); err != nil {
+		if err := rows.Scan(&id, &p, &startLine, &endLine, &text, &source, &sessionKey, &dist); err != nil {
 			return nil, err
 		}
 		score := 1 - dist
 		out = append(out, vectorResult{
 			ID: id,
 			SearchResult: SearchResult{
-				Path:      p,
-				StartLine: startLine,
-				EndLine:   endLine,
-				Score:     score,
-				Snippet:   truncateText(text, snippetMaxChars),
+				Path:       p,
+				StartLine:  startLine,
+				EndLine:    endLine,
+				Score:      score,
+				Snippet:    truncateText(text, snippetMaxChars),
+				Source:     source,
+				SessionKey: sessionKey,
 			},
 			VectorScore: score,
 		})
@@ -799,7 +1002,8 @@ func (m *IndexManager) searchVectorLocked(queryVec []float64, limit int) ([]vect
 	return out, nil
 }
 
-func (m *IndexManager) searchKeywordLocked(query string, limit int) ([]keywordResult, error) {
+// searchKeywordLocked is the full-text counterpart of searchVectorLocked.
+func (m *IndexManager) searchKeywordLocked(query string, limit int, filter string, filterArgs []any) ([]keywordResult, error) {
 	if !m.ftsReady || limit <= 0 {
 		return []keywordResult{}, nil
 	}
@@ -807,15 +1011,15 @@ func (m *IndexManager) searchKeywordLocked(query string, limit int) ([]keywordRe
 	if ftsQuery == "" {
 		return []keywordResult{}, nil
 	}
+	args := append([]any{ftsQuery, m.cfg.model}, filterArgs...)
 	rows, err := m.db.Query(
-		`SELECT id, path, start_line, end_line, text, bm25(`+ftsTableName+`) AS rank
-		   FROM `+ftsTableName+`
-		  WHERE `+ftsTableName+` MATCH ? AND model = ?
+		`SELECT f.id, f.path, f.start_line, f.end_line, f.text, c.source, c.session_key, bm25(`+ftsTableName+`) AS rank
+		   FROM `+ftsTableName+` f
+		   JOIN chunks c ON c.id = f.id
+		  WHERE `+ftsTableName+` MATCH ? AND f.model = ? AND `+filter+`
 		  ORDER BY rank ASC
 		  LIMIT ?`,
-		ftsQuery,
-		m.cfg.model,
-		limit,
+		append(args, limit)...,
 	)
 	if err != nil {
 		return nil, err
@@ -823,21 +1027,23 @@ func (m *IndexManager) searchKeywordLocked(query string, limit int) ([]keywordRe
 	defer rows.Close()
 	out := make([]keywordResult, 0, limit)
 	for rows.Next() {
-		var id, p, text string
+		var id, p, text, source, sessionKey string
 		var startLine, endLine int
 		var rank float64
-		if err := rows.Scan(&id, &p, &startLine, &endLine, &text, &rank); err != nil {
+		if err := rows.Scan(&id, &p, &startLine, &endLine, &text, &source, &sessionKey, &rank); err != nil {
 			return nil, err
 		}
 		textScore := bm25RankToScore(rank)
 		out = append(out, keywordResult{
 			ID: id,
 			SearchResult: SearchResult{
-				Path:      p,
-				StartLine: startLine,
-				EndLine:   endLine,
-				Score:     textScore,
-				Snippet:   truncateText(text, snippetMaxChars),
+				Path:       p,
+				StartLine:  startLine,
+				EndLine:    endLine,
+				Score:      textScore,
+				Snippet:    truncateText(text, snippetMaxChars),
+				Source:     source,
+				SessionKey: sessionKey,
 			},
 			TextScore: textScore,
 		})
@@ -878,6 +1084,15 @@ func (m *IndexManager) embedChunksWithCacheLocked(ctx context.Context, chunks []
 	if len(emb) != len(texts) {
 		return nil, fmt.Errorf("embedding count mismatch: got=%d want=%d", len(emb), len(texts))
 	}
+	dims, err := m.provider.dimensions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range emb {
+		if len(v) != dims {
+			return nil, fmt.Errorf("embedding dimensions changed: got=%d want=%d (reindex after switching models)", len(v), dims)
+		}
+	}
 	toCache := make([]cacheRow, 0, len(missingIdx))
 	for i, idx := range missingIdx {
 		result[idx] = emb[i]
@@ -998,6 +1213,37 @@ func (m *IndexManager) upsertEmbeddingCacheLocked(rows []cacheRow) error {
 	return nil
 }
 
+// PruneCache drops cached embeddings of other providers and models, then
+// trims the cache to its configured size. With all, the cache is emptied.
+// It returns the number of entries removed.
+func (m *IndexManager) PruneCache(ctx context.Context, all bool) (int, error) {
+	if m == nil {
+		return 0, errors.New("memory manager is nil")
+	}
+	m.dbMu.Lock()
+	defer m.dbMu.Unlock()
+	if err := m.ensureSchema(); err != nil {
+		return 0, err
+	}
+	before := queryCount(m.db, fmt.Sprintf(`SELECT COUNT(*) FROM %s`, cacheTableName))
+	var err error
+	if all {
+		_, err = m.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s`, cacheTableName))
+	} else {
+		_, err = m.db.ExecContext(ctx,
+			fmt.Sprintf(`DELETE FROM %s WHERE provider != ? OR model != ? OR provider_key != ?`, cacheTableName),
+			m.cfg.provider, m.cfg.model, m.provider.providerKey(),
+		)
+		if err == nil {
+			err = m.pruneEmbeddingCacheLocked()
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	return before - queryCount(m.db, fmt.Sprintf(`SELECT COUNT(*) FROM %s`, cacheTableName)), nil
+}
+
 func (m *IndexManager) pruneEmbeddingCacheLocked() error {
 	if !m.cfg.cacheEnabled || m.cfg.cacheMax <= 0 {
 		return nil
@@ -1020,53 +1266,163 @@ func (m *IndexManager) pruneEmbeddingCacheLocked() error {
 	return err
 }
 
-func (m *IndexManager) listMemoryFilesLocked() ([]memoryFileEntry, error) {
+// sourceFile is a file to index and the path it is indexed under.
+type sourceFile struct {
+	AbsPath string
+	RelPath string
+	Source  string
+}
+
+// listSourceFiles returns every file that belongs in the index.
+func (m *IndexManager) listSourceFiles() ([]sourceFile, error) {
 	paths, err := listMemoryPaths(m.workspaceDir)
 	if err != nil {
 		return nil, err
 	}
-	out := make([]memoryFileEntry, 0, len(paths))
+	seen := make(map[string]struct{}, len(paths))
+	var out []sourceFile
+	add := func(abs, source string) {
+		if _, ok := seen[abs]; ok {
+			return
+		}
+		seen[abs] = struct{}{}
+		rel, err := filepath.Rel(m.workspaceDir, abs)
+		if err != nil {
+			return
+		}
+		out = append(out, sourceFile{AbsPath: abs, RelPath: filepath.ToSlash(rel), Source: source})
+	}
 	for _, abs := range paths {
-		st, err := os.Stat(abs)
-		if err != nil || !st.Mode().IsRegular() {
-			continue
+		add(abs, SourceMemory)
+	}
+	for _, abs := range listWorkspaceSourcePaths(m.workspaceDir, m.cfg.sourcePaths) {
+		add(abs, SourceWorkspace)
+	}
+	for rel, abs := range listSessionFiles(m.cfg.sessionsDir) {
+		out = append(out, sourceFile{AbsPath: abs, RelPath: rel, Source: SourceSession})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].RelPath < out[j].RelPath })
+	return out, nil
+}
+
+// classifyPath maps an absolute path to the file it is indexed as, whether
+// or not it exists. ok is false for paths outside every source.
+func (m *IndexManager) classifyPath(abs string) (sourceFile, bool) {
+	abs = filepath.Clean(abs)
+	if m.cfg.sessionsDir != "" {
+		if rel, err := filepath.Rel(m.cfg.sessionsDir, abs); err == nil && rel != ".." && !strings.HasPrefix(filepath.ToSlash(rel), "../") {
+			name := sessionPathPrefix + filepath.ToSlash(rel)
+			if _, err := sessionFile(m.cfg.sessionsDir, name); err == nil {
+				return sourceFile{AbsPath: abs, RelPath: name, Source: SourceSession}, true
+			}
+			return sourceFile{}, false
+		}
+	}
+	rel, err := filepath.Rel(m.workspaceDir, abs)
+	rel = filepath.ToSlash(rel)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return sourceFile{}, false
+	}
+	if isMemoryPath(rel) && strings.HasSuffix(strings.ToLower(rel), ".md") {
+		return sourceFile{AbsPath: abs, RelPath: rel, Source: SourceMemory}, true
+	}
+	for _, p := range m.cfg.sourcePaths {
+		if matchGlob(p, rel) {
+			return sourceFile{AbsPath: abs, RelPath: rel, Source: SourceWorkspace}, true
 		}
-		b, err := os.ReadFile(abs)
+	}
+	return sourceFile{}, false
+}
+
+// readSourceFile loads f for indexing. ok is false when f no longer exists
+// or is not indexable (a directory, a large or binary workspace file).
+func readSourceFile(f sourceFile) (memoryFileEntry, bool, error) {
+	st, err := os.Lstat(f.AbsPath)
+	if err != nil || !st.Mode().IsRegular() {
+		return memoryFileEntry{}, false, nil
+	}
+	entry := memoryFileEntry{
+		AbsPath:  f.AbsPath,
+		RelPath:  f.RelPath,
+		Source:   f.Source,
+		Size:     st.Size(),
+		Modified: st.ModTime().UnixMilli(),
+	}
+	if f.Source == SourceSession {
+		// Transcripts are only read when they changed: appends change size
+		// and mtime.
+		entry.Hash = hashText(fmt.Sprintf("%d:%d", st.Size(), st.ModTime().UnixNano()))
+		return entry, true, nil
+	}
+	if f.Source == SourceWorkspace && st.Size() > maxSourceFileBytes {
+		return memoryFileEntry{}, false, nil
+	}
+	b, err := os.ReadFile(f.AbsPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return memoryFileEntry{}, false, nil
+		}
+		return memoryFileEntry{}, false, err
+	}
+	if f.Source == SourceWorkspace && bytes.IndexByte(b, 0) >= 0 {
+		return memoryFileEntry{}, false, nil // binary
+	}
+	entry.Content = string(b)
+	entry.Hash = hashText(entry.Content)
+	return entry, true, nil
+}
+
+func (m *IndexManager) listMemoryFilesLocked() ([]memoryFileEntry, error) {
+	files, err := m.listSourceFiles()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]memoryFileEntry, 0, len(files))
+	for _, f := range files {
+		entry, ok, err := readSourceFile(f)
 		if err != nil {
 			return nil, err
 		}
-		content := string(b)
-		rel, err := filepath.Rel(m.workspaceDir, abs)
-		if err != nil {
-			continue
+		if ok {
+			out = append(out, entry)
 		}
-		out = append(out, memoryFileEntry{
-			AbsPath:  abs,
-			RelPath:  filepath.ToSlash(rel),
-			Hash:     hashText(content),
-			Size:     st.Size(),
-			Modified: st.ModTime().UnixMilli(),
-			Content:  content,
-		})
 	}
-	sort.Slice(out, func(i, j int) bool { return out[i].RelPath < out[j].RelPath })
 	return out, nil
 }
 
+// isSourcePath reports whether rel (slash-separated, inside the workspace)
+// is indexed as a memory file or matches memorySearch.sources.paths.
+func (m *IndexManager) isSourcePath(rel string) bool {
+	if isMemoryPath(rel) && strings.HasSuffix(strings.ToLower(rel), ".md") {
+		return true
+	}
+	for _, p := range m.cfg.sourcePaths {
+		if matchGlob(p, rel) {
+			return true
+		}
+	}
+	return false
+}
+
 func resolveSearchConfig(cfg *config.Config, workspace string) (resolvedSearchConfig, error) {
 	raw := cfg.Agents.Defaults.MemorySearch
 	provider := strings.ToLower(strings.TrimSpace(raw.Provider))
-	if provider == "" {
-		provider = "openai"
+	switch provider {
+	case "":
+		provider = embeddingOpenAI
+	case "llama.cpp", "llama-cpp":
+		provider = embeddingLlamaCpp
 	}
 	out := resolvedSearchConfig{
 		enabled:            raw.EnabledValue(),
 		provider:           provider,
 		model:              strings.TrimSpace(raw.Model),
+		dimensions:         raw.Dimensions,
 		baseURL:            strings.TrimSpace(raw.Remote.BaseURL),
 		apiKey:             strings.TrimSpace(raw.Remote.APIKey),
 		headers:            copyHeaders(raw.Remote.Headers),
 		storePath:          strings.TrimSpace(raw.Store.Path),
+		sourcePaths:        make([]string, 0, len(raw.Sources.Paths)),
 		vectorEnabled:      raw.Store.Vector.EnabledValue(),
 		chunkTokens:        raw.Chunking.Tokens,
 		chunkOverlap:       raw.Chunking.Overlap,
@@ -1078,6 +1434,23 @@ func resolveSearchConfig(cfg *config.Config, workspace string) (resolvedSearchCo
 		cacheEnabled:       raw.Cache.EnabledValue(),
 		cacheMax:           raw.Cache.MaxEntries,
 		syncOnSearch:       raw.Sync.OnSearchValue(),
+		watchDebounce:      raw.Sync.WatchDebounceValue(),
+		pollInterval:       raw.Sync.PollIntervalValue(),
+		mmrEnabled:         raw.Query.MMR.EnabledValue(),
+		mmrLambda:          raw.Query.MMR.LambdaValue(),
+		recencyEnabled:     raw.Query.Recency.EnabledValue(),
+		recencyHalfLife:    time.Duration(raw.Query.Recency.HalfLifeDaysValue()) * 24 * time.Hour,
+		recencyWeight:      raw.Query.Recency.WeightValue(),
+		rerank: resolvedRerankConfig{
+			enabled:    raw.Query.Rerank.EnabledValue(),
+			provider:   strings.ToLower(strings.TrimSpace(raw.Query.Rerank.Provider)),
+			baseURL:    strings.TrimSpace(raw.Query.Rerank.BaseURL),
+			apiKey:     strings.TrimSpace(raw.Query.Rerank.APIKey),
+			model:      strings.TrimSpace(raw.Query.Rerank.Model),
+			headers:    copyHeaders(raw.Query.Rerank.Headers),
+			candidates: raw.Query.Rerank.CandidatesValue(),
+			weight:     raw.Query.Rerank.WeightValue(),
+		},
 	}
 	if raw.Query.MinScore != nil {
 		out.minScore = *raw.Query.MinScore
@@ -1088,20 +1461,51 @@ func resolveSearchConfig(cfg *config.Config, workspace string) (resolvedSearchCo
 	if raw.Query.Hybrid.TextWeight != nil {
 		out.hybridTextWeight = *raw.Query.Hybrid.TextWeight
 	}
+	if out.provider == embeddingHash {
+		if out.dimensions == 0 {
+			out.dimensions = config.DefaultMemorySearchHashDimensions
+		}
+		if out.model == "" {
+			out.model = embeddingHash
+		}
+	}
 	if out.enabled {
 		if out.model == "" {
 			return out, errors.New("agents.defaults.memorySearch.model is required when enabled")
 		}
 		switch out.provider {
-		case "openai":
+		case embeddingOpenAI, embeddingOllama, embeddingLlamaCpp, embeddingHash:
 		default:
-			return out, fmt.Errorf("unsupported memorySearch.provider: %s", out.provider)
+			return out, fmt.Errorf("unsupported memorySearch.provider: %s (expected openai, ollama, llamacpp or hash)", out.provider)
+		}
+		if out.dimensions < 0 {
+			return out, errors.New("agents.defaults.memorySearch.dimensions must not be negative")
 		}
 	}
+	switch out.rerank.provider {
+	case "", "cohere", "vllm", "llamacpp", "llama.cpp":
+		out.rerank.provider = rerankJina
+	case rerankJina, rerankTEI:
+	default:
+		if out.enabled && out.rerank.enabled {
+			return out, fmt.Errorf("unsupported memorySearch.query.rerank.provider: %s (expected jina or tei)", out.rerank.provider)
+		}
+	}
+	if out.rerank.baseURL == "" {
+		out.rerank.baseURL = config.DefaultRerankBaseURL
+	}
 	if out.baseURL == "" {
-		out.baseURL = config.DefaultOpenAIBaseURL
+		switch out.provider {
+		case embeddingOllama:
+			out.baseURL = config.DefaultOllamaEmbedBaseURL
+		case embeddingLlamaCpp:
+			out.baseURL = config.DefaultLlamaCppBaseURL
+		default:
+			out.baseURL = config.DefaultOpenAIBaseURL
+		}
 	}
-	if out.apiKey == "" {
+	// Local servers never get the OpenAI or OpenRouter keys.
+	if out.apiKey == "" && out.provider == embeddingOpenAI {
 		out.apiKey = strings.TrimSpace(cfg.Env["OPENAI_API_KEY"])
 		if out.apiKey == "" {
 			out.apiKey = strings.TrimSpace(cfg.Env["OPENROUTER_API_KEY"])
@@ -1110,6 +1514,22 @@ func resolveSearchConfig(cfg *config.Config, workspace string) (resolvedSearchCo
 			out.apiKey = strings.TrimSpace(cfg.LLM.APIKey)
 		}
 	}
+	for _, p := range raw.Sources.Paths {
+		if err := checkGlob(p); err != nil {
+			if out.enabled {
+				return out, err
+			}
+			continue
+		}
+		out.sourcePaths = append(out.sourcePaths, path.Clean(filepath.ToSlash(strings.TrimSpace(p))))
+	}
+	if raw.Sources.Sessions.EnabledValue() {
+		// Only the JSONL store writes transcripts as files.
+		if out.enabled && cfg.Sessions.StoreValue() != config.DefaultSessionsStore {
+			return out, fmt.Errorf("agents.defaults.memorySearch.sources.sessions requires sessions.store %q (got %q)", config.DefaultSessionsStore, cfg.Sessions.StoreValue())
+		}
+		out.sessionsDir = cmp.Or(strings.TrimSpace(raw.Sources.Sessions.Dir), paths.SessionsDir())
+	}
 	if out.storePath == "" {
 		out.storePath = filepath.Join(workspace, ".memory", "index.sqlite")
 	} else {
@@ -1145,91 +1565,6 @@ func resolveSearchConfig(cfg *config.Config, workspace string) (resolvedSearchCo
 	return out, nil
 }
 
-func (p *openAIEmbeddingProvider) EmbedBatch(ctx context.Context, texts []string) ([][]float64, error) {
-	if len(texts) == 0 {
-		return [][]float64{}, nil
-	}
-	if strings.TrimSpace(p.model) == "" {
-		return nil, errors.New("memory embedding model is empty")
-	}
-	endpoint := strings.TrimRight(p.baseURL, "/") + "/embeddings"
-	reqBody := map[string]any{
-		"model": p.model,
-		"input": texts,
-	}
-	b, _ := json.Marshal(reqBody)
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(string(b)))
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Content-Type", "application/json")
-	if strings.TrimSpace(p.apiKey) != "" {
-		req.Header.Set("Authorization", "Bearer "+p.apiKey)
-	}
-	for k, v := range p.headers {
-		k = strings.TrimSpace(k)
-		if k == "" {
-			continue
-		}
-		req.Header.Set(k, v)
-	}
-	resp, err := p.client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		buf := make([]byte, 0, 1024)
-		tmp := make([]byte, 1024)
-		for len(buf) < 4096 {
-			n, _ := resp.Body.Read(tmp)
-			if n <= 0 {
-				break
-			}
-			buf = append(buf, tmp[:n]...)
-		}
-		return nil, fmt.Errorf("embeddings http %d: %s", resp.StatusCode, strings.TrimSpace(string(buf)))
-	}
-	var parsed struct {
-		Data []struct {
-			Index     int       `json:"index"`
-			Embedding []float64 `json:"embedding"`
-		} `json:"data"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
-		return nil, err
-	}
-	if len(parsed.Data) == 0 {
-		return nil, errors.New("embeddings response has no data")
-	}
-	out := make([][]float64, len(texts))
-	for _, d := range parsed.Data {
-		if d.Index < 0 || d.Index >= len(out) {
-			continue
-		}
-		out[d.Index] = normalizeEmbedding(d.Embedding)
-	}
-	for i := range out {
-		if len(out[i]) == 0 {
-			return nil, fmt.Errorf("embedding index %d missing in response", i)
-		}
-	}
-	return out, nil
-}
-
-func (p *openAIEmbeddingProvider) providerKey() string {
-	headerPairs := make([]string, 0, len(p.headers))
-	for k, v := range p.headers {
-		if strings.EqualFold(strings.TrimSpace(k), "authorization") {
-			continue
-		}
-		headerPairs = append(headerPairs, strings.TrimSpace(k)+"="+v)
-	}
-	sort.Strings(headerPairs)
-	payload := fmt.Sprintf("%s|%s|%s|%s", p.provider, p.baseURL, p.model, strings.Join(headerPairs, "|"))
-	return hashText(payload)
-}
-
 func normalizeEmbedding(vec []float64) []float64 {
 	if len(vec) == 0 {
 		return vec
@@ -1445,53 +1780,41 @@ func bm25RankToScore(rank float64) float64 {
 	return 1 / (1 + rank)
 }
 
-func mergeHybrid(vector []vectorResult, keyword []keywordResult, vectorWeight, textWeight float64) []SearchResult {
+func mergeHybrid(vector []vectorResult, keyword []keywordResult, vectorWeight, textWeight float64) []rankedResult {
 	type merged struct {
-		SearchResult
+		rankedResult
 		vectorScore float64
 		textScore   float64
 	}
 	byID := map[string]merged{}
 	for _, r := range vector {
 		byID[r.ID] = merged{
-			SearchResult: SearchResult{
-				Path:      r.Path,
-				StartLine: r.StartLine,
-				EndLine:   r.EndLine,
-				Snippet:   r.Snippet,
-			},
-			vectorScore: r.VectorScore,
+			rankedResult: rankedResult{ID: r.ID, SearchResult: r.SearchResult},
+			vectorScore:  r.VectorScore,
 		}
 	}
 	for _, r := range keyword {
 		cur, ok := byID[r.ID]
 		if !ok {
-			cur = merged{
-				SearchResult: SearchResult{
-					Path:      r.Path,
-					StartLine: r.StartLine,
-					EndLine:   r.EndLine,
-					Snippet:   r.Snippet,
-				},
-			}
+			cur = merged{rankedResult: rankedResult{ID: r.ID, SearchResult: r.SearchResult}}
 		} else if strings.TrimSpace(r.Snippet) != "" {
 			cur.Snippet = r.Snippet
 		}
 		cur.textScore = r.TextScore
 		byID[r.ID] = cur
 	}
-	out := make([]SearchResult, 0, len(byID))
+	out := make([]rankedResult, 0, len(byID))
 	for _, row := range byID {
 		score := vectorWeight*row.vectorScore + textWeight*row.textScore
 		row.Score = score
-		out = append(out, row.SearchResult)
+		out = append(out, row.rankedResult)
 	}
 	sort.Slice(out, func(i, j int) bool { return out[i].Score > out[j].Score })
 	return out
 }
 
-func clampResults(results []SearchResult, maxResults int, minScore float64) []SearchResult {
-	out := make([]SearchResult, 0, len(results))
+func clampResults(results []rankedResult, maxResults int, minScore float64) []rankedResult {
+	out := make([]rankedResult, 0, min(len(results), maxResults))
 	for _, r := range results {
 		if r.Score < minScore {
 			continue
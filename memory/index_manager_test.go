@@ -241,3 +241,37 @@ func fakeEmbedding(text string) []float64 {
 	}
 	return out
 }
+
+func TestIndexManager_PruneCache(t *testing.T) {
+	ws := t.TempDir()
+	writeFile(t, filepath.Join(ws, "MEMORY.md"), "- project codename is Nebula\n")
+	open := func(dims int) *IndexManager {
+		t.Helper()
+		cfg := config.Default()
+		enabled := true
+		cfg.Agents.Defaults.MemorySearch.Enabled = &enabled
+		cfg.Agents.Defaults.MemorySearch.Provider = "hash"
+		cfg.Agents.Defaults.MemorySearch.Dimensions = dims
+		mgr, err := NewIndexManager(cfg, ws)
+		if err != nil {
+			t.Fatalf("NewIndexManager error: %v", err)
+		}
+		t.Cleanup(func() { _ = mgr.Close() })
+		if err := mgr.Sync(context.Background(), false); err != nil {
+			t.Fatal(err)
+		}
+		return mgr
+	}
+	_ = open(32).Close()
+	mgr := open(64)
+	if st := mgr.Status(context.Background()); st.CacheEntries != 2 || st.LastSyncAtMS == 0 {
+		t.Fatalf("status=%+v", st)
+	}
+	// Only the entry of the 32-dimension index is stale.
+	if n, err := mgr.PruneCache(context.Background(), false); err != nil || n != 1 {
+		t.Fatalf("PruneCache=%d, %v", n, err)
+	}
+	if n, err := mgr.PruneCache(context.Background(), true); err != nil || n != 1 {
+		t.Fatalf("PruneCache(all)=%d, %v", n, err)
+	}
+}
@@ -12,15 +12,26 @@ type Store struct {
 	Dir       string
 	LongTerm  string
 	History   string
+	// Facts holds the fact store MEMORY.md is rendered from.
+	Facts string
+	Scope Scope
 }
 
+// New returns the global memory store of workspace.
 func New(workspace string) *Store {
-	dir := filepath.Join(workspace, "memory")
+	return ForScope(workspace, Scope{Kind: ScopeGlobal})
+}
+
+// ForScope returns the memory store of sc in workspace.
+func ForScope(workspace string, sc Scope) *Store {
+	dir := filepath.Join(workspace, filepath.FromSlash(sc.Dir()))
 	return &Store{
 		Workspace: workspace,
 		Dir:       dir,
 		LongTerm:  filepath.Join(dir, "MEMORY.md"),
 		History:   filepath.Join(dir, "HISTORY.md"),
+		Facts:     filepath.Join(dir, "facts.json"),
+		Scope:     sc,
 	}
 }
 
@@ -37,7 +48,7 @@ func (s *Store) EnsureInitialized() error {
 		return err
 	}
 	if _, err := os.Stat(s.LongTerm); err != nil {
-		_ = os.WriteFile(s.LongTerm, []byte("# Long-term Memory\n\n"), 0o644)
+		_ = os.WriteFile(s.LongTerm, []byte(longTermHeader), 0o644)
 	}
 	return nil
 }
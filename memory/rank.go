@@ -0,0 +1,164 @@
+package memory
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// rankedResult is a search hit that still knows its chunk, for the stages
+// run after the hybrid merge.
+type rankedResult struct {
+	ID string
+	SearchResult
+}
+
+func sortRanked(results []rankedResult) {
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+}
+
+func searchResults(results []rankedResult) []SearchResult {
+	out := make([]SearchResult, len(results))
+	for i, r := range results {
+		out[i] = r.SearchResult
+	}
+	return out
+}
+
+var dailyNoteRe = regexp.MustCompile(`^memory/(\d{4}-\d{2}-\d{2})\.md$`)
+
+// applyRecency boosts daily notes by 1+weight at their date, decaying by
+// half every halfLife. Other files keep their score.
+func applyRecency(results []rankedResult, halfLife time.Duration, weight float64, now time.Time) []rankedResult {
+	if halfLife <= 0 || weight <= 0 {
+		return results
+	}
+	for i := range results {
+		m := dailyNoteRe.FindStringSubmatch(results[i].Path)
+		if m == nil {
+			continue
+		}
+		day, err := time.ParseInLocation(time.DateOnly, m[1], now.Location())
+		if err != nil {
+			continue
+		}
+		age := max(now.Sub(day), 0)
+		results[i].Score *= 1 + weight*math.Exp2(-float64(age)/float64(halfLife))
+	}
+	sortRanked(results)
+	return results
+}
+
+// selectMMRLocked picks up to maxResults results by Maximal Marginal
+// Relevance, comparing chunks by their stored embeddings.
+func (m *IndexManager) selectMMRLocked(results []rankedResult, maxResults int) []rankedResult {
+	if len(results) <= 1 {
+		return results[:min(len(results), maxResults)]
+	}
+	return selectMMR(results, m.loadChunkEmbeddingsLocked(results), maxResults, m.cfg.mmrLambda)
+}
+
+func (m *IndexManager) loadChunkEmbeddingsLocked(results []rankedResult) map[string][]float64 {
+	out := map[string][]float64{}
+	args := make([]any, len(results))
+	for i, r := range results {
+		args[i] = r.ID
+	}
+	rows, err := m.db.Query(
+		`SELECT id, embedding FROM chunks WHERE id IN (?`+strings.Repeat(",?", len(args)-1)+`)`,
+		args...,
+	)
+	if err != nil {
+		return out
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, raw string
+		if err := rows.Scan(&id, &raw); err != nil {
+			return out
+		}
+		if vec := parseEmbeddingJSON(raw); len(vec) > 0 {
+			out[id] = vec
+		}
+	}
+	return out
+}
+
+// selectMMR greedily picks the result maximizing
+// lambda*score - (1-lambda)*(similarity to the closest pick). Results keep
+// their relevance score and come back in pick order.
+func selectMMR(results []rankedResult, vecs map[string][]float64, maxResults int, lambda float64) []rankedResult {
+	picked := make([]rankedResult, 0, min(len(results), maxResults))
+	left := append([]rankedResult(nil), results...)
+	// maxSim[i] is the similarity of left[i] to its closest pick so far.
+	maxSim := make([]float64, len(left))
+	for len(left) > 0 && len(picked) < maxResults {
+		best, bestValue := 0, math.Inf(-1)
+		for i, r := range left {
+			if v := lambda*r.Score - (1-lambda)*maxSim[i]; v > bestValue {
+				best, bestValue = i, v
+			}
+		}
+		p := left[best]
+		picked = append(picked, p)
+		left = append(left[:best], left[best+1:]...)
+		maxSim = append(maxSim[:best], maxSim[best+1:]...)
+		for i, r := range left {
+			maxSim[i] = max(maxSim[i], resultSimilarity(p, r, vecs))
+		}
+	}
+	return picked
+}
+
+// resultSimilarity compares two chunks by embedding, or by shared words
+// when one has none. Overlapping or adjacent chunks of one file count as
+// near duplicates whatever their text.
+func resultSimilarity(a, b rankedResult, vecs map[string][]float64) float64 {
+	sim := 0.0
+	va, vb := vecs[a.ID], vecs[b.ID]
+	if len(va) > 0 && len(va) == len(vb) {
+		sim = max(cosineSimilarity(va, vb), 0)
+	} else {
+		sim = jaccardSimilarity(a.Snippet, b.Snippet)
+	}
+	if a.Path == b.Path && a.StartLine <= b.EndLine+1 && b.StartLine <= a.EndLine+1 {
+		sim = max(sim, 0.9)
+	}
+	return sim
+}
+
+func cosineSimilarity(a, b []float64) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+func jaccardSimilarity(a, b string) float64 {
+	words := func(s string) map[string]bool {
+		out := map[string]bool{}
+		for _, w := range tokenRe.FindAllString(strings.ToLower(s), -1) {
+			out[w] = true
+		}
+		return out
+	}
+	wa, wb := words(a), words(b)
+	if len(wa) == 0 || len(wb) == 0 {
+		return 0
+	}
+	shared := 0
+	for w := range wa {
+		if wb[w] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(wa)+len(wb)-shared)
+}
@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"testing"
+	"time"
+)
+
+func ranked(id, path string, start, end int, score float64, snippet string) rankedResult {
+	return rankedResult{ID: id, SearchResult: SearchResult{Path: path, StartLine: start, EndLine: end, Score: score, Snippet: snippet}}
+}
+
+func TestSelectMMR_SkipsOverlappingChunks(t *testing.T) {
+	results := []rankedResult{
+		ranked("a1", "memory/2026-01-10.md", 1, 20, 0.9, "trip to Lisbon, flight booked"),
+		ranked("a2", "memory/2026-01-10.md", 15, 35, 0.88, "Lisbon hotel near the flight"),
+		ranked("a3", "memory/2026-01-10.md", 21, 40, 0.86, "packing list for Lisbon"),
+		ranked("b1", "MEMORY.md", 3, 3, 0.7, "prefers aisle seats"),
+	}
+	got := selectMMR(results, nil, 2, 0.7)
+	if len(got) != 2 || got[0].ID != "a1" || got[1].ID != "b1" {
+		t.Fatalf("got %+v", got)
+	}
+	if got[1].Score != 0.7 {
+		t.Fatalf("relevance score changed: %v", got[1].Score)
+	}
+
+	// lambda 1 is plain relevance order.
+	got = selectMMR(results, nil, 3, 1)
+	if got[0].ID != "a1" || got[1].ID != "a2" || got[2].ID != "a3" {
+		t.Fatalf("got %+v", got)
+	}
+}
+
+func TestSelectMMR_UsesEmbeddings(t *testing.T) {
+	results := []rankedResult{
+		ranked("x", "notes/a.md", 1, 5, 0.9, "alpha"),
+		ranked("y", "notes/b.md", 1, 5, 0.85, "beta"),
+		ranked("z", "notes/c.md", 1, 5, 0.8, "gamma"),
+	}
+	vecs := map[string][]float64{
+		"x": {1, 0},
+		"y": {0.99, 0.1}, // near duplicate of x
+		"z": {0, 1},
+	}
+	got := selectMMR(results, vecs, 2, 0.7)
+	if got[0].ID != "x" || got[1].ID != "z" {
+		t.Fatalf("got %+v", got)
+	}
+}
+
+func TestApplyRecency(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	results := []rankedResult{
+		ranked("old", "memory/2025-03-01.md", 1, 1, 0.6, ""),
+		ranked("plain", "MEMORY.md", 1, 1, 0.58, ""),
+		ranked("new", "memory/2026-03-01.md", 1, 1, 0.55, ""),
+		ranked("month", "memory/2026-01-30.md", 1, 1, 0.54, ""),
+	}
+	got := applyRecency(results, 30*24*time.Hour, 0.2, now)
+	want := []string{"new", "old", "month", "plain"}
+	for i, id := range want {
+		if got[i].ID != id {
+			t.Fatalf("order=%v want %v", got, want)
+		}
+	}
+	// Today's note gets the full weight; the year-old one next to nothing.
+	if got[0].Score < 0.55*1.19 || got[1].Score > 0.6*1.001 {
+		t.Fatalf("scores=%v", got)
+	}
+	if got[3].Score != 0.58 {
+		t.Fatalf("MEMORY.md boosted: %v", got[3].Score)
+	}
+}
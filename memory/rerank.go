@@ -0,0 +1,156 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	rerankJina = "jina"
+	rerankTEI  = "tei"
+)
+
+// reranker scores documents against a query with a cross-encoder. Scores
+// are returned in document order.
+type reranker interface {
+	rerank(ctx context.Context, query string, documents []string) ([]float64, error)
+}
+
+type resolvedRerankConfig struct {
+	enabled    bool
+	provider   string
+	baseURL    string
+	apiKey     string
+	model      string
+	headers    map[string]string
+	candidates int
+	weight     float64
+}
+
+func newReranker(cfg resolvedRerankConfig) reranker {
+	if !cfg.enabled {
+		return nil
+	}
+	return &httpReranker{
+		provider: cfg.provider,
+		baseURL:  strings.TrimRight(cfg.baseURL, "/"),
+		apiKey:   cfg.apiKey,
+		model:    cfg.model,
+		headers:  copyHeaders(cfg.headers),
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// httpReranker calls POST {baseURL}/rerank, either the Jina/Cohere shape
+// also served by llama.cpp and vLLM, or the text-embeddings-inference one.
+type httpReranker struct {
+	provider string
+	baseURL  string
+	apiKey   string
+	model    string
+	headers  map[string]string
+	client   *http.Client
+}
+
+func (r *httpReranker) rerank(ctx context.Context, query string, documents []string) ([]float64, error) {
+	if len(documents) == 0 {
+		return []float64{}, nil
+	}
+	scores := make([]float64, len(documents))
+	seen := make([]bool, len(documents))
+	set := func(i int, score float64) error {
+		if i < 0 || i >= len(documents) {
+			return fmt.Errorf("rerank: index %d out of range", i)
+		}
+		scores[i], seen[i] = score, true
+		return nil
+	}
+	endpoint := r.baseURL + "/rerank"
+	if r.provider == rerankTEI {
+		var parsed []struct {
+			Index int     `json:"index"`
+			Score float64 `json:"score"`
+		}
+		body := map[string]any{"query": query, "texts": documents}
+		if err := requestJSON(ctx, r.client, "rerank", endpoint, r.apiKey, r.headers, body, &parsed); err != nil {
+			return nil, err
+		}
+		for _, item := range parsed {
+			if err := set(item.Index, item.Score); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		var parsed struct {
+			Results []struct {
+				Index          int     `json:"index"`
+				RelevanceScore float64 `json:"relevance_score"`
+			} `json:"results"`
+		}
+		body := map[string]any{"query": query, "documents": documents, "top_n": len(documents)}
+		if r.model != "" {
+			body["model"] = r.model
+		}
+		if err := requestJSON(ctx, r.client, "rerank", endpoint, r.apiKey, r.headers, body, &parsed); err != nil {
+			return nil, err
+		}
+		for _, item := range parsed.Results {
+			if err := set(item.Index, item.RelevanceScore); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, ok := range seen {
+		if !ok {
+			return nil, errors.New("rerank: response is missing documents")
+		}
+	}
+	return normalizeRerankScores(scores), nil
+}
+
+// normalizeRerankScores maps raw logits, as returned by llama.cpp and
+// unnormalized TEI, onto 0..1 so they blend with hybrid scores. Scores that
+// are already probabilities are kept.
+func normalizeRerankScores(scores []float64) []float64 {
+	for _, s := range scores {
+		if s < 0 || s > 1 {
+			for i, v := range scores {
+				scores[i] = 1 / (1 + math.Exp(-v))
+			}
+			break
+		}
+	}
+	return scores
+}
+
+// rerank rescores the best candidates, at least keep of them, and blends
+// the rerank score with the hybrid one; the other results are dropped. When
+// the endpoint fails the hybrid results are returned as they are, with the
+// error for Status. It is called without dbMu, so a slow endpoint doesn't
+// hold up other searches or re-indexing; results must not be shared.
+func (m *IndexManager) rerank(ctx context.Context, query string, results []rankedResult, keep int) ([]rankedResult, error) {
+	if m.reranker == nil || len(results) == 0 {
+		return results, nil
+	}
+	n := min(len(results), max(m.cfg.rerank.candidates, keep))
+	docs := make([]string, n)
+	for i, r := range results[:n] {
+		docs[i] = r.Snippet
+	}
+	scores, err := m.reranker.rerank(ctx, query, docs)
+	if err != nil {
+		return results, err
+	}
+	w := m.cfg.rerank.weight
+	for i := range n {
+		results[i].Score = w*scores[i] + (1-w)*results[i].Score
+	}
+	results = results[:n]
+	sortRanked(results)
+	return results, nil
+}
@@ -0,0 +1,157 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/config"
+)
+
+func TestHTTPReranker_ResponseShapes(t *testing.T) {
+	var gotPath string
+	var gotBody map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		if _, ok := gotBody["texts"]; ok {
+			// text-embeddings-inference returns raw logits, best first.
+			_, _ = w.Write([]byte(`[{"index":1,"score":4.2},{"index":0,"score":-3.1}]`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"results":[{"index":1,"relevance_score":0.9},{"index":0,"relevance_score":0.1}]}`))
+	}))
+	defer srv.Close()
+
+	for _, provider := range []string{rerankJina, rerankTEI} {
+		r := newReranker(resolvedRerankConfig{enabled: true, provider: provider, baseURL: srv.URL + "/", model: "bge-reranker"})
+		scores, err := r.rerank(context.Background(), "cat name", []string{"tea", "the cat is Miso"})
+		if err != nil {
+			t.Fatalf("%s: %v", provider, err)
+		}
+		if gotPath != "/rerank" || len(scores) != 2 || scores[1] <= scores[0] || scores[0] < 0 || scores[1] > 1 {
+			t.Fatalf("%s: path=%s scores=%v", provider, gotPath, scores)
+		}
+		if provider == rerankJina && (gotBody["model"] != "bge-reranker" || gotBody["query"] != "cat name") {
+			t.Fatalf("body=%v", gotBody)
+		}
+	}
+}
+
+func TestIndexManager_SearchReranks(t *testing.T) {
+	var fail atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			http.Error(w, "model not loaded", http.StatusServiceUnavailable)
+			return
+		}
+		var req struct {
+			Documents []string `json:"documents"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		type item struct {
+			Index int     `json:"index"`
+			Score float64 `json:"relevance_score"`
+		}
+		var out struct {
+			Results []item `json:"results"`
+		}
+		for i, d := range req.Documents {
+			score := 0.0
+			if strings.Contains(d, "oolong") {
+				score = 1
+			}
+			out.Results = append(out.Results, item{i, score})
+		}
+		_ = json.NewEncoder(w).Encode(out)
+	}))
+	defer srv.Close()
+
+	ws := t.TempDir()
+	writeFile(t, filepath.Join(ws, "MEMORY.md"), "- drinks tea every morning, tea tea tea\n")
+	writeFile(t, filepath.Join(ws, "memory", "2026-01-10.md"), "Favourite tea is oolong.\n")
+	cfg := config.Default()
+	enabled := true
+	cfg.Agents.Defaults.MemorySearch.Enabled = &enabled
+	cfg.Agents.Defaults.MemorySearch.Provider = "hash"
+	cfg.Agents.Defaults.MemorySearch.Query.Rerank.Enabled = &enabled
+	cfg.Agents.Defaults.MemorySearch.Query.Rerank.BaseURL = srv.URL
+	weight := 1.0
+	cfg.Agents.Defaults.MemorySearch.Query.Rerank.Weight = &weight
+	mgr, err := NewIndexManager(cfg, ws)
+	if err != nil {
+		t.Fatalf("NewIndexManager error: %v", err)
+	}
+	t.Cleanup(func() { _ = mgr.Close() })
+
+	results, err := mgr.Search(context.Background(), "favourite tea", SearchOptions{MinScore: 0.01})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Path != "memory/2026-01-10.md" || results[0].Score != 1 {
+		t.Fatalf("results=%+v", results)
+	}
+
+	// A failing endpoint leaves the hybrid ranking in place.
+	fail.Store(true)
+	results, err = mgr.Search(context.Background(), "favourite tea", SearchOptions{MinScore: 0.01})
+	if err != nil || len(results) != 2 {
+		t.Fatalf("results=%+v err=%v", results, err)
+	}
+	if st := mgr.Status(context.Background()); !strings.Contains(st.LastError, "rerank http 503") {
+		t.Fatalf("lastError=%q", st.LastError)
+	}
+}
+
+func TestIndexManager_SlowRerankDoesNotHoldTheIndex(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		_, _ = io.WriteString(w, `{"results":[{"index":0,"relevance_score":1}]}`)
+	}))
+	defer srv.Close()
+
+	ws := t.TempDir()
+	writeFile(t, filepath.Join(ws, "MEMORY.md"), "- drinks tea every morning\n")
+	cfg := config.Default()
+	enabled := true
+	cfg.Agents.Defaults.MemorySearch.Enabled = &enabled
+	cfg.Agents.Defaults.MemorySearch.Provider = "hash"
+	cfg.Agents.Defaults.MemorySearch.Query.Rerank.Enabled = &enabled
+	cfg.Agents.Defaults.MemorySearch.Query.Rerank.BaseURL = srv.URL
+	mgr, err := NewIndexManager(cfg, ws)
+	if err != nil {
+		t.Fatalf("NewIndexManager error: %v", err)
+	}
+	t.Cleanup(func() { _ = mgr.Close() })
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := mgr.Search(context.Background(), "tea", SearchOptions{MinScore: 0.01})
+		done <- err
+	}()
+	<-started
+	synced := make(chan error, 1)
+	go func() { synced <- mgr.Sync(context.Background(), true) }()
+	select {
+	case err := <-synced:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("re-index waited for the rerank endpoint")
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
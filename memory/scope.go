@@ -0,0 +1,315 @@
+package memory
+
+import (
+	"errors"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mosaxiv/clawlet/config"
+)
+
+// Scope kinds. The global store is memory/ itself; user and chat stores live
+// in memory/users/<key>/ and memory/chats/<key>/.
+const (
+	ScopeGlobal = "global"
+	ScopeUser   = "user"
+	ScopeChat   = "chat"
+)
+
+// Scope names one long-term memory store.
+type Scope struct {
+	Kind string `json:"kind"`
+	// Key is "channel:senderID" for users and the session key for chats.
+	Key string `json:"key,omitempty"`
+}
+
+// Dir returns the workspace-relative, slash-separated directory of the
+// store of s.
+func (s Scope) Dir() string {
+	switch s.Kind {
+	case ScopeUser:
+		return "memory/users/" + scopeDirName(s.Key)
+	case ScopeChat:
+		return "memory/chats/" + scopeDirName(s.Key)
+	default:
+		return "memory"
+	}
+}
+
+// idPrefix starts the fact IDs of the store, so an ID tells its scope.
+func (s Scope) idPrefix() string {
+	switch s.Kind {
+	case ScopeUser:
+		return "u"
+	case ScopeChat:
+		return "c"
+	default:
+		return "f"
+	}
+}
+
+func scopeOfID(id string) string {
+	switch {
+	case strings.HasPrefix(id, "u"):
+		return ScopeUser
+	case strings.HasPrefix(id, "c"):
+		return ScopeChat
+	default:
+		return ScopeGlobal
+	}
+}
+
+var scopeDirUnsafeRe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+func scopeDirName(key string) string {
+	name := scopeDirUnsafeRe.ReplaceAllString(strings.TrimSpace(key), "_")
+	if strings.Trim(name, ".") == "" {
+		name = "_" + name
+	}
+	return name
+}
+
+// View is the long-term memory one conversation sees: the stores injected
+// into its system prompt, searched and written.
+type View struct {
+	Workspace string
+	// Scoped is false when memory scopes are off: everything is global and
+	// search results are not filtered.
+	Scoped bool
+	// Scopes are ordered from global to chat.
+	Scopes []Scope
+	// Default is the kind of scope new facts go to unless one is picked.
+	Default string
+}
+
+// GlobalView is the view of every conversation when scopes are off.
+func GlobalView(workspace string) View {
+	return View{Workspace: workspace, Scopes: []Scope{{Kind: ScopeGlobal}}, Default: ScopeGlobal}
+}
+
+// ViewFor returns the view of the chat with session key chat on channel.
+// user ("channel:senderID") is empty unless the chat is with one person, so
+// one member's facts are never shown to a group.
+func ViewFor(cfg config.MemoryScopesConfig, workspace, channel, user, chat string) View {
+	if !cfg.EnabledValue() {
+		return GlobalView(workspace)
+	}
+	v := View{Workspace: workspace, Scoped: true, Scopes: []Scope{}}
+	allowed := cfg.InjectValue(channel)
+	for _, sc := range []Scope{{Kind: ScopeGlobal}, {Kind: ScopeUser, Key: user}, {Kind: ScopeChat, Key: chat}} {
+		if slices.Contains(allowed, sc.Kind) && (sc.Kind == ScopeGlobal || strings.TrimSpace(sc.Key) != "") {
+			v.Scopes = append(v.Scopes, sc)
+		}
+	}
+	v.Default = cfg.DefaultValue()
+	if _, ok := v.scope(v.Default); !ok && len(v.Scopes) > 0 {
+		v.Default = v.Scopes[len(v.Scopes)-1].Kind
+	}
+	return v
+}
+
+func (v View) scope(kind string) (Scope, bool) {
+	i := slices.IndexFunc(v.Scopes, func(sc Scope) bool { return sc.Kind == kind })
+	if i < 0 {
+		return Scope{}, false
+	}
+	return v.Scopes[i], true
+}
+
+// SearchScopes returns the scopes to limit memory search to, nil for all.
+func (v View) SearchScopes() []Scope {
+	if !v.Scoped {
+		return nil
+	}
+	return v.Scopes
+}
+
+// Context renders memory for the system prompt: the global long-term memory
+// and today's notes, then the facts of the user and chat scopes.
+func (v View) Context() string {
+	if !v.Scoped {
+		return New(v.Workspace).GetContext()
+	}
+	var parts []string
+	for _, sc := range v.Scopes {
+		if sc.Kind == ScopeGlobal {
+			if c := New(v.Workspace).GetContext(); c != "" {
+				parts = append(parts, c)
+			}
+			continue
+		}
+		md := readScopedLongTerm(ForScope(v.Workspace, sc))
+		if len(parseMemoryMarkdown(md)) == 0 {
+			continue
+		}
+		parts = append(parts, "## "+scopeTitle(sc)+"\n"+truncate(strings.TrimSpace(md), 64<<10))
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// LongTerm returns the long-term memory of every scope of v, headed by its
+// scope when scopes are on.
+func (v View) LongTerm() string {
+	if !v.Scoped {
+		return New(v.Workspace).ReadLongTerm()
+	}
+	var b strings.Builder
+	for _, sc := range v.Scopes {
+		st := ForScope(v.Workspace, sc)
+		md := readScopedLongTerm(st)
+		if sc.Kind == ScopeGlobal {
+			md = st.ReadLongTerm()
+		}
+		if strings.TrimSpace(md) == "" {
+			md = "(empty)"
+		}
+		b.WriteString("## Scope: " + sc.Kind)
+		if sc.Key != "" {
+			b.WriteString(" (" + sc.Key + ")")
+		}
+		b.WriteString("\n\n")
+		b.WriteString(strings.TrimSpace(md) + "\n\n")
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// LoadFacts takes over hand edits of the MEMORY.md of each scope.
+func (v View) LoadFacts() error {
+	for _, sc := range v.Scopes {
+		st := ForScope(v.Workspace, sc)
+		if sc.Kind != ScopeGlobal {
+			if _, err := os.Stat(st.LongTerm); err != nil {
+				continue
+			}
+		}
+		if _, err := st.LoadFacts(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyFacts routes changes to the stores of v and applies them: adds go to
+// the scope they name (Default if it is not in v), updates and forgets to
+// the scope their ID belongs to.
+func (v View) ApplyFacts(changes []FactChange, source string) (FactDiff, error) {
+	var diff FactDiff
+	byKind := map[string][]FactChange{}
+	for _, c := range changes {
+		var kind string
+		if id := strings.TrimSpace(c.ID); id != "" {
+			kind = scopeOfID(id)
+			if _, ok := v.scope(kind); !ok {
+				diff.Unknown = append(diff.Unknown, id)
+				continue
+			}
+		} else {
+			kind = strings.ToLower(strings.TrimSpace(c.Scope))
+			if _, ok := v.scope(kind); !ok {
+				kind = v.Default
+			}
+			if _, ok := v.scope(kind); !ok {
+				return diff, errors.New("no memory scope is enabled for this chat")
+			}
+		}
+		byKind[kind] = append(byKind[kind], c)
+	}
+	for _, sc := range v.Scopes {
+		if len(byKind[sc.Kind]) == 0 {
+			continue
+		}
+		d, err := ForScope(v.Workspace, sc).ApplyFacts(byKind[sc.Kind], source)
+		diff.Added = append(diff.Added, d.Added...)
+		diff.Updated = append(diff.Updated, d.Updated...)
+		diff.Forgotten = append(diff.Forgotten, d.Forgotten...)
+		diff.Unknown = append(diff.Unknown, d.Unknown...)
+		if err != nil {
+			return diff, err
+		}
+	}
+	return diff, nil
+}
+
+// AppendHistory appends entry to HISTORY.md of the narrowest scope of v, so
+// a chat's summaries stay with the chat.
+func (v View) AppendHistory(entry string) error {
+	if len(v.Scopes) == 0 {
+		return nil
+	}
+	return ForScope(v.Workspace, v.Scopes[len(v.Scopes)-1]).AppendHistory(entry)
+}
+
+// allowsResult reports whether a search result belongs to one of scopes.
+// Transcripts belong to the chat of their session, files below
+// memory/users/ and memory/chats/ to their scope, everything else to the
+// global scope.
+func allowsResult(scopes []Scope, source, relPath, sessionKey string) bool {
+	if source == SourceSession {
+		return slices.ContainsFunc(scopes, func(sc Scope) bool { return sc.Kind == ScopeChat && sc.Key == sessionKey })
+	}
+	rel := path.Clean(relPath)
+	if strings.HasPrefix(rel, "memory/users/") || strings.HasPrefix(rel, "memory/chats/") {
+		return slices.ContainsFunc(scopes, func(sc Scope) bool {
+			return sc.Kind != ScopeGlobal && strings.HasPrefix(rel, sc.Dir()+"/")
+		})
+	}
+	return slices.ContainsFunc(scopes, func(sc Scope) bool { return sc.Kind == ScopeGlobal })
+}
+
+// scopeFilterSQL returns the SQL condition on chunks c matching allowsResult,
+// so search candidates are capped after the filter rather than before it.
+func scopeFilterSQL(scopes []Scope) (string, []any) {
+	var conds []string
+	var args []any
+	var keys []string
+	for _, sc := range scopes {
+		switch sc.Kind {
+		case ScopeGlobal:
+			conds = append(conds, `(c.source != ? AND substr(c.path, 1, 13) != 'memory/users/' AND substr(c.path, 1, 13) != 'memory/chats/')`)
+			args = append(args, SourceSession)
+		default:
+			// Scope directories are ASCII, so substr counts bytes here.
+			dir := sc.Dir() + "/"
+			conds = append(conds, `(c.source != ? AND substr(c.path, 1, ?) = ?)`)
+			args = append(args, SourceSession, len(dir), dir)
+			if sc.Kind == ScopeChat {
+				keys = append(keys, sc.Key)
+			}
+		}
+	}
+	if len(keys) > 0 {
+		conds = append(conds, `(c.source = ? AND c.session_key IN (?`+strings.Repeat(", ?", len(keys)-1)+`))`)
+		args = append(args, SourceSession)
+		for _, k := range keys {
+			args = append(args, k)
+		}
+	}
+	if len(conds) == 0 {
+		return "0", nil
+	}
+	return "(" + strings.Join(conds, " OR ") + ")", args
+}
+
+func scopeTitle(sc Scope) string {
+	switch sc.Kind {
+	case ScopeUser:
+		return "User Memory: " + sc.Key
+	case ScopeChat:
+		return "Chat Memory: " + sc.Key
+	default:
+		return "Long-term Memory"
+	}
+}
+
+// readScopedLongTerm reads MEMORY.md without creating the store, which
+// would leave a directory behind for every chat.
+func readScopedLongTerm(s *Store) string {
+	b, err := os.ReadFile(s.LongTerm)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
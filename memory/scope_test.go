@@ -0,0 +1,172 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mosaxiv/clawlet/config"
+)
+
+func scopedConfig(def string, inject map[string][]string) config.MemoryScopesConfig {
+	enabled := true
+	return config.MemoryScopesConfig{Enabled: &enabled, Default: def, Inject: inject}
+}
+
+func scopeKinds(v View) []string {
+	var out []string
+	for _, sc := range v.Scopes {
+		out = append(out, sc.Kind)
+	}
+	return out
+}
+
+func TestViewFor(t *testing.T) {
+	if v := ViewFor(config.MemoryScopesConfig{}, "/ws", "telegram", "telegram:1", "telegram:1"); v.Scoped || v.SearchScopes() != nil || v.Default != ScopeGlobal {
+		t.Fatalf("disabled view=%+v", v)
+	}
+
+	cfg := scopedConfig("", map[string][]string{"discord": {"chat"}})
+	v := ViewFor(cfg, "/ws", "telegram", "telegram:1", "telegram:1")
+	if got := scopeKinds(v); !slices.Equal(got, []string{"global", "user", "chat"}) || v.Default != ScopeUser {
+		t.Fatalf("telegram view=%+v", v)
+	}
+	// A group chat has no user; the default falls back to the chat.
+	v = ViewFor(cfg, "/ws", "telegram", "", "telegram:-100")
+	if got := scopeKinds(v); !slices.Equal(got, []string{"global", "chat"}) || v.Default != ScopeChat {
+		t.Fatalf("group view=%+v", v)
+	}
+	v = ViewFor(cfg, "/ws", "discord", "discord:9", "discord:c9")
+	if got := scopeKinds(v); !slices.Equal(got, []string{"chat"}) || v.Default != ScopeChat {
+		t.Fatalf("discord view=%+v", v)
+	}
+
+	if got := (Scope{Kind: ScopeChat, Key: "slack:C1/../x"}).Dir(); got != "memory/chats/slack_C1_.._x" {
+		t.Fatalf("dir=%s", got)
+	}
+}
+
+func TestViewApplyFacts_RoutesToScopes(t *testing.T) {
+	ws := t.TempDir()
+	cfg := scopedConfig("user", nil)
+	alice := ViewFor(cfg, ws, "telegram", "telegram:1", "telegram:1")
+	bob := ViewFor(cfg, ws, "slack", "slack:U2", "slack:D2")
+
+	diff, err := alice.ApplyFacts([]FactChange{
+		{Text: "Alice is vegetarian"},
+		{Text: "Project Nebula ships in May", Scope: "global"},
+		{Text: "This chat plans the Lisbon trip", Scope: "chat"},
+	}, "telegram:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, f := range diff.Added {
+		ids = append(ids, f.ID)
+	}
+	if !slices.Equal(ids, []string{"f1", "u1", "c1"}) {
+		t.Fatalf("ids=%v", ids)
+	}
+	if _, err := os.Stat(filepath.Join(ws, "memory", "users", "telegram_1", "MEMORY.md")); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := alice.Context()
+	if !strings.Contains(ctx, "Alice is vegetarian") || !strings.Contains(ctx, "Lisbon") || !strings.Contains(ctx, "Nebula") {
+		t.Fatalf("alice context=%s", ctx)
+	}
+	ctx = bob.Context()
+	if strings.Contains(ctx, "vegetarian") || strings.Contains(ctx, "Lisbon") || !strings.Contains(ctx, "Nebula") {
+		t.Fatalf("bob context=%s", ctx)
+	}
+
+	// Bob cannot touch Alice's facts, nor update a chat fact without a chat.
+	diff, err = bob.ApplyFacts([]FactChange{{ID: "u1", Forget: true}}, "slack:D2")
+	if err != nil || len(diff.Forgotten) != 0 || !slices.Equal(diff.Unknown, []string{"u1"}) {
+		t.Fatalf("diff=%+v err=%v", diff, err)
+	}
+	diff, err = alice.ApplyFacts([]FactChange{{ID: "u1", Text: "Alice is vegan"}}, "telegram:1")
+	if err != nil || len(diff.Updated) != 1 {
+		t.Fatalf("diff=%+v err=%v", diff, err)
+	}
+
+	if err := alice.AppendHistory("[2026-03-01 10:00] Planned Lisbon."); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(ws, "memory", "chats", "telegram_1", "HISTORY.md")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIndexManager_SearchScopes(t *testing.T) {
+	ws := t.TempDir()
+	writeFile(t, filepath.Join(ws, "MEMORY.md"), "- team lunch is on Fridays\n")
+	writeFile(t, filepath.Join(ws, "memory", "users", "telegram_1", "MEMORY.md"), "- alice lunch is vegetarian\n")
+	writeFile(t, filepath.Join(ws, "memory", "users", "slack_U2", "MEMORY.md"), "- bob lunch is sushi\n")
+	cfg := config.Default()
+	enabled := true
+	cfg.Agents.Defaults.MemorySearch.Enabled = &enabled
+	cfg.Agents.Defaults.MemorySearch.Provider = "hash"
+	mgr, err := NewIndexManager(cfg, ws)
+	if err != nil {
+		t.Fatalf("NewIndexManager error: %v", err)
+	}
+	t.Cleanup(func() { _ = mgr.Close() })
+
+	paths := func(scopes []Scope) []string {
+		results, err := mgr.Search(context.Background(), "lunch", SearchOptions{MinScore: 0.01, Scopes: scopes})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []string
+		for _, r := range results {
+			out = append(out, r.Path)
+		}
+		slices.Sort(out)
+		return out
+	}
+	if got := paths(nil); len(got) != 3 {
+		t.Fatalf("unscoped=%v", got)
+	}
+	alice := ViewFor(scopedConfig("", nil), ws, "telegram", "telegram:1", "telegram:1")
+	if got := paths(alice.SearchScopes()); !slices.Equal(got, []string{"MEMORY.md", "memory/users/telegram_1/MEMORY.md"}) {
+		t.Fatalf("alice=%v", got)
+	}
+
+	if _, _, err := mgr.ReadFile("memory/users/slack_U2/MEMORY.md", ReadFileOptions{Scopes: alice.SearchScopes()}); err == nil {
+		t.Fatal("read another user's memory")
+	}
+	if text, _, err := mgr.ReadFile("memory/users/telegram_1/MEMORY.md", ReadFileOptions{Scopes: alice.SearchScopes()}); err != nil || !strings.Contains(text, "vegetarian") {
+		t.Fatalf("text=%q err=%v", text, err)
+	}
+}
+
+func TestIndexManager_SearchScopesBeyondCandidates(t *testing.T) {
+	ws := t.TempDir()
+	for i := range 20 {
+		writeFile(t, filepath.Join(ws, "memory", "users", fmt.Sprintf("slack_U%d", i), "MEMORY.md"), "- lunch lunch lunch\n")
+	}
+	writeFile(t, filepath.Join(ws, "memory", "users", "telegram_1", "MEMORY.md"), "- alice eats lunch at noon with the design team\n")
+	cfg := config.Default()
+	enabled := true
+	cfg.Agents.Defaults.MemorySearch.Enabled = &enabled
+	cfg.Agents.Defaults.MemorySearch.Provider = "hash"
+	mgr, err := NewIndexManager(cfg, ws)
+	if err != nil {
+		t.Fatalf("NewIndexManager error: %v", err)
+	}
+	t.Cleanup(func() { _ = mgr.Close() })
+
+	alice := ViewFor(scopedConfig("", nil), ws, "telegram", "telegram:1", "telegram:1")
+	results, err := mgr.Search(context.Background(), "lunch", SearchOptions{MaxResults: 1, MinScore: 0.01, Scopes: alice.SearchScopes()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Path != "memory/users/telegram_1/MEMORY.md" {
+		t.Fatalf("results=%+v", results)
+	}
+}
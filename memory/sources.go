@@ -0,0 +1,234 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mosaxiv/clawlet/session"
+)
+
+// Sources of indexed content, reported in SearchResult.Source.
+const (
+	SourceMemory    = "memory"    // MEMORY.md and memory/
+	SourceWorkspace = "workspace" // memorySearch.sources.paths
+	SourceSession   = "session"   // chat transcripts
+)
+
+// sessionPathPrefix marks the paths of session transcripts, which live
+// outside the workspace.
+const sessionPathPrefix = "sessions:"
+
+// maxSourceFileBytes skips files too large to be notes (logs, dumps).
+const maxSourceFileBytes = 2 << 20
+
+// checkGlob rejects patterns that could reach outside the workspace.
+func checkGlob(pattern string) error {
+	p := filepath.ToSlash(strings.TrimSpace(pattern))
+	if p == "" || path.IsAbs(p) || filepath.IsAbs(pattern) {
+		return fmt.Errorf("memorySearch.sources.paths: %q must be relative to the workspace", pattern)
+	}
+	for seg := range strings.SplitSeq(p, "/") {
+		if seg == ".." {
+			return fmt.Errorf("memorySearch.sources.paths: %q must stay inside the workspace", pattern)
+		}
+		if seg != "**" {
+			if _, err := path.Match(seg, ""); err != nil {
+				return fmt.Errorf("memorySearch.sources.paths: %q: %w", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// matchGlob reports whether the slash-separated rel matches pattern, where
+// "**" stands for zero or more directories.
+func matchGlob(pattern, rel string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(rel, "/"))
+}
+
+func matchSegments(pat, name []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pat[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], name[0]); !ok {
+			return false
+		}
+		pat, name = pat[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// globRoot is the directory below which pattern can match: its leading
+// segments without wildcards.
+func globRoot(pattern string) string {
+	segs := strings.Split(pattern, "/")
+	var fixed []string
+	for _, s := range segs[:len(segs)-1] {
+		if s == "**" || strings.ContainsAny(s, `*?[\`) {
+			break
+		}
+		fixed = append(fixed, s)
+	}
+	return strings.Join(fixed, "/")
+}
+
+// listWorkspaceSourcePaths returns the regular files of workspace matching
+// any of patterns. Symlinks and the index directory are skipped.
+func listWorkspaceSourcePaths(workspace string, patterns []string) []string {
+	seen := map[string]struct{}{}
+	var out []string
+	for _, pattern := range patterns {
+		root := filepath.Join(workspace, filepath.FromSlash(globRoot(pattern)))
+		_ = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.Type()&os.ModeSymlink != 0 {
+				return nil
+			}
+			rel, err := filepath.Rel(workspace, p)
+			if err != nil {
+				return nil
+			}
+			rel = filepath.ToSlash(rel)
+			if d.IsDir() {
+				if d.Name() == ".memory" || d.Name() == ".git" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !matchGlob(pattern, rel) {
+				return nil
+			}
+			if _, ok := seen[rel]; ok {
+				return nil
+			}
+			seen[rel] = struct{}{}
+			out = append(out, p)
+			return nil
+		})
+	}
+	sort.Strings(out)
+	return out
+}
+
+// listSessionFiles returns the transcripts in dir and dir/archive keyed by
+// their index path ("sessions:telegram_123.jsonl").
+func listSessionFiles(dir string) map[string]string {
+	out := map[string]string{}
+	if dir == "" {
+		return out
+	}
+	for _, sub := range []string{"", "archive"} {
+		entries, err := os.ReadDir(filepath.Join(dir, sub))
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if !e.Type().IsRegular() || !strings.HasSuffix(e.Name(), ".jsonl") {
+				continue
+			}
+			out[sessionPathPrefix+path.Join(sub, e.Name())] = filepath.Join(dir, sub, e.Name())
+		}
+	}
+	return out
+}
+
+// sessionFile resolves an index path of a transcript below dir.
+func sessionFile(dir, rel string) (string, error) {
+	name, ok := strings.CutPrefix(rel, sessionPathPrefix)
+	if !ok || dir == "" {
+		return "", errors.New("path required")
+	}
+	clean := path.Clean(name)
+	if clean != name || strings.HasPrefix(clean, "../") || !strings.HasSuffix(clean, ".jsonl") ||
+		(path.Dir(clean) != "." && path.Dir(clean) != "archive") {
+		return "", errors.New("path required")
+	}
+	return filepath.Join(dir, filepath.FromSlash(clean)), nil
+}
+
+// chunkSession splits a transcript into one chunk per turn: a user message
+// and the replies up to the next one. Tool traffic is left out. Line numbers
+// are message numbers (1-based); turns longer than a chunk are split.
+func chunkSession(s *session.Session, tokens int) []chunkEntry {
+	var chunks []chunkEntry
+	var b strings.Builder
+	start, end := 0, 0
+	flush := func() {
+		text := strings.TrimSpace(b.String())
+		b.Reset()
+		if text == "" {
+			return
+		}
+		for _, c := range chunkMarkdown(text, tokens, 0) {
+			c.StartLine, c.EndLine = start, end
+			chunks = append(chunks, c)
+		}
+	}
+	for i, m := range s.Messages {
+		if m.Role == "user" {
+			flush()
+			start = i + 1
+		}
+		if m.Role != "user" && m.Role != "assistant" {
+			continue
+		}
+		content := strings.TrimSpace(m.Content)
+		if content == "" {
+			continue
+		}
+		if start == 0 {
+			start = i + 1
+		}
+		end = i + 1
+		b.WriteString(formatSessionMessage(m))
+		b.WriteString("\n")
+	}
+	flush()
+	return chunks
+}
+
+// formatSessionMessage renders m as "[2026-02-14 10:03] user: text".
+func formatSessionMessage(m session.Message) string {
+	line := m.Role + ": " + strings.TrimSpace(m.Content)
+	if t, err := time.Parse(time.RFC3339Nano, m.Timestamp); err == nil {
+		line = "[" + t.Local().Format("2006-01-02 15:04") + "] " + line
+	}
+	return line
+}
+
+// renderSessionMessages renders messages from..from+lines-1 of a transcript.
+func renderSessionMessages(s *session.Session, opts ReadFileOptions) string {
+	from := max(opts.From, 1) - 1
+	from = min(from, len(s.Messages))
+	to := len(s.Messages)
+	if opts.Lines > 0 {
+		to = min(from+opts.Lines, to)
+	}
+	var b strings.Builder
+	for _, m := range s.Messages[from:to] {
+		if (m.Role != "user" && m.Role != "assistant") || strings.TrimSpace(m.Content) == "" {
+			continue
+		}
+		b.WriteString(formatSessionMessage(m))
+		b.WriteString("\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,189 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/session"
+)
+
+func TestMatchGlob(t *testing.T) {
+	cases := []struct {
+		pattern, rel string
+		want         bool
+	}{
+		{"notes/**/*.md", "notes/a.md", true},
+		{"notes/**/*.md", "notes/2026/02/a.md", true},
+		{"notes/**/*.md", "notes/a.txt", false},
+		{"notes/**/*.md", "other/notes/a.md", false},
+		{"docs/*.txt", "docs/readme.txt", true},
+		{"docs/*.txt", "docs/sub/readme.txt", false},
+		{"**/TODO.md", "TODO.md", true},
+		{"**/TODO.md", "a/b/TODO.md", true},
+	}
+	for _, c := range cases {
+		if got := matchGlob(c.pattern, c.rel); got != c.want {
+			t.Errorf("matchGlob(%q, %q) = %v", c.pattern, c.rel, got)
+		}
+	}
+	for _, bad := range []string{"../secrets/*.md", "/etc/*.conf", "notes/[a.md"} {
+		if err := checkGlob(bad); err == nil {
+			t.Errorf("checkGlob(%q) accepted", bad)
+		}
+	}
+}
+
+func TestChunkSession_OneChunkPerTurn(t *testing.T) {
+	s := session.New("telegram:1")
+	s.Messages = []session.Message{
+		{Role: "user", Content: "Which database should we use?", Timestamp: "2026-01-10T09:00:00Z"},
+		{Role: "assistant", Content: "", ToolCalls: []session.ToolCall{{ID: "1", Name: "web_search"}}},
+		{Role: "tool", Content: "search results", Name: "web_search"},
+		{Role: "assistant", Content: "Let's go with SQLite."},
+		{Role: "user", Content: "ok, thanks"},
+	}
+	chunks := chunkSession(s, 400)
+	if len(chunks) != 2 {
+		t.Fatalf("chunks=%+v", chunks)
+	}
+	if chunks[0].StartLine != 1 || chunks[0].EndLine != 4 || strings.Contains(chunks[0].Text, "search results") ||
+		!strings.Contains(chunks[0].Text, "assistant: Let's go with SQLite.") {
+		t.Fatalf("first turn=%+v", chunks[0])
+	}
+	if chunks[1].StartLine != 5 || chunks[1].EndLine != 5 {
+		t.Fatalf("second turn=%+v", chunks[1])
+	}
+}
+
+func TestIndexManager_ExtraSources(t *testing.T) {
+	ws := t.TempDir()
+	sessDir := t.TempDir()
+	writeFile(t, filepath.Join(ws, "MEMORY.md"), "- likes green tea\n")
+	writeFile(t, filepath.Join(ws, "notes", "2026", "trip.md"), "Flight to Lisbon on March 3rd.\n")
+	writeFile(t, filepath.Join(ws, "docs", "api.txt"), "The billing API uses webhooks.\n")
+	writeFile(t, filepath.Join(ws, "docs", "skip.md"), "Lisbon is not indexed here.\n")
+
+	s := session.New("telegram:42")
+	s.Add("user", "What did we decide about the logo?")
+	s.Add("assistant", "We decided on the orange fox logo.")
+	if err := session.NewJSONLStore(sessDir).Save(s); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Default()
+	enabled := true
+	cfg.Agents.Defaults.MemorySearch.Enabled = &enabled
+	cfg.Agents.Defaults.MemorySearch.Provider = "hash"
+	cfg.Agents.Defaults.MemorySearch.Sources.Paths = []string{"notes/**/*.md", "docs/*.txt"}
+	cfg.Agents.Defaults.MemorySearch.Sources.Sessions.Enabled = &enabled
+	cfg.Agents.Defaults.MemorySearch.Sources.Sessions.Dir = sessDir
+	mgr, err := NewIndexManager(cfg, ws)
+	if err != nil {
+		t.Fatalf("NewIndexManager error: %v", err)
+	}
+	t.Cleanup(func() { _ = mgr.Close() })
+
+	search := func(q string) SearchResult {
+		t.Helper()
+		results, err := mgr.Search(context.Background(), q, SearchOptions{MinScore: 0.01})
+		if err != nil || len(results) == 0 {
+			t.Fatalf("Search(%q) = %+v, %v", q, results, err)
+		}
+		return results[0]
+	}
+	if r := search("Lisbon flight"); r.Path != "notes/2026/trip.md" || r.Source != SourceWorkspace {
+		t.Fatalf("notes result=%+v", r)
+	}
+	if r := search("billing webhooks"); r.Path != "docs/api.txt" {
+		t.Fatalf("docs result=%+v", r)
+	}
+	r := search("decide logo")
+	if r.Source != SourceSession || r.SessionKey != "telegram:42" || r.Path != "sessions:telegram_42.jsonl" || r.StartLine != 1 || r.EndLine != 2 {
+		t.Fatalf("session result=%+v", r)
+	}
+	text, _, err := mgr.ReadFile(r.Path, ReadFileOptions{From: 2, Lines: 1})
+	if err != nil || !strings.Contains(text, "assistant: We decided on the orange fox logo.") || strings.Contains(text, "user:") {
+		t.Fatalf("ReadFile=%q, %v", text, err)
+	}
+	if text, _, err := mgr.ReadFile("docs/api.txt", ReadFileOptions{}); err != nil || !strings.Contains(text, "billing") {
+		t.Fatalf("ReadFile docs=%q, %v", text, err)
+	}
+	for _, p := range []string{"docs/skip.md", "sessions:../secret.jsonl"} {
+		if _, _, err := mgr.ReadFile(p, ReadFileOptions{}); err == nil {
+			t.Fatalf("ReadFile(%q) allowed", p)
+		}
+	}
+
+	// New messages are picked up on the next sync.
+	s.Add("user", "And the mascot name?")
+	s.Add("assistant", "The mascot is called Pixel.")
+	time.Sleep(10 * time.Millisecond)
+	if err := session.NewJSONLStore(sessDir).Save(s); err != nil {
+		t.Fatal(err)
+	}
+	if r := search("mascot Pixel"); r.SessionKey != "telegram:42" || r.StartLine != 3 {
+		t.Fatalf("appended turn=%+v", r)
+	}
+}
+
+func TestIndexManager_SessionSourceNeedsJSONLStore(t *testing.T) {
+	cfg := config.Default()
+	enabled := true
+	cfg.Agents.Defaults.MemorySearch.Enabled = &enabled
+	cfg.Agents.Defaults.MemorySearch.Provider = "hash"
+	cfg.Agents.Defaults.MemorySearch.Sources.Sessions.Enabled = &enabled
+	cfg.Sessions.Store = "sqlite"
+	if mgr, err := NewIndexManager(cfg, t.TempDir()); err == nil {
+		_ = mgr.Close()
+		t.Fatal("expected session indexing with the sqlite store to be rejected")
+	}
+}
+
+func TestIndexManager_MigratesChunksTable(t *testing.T) {
+	ws := t.TempDir()
+	dbPath := filepath.Join(ws, ".memory", "index.sqlite")
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`CREATE TABLE chunks (
+		id TEXT PRIMARY KEY, path TEXT NOT NULL, start_line INTEGER NOT NULL, end_line INTEGER NOT NULL,
+		hash TEXT NOT NULL, model TEXT NOT NULL, text TEXT NOT NULL, embedding TEXT NOT NULL, updated_at INTEGER NOT NULL)`); err != nil {
+		t.Fatal(err)
+	}
+	_ = db.Close()
+	writeFile(t, filepath.Join(ws, "MEMORY.md"), "- project codename is Nebula\n")
+
+	cfg := config.Default()
+	enabled := true
+	cfg.Agents.Defaults.MemorySearch.Enabled = &enabled
+	cfg.Agents.Defaults.MemorySearch.Provider = "hash"
+	mgr, err := NewIndexManager(cfg, ws)
+	if err != nil {
+		t.Fatalf("NewIndexManager error: %v", err)
+	}
+	t.Cleanup(func() { _ = mgr.Close() })
+	results, err := mgr.Search(context.Background(), "codename", SearchOptions{})
+	if err != nil || len(results) == 0 || results[0].Source != SourceMemory {
+		t.Fatalf("results=%+v err=%v", results, err)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
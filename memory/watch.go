@@ -0,0 +1,222 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"os"
+	"slices"
+	"time"
+)
+
+// Watch modes reported in SearchStatus.WatchMode.
+const (
+	watchModeInotify = "inotify"
+	watchModePoll    = "poll"
+)
+
+type watchState struct {
+	mode       string
+	pending    map[string]struct{} // absolute paths waiting for the debounce
+	full       bool                // rescan every source
+	processing int
+}
+
+// watchChange is a changed file, or a request to rescan every source when
+// the watcher lost track (overflow, deleted directory).
+type watchChange struct {
+	path string
+	full bool
+}
+
+// sourceWatcher reports changes below the memory sources until ctx is done.
+type sourceWatcher interface {
+	run(ctx context.Context, changes chan<- watchChange) error
+}
+
+// Watch keeps the index in sync with the memory sources until ctx is done.
+// Changes are picked up with inotify where available and by polling
+// otherwise; after edits settle for the debounce, only the changed files are
+// re-indexed. Errors are reported in Status rather than stopping the watch.
+func (m *IndexManager) Watch(ctx context.Context) error {
+	if m == nil {
+		return nil
+	}
+	w, err := newInotifyWatcher(m)
+	if err != nil {
+		return m.watchWith(ctx, newPollWatcher(m), watchModePoll)
+	}
+	return m.watchWith(ctx, w, watchModeInotify)
+}
+
+func (m *IndexManager) watchWith(ctx context.Context, w sourceWatcher, mode string) error {
+	m.setWatchMode(mode)
+	defer m.setWatchMode("")
+
+	changes := make(chan watchChange, 256)
+	errc := make(chan error, 1)
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() { errc <- w.run(runCtx, changes) }()
+
+	// Catch up with edits made while nothing was watching.
+	if err := m.Sync(ctx, false); err != nil && ctx.Err() == nil {
+		m.setLastError(err)
+	}
+
+	debounce := m.cfg.watchDebounce
+	maxWait := 10 * debounce
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	var first time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errc:
+			if ctx.Err() != nil {
+				return nil
+			}
+			if mode == watchModePoll {
+				return err
+			}
+			// inotify gave up (e.g. out of watches): poll from here on and
+			// rescan for whatever was missed.
+			m.setLastError(err)
+			w, mode = newPollWatcher(m), watchModePoll
+			m.setWatchMode(mode)
+			go func() { errc <- w.run(runCtx, changes) }()
+			m.enqueueChange(watchChange{full: true})
+			timer.Reset(0)
+		case c := <-changes:
+			m.enqueueChange(c)
+			// Keep postponing while edits continue, but not forever.
+			now := time.Now()
+			if first.IsZero() {
+				first = now
+			}
+			timer.Reset(min(debounce, max(maxWait-now.Sub(first), 0)))
+		case <-timer.C:
+			first = time.Time{}
+			m.flushWatchQueue(ctx)
+		}
+	}
+}
+
+func (m *IndexManager) setWatchMode(mode string) {
+	m.watchMu.Lock()
+	defer m.watchMu.Unlock()
+	m.watch.mode = mode
+}
+
+func (m *IndexManager) setLastError(err error) {
+	m.dbMu.Lock()
+	defer m.dbMu.Unlock()
+	m.lastError = err.Error()
+}
+
+func (m *IndexManager) enqueueChange(c watchChange) {
+	m.watchMu.Lock()
+	defer m.watchMu.Unlock()
+	if c.full {
+		m.watch.full = true
+		return
+	}
+	if m.watch.pending == nil {
+		m.watch.pending = map[string]struct{}{}
+	}
+	m.watch.pending[c.path] = struct{}{}
+}
+
+// flushWatchQueue re-indexes the queued files, or every source when a full
+// rescan was requested.
+func (m *IndexManager) flushWatchQueue(ctx context.Context) {
+	m.watchMu.Lock()
+	paths := slices.Sorted(maps.Keys(m.watch.pending))
+	full := m.watch.full
+	m.watch.pending, m.watch.full = nil, false
+	m.watch.processing = len(paths)
+	m.watchMu.Unlock()
+	defer func() {
+		m.watchMu.Lock()
+		m.watch.processing = 0
+		m.watchMu.Unlock()
+	}()
+	if len(paths) == 0 && !full {
+		return
+	}
+
+	m.dbMu.Lock()
+	defer m.dbMu.Unlock()
+	var err error
+	if full {
+		err = m.syncLocked(ctx, false)
+	} else {
+		err = m.syncFilesLocked(ctx, paths)
+	}
+	if err != nil && ctx.Err() == nil {
+		m.lastError = err.Error()
+	}
+}
+
+// pollWatcher finds changes by comparing the size and mtime of every source
+// file between scans.
+type pollWatcher struct {
+	m        *IndexManager
+	interval time.Duration
+	last     map[string]fileStamp
+}
+
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+func newPollWatcher(m *IndexManager) *pollWatcher {
+	return &pollWatcher{m: m, interval: m.cfg.pollInterval, last: m.snapshotSources()}
+}
+
+func (w *pollWatcher) run(ctx context.Context, changes chan<- watchChange) error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		next := w.m.snapshotSources()
+		var changed []string
+		for p, st := range next {
+			if old, ok := w.last[p]; !ok || old != st {
+				changed = append(changed, p)
+			}
+		}
+		for p := range w.last {
+			if _, ok := next[p]; !ok {
+				changed = append(changed, p)
+			}
+		}
+		w.last = next
+		for _, p := range changed {
+			select {
+			case changes <- watchChange{path: p}:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+func (m *IndexManager) snapshotSources() map[string]fileStamp {
+	out := map[string]fileStamp{}
+	files, err := m.listSourceFiles()
+	if err != nil {
+		return out
+	}
+	for _, f := range files {
+		if st, err := os.Lstat(f.AbsPath); err == nil {
+			out[f.AbsPath] = fileStamp{size: st.Size(), modTime: st.ModTime()}
+		}
+	}
+	return out
+}
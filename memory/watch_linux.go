@@ -0,0 +1,218 @@
+//go:build linux
+
+package memory
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+const inotifyMask = syscall.IN_CLOSE_WRITE | syscall.IN_CREATE | syscall.IN_DELETE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF | syscall.IN_MOVE_SELF
+
+// inotifyWatcher watches the directories that can hold sources: the
+// workspace root, memory/, the roots of the source globs and the sessions
+// directory. Directories created later are added as they appear.
+type inotifyWatcher struct {
+	m    *IndexManager
+	fd   int
+	file *os.File
+	dirs map[int]string // watch descriptor -> directory
+	wds  map[string]int
+}
+
+// watchRoot is a directory to watch, with its subdirectories if recursive.
+type watchRoot struct {
+	dir       string
+	recursive bool
+}
+
+func newInotifyWatcher(m *IndexManager) (sourceWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	w := &inotifyWatcher{
+		m:  m,
+		fd: fd,
+		// Non-blocking, so the runtime poller serves Read and Close
+		// interrupts it.
+		file: os.NewFile(uintptr(fd), "inotify"),
+		dirs: map[int]string{},
+		wds:  map[string]int{},
+	}
+	if err := w.addRoots(); err != nil {
+		_ = w.file.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+func (m *IndexManager) watchRoots() []watchRoot {
+	roots := []watchRoot{
+		{dir: m.workspaceDir},
+		{dir: filepath.Join(m.workspaceDir, "memory"), recursive: true},
+	}
+	for _, p := range m.cfg.sourcePaths {
+		roots = append(roots, watchRoot{dir: filepath.Join(m.workspaceDir, filepath.FromSlash(globRoot(p))), recursive: true})
+	}
+	if m.cfg.sessionsDir != "" {
+		roots = append(roots,
+			watchRoot{dir: m.cfg.sessionsDir},
+			watchRoot{dir: filepath.Join(m.cfg.sessionsDir, "archive")})
+	}
+	return roots
+}
+
+// addRoots adds watches for every root. A root that does not exist yet is
+// noticed through its closest existing parent.
+func (w *inotifyWatcher) addRoots() error {
+	for _, r := range w.m.watchRoots() {
+		if st, err := os.Stat(r.dir); err != nil || !st.IsDir() {
+			dir := filepath.Dir(r.dir)
+			for {
+				if st, err := os.Stat(dir); err == nil && st.IsDir() {
+					break
+				}
+				if dir == filepath.Dir(dir) {
+					break
+				}
+				dir = filepath.Dir(dir)
+			}
+			if err := w.add(dir); err != nil {
+				return err
+			}
+			continue
+		}
+		if !r.recursive {
+			if err := w.add(r.dir); err != nil {
+				return err
+			}
+			continue
+		}
+		err := filepath.WalkDir(r.dir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || !d.IsDir() {
+				return nil
+			}
+			if p != r.dir && (d.Name() == ".memory" || d.Name() == ".git") {
+				return filepath.SkipDir
+			}
+			return w.add(p)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *inotifyWatcher) add(dir string) error {
+	if _, ok := w.wds[dir]; ok {
+		return nil
+	}
+	wd, err := syscall.InotifyAddWatch(w.fd, dir, inotifyMask|syscall.IN_ONLYDIR|syscall.IN_DONT_FOLLOW)
+	if err != nil {
+		// Removed in the meantime; its parent reports that.
+		if errors.Is(err, syscall.ENOENT) || errors.Is(err, syscall.ENOTDIR) {
+			return nil
+		}
+		return err
+	}
+	w.dirs[wd] = dir
+	w.wds[dir] = wd
+	return nil
+}
+
+func (w *inotifyWatcher) run(ctx context.Context, changes chan<- watchChange) error {
+	stop := context.AfterFunc(ctx, func() { _ = w.file.Close() })
+	defer stop()
+	defer w.file.Close()
+
+	emit := func(c watchChange) bool {
+		select {
+		case changes <- c:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	buf := make([]byte, 64<<10)
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		for off := 0; off+syscall.SizeofInotifyEvent <= n; {
+			ev := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			name := buf[off+syscall.SizeofInotifyEvent : off+syscall.SizeofInotifyEvent+int(ev.Len)]
+			off += syscall.SizeofInotifyEvent + int(ev.Len)
+			if err := w.handle(int(ev.Wd), ev.Mask, strings.TrimRight(string(name), "\x00"), emit); err != nil {
+				return err
+			}
+			if ctx.Err() != nil {
+				return nil
+			}
+		}
+	}
+}
+
+func (w *inotifyWatcher) handle(wd int, mask uint32, name string, emit func(watchChange) bool) error {
+	if mask&syscall.IN_Q_OVERFLOW != 0 {
+		emit(watchChange{full: true})
+		return nil
+	}
+	dir, ok := w.dirs[wd]
+	if !ok {
+		return nil
+	}
+	if mask&syscall.IN_IGNORED != 0 {
+		delete(w.dirs, wd)
+		delete(w.wds, dir)
+		return nil
+	}
+	if mask&(syscall.IN_DELETE_SELF|syscall.IN_MOVE_SELF) != 0 {
+		emit(watchChange{full: true})
+		return nil
+	}
+	p := filepath.Join(dir, name)
+	if mask&syscall.IN_ISDIR == 0 {
+		emit(watchChange{path: p})
+		return nil
+	}
+	if mask&(syscall.IN_DELETE|syscall.IN_MOVED_FROM) != 0 {
+		// Everything below it is gone; the rescan drops it.
+		emit(watchChange{full: true})
+		return nil
+	}
+	if err := w.addRoots(); err != nil {
+		return err
+	}
+	if _, watched := w.wds[p]; !watched {
+		return nil
+	}
+	// Files may have landed before the watch was in place.
+	return filepath.WalkDir(p, func(fp string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if d.Name() == ".memory" || d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() && !emit(watchChange{path: fp}) {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+}
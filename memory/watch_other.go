@@ -0,0 +1,10 @@
+//go:build !linux
+
+package memory
+
+import "errors"
+
+// newInotifyWatcher is only available on Linux; elsewhere Watch polls.
+func newInotifyWatcher(m *IndexManager) (sourceWatcher, error) {
+	return nil, errors.New("inotify is not supported on this platform")
+}
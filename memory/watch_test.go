@@ -0,0 +1,137 @@
+package memory
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/mosaxiv/clawlet/config"
+)
+
+func newWatchedManager(t *testing.T, ws string, poll bool) *IndexManager {
+	t.Helper()
+	cfg := config.Default()
+	enabled, off := true, false
+	cfg.Agents.Defaults.MemorySearch.Enabled = &enabled
+	cfg.Agents.Defaults.MemorySearch.Provider = "hash"
+	cfg.Agents.Defaults.MemorySearch.Sync.OnSearch = &off
+	cfg.Agents.Defaults.MemorySearch.Sync.WatchDebounceMs = 20
+	cfg.Agents.Defaults.MemorySearch.Sources.Paths = []string{"notes/**/*.md"}
+	mgr, err := NewIndexManager(cfg, ws)
+	if err != nil {
+		t.Fatalf("NewIndexManager error: %v", err)
+	}
+	t.Cleanup(func() { _ = mgr.Close() })
+	if poll {
+		mgr.cfg.pollInterval = 20 * time.Millisecond
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if poll {
+			mgr.watchWith(ctx, newPollWatcher(mgr), watchModePoll)
+			return
+		}
+		_ = mgr.Watch(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	waitFor(t, "watcher start", func() bool {
+		st := mgr.Status(context.Background())
+		return st.Watching && st.LastSyncAtMS > 0
+	})
+	return mgr
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if cond() {
+			return
+		}
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+func hasResult(mgr *IndexManager, query, path string) bool {
+	results, err := mgr.Search(context.Background(), query, SearchOptions{MinScore: 0.01})
+	if err != nil {
+		return false
+	}
+	for _, r := range results {
+		if r.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+func TestIndexManagerWatch(t *testing.T) {
+	for _, mode := range []string{watchModeInotify, watchModePoll} {
+		t.Run(mode, func(t *testing.T) {
+			if mode == watchModeInotify && runtime.GOOS != "linux" {
+				t.Skip("inotify is Linux only")
+			}
+			ws := t.TempDir()
+			writeFile(t, filepath.Join(ws, "MEMORY.md"), "- likes green tea\n")
+			mgr := newWatchedManager(t, ws, mode == watchModePoll)
+			if st := mgr.Status(context.Background()); mode == watchModePoll && st.WatchMode != watchModePoll {
+				t.Fatalf("status=%+v", st)
+			}
+			if !hasResult(mgr, "green tea", "MEMORY.md") {
+				t.Fatal("initial sync missed MEMORY.md")
+			}
+
+			// A new directory and file below a source glob.
+			writeFile(t, filepath.Join(ws, "notes", "2026", "trip.md"), "Flight to Lisbon on March 3rd.\n")
+			waitFor(t, "new note", func() bool { return hasResult(mgr, "Lisbon flight", "notes/2026/trip.md") })
+
+			writeFile(t, filepath.Join(ws, "memory", "2026-01-10.md"), "Adopted a cat named Miso.\n")
+			waitFor(t, "daily note", func() bool { return hasResult(mgr, "cat Miso", "memory/2026-01-10.md") })
+
+			if err := os.Remove(filepath.Join(ws, "notes", "2026", "trip.md")); err != nil {
+				t.Fatal(err)
+			}
+			waitFor(t, "removal", func() bool { return !hasResult(mgr, "Lisbon flight", "notes/2026/trip.md") })
+			waitFor(t, "empty queue", func() bool { return mgr.Status(context.Background()).QueuedFiles == 0 })
+		})
+	}
+}
+
+func TestSyncFilesLocked_OnlyTouchesGivenFiles(t *testing.T) {
+	ws := t.TempDir()
+	writeFile(t, filepath.Join(ws, "MEMORY.md"), "- likes green tea\n")
+	cfg := config.Default()
+	enabled := true
+	cfg.Agents.Defaults.MemorySearch.Enabled = &enabled
+	cfg.Agents.Defaults.MemorySearch.Provider = "hash"
+	mgr, err := NewIndexManager(cfg, ws)
+	if err != nil {
+		t.Fatalf("NewIndexManager error: %v", err)
+	}
+	t.Cleanup(func() { _ = mgr.Close() })
+	if err := mgr.Sync(context.Background(), false); err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, filepath.Join(ws, "memory", "a.md"), "alpha note\n")
+	writeFile(t, filepath.Join(ws, "memory", "b.md"), "beta note\n")
+	writeFile(t, filepath.Join(ws, "other.md"), "not a memory file\n")
+	mgr.dbMu.Lock()
+	err = mgr.syncFilesLocked(context.Background(), []string{
+		filepath.Join(ws, "memory", "a.md"),
+		filepath.Join(ws, "other.md"),
+	})
+	files := queryCount(mgr.db, `SELECT COUNT(*) FROM files`)
+	mgr.dbMu.Unlock()
+	if err != nil || files != 2 {
+		t.Fatalf("files=%d err=%v", files, err)
+	}
+}
@@ -31,6 +31,20 @@ func SessionsDir() string {
 	return filepath.Join(dir, "sessions")
 }
 
+// SessionsArchiveDir holds sessions archived by `clawlet sessions reset`.
+func SessionsArchiveDir() string {
+	return filepath.Join(SessionsDir(), "archive")
+}
+
+// SessionsDBPath is the database used when sessions.store is "sqlite".
+func SessionsDBPath() string {
+	dir, err := ConfigDir()
+	if err != nil {
+		return ".clawlet/sessions.sqlite"
+	}
+	return filepath.Join(dir, "sessions.sqlite")
+}
+
 func CronStorePath() string {
 	dir, err := ConfigDir()
 	if err != nil {
@@ -39,6 +53,15 @@ func CronStorePath() string {
 	return filepath.Join(dir, "cron.json")
 }
 
+// LLMStatusPath stores the provider attempts of the most recent LLM request.
+func LLMStatusPath() string {
+	dir, err := ConfigDir()
+	if err != nil {
+		return ".clawlet/llm-status.json"
+	}
+	return filepath.Join(dir, "llm-status.json")
+}
+
 func WorkspaceDir() string {
 	dir, err := ConfigDir()
 	if err != nil {
@@ -0,0 +1,138 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+)
+
+// Export formats.
+const (
+	FormatMarkdown = "md"
+	FormatJSON     = "json"
+	FormatHTML     = "html"
+)
+
+type exportSession struct {
+	Key       string    `json:"key"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Messages  []Message `json:"messages"`
+}
+
+// Export writes s to w as Markdown, JSON or HTML.
+func Export(w io.Writer, s *Session, format string) error {
+	s.mu.Lock()
+	es := exportSession{
+		Key:       s.Key,
+		CreatedAt: s.CreatedAt,
+		UpdatedAt: s.UpdatedAt,
+		Messages:  cloneMessages(s.Messages),
+	}
+	s.mu.Unlock()
+
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case FormatMarkdown, "markdown":
+		return exportMarkdown(w, es)
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(es)
+	case FormatHTML:
+		return htmlTemplate.Execute(w, es)
+	default:
+		return fmt.Errorf("unknown export format: %s (expected md, json or html)", format)
+	}
+}
+
+func exportMarkdown(w io.Writer, s exportSession) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Session %s\n\n", s.Key)
+	fmt.Fprintf(&b, "- Created: %s\n- Updated: %s\n- Messages: %d\n", s.CreatedAt.Format(time.RFC3339), s.UpdatedAt.Format(time.RFC3339), len(s.Messages))
+	for _, m := range s.Messages {
+		fmt.Fprintf(&b, "\n## %s", messageTitle(m))
+		if ts := formatTimestamp(m.Timestamp); ts != "" {
+			fmt.Fprintf(&b, " · %s", ts)
+		}
+		b.WriteString("\n\n")
+		if m.Role == "tool" {
+			b.WriteString(fence(m.Content))
+		} else if strings.TrimSpace(m.Content) != "" {
+			b.WriteString(strings.TrimSpace(m.Content) + "\n")
+		}
+		for _, tc := range m.ToolCalls {
+			fmt.Fprintf(&b, "\nCall `%s`:\n\n%s", tc.Name, fence(tc.Arguments))
+		}
+		if len(m.ToolsUsed) > 0 {
+			fmt.Fprintf(&b, "\n_Tools used: %s_\n", strings.Join(m.ToolsUsed, ", "))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// messageTitle is "user", "assistant", or "tool result (exec)".
+func messageTitle(m Message) string {
+	switch {
+	case m.Role == "tool" && m.Name != "":
+		return "tool result (" + m.Name + ")"
+	case m.Role == "tool":
+		return "tool result"
+	case m.Role == "":
+		return "unknown"
+	default:
+		return m.Role
+	}
+}
+
+func formatTimestamp(ts string) string {
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return strings.TrimSpace(ts)
+	}
+	return t.Format("2006-01-02 15:04:05 MST")
+}
+
+func fence(s string) string {
+	f := "```"
+	for strings.Contains(s, f) {
+		f += "`"
+	}
+	return f + "\n" + strings.TrimRight(s, "\n") + "\n" + f + "\n"
+}
+
+var htmlTemplate = template.Must(template.New("session").Funcs(template.FuncMap{
+	"title": messageTitle,
+	"ts":    formatTimestamp,
+	"join":  strings.Join,
+	"date":  func(t time.Time) string { return t.Format(time.RFC3339) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Session {{.Key}}</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 52rem; margin: 2rem auto; padding: 0 1rem; color: #222; }
+.msg { border-radius: 8px; padding: .6rem .9rem; margin: .8rem 0; background: #f4f4f5; }
+.user { background: #e0f2fe; }
+.tool { background: #fafafa; border: 1px solid #e4e4e7; }
+.meta { font-size: .8rem; color: #666; margin-bottom: .3rem; }
+pre { white-space: pre-wrap; word-break: break-word; margin: .3rem 0; }
+.tools { font-size: .8rem; color: #555; font-style: italic; }
+</style>
+</head>
+<body>
+<h1>Session {{.Key}}</h1>
+<p class="meta">Created {{date .CreatedAt}} · Updated {{date .UpdatedAt}} · {{len .Messages}} messages</p>
+{{range .Messages}}<div class="msg {{.Role}}">
+<div class="meta">{{title .}}{{with ts .Timestamp}} · {{.}}{{end}}</div>
+{{if .Content}}<pre>{{.Content}}</pre>{{end}}
+{{range .ToolCalls}}<div class="meta">call {{.Name}}</div><pre>{{.Arguments}}</pre>{{end}}
+{{if .ToolsUsed}}<div class="tools">Tools used: {{join .ToolsUsed ", "}}</div>{{end}}
+</div>
+{{end}}</body>
+</html>
+`))
@@ -0,0 +1,73 @@
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func exportFixture() *Session {
+	s := New("telegram:42")
+	s.AddMessages([]Message{
+		{Role: "user", Content: "show <b>files</b>", Timestamp: "2026-03-01T10:00:00Z"},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "c1", Name: "list_dir", Arguments: `{"path":"."}`}}, Timestamp: "2026-03-01T10:00:01Z"},
+		{Role: "tool", Name: "list_dir", ToolCallID: "c1", Content: "a.txt\nb.txt", Timestamp: "2026-03-01T10:00:02Z"},
+		{Role: "assistant", Content: "a.txt and b.txt", ToolsUsed: []string{"list_dir"}, Timestamp: "2026-03-01T10:00:03Z"},
+	})
+	return s
+}
+
+func TestExport_Markdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Export(&buf, exportFixture(), FormatMarkdown); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"# Session telegram:42",
+		"## user · 2026-03-01 10:00:00 UTC",
+		"Call `list_dir`:",
+		"## tool result (list_dir)",
+		"```\na.txt\nb.txt\n```",
+		"_Tools used: list_dir_",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestExport_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Export(&buf, exportFixture(), FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	var got exportSession
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Key != "telegram:42" || len(got.Messages) != 4 || got.Messages[3].ToolsUsed[0] != "list_dir" {
+		t.Fatalf("got=%+v", got)
+	}
+}
+
+func TestExport_HTMLEscapes(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Export(&buf, exportFixture(), FormatHTML); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if strings.Contains(out, "<b>files</b>") || !strings.Contains(out, "&lt;b&gt;files&lt;/b&gt;") {
+		t.Fatalf("content not escaped:\n%s", out)
+	}
+	if !strings.Contains(out, "Tools used: list_dir") || !strings.Contains(out, "2026-03-01 10:00:03 UTC") {
+		t.Fatalf("missing annotations:\n%s", out)
+	}
+}
+
+func TestExport_UnknownFormat(t *testing.T) {
+	if err := Export(&bytes.Buffer{}, New("x"), "pdf"); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,360 @@
+package session
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type metadataLine struct {
+	Type      string         `json:"_type"`
+	Key       string         `json:"key,omitempty"`
+	CreatedAt string         `json:"created_at"`
+	UpdatedAt string         `json:"updated_at"`
+	Metadata  map[string]any `json:"metadata"`
+}
+
+// maxLineBytes bounds a single JSONL line (a message with a large tool result).
+const maxLineBytes = 16 << 20
+
+// JSONLStore keeps one JSONL file per session under Dir: a metadata line
+// followed by one line per message. New messages are appended; the file is
+// only rewritten (atomically) after the session was compacted or its
+// metadata changed.
+type JSONLStore struct {
+	Dir string
+
+	mu sync.Mutex
+}
+
+func NewJSONLStore(dir string) *JSONLStore {
+	return &JSONLStore{Dir: dir}
+}
+
+func (st *JSONLStore) Load(key string) (*Session, error) {
+	return Load(st.Dir, key)
+}
+
+func (st *JSONLStore) Save(s *Session) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	msgs, ok := s.unsaved()
+	path := sessionPath(st.Dir, s.Key)
+	_, statErr := os.Stat(path)
+	if s.stored && errors.Is(statErr, fs.ErrNotExist) {
+		return ErrDeleted
+	}
+	if ok && s.persisted > 0 && !s.metaDirty && statErr == nil {
+		if len(msgs) == 0 {
+			return nil
+		}
+		if err := appendMessages(path, msgs); err != nil {
+			return err
+		}
+		s.markSaved()
+		return nil
+	}
+	return writeSessionLocked(st.Dir, s)
+}
+
+func (st *JSONLStore) List(q Query) ([]Info, error) {
+	entries, err := os.ReadDir(st.Dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var out []Info
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".jsonl") {
+			continue
+		}
+		s, err := loadFile(filepath.Join(st.Dir, e.Name()), legacyKey(e.Name()))
+		if err != nil || s == nil {
+			continue
+		}
+		info := Info{Key: s.Key, CreatedAt: s.CreatedAt, UpdatedAt: s.UpdatedAt, Messages: len(s.Messages)}
+		if q.match(info) {
+			out = append(out, info)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].UpdatedAt.After(out[j].UpdatedAt) })
+	if q.Limit > 0 && len(out) > q.Limit {
+		out = out[:q.Limit]
+	}
+	return out, nil
+}
+
+func (st *JSONLStore) Delete(key string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if err := os.Remove(sessionPath(st.Dir, key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (st *JSONLStore) Exists(key string) (bool, error) {
+	_, err := os.Stat(sessionPath(st.Dir, key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (st *JSONLStore) Close() error { return nil }
+
+func sessionPath(dir, key string) string {
+	return filepath.Join(dir, safeFilename(strings.ReplaceAll(key, ":", "_"))+".jsonl")
+}
+
+// legacyKey guesses the key of a session file written before keys were
+// stored in the metadata line ("telegram_123.jsonl" -> "telegram:123").
+func legacyKey(name string) string {
+	return strings.Replace(strings.TrimSuffix(name, ".jsonl"), "_", ":", 1)
+}
+
+func Load(dir, key string) (*Session, error) {
+	s, err := loadFile(sessionPath(dir, key), key)
+	if s != nil {
+		s.Key = key
+	}
+	return s, err
+}
+
+// ReadFile loads a session file by path, such as an archived session. It
+// returns nil if the file does not exist.
+func ReadFile(path string) (*Session, error) {
+	return loadFile(path, legacyKey(filepath.Base(path)))
+}
+
+func loadFile(path, key string) (*Session, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	s := &Session{
+		Key:      key,
+		Messages: []Message{},
+		Metadata: map[string]any{},
+	}
+
+	// Files from older versions lack the key; rewrite them on the next save.
+	s.rewrite = true
+	var lastMessageAt time.Time
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), maxLineBytes)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		var raw map[string]any
+		if err := json.Unmarshal([]byte(line), &raw); err != nil {
+			// e.g. a line cut short by a crash while appending
+			continue
+		}
+		if raw["_type"] == "metadata" {
+			var ml metadataLine
+			if err := json.Unmarshal([]byte(line), &ml); err == nil {
+				if ml.Key != "" {
+					s.Key = ml.Key
+					s.rewrite = false
+				}
+				if t, err := time.Parse(time.RFC3339Nano, ml.CreatedAt); err == nil {
+					s.CreatedAt = t
+				}
+				if t, err := time.Parse(time.RFC3339Nano, ml.UpdatedAt); err == nil {
+					s.UpdatedAt = t
+				}
+				if ml.Metadata != nil {
+					s.Metadata = ml.Metadata
+				}
+			}
+			continue
+		}
+		var m Message
+		if err := json.Unmarshal([]byte(line), &m); err == nil {
+			s.Messages = append(s.Messages, m)
+			if t, err := time.Parse(time.RFC3339Nano, m.Timestamp); err == nil {
+				lastMessageAt = t
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	// The metadata line is not rewritten on append; appended messages carry
+	// the latest activity.
+	if lastMessageAt.After(s.UpdatedAt) {
+		s.UpdatedAt = lastMessageAt
+	}
+	if s.CreatedAt.IsZero() {
+		s.CreatedAt = time.Now()
+	}
+	if s.UpdatedAt.IsZero() {
+		s.UpdatedAt = time.Now()
+	}
+	s.persisted = len(s.Messages)
+	s.stored = true
+	return s, nil
+}
+
+// Save rewrites the whole session file.
+func Save(dir string, s *Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return writeSessionLocked(dir, s)
+}
+
+func writeSessionLocked(dir string, s *Session) error {
+	if err := writeSessionFile(sessionPath(dir, s.Key), s); err != nil {
+		return err
+	}
+	s.markSaved()
+	return nil
+}
+
+// writeSessionFile writes s to a temporary file and renames it over path, so
+// a crash never leaves a truncated session behind.
+func writeSessionFile(path string, s *Session) error {
+	tmp, err := writeSessionTemp(filepath.Dir(path), s)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// createSessionFile is like writeSessionFile but fails with fs.ErrExist
+// instead of replacing an existing file.
+func createSessionFile(path string, s *Session) error {
+	tmp, err := writeSessionTemp(filepath.Dir(path), s)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	return os.Link(tmp, path)
+}
+
+// writeSessionTemp writes s to a new temporary file in dir and returns its
+// path.
+func writeSessionTemp(dir string, s *Session) (string, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+
+	f, err := os.CreateTemp(dir, ".session-*.tmp")
+	if err != nil {
+		return "", err
+	}
+	tmp := f.Name()
+	fail := func(err error) (string, error) {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return "", err
+	}
+	bw := bufio.NewWriter(f)
+
+	meta := metadataLine{
+		Type:      "metadata",
+		Key:       s.Key,
+		CreatedAt: s.CreatedAt.Format(time.RFC3339Nano),
+		UpdatedAt: s.UpdatedAt.Format(time.RFC3339Nano),
+		Metadata:  s.Metadata,
+	}
+	if b, err := json.Marshal(meta); err == nil {
+		if _, err := bw.Write(append(b, '\n')); err != nil {
+			return fail(err)
+		}
+	}
+
+	for _, m := range s.Messages {
+		if b, err := json.Marshal(m); err == nil {
+			if _, err := bw.Write(append(b, '\n')); err != nil {
+				return fail(err)
+			}
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return fail(err)
+	}
+	if err := f.Sync(); err != nil {
+		return fail(err)
+	}
+	if err := f.Chmod(0o600); err != nil {
+		return fail(err)
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return "", err
+	}
+	return tmp, nil
+}
+
+func appendMessages(path string, msgs []Message) error {
+	var buf []byte
+	for _, m := range msgs {
+		b, err := json.Marshal(m)
+		if err != nil {
+			continue
+		}
+		buf = append(buf, b...)
+		buf = append(buf, '\n')
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_RDWR, 0o600)
+	if err != nil {
+		return err
+	}
+	// Start on a fresh line if a previous append was cut short.
+	if st, err := f.Stat(); err == nil && st.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, st.Size()-1); err == nil && last[0] != '\n' {
+			buf = append([]byte{'\n'}, buf...)
+		}
+	}
+	if _, err := f.Write(buf); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+var safeRe = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+func safeFilename(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "default"
+	}
+	s = safeRe.ReplaceAllString(s, "_")
+	s = strings.Trim(s, "._-")
+	if s == "" {
+		return "default"
+	}
+	return s
+}
@@ -0,0 +1,169 @@
+package session
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+)
+
+// DefaultMaxCached is the number of sessions Manager keeps in memory when
+// MaxCached is not set.
+const DefaultMaxCached = 256
+
+// Manager caches sessions loaded from a Store. The least recently used
+// sessions are evicted once more than MaxCached are loaded; they are saved
+// after every turn, so eviction only costs a reload. A cached session that
+// was deleted from the store by another process (`clawlet sessions reset`)
+// is dropped on its next use.
+type Manager struct {
+	Store     Store
+	MaxCached int
+
+	mu    sync.Mutex
+	cache map[string]*list.Element
+	lru   *list.List // front = most recently used *Session
+}
+
+func NewManager(store Store, maxCached int) *Manager {
+	if maxCached <= 0 {
+		maxCached = DefaultMaxCached
+	}
+	return &Manager{
+		Store:     store,
+		MaxCached: maxCached,
+		cache:     map[string]*list.Element{},
+		lru:       list.New(),
+	}
+}
+
+func (m *Manager) GetOrCreate(key string) (*Session, error) {
+	m.mu.Lock()
+	s := m.getLocked(key)
+	m.mu.Unlock()
+	if s != nil {
+		s.mu.Lock()
+		stored := s.stored
+		s.mu.Unlock()
+		if !stored {
+			return s, nil
+		}
+		ok, err := m.Store.Exists(key)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return s, nil
+		}
+		m.forget(s)
+	}
+
+	s, err := m.Store.Load(key)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		s = New(key)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// Another caller may have loaded the same session meanwhile.
+	if cached := m.getLocked(key); cached != nil {
+		return cached, nil
+	}
+	m.putLocked(s)
+	return s, nil
+}
+
+// Save persists s and caches it. A session that was deleted from the store,
+// or replaced in the cache by a newer one (after a reset), is not saved:
+// ErrDeleted is returned and s is dropped.
+func (m *Manager) Save(s *Session) error {
+	m.mu.Lock()
+	stale := false
+	if el, ok := m.cache[s.Key]; ok && el.Value.(*Session) != s {
+		stale = true
+	}
+	m.mu.Unlock()
+	if stale {
+		return ErrDeleted
+	}
+	if err := m.Store.Save(s); err != nil {
+		if errors.Is(err, ErrDeleted) {
+			m.forget(s)
+		}
+		return err
+	}
+	m.mu.Lock()
+	m.putLocked(s)
+	m.mu.Unlock()
+	return nil
+}
+
+// forget drops s from the cache unless another session replaced it.
+func (m *Manager) forget(s *Session) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if el, ok := m.cache[s.Key]; ok && el.Value.(*Session) == s {
+		m.lru.Remove(el)
+		delete(m.cache, s.Key)
+	}
+}
+
+// Forget drops key from the cache so the next GetOrCreate reloads it.
+func (m *Manager) Forget(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if el, ok := m.cache[key]; ok {
+		m.lru.Remove(el)
+		delete(m.cache, key)
+	}
+}
+
+// Reset archives the session stored under key to archiveDir and drops it,
+// so the next GetOrCreate starts an empty session. It returns the archive
+// path, or "" when the session had no messages.
+func (m *Manager) Reset(key, archiveDir string) (string, error) {
+	s, err := m.Store.Load(key)
+	if err != nil {
+		return "", err
+	}
+	m.Forget(key)
+	if s == nil {
+		return "", nil
+	}
+	if len(s.Messages) == 0 {
+		return "", m.Store.Delete(key)
+	}
+	return Archive(m.Store, key, archiveDir)
+}
+
+// Cached returns the number of sessions held in memory.
+func (m *Manager) Cached() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lru.Len()
+}
+
+func (m *Manager) getLocked(key string) *Session {
+	el, ok := m.cache[key]
+	if !ok {
+		return nil
+	}
+	m.lru.MoveToFront(el)
+	return el.Value.(*Session)
+}
+
+func (m *Manager) putLocked(s *Session) {
+	if el, ok := m.cache[s.Key]; ok {
+		el.Value = s
+		m.lru.MoveToFront(el)
+		return
+	}
+	m.cache[s.Key] = m.lru.PushFront(s)
+	for m.lru.Len() > m.MaxCached {
+		oldest := m.lru.Back()
+		m.lru.Remove(oldest)
+		delete(m.cache, oldest.Value.(*Session).Key)
+	}
+}
@@ -0,0 +1,130 @@
+package session
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestManager_EvictsLeastRecentlyUsed(t *testing.T) {
+	st := NewJSONLStore(t.TempDir())
+	m := NewManager(st, 2)
+
+	a, _ := m.GetOrCreate("cli:a")
+	a.Add("user", "from a")
+	if err := m.Save(a); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.GetOrCreate("cli:b"); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := m.GetOrCreate("cli:a"); again != a {
+		t.Fatal("expected cached session")
+	}
+	if _, err := m.GetOrCreate("cli:c"); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Cached(); got != 2 {
+		t.Fatalf("cached=%d", got)
+	}
+
+	// b was least recently used and is gone; a is still cached.
+	if again, _ := m.GetOrCreate("cli:a"); again != a {
+		t.Fatal("a was evicted")
+	}
+	m.Forget("cli:a")
+	reloaded, err := m.GetOrCreate("cli:a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded == a || len(reloaded.Messages) != 1 {
+		t.Fatalf("reloaded=%+v", reloaded.Messages)
+	}
+}
+
+func TestManager_Reset(t *testing.T) {
+	st := NewJSONLStore(t.TempDir())
+	m := NewManager(st, 4)
+	archive := t.TempDir()
+
+	if path, err := m.Reset("cli:none", archive); err != nil || path != "" {
+		t.Fatalf("path=%q err=%v", path, err)
+	}
+
+	s, _ := m.GetOrCreate("cli:a")
+	s.Add("user", "hello")
+	if err := m.Save(s); err != nil {
+		t.Fatal(err)
+	}
+	path, err := m.Reset("cli:a", archive)
+	if err != nil || path == "" {
+		t.Fatalf("path=%q err=%v", path, err)
+	}
+	fresh, err := m.GetOrCreate("cli:a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fresh == s || len(fresh.Messages) != 0 {
+		t.Fatalf("expected an empty session, got %+v", fresh.Messages)
+	}
+}
+
+func TestManager_DropsSessionsDeletedFromStore(t *testing.T) {
+	for name, st := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			m := NewManager(st, 4)
+			s, _ := m.GetOrCreate("telegram:9")
+			s.Add("user", "secret plan")
+			if err := m.Save(s); err != nil {
+				t.Fatal(err)
+			}
+
+			// Another process (clawlet sessions reset) archives the session.
+			if _, err := Archive(st, "telegram:9", t.TempDir()); err != nil {
+				t.Fatal(err)
+			}
+			// A turn still holding the cached session must not restore it.
+			s.Add("assistant", "more about the plan")
+			if err := m.Save(s); !errors.Is(err, ErrDeleted) {
+				t.Fatalf("save err=%v", err)
+			}
+			if gone, _ := st.Load("telegram:9"); gone != nil {
+				t.Fatalf("session restored: %+v", gone.Messages)
+			}
+
+			fresh, err := m.GetOrCreate("telegram:9")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fresh == s || len(fresh.Messages) != 0 {
+				t.Fatalf("fresh=%+v", fresh.Messages)
+			}
+		})
+	}
+}
+
+func TestManager_GetOrCreateNoticesDeletion(t *testing.T) {
+	for name, st := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			m := NewManager(st, 4)
+			s, _ := m.GetOrCreate("telegram:9")
+			s.Add("user", "secret plan")
+			if err := m.Save(s); err != nil {
+				t.Fatal(err)
+			}
+			if err := st.Delete("telegram:9"); err != nil {
+				t.Fatal(err)
+			}
+			fresh, err := m.GetOrCreate("telegram:9")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fresh == s || len(fresh.History(10)) != 0 {
+				t.Fatalf("cached history survived deletion: %+v", fresh.Messages)
+			}
+			// The stale session can no longer be saved over the fresh one.
+			if err := m.Save(s); !errors.Is(err, ErrDeleted) {
+				t.Fatalf("save err=%v", err)
+			}
+		})
+	}
+}
@@ -1,11 +1,6 @@
 package session
 
 import (
-	"bufio"
-	"encoding/json"
-	"os"
-	"path/filepath"
-	"regexp"
 	"strings"
 	"sync"
 	"time"
@@ -16,13 +11,18 @@ type Message struct {
 	Content   string   `json:"content"`
 	Timestamp string   `json:"timestamp,omitempty"`
 	ToolsUsed []string `json:"tools_used,omitempty"`
+
+	// Set only when tool-call transcripts are persisted.
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`   // assistant
+	ToolCallID string     `json:"tool_call_id,omitempty"` // tool
+	Name       string     `json:"name,omitempty"`         // tool
 }
 
-type metadataLine struct {
-	Type      string         `json:"_type"`
-	CreatedAt string         `json:"created_at"`
-	UpdatedAt string         `json:"updated_at"`
-	Metadata  map[string]any `json:"metadata"`
+// ToolCall is a tool invocation requested by the assistant.
+type ToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments,omitempty"`
 }
 
 type Session struct {
@@ -34,105 +34,14 @@ type Session struct {
 
 	mu      sync.Mutex
 	version uint64
-}
-
-type Manager struct {
-	Dir   string
-	cache map[string]*Session
-	mu    sync.Mutex
-}
-
-func NewManager(dir string) *Manager {
-	return &Manager{Dir: dir, cache: map[string]*Session{}}
-}
-
-func (m *Manager) GetOrCreate(key string) (*Session, error) {
-	m.mu.Lock()
-	if s, ok := m.cache[key]; ok {
-		m.mu.Unlock()
-		return s, nil
-	}
-	m.mu.Unlock()
-	s, err := Load(m.Dir, key)
-	if err != nil {
-		return nil, err
-	}
-	if s == nil {
-		s = New(key)
-	}
-	m.mu.Lock()
-	m.cache[key] = s
-	m.mu.Unlock()
-	return s, nil
-}
-
-func (m *Manager) Save(s *Session) error {
-	if err := Save(m.Dir, s); err != nil {
-		return err
-	}
-	m.mu.Lock()
-	m.cache[s.Key] = s
-	m.mu.Unlock()
-	return nil
-}
-
-func Load(dir, key string) (*Session, error) {
-	path := filepath.Join(dir, safeFilename(strings.ReplaceAll(key, ":", "_"))+".jsonl")
-	f, err := os.Open(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, nil
-		}
-		return nil, err
-	}
-	defer f.Close()
-
-	s := &Session{
-		Key:      key,
-		Messages: []Message{},
-		Metadata: map[string]any{},
-	}
-
-	sc := bufio.NewScanner(f)
-	for sc.Scan() {
-		line := strings.TrimSpace(sc.Text())
-		if line == "" {
-			continue
-		}
-		var raw map[string]any
-		if err := json.Unmarshal([]byte(line), &raw); err != nil {
-			continue
-		}
-		if raw["_type"] == "metadata" {
-			var ml metadataLine
-			if err := json.Unmarshal([]byte(line), &ml); err == nil {
-				if t, err := time.Parse(time.RFC3339Nano, ml.CreatedAt); err == nil {
-					s.CreatedAt = t
-				}
-				if t, err := time.Parse(time.RFC3339Nano, ml.UpdatedAt); err == nil {
-					s.UpdatedAt = t
-				}
-				if ml.Metadata != nil {
-					s.Metadata = ml.Metadata
-				}
-			}
-			continue
-		}
-		var m Message
-		if err := json.Unmarshal([]byte(line), &m); err == nil {
-			s.Messages = append(s.Messages, m)
-		}
-	}
-	if err := sc.Err(); err != nil {
-		return nil, err
-	}
-	if s.CreatedAt.IsZero() {
-		s.CreatedAt = time.Now()
-	}
-	if s.UpdatedAt.IsZero() {
-		s.UpdatedAt = time.Now()
-	}
-	return s, nil
+	// persisted is the number of leading messages already written by a
+	// Store; rewrite forces the next save to replace them all.
+	persisted int
+	rewrite   bool
+	// stored is set once s was loaded from or written to a Store.
+	stored bool
+	// metaDirty marks Metadata changes not yet persisted.
+	metaDirty bool
 }
 
 func New(key string) *Session {
@@ -174,6 +83,51 @@ func (s *Session) AddWithTools(role, content string, toolsUsed []string) {
 	s.version++
 }
 
+// AddMessages appends a turn's messages (e.g. including tool rounds) at once.
+func (s *Session) AddMessages(msgs []Message) {
+	if len(msgs) == 0 {
+		return
+	}
+	now := time.Now()
+	ts := now.Format(time.RFC3339Nano)
+	msgs = cloneMessages(msgs)
+	for i := range msgs {
+		if msgs[i].Timestamp == "" {
+			msgs[i].Timestamp = ts
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Messages = append(s.Messages, msgs...)
+	s.UpdatedAt = now
+	s.version++
+}
+
+// MetaString returns the string stored under key in Metadata, or "".
+func (s *Session) MetaString(key string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, _ := s.Metadata[key].(string)
+	return v
+}
+
+// SetMeta stores value under key in Metadata; an empty value removes the key.
+func (s *Session) SetMeta(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Metadata == nil {
+		s.Metadata = map[string]any{}
+	}
+	if value == "" {
+		delete(s.Metadata, key)
+	} else {
+		s.Metadata[key] = value
+	}
+	s.metaDirty = true
+}
+
+// History returns the last max messages. Tool results cut off from the
+// assistant message that requested them are dropped.
 func (s *Session) History(max int) []Message {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -181,6 +135,9 @@ func (s *Session) History(max int) []Message {
 	if max > 0 && len(msgs) > max {
 		msgs = msgs[len(msgs)-max:]
 	}
+	for len(msgs) > 0 && msgs[0].Role == "tool" {
+		msgs = msgs[1:]
+	}
 	return cloneMessages(msgs)
 }
 
@@ -223,82 +180,44 @@ func (s *Session) ApplyConsolidation(version uint64, keep int) bool {
 	s.Messages = cloneMessages(s.Messages[len(s.Messages)-keep:])
 	s.UpdatedAt = time.Now()
 	s.version++
+	s.rewrite = true
 	return true
 }
 
-func Save(dir string, s *Session) error {
-	if err := os.MkdirAll(dir, 0o700); err != nil {
-		return err
-	}
-	path := filepath.Join(dir, safeFilename(strings.ReplaceAll(s.Key, ":", "_"))+".jsonl")
-
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
-	if err != nil {
-		return err
-	}
-	bw := bufio.NewWriter(f)
-
-	meta := metadataLine{
-		Type:      "metadata",
-		CreatedAt: s.CreatedAt.Format(time.RFC3339Nano),
-		UpdatedAt: s.UpdatedAt.Format(time.RFC3339Nano),
-		Metadata:  s.Metadata,
-	}
-	if b, err := json.Marshal(meta); err == nil {
-		if _, err := bw.Write(append(b, '\n')); err != nil {
-			_ = f.Close()
-			return err
-		}
-	}
-
-	for _, m := range s.Messages {
-		if b, err := json.Marshal(m); err == nil {
-			if _, err := bw.Write(append(b, '\n')); err != nil {
-				_ = f.Close()
-				return err
-			}
-		}
-	}
-	if err := bw.Flush(); err != nil {
-		_ = f.Close()
-		return err
-	}
-	if err := f.Close(); err != nil {
-		return err
-	}
-	return nil
-}
-
 func cloneMessages(in []Message) []Message {
 	out := make([]Message, 0, len(in))
 	for _, m := range in {
 		msg := Message{
-			Role:      m.Role,
-			Content:   m.Content,
-			Timestamp: m.Timestamp,
+			Role:       m.Role,
+			Content:    m.Content,
+			Timestamp:  m.Timestamp,
+			ToolCallID: m.ToolCallID,
+			Name:       m.Name,
 		}
 		if len(m.ToolsUsed) > 0 {
 			msg.ToolsUsed = append([]string{}, m.ToolsUsed...)
 		}
+		if len(m.ToolCalls) > 0 {
+			msg.ToolCalls = append([]ToolCall{}, m.ToolCalls...)
+		}
 		out = append(out, msg)
 	}
 	return out
 }
 
-var safeRe = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+// markSaved records that all messages of s are persisted. Callers hold s.mu.
+func (s *Session) markSaved() {
+	s.persisted = len(s.Messages)
+	s.rewrite = false
+	s.metaDirty = false
+	s.stored = true
+}
 
-func safeFilename(s string) string {
-	s = strings.TrimSpace(s)
-	if s == "" {
-		return "default"
-	}
-	s = safeRe.ReplaceAllString(s, "_")
-	s = strings.Trim(s, "._-")
-	if s == "" {
-		return "default"
+// unsaved returns the messages not yet persisted, or ok=false when the whole
+// session has to be rewritten. Callers hold s.mu.
+func (s *Session) unsaved() (msgs []Message, ok bool) {
+	if s.rewrite || s.persisted > len(s.Messages) {
+		return nil, false
 	}
-	return s
+	return s.Messages[s.persisted:], true
 }
@@ -90,3 +90,72 @@ func TestSave_AfterConsolidationPersistsTrimmedMessages(t *testing.T) {
 		t.Fatalf("messages=%d want=%d", got, keep)
 	}
 }
+
+func TestLoad_ToolCallTranscriptAndLegacyLines(t *testing.T) {
+	dir := t.TempDir()
+	key := "cli:tools"
+	path := filepath.Join(dir, safeFilename(strings.ReplaceAll(key, ":", "_"))+".jsonl")
+	content := strings.Join([]string{
+		`{"_type":"metadata","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","metadata":{}}`,
+		`{"role":"user","content":"old","timestamp":"2026-01-01T00:00:00Z"}`,
+		`{"role":"assistant","content":"old answer","tools_used":["exec"]}`,
+		`{"role":"user","content":"list files"}`,
+		`{"role":"assistant","content":"","tool_calls":[{"id":"c1","name":"list_dir","arguments":"{\"path\":\".\"}"}]}`,
+		`{"role":"tool","content":"a.txt","tool_call_id":"c1","name":"list_dir"}`,
+		`{"role":"assistant","content":"a.txt","tools_used":["list_dir"]}`,
+	}, "\n") + "\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Load(dir, key)
+	if err != nil || s == nil {
+		t.Fatalf("load: s=%v err=%v", s, err)
+	}
+	if got := len(s.Messages); got != 6 {
+		t.Fatalf("messages=%d", got)
+	}
+	if got := strings.Join(s.Messages[1].ToolsUsed, ","); got != "exec" {
+		t.Fatalf("legacy tools_used=%q", got)
+	}
+	call := s.Messages[3]
+	if len(call.ToolCalls) != 1 || call.ToolCalls[0].Name != "list_dir" || call.ToolCalls[0].Arguments != `{"path":"."}` {
+		t.Fatalf("tool_calls=%+v", call.ToolCalls)
+	}
+	if res := s.Messages[4]; res.ToolCallID != "c1" || res.Name != "list_dir" {
+		t.Fatalf("tool result=%+v", res)
+	}
+
+	// Round-trip through Save keeps the tool fields.
+	if err := Save(dir, s); err != nil {
+		t.Fatal(err)
+	}
+	again, err := Load(dir, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := again.Messages[3].ToolCalls; len(got) != 1 || got[0].ID != "c1" {
+		t.Fatalf("after save tool_calls=%+v", got)
+	}
+}
+
+func TestHistory_DropsLeadingToolResults(t *testing.T) {
+	s := New("cli:test")
+	s.AddMessages([]Message{
+		{Role: "user", Content: "q"},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "1", Name: "exec"}, {ID: "2", Name: "exec"}}},
+		{Role: "tool", ToolCallID: "1", Content: "r1"},
+		{Role: "tool", ToolCallID: "2", Content: "r2"},
+		{Role: "assistant", Content: "done"},
+	})
+	h := s.History(3)
+	if len(h) != 1 || h[0].Content != "done" {
+		t.Fatalf("history=%+v", h)
+	}
+	if h := s.History(0); len(h) != 5 || h[1].ToolCalls[1].ID != "2" {
+		t.Fatalf("full history=%+v", h)
+	}
+	if s.Messages[0].Timestamp == "" {
+		t.Fatal("timestamp not set")
+	}
+}
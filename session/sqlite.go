@@ -0,0 +1,273 @@
+package session
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	_ "github.com/mosaxiv/clawlet/internal/sqlite3"
+)
+
+// SQLiteStore keeps all sessions in one SQLite database. Each save is a
+// single transaction that inserts the new messages.
+type SQLiteStore struct {
+	db *sql.DB
+	mu sync.Mutex
+}
+
+func OpenSQLiteStore(path string) (*SQLiteStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+	st := &SQLiteStore{db: db}
+	if err := st.ensureSchema(); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return st, nil
+}
+
+func (st *SQLiteStore) ensureSchema() error {
+	stmts := []string{
+		`PRAGMA busy_timeout = 5000`,
+		`PRAGMA synchronous = FULL`,
+		`CREATE TABLE IF NOT EXISTS sessions (
+			key TEXT PRIMARY KEY,
+			created_at INTEGER NOT NULL,
+			updated_at INTEGER NOT NULL,
+			metadata TEXT NOT NULL DEFAULT '{}'
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_sessions_updated_at ON sessions(updated_at)`,
+		`CREATE TABLE IF NOT EXISTS messages (
+			session_key TEXT NOT NULL,
+			seq INTEGER NOT NULL,
+			role TEXT NOT NULL,
+			content TEXT NOT NULL,
+			created_at INTEGER NOT NULL,
+			data TEXT NOT NULL,
+			PRIMARY KEY (session_key, seq)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_messages_created_at ON messages(created_at)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := st.db.Exec(stmt); err != nil {
+			return fmt.Errorf("session store schema: %w", err)
+		}
+	}
+	return nil
+}
+
+func (st *SQLiteStore) Load(key string) (*Session, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var created, updated int64
+	var meta string
+	err := st.db.QueryRow(`SELECT created_at, updated_at, metadata FROM sessions WHERE key = ?`, key).Scan(&created, &updated, &meta)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := &Session{
+		Key:       key,
+		CreatedAt: time.UnixMilli(created),
+		UpdatedAt: time.UnixMilli(updated),
+		Messages:  []Message{},
+		Metadata:  map[string]any{},
+	}
+	_ = json.Unmarshal([]byte(meta), &s.Metadata)
+	if s.Metadata == nil {
+		s.Metadata = map[string]any{}
+	}
+
+	rows, err := st.db.Query(`SELECT data FROM messages WHERE session_key = ? ORDER BY seq`, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var m Message
+		if err := json.Unmarshal([]byte(data), &m); err == nil {
+			s.Messages = append(s.Messages, m)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	s.persisted = len(s.Messages)
+	s.stored = true
+	return s, nil
+}
+
+func (st *SQLiteStore) Save(s *Session) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	meta, err := json.Marshal(s.Metadata)
+	if err != nil {
+		meta = []byte("{}")
+	}
+
+	tx, err := st.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if s.stored {
+		var one int
+		err := tx.QueryRow(`SELECT 1 FROM sessions WHERE key = ?`, s.Key).Scan(&one)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrDeleted
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`INSERT INTO sessions (key, created_at, updated_at, metadata) VALUES (?, ?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET updated_at = excluded.updated_at, metadata = excluded.metadata`,
+		s.Key, s.CreatedAt.UnixMilli(), s.UpdatedAt.UnixMilli(), string(meta)); err != nil {
+		return err
+	}
+
+	msgs, ok := s.unsaved()
+	start := s.persisted
+	if !ok {
+		if _, err := tx.Exec(`UPDATE sessions SET created_at = ? WHERE key = ?`, s.CreatedAt.UnixMilli(), s.Key); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM messages WHERE session_key = ?`, s.Key); err != nil {
+			return err
+		}
+		msgs, start = s.Messages, 0
+	}
+	if len(msgs) > 0 {
+		stmt, err := tx.Prepare(`INSERT OR REPLACE INTO messages (session_key, seq, role, content, created_at, data) VALUES (?, ?, ?, ?, ?, ?)`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for i, m := range msgs {
+			data, err := json.Marshal(m)
+			if err != nil {
+				return err
+			}
+			if _, err := stmt.Exec(s.Key, start+i, m.Role, m.Content, messageTime(m, s.UpdatedAt).UnixMilli(), string(data)); err != nil {
+				return err
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.markSaved()
+	return nil
+}
+
+func (st *SQLiteStore) List(q Query) ([]Info, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	where := []string{`key LIKE ? ESCAPE '\'`}
+	args := []any{likePrefix(q.Prefix)}
+	if !q.Since.IsZero() {
+		where = append(where, `updated_at >= ?`)
+		args = append(args, q.Since.UnixMilli())
+	}
+	if !q.Until.IsZero() {
+		where = append(where, `created_at < ?`)
+		args = append(args, q.Until.UnixMilli())
+	}
+	query := `SELECT key, created_at, updated_at,
+		(SELECT COUNT(*) FROM messages m WHERE m.session_key = s.key)
+		FROM sessions s WHERE ` + strings.Join(where, " AND ") + ` ORDER BY updated_at DESC`
+	if q.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, q.Limit)
+	}
+	rows, err := st.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Info
+	for rows.Next() {
+		var info Info
+		var created, updated int64
+		if err := rows.Scan(&info.Key, &created, &updated, &info.Messages); err != nil {
+			return nil, err
+		}
+		info.CreatedAt = time.UnixMilli(created)
+		info.UpdatedAt = time.UnixMilli(updated)
+		out = append(out, info)
+	}
+	return out, rows.Err()
+}
+
+func (st *SQLiteStore) Delete(key string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	tx, err := st.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM messages WHERE session_key = ?`, key); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM sessions WHERE key = ?`, key); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (st *SQLiteStore) Exists(key string) (bool, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	var one int
+	err := st.db.QueryRow(`SELECT 1 FROM sessions WHERE key = ?`, key).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (st *SQLiteStore) Close() error {
+	if st == nil || st.db == nil {
+		return nil
+	}
+	return st.db.Close()
+}
+
+func messageTime(m Message, fallback time.Time) time.Time {
+	if t, err := time.Parse(time.RFC3339Nano, m.Timestamp); err == nil {
+		return t
+	}
+	return fallback
+}
+
+func likePrefix(prefix string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(prefix) + "%"
+}
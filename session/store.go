@@ -0,0 +1,197 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Store persists sessions.
+type Store interface {
+	// Load returns the session stored under key, or nil if there is none.
+	Load(key string) (*Session, error)
+	// Save persists s. Only messages added since the last save are written,
+	// unless the session was compacted (e.g. by consolidation).
+	Save(s *Session) error
+	// List returns sessions matching q, most recently updated first.
+	List(q Query) ([]Info, error)
+	// Delete removes the session stored under key. Missing keys are ignored.
+	Delete(key string) error
+	// Exists reports whether a session is stored under key.
+	Exists(key string) (bool, error)
+	Close() error
+}
+
+// ErrDeleted is returned by Save for a session that was loaded or saved
+// before and has since been deleted from the store (e.g. by `clawlet
+// sessions reset` while the gateway had it cached). Saving it would bring
+// the deleted history back.
+var ErrDeleted = errors.New("session was deleted from the store")
+
+// Query filters Store.List. Zero fields match everything.
+type Query struct {
+	// Prefix matches the start of the session key (e.g. "telegram:").
+	Prefix string
+	// Since and Until select sessions active in that time range: updated at
+	// or after Since and created before Until.
+	Since time.Time
+	Until time.Time
+	Limit int
+}
+
+func (q Query) match(info Info) bool {
+	if !strings.HasPrefix(info.Key, q.Prefix) {
+		return false
+	}
+	if !q.Since.IsZero() && info.UpdatedAt.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !info.CreatedAt.Before(q.Until) {
+		return false
+	}
+	return true
+}
+
+// Info summarizes a stored session.
+type Info struct {
+	Key       string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Messages  int
+}
+
+const (
+	StoreJSONL  = "jsonl"
+	StoreSQLite = "sqlite"
+)
+
+// OpenStore opens the store of the given kind: JSONL files under dir, or a
+// SQLite database at dbPath.
+func OpenStore(kind, dir, dbPath string) (Store, error) {
+	switch strings.ToLower(strings.TrimSpace(kind)) {
+	case "", StoreJSONL:
+		return NewJSONLStore(dir), nil
+	case StoreSQLite:
+		return OpenSQLiteStore(dbPath)
+	default:
+		return nil, fmt.Errorf("unknown session store: %s (expected %s or %s)", kind, StoreJSONL, StoreSQLite)
+	}
+}
+
+// Migrate copies every session from src to dst, replacing sessions with the
+// same key in dst. It returns the number of sessions copied.
+func Migrate(src, dst Store) (int, error) {
+	infos, err := src.List(Query{})
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, info := range infos {
+		s, err := src.Load(info.Key)
+		if err != nil {
+			return n, fmt.Errorf("load %s: %w", info.Key, err)
+		}
+		if s == nil {
+			continue
+		}
+		s.mu.Lock()
+		s.rewrite = true
+		s.stored = false
+		s.mu.Unlock()
+		if err := dst.Save(s); err != nil {
+			return n, fmt.Errorf("save %s: %w", info.Key, err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Archive writes the session stored under key to a timestamped JSONL file in
+// dir and then removes it from st. It returns the archive path. An existing
+// archive is never replaced, even when the same key is reset twice within
+// one millisecond.
+func Archive(st Store, key, dir string) (string, error) {
+	s, err := st.Load(key)
+	if err != nil {
+		return "", err
+	}
+	if s == nil {
+		return "", fmt.Errorf("session not found: %s", key)
+	}
+	base := safeFilename(strings.ReplaceAll(key, ":", "_")) + "-" + time.Now().Format("20060102-150405.000")
+	var path string
+	s.mu.Lock()
+	for i := 1; ; i++ {
+		path = filepath.Join(dir, base+".jsonl")
+		if i > 1 {
+			path = filepath.Join(dir, fmt.Sprintf("%s-%d.jsonl", base, i))
+		}
+		if err = createSessionFile(path, s); !errors.Is(err, fs.ErrExist) {
+			break
+		}
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return "", err
+	}
+	if err := st.Delete(key); err != nil {
+		return path, err
+	}
+	return path, nil
+}
+
+// Hit is a message matched by Search.
+type Hit struct {
+	Key     string
+	Index   int
+	Message Message
+}
+
+// Search returns messages of sessions matching q whose content or tool call
+// arguments contain text (case-insensitive), most recent sessions first. At
+// most limit hits are returned when limit > 0.
+func Search(st Store, q Query, text string, limit int) ([]Hit, error) {
+	needle := strings.ToLower(strings.TrimSpace(text))
+	if needle == "" {
+		return nil, fmt.Errorf("search text is empty")
+	}
+	infos, err := st.List(q)
+	if err != nil {
+		return nil, err
+	}
+	var hits []Hit
+	for _, info := range infos {
+		s, err := st.Load(info.Key)
+		if err != nil {
+			return hits, fmt.Errorf("load %s: %w", info.Key, err)
+		}
+		if s == nil {
+			continue
+		}
+		for i, m := range s.Messages {
+			if !messageContains(m, needle) {
+				continue
+			}
+			hits = append(hits, Hit{Key: s.Key, Index: i, Message: m})
+			if limit > 0 && len(hits) >= limit {
+				return hits, nil
+			}
+		}
+	}
+	return hits, nil
+}
+
+func messageContains(m Message, needle string) bool {
+	if strings.Contains(strings.ToLower(m.Content), needle) {
+		return true
+	}
+	for _, tc := range m.ToolCalls {
+		if strings.Contains(strings.ToLower(tc.Arguments), needle) {
+			return true
+		}
+	}
+	return false
+}
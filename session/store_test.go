@@ -0,0 +1,377 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testStores(t *testing.T) map[string]Store {
+	t.Helper()
+	dir := t.TempDir()
+	sqlite, err := OpenSQLiteStore(filepath.Join(dir, "sessions.sqlite"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { _ = sqlite.Close() })
+	return map[string]Store{
+		StoreJSONL:  NewJSONLStore(filepath.Join(dir, "jsonl")),
+		StoreSQLite: sqlite,
+	}
+}
+
+func TestStore_AppendLoadAndCompact(t *testing.T) {
+	for name, st := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			s := New("telegram:42")
+			s.Add("user", "u1")
+			s.AddMessages([]Message{
+				{Role: "assistant", ToolCalls: []ToolCall{{ID: "c1", Name: "exec", Arguments: `{"command":"ls"}`}}},
+				{Role: "tool", ToolCallID: "c1", Name: "exec", Content: "a.txt"},
+				{Role: "assistant", Content: "a1", ToolsUsed: []string{"exec"}},
+			})
+			if err := st.Save(s); err != nil {
+				t.Fatalf("save #1: %v", err)
+			}
+			s.Add("user", "u2")
+			s.Add("assistant", "a2")
+			if err := st.Save(s); err != nil {
+				t.Fatalf("save #2: %v", err)
+			}
+
+			loaded, err := st.Load("telegram:42")
+			if err != nil || loaded == nil {
+				t.Fatalf("load: %v %v", loaded, err)
+			}
+			if got := len(loaded.Messages); got != 6 {
+				t.Fatalf("messages=%d", got)
+			}
+			if tc := loaded.Messages[1].ToolCalls; len(tc) != 1 || tc[0].Arguments != `{"command":"ls"}` {
+				t.Fatalf("tool calls=%+v", tc)
+			}
+			if loaded.Messages[5].Content != "a2" {
+				t.Fatalf("last=%+v", loaded.Messages[5])
+			}
+
+			// Appending to a loaded session continues where the store left off.
+			loaded.Add("user", "u3")
+			if err := st.Save(loaded); err != nil {
+				t.Fatal(err)
+			}
+			_, keep, ver, ok := loaded.SnapshotForConsolidation(4)
+			if !ok || !loaded.ApplyConsolidation(ver, keep) {
+				t.Fatal("consolidation failed")
+			}
+			if err := st.Save(loaded); err != nil {
+				t.Fatal(err)
+			}
+			again, err := st.Load("telegram:42")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := len(again.Messages); got != keep || again.Messages[keep-1].Content != "u3" {
+				t.Fatalf("after compaction messages=%d want %d: %+v", got, keep, again.Messages)
+			}
+
+			if err := st.Delete("telegram:42"); err != nil {
+				t.Fatal(err)
+			}
+			if gone, err := st.Load("telegram:42"); err != nil || gone != nil {
+				t.Fatalf("after delete: %v %v", gone, err)
+			}
+		})
+	}
+}
+
+func TestStore_List(t *testing.T) {
+	for name, st := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+			for i, key := range []string{"telegram:1", "telegram:2", "slack:C1"} {
+				s := New(key)
+				s.CreatedAt = base.Add(time.Duration(i) * time.Hour)
+				s.UpdatedAt = s.CreatedAt.Add(30 * time.Minute)
+				s.Messages = []Message{{Role: "user", Content: "hi", Timestamp: s.UpdatedAt.Format(time.RFC3339Nano)}}
+				if err := st.Save(s); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			all, err := st.List(Query{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(all) != 3 || all[0].Key != "slack:C1" || all[2].Key != "telegram:1" || all[0].Messages != 1 {
+				t.Fatalf("all=%+v", all)
+			}
+			tg, err := st.List(Query{Prefix: "telegram:"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tg) != 2 {
+				t.Fatalf("prefix=%+v", tg)
+			}
+			ranged, err := st.List(Query{Since: base.Add(time.Hour), Until: base.Add(90 * time.Minute)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ranged) != 1 || ranged[0].Key != "telegram:2" {
+				t.Fatalf("range=%+v", ranged)
+			}
+			limited, err := st.List(Query{Limit: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(limited) != 1 {
+				t.Fatalf("limit=%+v", limited)
+			}
+		})
+	}
+}
+
+func TestJSONLStore_AppendsInsteadOfRewriting(t *testing.T) {
+	dir := t.TempDir()
+	st := NewJSONLStore(dir)
+	s := New("cli:test")
+	s.Add("user", "u1")
+	if err := st.Save(s); err != nil {
+		t.Fatal(err)
+	}
+	path := sessionPath(dir, s.Key)
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.Add("assistant", "a1")
+	if err := st.Save(s); err != nil {
+		t.Fatal(err)
+	}
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(after), string(before)) {
+		t.Fatalf("file was rewritten:\n%s", after)
+	}
+	if strings.Count(string(after), "\n") != 3 {
+		t.Fatalf("lines:\n%s", after)
+	}
+}
+
+func TestJSONLStore_RecoversFromTornWrite(t *testing.T) {
+	dir := t.TempDir()
+	st := NewJSONLStore(dir)
+	s := New("cli:test")
+	s.Add("user", "u1")
+	if err := st.Save(s); err != nil {
+		t.Fatal(err)
+	}
+	path := sessionPath(dir, s.Key)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString(`{"role":"assistant","cont`)
+	_ = f.Close()
+
+	loaded, err := st.Load(s.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Messages) != 1 {
+		t.Fatalf("messages=%+v", loaded.Messages)
+	}
+	loaded.Add("assistant", "a1")
+	if err := st.Save(loaded); err != nil {
+		t.Fatal(err)
+	}
+	again, err := st.Load(s.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again.Messages) != 2 || again.Messages[1].Content != "a1" {
+		t.Fatalf("messages=%+v", again.Messages)
+	}
+}
+
+func TestJSONLStore_LegacyFileWithoutKey(t *testing.T) {
+	dir := t.TempDir()
+	content := `{"_type":"metadata","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","metadata":{}}
+{"role":"user","content":"hello"}
+`
+	if err := os.WriteFile(filepath.Join(dir, "discord_123.jsonl"), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	st := NewJSONLStore(dir)
+	infos, err := st.List(Query{Prefix: "discord:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 || infos[0].Key != "discord:123" {
+		t.Fatalf("infos=%+v", infos)
+	}
+
+	s, err := st.Load("discord:123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Add("assistant", "hi")
+	if err := st.Save(s); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := os.ReadFile(filepath.Join(dir, "discord_123.jsonl"))
+	if !strings.Contains(string(b), `"key":"discord:123"`) {
+		t.Fatalf("legacy file not upgraded:\n%s", b)
+	}
+}
+
+func TestMigrate_JSONLToSQLite(t *testing.T) {
+	stores := testStores(t)
+	src, dst := stores[StoreJSONL], stores[StoreSQLite]
+	for _, key := range []string{"cli:default", "slack:C1"} {
+		s := New(key)
+		s.Add("user", "q "+key)
+		s.Add("assistant", "a "+key)
+		if err := src.Save(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	n, err := Migrate(src, dst)
+	if err != nil || n != 2 {
+		t.Fatalf("n=%d err=%v", n, err)
+	}
+	// Migrating again replaces instead of duplicating.
+	if _, err := Migrate(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	s, err := dst.Load("slack:C1")
+	if err != nil || s == nil {
+		t.Fatalf("load: %v %v", s, err)
+	}
+	if len(s.Messages) != 2 || s.Messages[0].Content != "q slack:C1" {
+		t.Fatalf("messages=%+v", s.Messages)
+	}
+}
+
+func TestOpenStore_Unknown(t *testing.T) {
+	if _, err := OpenStore("redis", t.TempDir(), ""); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestArchive_MovesSessionOutOfStore(t *testing.T) {
+	for name, st := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			s := New("telegram:7")
+			s.Add("user", "remember this")
+			if err := st.Save(s); err != nil {
+				t.Fatal(err)
+			}
+			archiveDir := filepath.Join(t.TempDir(), "archive")
+			path, err := Archive(st, "telegram:7", archiveDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gone, _ := st.Load("telegram:7"); gone != nil {
+				t.Fatalf("session still in store: %+v", gone)
+			}
+			b, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(b), "remember this") {
+				t.Fatalf("archive=%s", b)
+			}
+			if _, err := Archive(st, "telegram:7", archiveDir); err == nil {
+				t.Fatal("expected not found error")
+			}
+
+			// Resetting again right away keeps both archives.
+			for range 2 {
+				s := New("telegram:8")
+				s.Add("user", "again")
+				if err := st.Save(s); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := Archive(st, "telegram:8", archiveDir); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if matches, _ := filepath.Glob(filepath.Join(archiveDir, "telegram_8-*.jsonl")); len(matches) != 2 {
+				t.Fatalf("archives=%v", matches)
+			}
+		})
+	}
+}
+
+func TestSearch(t *testing.T) {
+	st := NewJSONLStore(t.TempDir())
+	for key, msgs := range map[string][]Message{
+		"telegram:1": {{Role: "user", Content: "Where is the Invoice?"}, {Role: "assistant", Content: "in docs/"}},
+		"slack:C1": {
+			{Role: "user", Content: "grep it"},
+			{Role: "assistant", ToolCalls: []ToolCall{{Name: "exec", Arguments: `{"command":"grep invoice"}`}}},
+		},
+	} {
+		s := New(key)
+		s.AddMessages(msgs)
+		if err := st.Save(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hits, err := Search(st, Query{}, "invoice", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 2 {
+		t.Fatalf("hits=%+v", hits)
+	}
+	hits, err = Search(st, Query{Prefix: "telegram:"}, "INVOICE", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 1 || hits[0].Key != "telegram:1" || hits[0].Index != 0 {
+		t.Fatalf("hits=%+v", hits)
+	}
+	if _, err := Search(st, Query{}, " ", 0); err == nil {
+		t.Fatal("expected error for empty text")
+	}
+}
+
+func TestStore_PersistsMetadataChanges(t *testing.T) {
+	for name, st := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			s := New("cli:meta")
+			s.Add("user", "hi")
+			if err := st.Save(s); err != nil {
+				t.Fatal(err)
+			}
+			s.SetMeta("model", "anthropic/claude-sonnet-4-5")
+			if err := st.Save(s); err != nil {
+				t.Fatal(err)
+			}
+			got, err := st.Load("cli:meta")
+			if err != nil || got == nil {
+				t.Fatalf("load: %v", err)
+			}
+			if m := got.MetaString("model"); m != "anthropic/claude-sonnet-4-5" {
+				t.Fatalf("model=%q", m)
+			}
+			if len(got.Messages) != 1 {
+				t.Fatalf("messages=%d", len(got.Messages))
+			}
+
+			got.SetMeta("model", "")
+			if err := st.Save(got); err != nil {
+				t.Fatal(err)
+			}
+			again, _ := st.Load("cli:meta")
+			if m := again.MetaString("model"); m != "" {
+				t.Fatalf("model=%q after clearing", m)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+)
+
+const redacted = "[REDACTED]"
+
+// defaultRedactPatterns match common credentials. A first capture group, if
+// present, is kept (e.g. the `api_key=` prefix).
+var defaultRedactPatterns = []string{
+	`sk-(?:ant-|proj-|or-)?[A-Za-z0-9_-]{16,}`,
+	`gh[pousr]_[A-Za-z0-9]{20,}`,
+	`xox[abprs]-[A-Za-z0-9-]{10,}`,
+	`xapp-[A-Za-z0-9-]{10,}`,
+	`AKIA[0-9A-Z]{16}`,
+	`AIza[0-9A-Za-z_-]{30,}`,
+	`(?i)(bearer\s+)[A-Za-z0-9._~+/=-]{16,}`,
+	`(?i)("?(?:api[_-]?key|access[_-]?token|secret|password|passwd|token)"?\s*[:=]\s*"?)[^"\s,}&]{6,}`,
+}
+
+// TranscriptPolicy caps and redacts tool calls and results before they are
+// persisted to a session.
+type TranscriptPolicy struct {
+	MaxArgsChars   int
+	MaxResultChars int
+	redact         []*regexp.Regexp
+}
+
+// NewTranscriptPolicy compiles the built-in redaction patterns plus extra.
+func NewTranscriptPolicy(maxArgsChars, maxResultChars int, extra []string) (*TranscriptPolicy, error) {
+	p := &TranscriptPolicy{MaxArgsChars: maxArgsChars, MaxResultChars: maxResultChars}
+	for _, pat := range append(append([]string{}, defaultRedactPatterns...), extra...) {
+		re, err := regexp.Compile(pat)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q: %w", pat, err)
+		}
+		p.redact = append(p.redact, re)
+	}
+	return p, nil
+}
+
+// Apply returns m with tool call arguments and tool results redacted and
+// truncated. Other messages are returned unchanged.
+func (p *TranscriptPolicy) Apply(m Message) Message {
+	if p == nil {
+		return m
+	}
+	if m.Role == "tool" {
+		m.Content = truncate(p.Redact(m.Content), p.MaxResultChars)
+	}
+	if len(m.ToolCalls) > 0 {
+		calls := make([]ToolCall, len(m.ToolCalls))
+		for i, tc := range m.ToolCalls {
+			tc.Arguments = truncateArgs(p.Redact(tc.Arguments), p.MaxArgsChars)
+			calls[i] = tc
+		}
+		m.ToolCalls = calls
+	}
+	return m
+}
+
+// Redact masks every match of the policy's patterns in s.
+func (p *TranscriptPolicy) Redact(s string) string {
+	if p == nil || s == "" {
+		return s
+	}
+	for _, re := range p.redact {
+		if re.NumSubexp() > 0 {
+			s = re.ReplaceAllString(s, "${1}"+redacted)
+		} else {
+			s = re.ReplaceAllLiteralString(s, redacted)
+		}
+	}
+	return s
+}
+
+func truncate(s string, maxChars int) string {
+	if maxChars <= 0 || len(s) <= maxChars {
+		return s
+	}
+	cut := maxChars
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + fmt.Sprintf("\n…[truncated %d chars]", len(s)-cut)
+}
+
+// truncateArgs keeps oversized arguments as a valid JSON object, since
+// providers reject tool calls whose arguments don't parse.
+func truncateArgs(args string, maxChars int) string {
+	if maxChars <= 0 || len(args) <= maxChars {
+		return args
+	}
+	b, _ := json.Marshal(map[string]any{
+		"_truncated": true,
+		"preview":    truncate(args, maxChars),
+	})
+	return string(b)
+}
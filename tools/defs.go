@@ -198,18 +198,27 @@ func defCron() llm.ToolDefinition {
 		Type: "function",
 		Function: llm.FunctionDefinition{
 			Name:        "cron",
-			Description: "Schedule reminders and recurring tasks. Actions: add, list, remove.",
+			Description: "Schedule reminders and recurring tasks. Actions: add, list, update, pause, resume, run (now), remove. For add and update give one of every_seconds, cron_expr or at.",
 			Parameters: llm.JSONSchema{
 				Type: "object",
 				Properties: map[string]llm.JSONSchema{
 					"action": {
 						Type: "string",
-						Enum: []string{"add", "list", "remove"},
+						Enum: []string{"add", "list", "update", "pause", "resume", "run", "remove"},
+					},
+					"kind": {
+						Type:        "string",
+						Enum:        []string{"agent_turn", "message", "exec", "webhook"},
+						Description: "what add schedules: agent_turn (default) runs message as a task for you; message sends message to this chat as is, without using the model (use for plain reminders); exec runs command in the workspace and sends its output here; webhook POSTs the job to url",
 					},
 					"message":       {Type: "string"},
+					"command":       {Type: "string", Description: "shell command for kind=exec (same safety rules as the exec tool)"},
+					"url":           {Type: "string", Description: "http(s) URL for kind=webhook"},
 					"every_seconds": {Type: "integer"},
-					"cron_expr":     {Type: "string"},
-					"job_id":        {Type: "string"},
+					"cron_expr":     {Type: "string", Description: "5-field cron (minute hour day month weekday), optional leading seconds field, or @hourly/@daily/@weekly/@monthly/@yearly/@every <duration>. Day-of-month accepts L, L-n, LW, nW; weekday accepts 5L (last Friday) and 1#2 (second Monday)."},
+					"at":            {Type: "string", Description: "one-shot run time, e.g. 2026-02-10T17:30 (in tz) or RFC3339; the job is deleted after it runs"},
+					"tz":            {Type: "string", Description: "IANA timezone for cron_expr and at, e.g. Europe/Berlin (default: the configured cron.defaultTimezone)"},
+					"job_id":        {Type: "string", Description: "required for update, pause, resume, run and remove"},
 				},
 				Required: []string{"action"},
 			},
@@ -217,12 +226,53 @@ func defCron() llm.ToolDefinition {
 	}
 }
 
+func defMemoryWrite() llm.ToolDefinition {
+	return llm.ToolDefinition{
+		Type: "function",
+		Function: llm.FunctionDefinition{
+			Name:        "memory_write",
+			Description: "Save a durable fact (preference, profile detail, decision, project context) to long-term memory. One short fact per call. Pass the id shown in MEMORY.md (e.g. f12) to correct an existing fact instead of adding one.",
+			Parameters: llm.JSONSchema{
+				Type: "object",
+				Properties: map[string]llm.JSONSchema{
+					"text": {Type: "string", Description: "The fact, as one sentence."},
+					"tags": {Type: "array", Items: &llm.JSONSchema{Type: "string"}, Description: "Topics such as preferences or work; the first one is its MEMORY.md section."},
+					"id":   {Type: "string", Description: "Id of an existing fact to update."},
+					"scope": {
+						Type:        "string",
+						Enum:        []string{"global", "user", "chat"},
+						Description: "Where a new fact is kept when memory is scoped: user for facts about the person you talk to, chat for this conversation, global for facts every chat may see. Defaults to the configured scope.",
+					},
+				},
+				Required: []string{"text"},
+			},
+		},
+	}
+}
+
+func defMemoryForget() llm.ToolDefinition {
+	return llm.ToolDefinition{
+		Type: "function",
+		Function: llm.FunctionDefinition{
+			Name:        "memory_forget",
+			Description: "Remove a fact from long-term memory when it is wrong, outdated, or the user asks to forget it.",
+			Parameters: llm.JSONSchema{
+				Type: "object",
+				Properties: map[string]llm.JSONSchema{
+					"id": {Type: "string", Description: "Fact id shown in MEMORY.md, e.g. f12."},
+				},
+				Required: []string{"id"},
+			},
+		},
+	}
+}
+
 func defMemorySearch() llm.ToolDefinition {
 	return llm.ToolDefinition{
 		Type: "function",
 		Function: llm.FunctionDefinition{
 			Name:        "memory_search",
-			Description: "Semantic memory search over MEMORY.md and memory/*.md.",
+			Description: "Semantic memory search over MEMORY.md, memory/*.md and any configured notes or past chat sessions. Each result has a source (memory, workspace or session); session results carry the sessionKey, and their lines are message numbers.",
 			Parameters: llm.JSONSchema{
 				Type: "object",
 				Properties: map[string]llm.JSONSchema{
@@ -241,7 +291,7 @@ func defMemoryGet() llm.ToolDefinition {
 		Type: "function",
 		Function: llm.FunctionDefinition{
 			Name:        "memory_get",
-			Description: "Read a safe snippet from MEMORY.md or memory/*.md.",
+			Description: "Read a safe snippet of a path returned by memory_search (for sessions:... paths, from/lines count messages).",
 			Parameters: llm.JSONSchema{
 				Type: "object",
 				Properties: map[string]llm.JSONSchema{
@@ -201,6 +201,39 @@ func (r *Registry) writeFile(path, content string) (string, error) {
 	return fmt.Sprintf("wrote %d bytes to %s", len(content), target), nil
 }
 
+// editFileTool dispatches edit_file arguments to the old_text/new_text
+// replacement or, for older callers, the line-range edit.
+func (r *Registry) editFileTool(args json.RawMessage) (string, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(args, &raw); err != nil {
+		return "", &ArgsError{Tool: "edit_file", Err: err}
+	}
+	_, hasOld := raw["old_text"]
+	_, hasNew := raw["new_text"]
+	if !hasOld && !hasNew {
+		// Back-compat: older line-range edit.
+		var a struct {
+			Path      string `json:"path"`
+			StartLine int    `json:"startLine"`
+			EndLine   int    `json:"endLine"`
+			NewText   string `json:"newText"`
+		}
+		if err := json.Unmarshal(args, &a); err != nil {
+			return "", &ArgsError{Tool: "edit_file", Err: err}
+		}
+		return r.editFile(a.Path, a.StartLine, a.EndLine, a.NewText)
+	}
+	var a struct {
+		Path    string `json:"path"`
+		OldText string `json:"old_text"`
+		NewText string `json:"new_text"`
+	}
+	if err := json.Unmarshal(args, &a); err != nil {
+		return "", &ArgsError{Tool: "edit_file", Err: err}
+	}
+	return r.editFileReplace(a.Path, a.OldText, a.NewText)
+}
+
 func (r *Registry) editFile(path string, startLine, endLine int, newText string) (string, error) {
 	abs, err := r.resolvePath(path)
 	if err != nil {
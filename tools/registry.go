@@ -3,14 +3,15 @@ package tools
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/mosaxiv/clawlet/bus"
 	"github.com/mosaxiv/clawlet/cron"
 	"github.com/mosaxiv/clawlet/llm"
+	"github.com/mosaxiv/clawlet/mcp"
 	"github.com/mosaxiv/clawlet/memory"
 )
 
@@ -18,6 +19,9 @@ type Context struct {
 	Channel    string
 	ChatID     string
 	SessionKey string
+	// Memory is the long-term memory of the conversation; nil means the
+	// global memory of the workspace.
+	Memory *memory.View
 }
 
 type Registry struct {
@@ -33,36 +37,27 @@ type Registry struct {
 	Outbound     func(ctx context.Context, msg bus.OutboundMessage) error
 	Spawn        func(ctx context.Context, task, label, originChannel, originChatID string) (string, error)
 	Cron         *cron.Service
+	CronTimezone string // default zone of cron expressions (cron.defaultTimezone)
 	ReadSkill    func(name string) (string, bool)
 	MemorySearch memory.SearchManager
+	// MCP proxies tools from configured MCP servers (mcp_<server>__<tool>).
+	MCP *mcp.Manager
+
+	// OnCall, if set, is called after every tool execution.
+	OnCall func(CallInfo)
+
+	mu     sync.RWMutex
+	custom []Tool
 }
 
 func (r *Registry) Definitions() []llm.ToolDefinition {
-	defs := []llm.ToolDefinition{
-		defReadFile(),
-		defWriteFile(),
-		defEditFile(),
-		defListDir(),
-		defExec(),
-		defWebFetch(),
-	}
-	if r.ReadSkill != nil {
-		defs = append(defs, defReadSkill())
-	}
-	if strings.TrimSpace(r.BraveAPIKey) != "" {
-		defs = append(defs, defWebSearch())
-	}
-	if r.Outbound != nil {
-		defs = append(defs, defMessage())
-	}
-	if r.Spawn != nil {
-		defs = append(defs, defSpawn())
-	}
-	if r.Cron != nil {
-		defs = append(defs, defCron())
+	ts := append(r.builtinTools(false), r.customTools()...)
+	defs := make([]llm.ToolDefinition, 0, len(ts)+8)
+	for _, t := range ts {
+		defs = append(defs, t.Definition())
 	}
-	if r.MemorySearch != nil {
-		defs = append(defs, defMemorySearch(), defMemoryGet())
+	if r.MCP != nil {
+		defs = append(defs, r.MCP.Definitions()...)
 	}
 	if len(r.AllowTools) == 0 {
 		return defs
@@ -82,164 +77,133 @@ func (r *Registry) Execute(ctx context.Context, tctx Context, name string, args
 	if !r.allowed(name) {
 		return "", fmt.Errorf("tool disabled: %s", name)
 	}
-	switch name {
-	case "read_file":
-		var a struct {
+	t := r.lookup(name)
+	if t == nil {
+		return "", fmt.Errorf("unknown tool: %s", name)
+	}
+	start := time.Now()
+	out, err := t.Execute(ctx, tctx, args)
+	if r.OnCall != nil {
+		r.OnCall(CallInfo{
+			Name:        name,
+			Context:     tctx,
+			StartedAt:   start,
+			Duration:    time.Since(start),
+			OutputBytes: len(out),
+			Err:         err,
+		})
+	}
+	return out, err
+}
+
+// builtinTools returns the built-in tools whose dependencies are configured,
+// or every built-in when all is set.
+func (r *Registry) builtinTools(all bool) []Tool {
+	ts := []Tool{
+		WithSerialKey(NewTool(defReadFile(), func(ctx context.Context, tctx Context, a struct {
 			Path string `json:"path"`
-		}
-		if err := json.Unmarshal(args, &a); err != nil {
-			return "", err
-		}
-		return r.readFile(a.Path)
-	case "write_file":
-		var a struct {
+		}) (string, error) {
+			return r.readFile(a.Path)
+		}), r.pathSerialKey),
+		WithSerialKey(NewTool(defWriteFile(), func(ctx context.Context, tctx Context, a struct {
 			Path    string `json:"path"`
 			Content string `json:"content"`
-		}
-		if err := json.Unmarshal(args, &a); err != nil {
-			return "", err
-		}
-		return r.writeFile(a.Path, a.Content)
-	case "edit_file":
-		var raw map[string]json.RawMessage
-		if err := json.Unmarshal(args, &raw); err != nil {
-			return "", err
-		}
-		_, hasOld := raw["old_text"]
-		_, hasNew := raw["new_text"]
-		if !hasOld && !hasNew {
-			// Back-compat: older line-range edit.
-			var a struct {
-				Path      string `json:"path"`
-				StartLine int    `json:"startLine"`
-				EndLine   int    `json:"endLine"`
-				NewText   string `json:"newText"`
-			}
-			if err := json.Unmarshal(args, &a); err != nil {
-				return "", err
-			}
-			return r.editFile(a.Path, a.StartLine, a.EndLine, a.NewText)
-		}
-		var a struct {
-			Path    string `json:"path"`
-			OldText string `json:"old_text"`
-			NewText string `json:"new_text"`
-		}
-		if err := json.Unmarshal(args, &a); err != nil {
-			return "", err
-		}
-		return r.editFileReplace(a.Path, a.OldText, a.NewText)
-	case "list_dir":
-		var a struct {
+		}) (string, error) {
+			return r.writeFile(a.Path, a.Content)
+		}), r.pathSerialKey),
+		WithSerialKey(NewTool(defEditFile(), func(ctx context.Context, tctx Context, args json.RawMessage) (string, error) {
+			return r.editFileTool(args)
+		}), r.pathSerialKey),
+		NewTool(defListDir(), func(ctx context.Context, tctx Context, a struct {
 			Path       string `json:"path"`
 			Recursive  bool   `json:"recursive"`
 			MaxEntries int    `json:"maxEntries"`
-		}
-		if err := json.Unmarshal(args, &a); err != nil {
-			return "", err
-		}
-		return r.listDir(a.Path, a.Recursive, a.MaxEntries)
-	case "exec":
-		var a struct {
+		}) (string, error) {
+			return r.listDir(a.Path, a.Recursive, a.MaxEntries)
+		}),
+		WithSerialKey(NewTool(defExec(), func(ctx context.Context, tctx Context, a struct {
 			Command string `json:"command"`
-		}
-		if err := json.Unmarshal(args, &a); err != nil {
-			return "", err
-		}
-		return r.exec(ctx, a.Command)
-	case "read_skill":
-		var a struct {
-			Name string `json:"name"`
-		}
-		if err := json.Unmarshal(args, &a); err != nil {
-			return "", err
-		}
-		return r.readSkill(a.Name)
-	case "web_fetch":
-		var a struct {
+		}) (string, error) {
+			return r.exec(ctx, a.Command)
+		}), serialAllKey),
+		WithSerialKey(NewTool(defMemoryWrite(), func(ctx context.Context, tctx Context, a struct {
+			ID    string   `json:"id"`
+			Text  string   `json:"text"`
+			Tags  []string `json:"tags"`
+			Scope string   `json:"scope"`
+		}) (string, error) {
+			return r.memoryWrite(tctx, a.ID, a.Text, a.Tags, a.Scope)
+		}), globalSerialKey("memory")),
+		WithSerialKey(NewTool(defMemoryForget(), func(ctx context.Context, tctx Context, a struct {
+			ID string `json:"id"`
+		}) (string, error) {
+			return r.memoryForget(tctx, a.ID)
+		}), globalSerialKey("memory")),
+		NewTool(defWebFetch(), func(ctx context.Context, tctx Context, a struct {
 			URL         string            `json:"url"`
 			ExtractMode string            `json:"extractMode"`
 			MaxChars    int               `json:"maxChars"`
 			Headers     map[string]string `json:"headers"`
-		}
-		if err := json.Unmarshal(args, &a); err != nil {
-			return "", err
-		}
-		return r.webFetch(ctx, a.URL, a.ExtractMode, a.MaxChars, a.Headers)
-	case "web_search":
-		var a struct {
+		}) (string, error) {
+			return r.webFetch(ctx, a.URL, a.ExtractMode, a.MaxChars, a.Headers)
+		}),
+	}
+	if all || r.ReadSkill != nil {
+		ts = append(ts, NewTool(defReadSkill(), func(ctx context.Context, tctx Context, a struct {
+			Name string `json:"name"`
+		}) (string, error) {
+			return r.readSkill(a.Name)
+		}))
+	}
+	if all || strings.TrimSpace(r.BraveAPIKey) != "" {
+		ts = append(ts, NewTool(defWebSearch(), func(ctx context.Context, tctx Context, a struct {
 			Query string `json:"query"`
 			Count int    `json:"count"`
-		}
-		if err := json.Unmarshal(args, &a); err != nil {
-			return "", err
-		}
-		return r.webSearch(ctx, a.Query, a.Count)
-	case "message":
-		var a struct {
+		}) (string, error) {
+			return r.webSearch(ctx, a.Query, a.Count)
+		}))
+	}
+	if all || r.Outbound != nil {
+		ts = append(ts, NewTool(defMessage(), func(ctx context.Context, tctx Context, a struct {
 			Content string `json:"content"`
 			Channel string `json:"channel"`
 			ChatID  string `json:"chat_id"`
-		}
-		if err := json.Unmarshal(args, &a); err != nil {
-			return "", err
-		}
-		ch := strings.TrimSpace(a.Channel)
-		cid := strings.TrimSpace(a.ChatID)
-		if ch == "" || cid == "" {
-			return "", errors.New("message requires explicit channel and chat_id")
-		}
-		// Avoid duplicate sends to the active conversation; reply with normal assistant text instead.
-		if strings.TrimSpace(tctx.Channel) != "" && strings.TrimSpace(tctx.ChatID) != "" {
-			if ch == strings.TrimSpace(tctx.Channel) && cid == strings.TrimSpace(tctx.ChatID) {
-				return "", errors.New("message to current session is not allowed; respond with assistant text instead")
-			}
-		}
-		return r.message(ctx, ch, cid, a.Content)
-	case "spawn":
-		var a struct {
+		}) (string, error) {
+			return r.messageTool(ctx, tctx, a.Channel, a.ChatID, a.Content)
+		}))
+	}
+	if all || r.Spawn != nil {
+		ts = append(ts, NewTool(defSpawn(), func(ctx context.Context, tctx Context, a struct {
 			Task  string `json:"task"`
 			Label string `json:"label"`
-		}
-		if err := json.Unmarshal(args, &a); err != nil {
-			return "", err
-		}
-		return r.spawn(ctx, a.Task, a.Label, tctx.Channel, tctx.ChatID)
-	case "cron":
-		var a struct {
-			Action       string `json:"action"`
-			Message      string `json:"message"`
-			EverySeconds int    `json:"every_seconds"`
-			CronExpr     string `json:"cron_expr"`
-			JobID        string `json:"job_id"`
-		}
-		if err := json.Unmarshal(args, &a); err != nil {
-			return "", err
-		}
-		return r.cronTool(ctx, tctx, a.Action, a.Message, a.EverySeconds, a.CronExpr, a.JobID)
-	case "memory_search":
-		var a struct {
-			Query      string   `json:"query"`
-			MaxResults *int     `json:"maxResults"`
-			MinScore   *float64 `json:"minScore"`
-		}
-		if err := json.Unmarshal(args, &a); err != nil {
-			return "", err
-		}
-		return r.memorySearch(ctx, a.Query, a.MaxResults, a.MinScore)
-	case "memory_get":
-		var a struct {
-			Path  string `json:"path"`
-			From  *int   `json:"from"`
-			Lines *int   `json:"lines"`
-		}
-		if err := json.Unmarshal(args, &a); err != nil {
-			return "", err
-		}
-		return r.memoryGet(a.Path, a.From, a.Lines)
-	default:
-		return "", fmt.Errorf("unknown tool: %s", name)
+		}) (string, error) {
+			return r.spawn(ctx, a.Task, a.Label, tctx.Channel, tctx.ChatID)
+		}))
+	}
+	if all || r.Cron != nil {
+		ts = append(ts, WithSerialKey(NewTool(defCron(), func(ctx context.Context, tctx Context, a cronArgs) (string, error) {
+			return r.cronTool(ctx, tctx, a)
+		}), globalSerialKey("cron")))
+	}
+	if all || r.MemorySearch != nil {
+		ts = append(ts,
+			NewTool(defMemorySearch(), func(ctx context.Context, tctx Context, a struct {
+				Query      string   `json:"query"`
+				MaxResults *int     `json:"maxResults"`
+				MinScore   *float64 `json:"minScore"`
+			}) (string, error) {
+				return r.memorySearch(ctx, tctx, a.Query, a.MaxResults, a.MinScore)
+			}),
+			NewTool(defMemoryGet(), func(ctx context.Context, tctx Context, a struct {
+				Path  string `json:"path"`
+				From  *int   `json:"from"`
+				Lines *int   `json:"lines"`
+			}) (string, error) {
+				return r.memoryGet(tctx, a.Path, a.From, a.Lines)
+			}),
+		)
 	}
+	return ts
 }
 
 func (r *Registry) allowed(name string) bool {
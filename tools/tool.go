@@ -0,0 +1,208 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mosaxiv/clawlet/llm"
+	"github.com/mosaxiv/clawlet/mcp"
+)
+
+// Tool is a function the model can call. Built-in tools, tools registered by
+// embedders and MCP tools all go through this interface.
+type Tool interface {
+	Name() string
+	Definition() llm.ToolDefinition
+	Execute(ctx context.Context, tctx Context, args json.RawMessage) (string, error)
+}
+
+// NewTool builds a Tool from a definition and a typed handler. Arguments are
+// decoded into A before fn is called; malformed arguments are reported as an
+// *ArgsError without calling fn.
+func NewTool[A any](def llm.ToolDefinition, fn func(ctx context.Context, tctx Context, args A) (string, error)) Tool {
+	return &funcTool[A]{def: def, fn: fn}
+}
+
+type funcTool[A any] struct {
+	def llm.ToolDefinition
+	fn  func(ctx context.Context, tctx Context, args A) (string, error)
+}
+
+func (t *funcTool[A]) Name() string                   { return t.def.Function.Name }
+func (t *funcTool[A]) Definition() llm.ToolDefinition { return t.def }
+
+func (t *funcTool[A]) Execute(ctx context.Context, tctx Context, args json.RawMessage) (string, error) {
+	var a A
+	if err := decodeArgs(args, &a); err != nil {
+		return "", &ArgsError{Tool: t.Name(), Err: err}
+	}
+	return t.fn(ctx, tctx, a)
+}
+
+// ArgsError reports tool arguments that could not be decoded.
+type ArgsError struct {
+	Tool string
+	Err  error
+}
+
+func (e *ArgsError) Error() string {
+	return fmt.Sprintf("invalid arguments for %s: %v", e.Tool, e.Err)
+}
+
+func (e *ArgsError) Unwrap() error { return e.Err }
+
+func decodeArgs(args json.RawMessage, v any) error {
+	if s := strings.TrimSpace(string(args)); s == "" || s == "null" {
+		args = json.RawMessage(`{}`)
+	}
+	if raw, ok := v.(*json.RawMessage); ok {
+		*raw = append((*raw)[:0], args...)
+		return nil
+	}
+	return json.Unmarshal(args, v)
+}
+
+// CallInfo describes one tool execution; see Registry.OnCall.
+type CallInfo struct {
+	Name        string
+	Context     Context
+	StartedAt   time.Time
+	Duration    time.Duration
+	OutputBytes int
+	Err         error
+}
+
+// Register adds a custom tool. Names must be unique and must not collide with
+// built-in or MCP tool names. Registered tools are still subject to AllowTools.
+func (r *Registry) Register(t Tool) error {
+	if t == nil {
+		return fmt.Errorf("tool is nil")
+	}
+	name := strings.TrimSpace(t.Name())
+	if name == "" {
+		return fmt.Errorf("tool name is empty")
+	}
+	if name != t.Definition().Function.Name {
+		return fmt.Errorf("tool %s: definition name mismatch: %s", name, t.Definition().Function.Name)
+	}
+	if strings.HasPrefix(name, mcp.ToolPrefix) {
+		return fmt.Errorf("tool %s: prefix %q is reserved for MCP tools", name, mcp.ToolPrefix)
+	}
+	for _, b := range r.builtinTools(true) {
+		if b.Name() == name {
+			return fmt.Errorf("tool %s: conflicts with built-in tool", name)
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range r.custom {
+		if c.Name() == name {
+			return fmt.Errorf("tool %s: already registered", name)
+		}
+	}
+	r.custom = append(r.custom, t)
+	return nil
+}
+
+func (r *Registry) customTools() []Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]Tool(nil), r.custom...)
+}
+
+// lookup finds an available tool by name.
+func (r *Registry) lookup(name string) Tool {
+	for _, t := range r.builtinTools(false) {
+		if t.Name() == name {
+			return t
+		}
+	}
+	for _, t := range r.customTools() {
+		if t.Name() == name {
+			return t
+		}
+	}
+	if r.MCP.Has(name) {
+		return mcpTool{m: r.MCP, name: name}
+	}
+	return nil
+}
+
+// mcpTool adapts a tool proxied by the MCP manager for Execute.
+type mcpTool struct {
+	m    *mcp.Manager
+	name string
+}
+
+func (t mcpTool) Name() string { return t.name }
+
+func (t mcpTool) Definition() llm.ToolDefinition {
+	for _, d := range t.m.Definitions() {
+		if d.Function.Name == t.name {
+			return d
+		}
+	}
+	return llm.ToolDefinition{Type: "function", Function: llm.FunctionDefinition{Name: t.name}}
+}
+
+func (t mcpTool) Execute(ctx context.Context, _ Context, args json.RawMessage) (string, error) {
+	return t.m.Call(ctx, t.name, args)
+}
+
+// SerialTool is implemented by tools that are unsafe to run concurrently.
+// Calls in one round whose SerialKey is equal (and non-empty) run one at a
+// time, in the order the model issued them; other calls may run in parallel.
+// A round with a SerialAll call runs entirely in order.
+type SerialTool interface {
+	Tool
+	SerialKey(args json.RawMessage) string
+}
+
+// WithSerialKey makes t a SerialTool using key.
+func WithSerialKey(t Tool, key func(args json.RawMessage) string) Tool {
+	return &serialTool{Tool: t, key: key}
+}
+
+type serialTool struct {
+	Tool
+	key func(args json.RawMessage) string
+}
+
+func (t *serialTool) SerialKey(args json.RawMessage) string { return t.key(args) }
+
+// SerialKey returns the serialization key for a call, or "" when the call may
+// run concurrently with others.
+func (r *Registry) SerialKey(name string, args json.RawMessage) string {
+	t, ok := r.lookup(name).(SerialTool)
+	if !ok {
+		return ""
+	}
+	return t.SerialKey(args)
+}
+
+// SerialAll is the serial key of calls, e.g. exec, whose side effects can
+// touch any file, so nothing else in their round may run alongside them.
+const SerialAll = "*"
+
+func serialAllKey(json.RawMessage) string { return SerialAll }
+
+// globalSerialKey serializes every call of a tool, e.g. memory_write, whose
+// calls share one resource.
+func globalSerialKey(name string) func(json.RawMessage) string {
+	return func(json.RawMessage) string { return "tool:" + name }
+}
+
+// pathSerialKey serializes calls that read or modify the same file.
+func (r *Registry) pathSerialKey(args json.RawMessage) string {
+	var a struct {
+		Path string `json:"path"`
+	}
+	_ = decodeArgs(args, &a)
+	if abs, err := r.resolvePath(a.Path); err == nil {
+		return "file:" + abs
+	}
+	return "file:" + strings.TrimSpace(a.Path)
+}
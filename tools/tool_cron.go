@@ -1,72 +1,252 @@
 package tools
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/mosaxiv/clawlet/cron"
 )
 
-func (r *Registry) cronTool(ctx context.Context, tctx Context, action, message string, everySeconds int, cronExpr, jobID string) (string, error) {
+type cronArgs struct {
+	Action       string `json:"action"`
+	Kind         string `json:"kind"`
+	Message      string `json:"message"`
+	Command      string `json:"command"`
+	URL          string `json:"url"`
+	EverySeconds int    `json:"every_seconds"`
+	CronExpr     string `json:"cron_expr"`
+	At           string `json:"at"`
+	TZ           string `json:"tz"`
+	JobID        string `json:"job_id"`
+}
+
+func (r *Registry) cronTool(ctx context.Context, tctx Context, a cronArgs) (string, error) {
 	if r.Cron == nil {
 		return "", errors.New("cron service not configured")
 	}
-	action = strings.TrimSpace(action)
+	action := strings.TrimSpace(a.Action)
+	message, jobID := strings.TrimSpace(a.Message), strings.TrimSpace(a.JobID)
+	command, webhookURL := strings.TrimSpace(a.Command), strings.TrimSpace(a.URL)
+	if action != "add" && action != "list" && jobID == "" {
+		return "", errors.New("job_id is required")
+	}
 	switch action {
 	case "add":
-		message = strings.TrimSpace(message)
-		if message == "" {
-			return "", errors.New("message is required")
-		}
+		kind := cmp.Or(strings.TrimSpace(a.Kind), cron.PayloadAgentTurn)
 		if tctx.Channel == "" || tctx.ChatID == "" {
 			return "", errors.New("no session context (channel/chat_id)")
 		}
-		var sched cron.Schedule
-		if everySeconds > 0 {
-			sched = cron.Schedule{Kind: "every", EveryMS: int64(everySeconds) * 1000}
-		} else if strings.TrimSpace(cronExpr) != "" {
-			sched = cron.Schedule{Kind: "cron", Expr: strings.TrimSpace(cronExpr)}
-		} else {
-			return "", errors.New("either every_seconds or cron_expr is required")
-		}
-		payload := cron.Payload{
-			Kind:    "agent_turn",
-			Message: message,
-			Deliver: true,
-			Channel: tctx.Channel,
-			To:      tctx.ChatID,
-		}
-		j, err := r.Cron.Add(shortName(message), sched, payload)
+		sched, ok, err := r.cronSchedule(a)
 		if err != nil {
 			return "", err
 		}
-		return fmt.Sprintf("Created job '%s' (id: %s)", j.Name, j.ID), nil
+		if !ok {
+			return "", errors.New("one of every_seconds, cron_expr or at is required")
+		}
+		j, err := r.Cron.AddJob(cron.Job{
+			Name:     shortName(cmp.Or(message, command, webhookURL)),
+			Schedule: sched,
+			Payload: cron.Payload{
+				Kind:    kind,
+				Message: message,
+				Deliver: true,
+				Channel: tctx.Channel,
+				To:      tctx.ChatID,
+				Command: command,
+				URL:     webhookURL,
+			},
+			// One-shot reminders are removed once they have run.
+			DeleteAfterRun: sched.Kind == "at",
+		})
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Created job '%s' (id: %s, next run %s)", j.Name, j.ID, r.formatNextRun(j)), nil
 	case "list":
-		jobs := r.Cron.List(false)
+		jobs := r.Cron.List(true)
 		if len(jobs) == 0 {
 			return "No scheduled jobs.", nil
 		}
 		var b strings.Builder
 		b.WriteString("Scheduled jobs:\n")
 		for _, j := range jobs {
-			b.WriteString(fmt.Sprintf("- %s (id: %s, %s)\n", j.Name, j.ID, j.Schedule.Kind))
+			next := "next run " + r.formatNextRun(j)
+			if !j.Enabled {
+				next = "paused"
+			}
+			kind := ""
+			if j.Payload.Kind != "" && j.Payload.Kind != cron.PayloadAgentTurn {
+				kind = j.Payload.Kind + ", "
+			}
+			b.WriteString(fmt.Sprintf("- %s (id: %s, %s%s, %s)\n", j.Name, j.ID, kind, describeSchedule(j.Schedule), next))
+			r.writeRecentRuns(&b, j, cronListRuns)
 		}
 		return strings.TrimRight(b.String(), "\n"), nil
-	case "remove":
-		if strings.TrimSpace(jobID) == "" {
-			return "", errors.New("job_id is required")
+	case "update":
+		sched, changeSchedule, err := r.cronSchedule(a)
+		if err != nil {
+			return "", err
 		}
-		if r.Cron.Remove(strings.TrimSpace(jobID)) {
-			return "Removed job " + strings.TrimSpace(jobID), nil
+		if message == "" && command == "" && webhookURL == "" && !changeSchedule {
+			return "", errors.New("nothing to update: give message, command, url, every_seconds, cron_expr or at")
 		}
-		return "Job not found: " + strings.TrimSpace(jobID), nil
+		j, err := r.Cron.Update(jobID, func(j *cron.Job) error {
+			if message != "" {
+				j.Name = shortName(message)
+				j.Payload.Message = message
+			}
+			if command != "" {
+				j.Payload.Command = command
+			}
+			if webhookURL != "" {
+				j.Payload.URL = webhookURL
+			}
+			if changeSchedule {
+				j.Schedule = sched
+				j.DeleteAfterRun = sched.Kind == "at"
+			}
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+		if !j.Enabled {
+			return fmt.Sprintf("Updated job '%s' (id: %s, paused)", j.Name, j.ID), nil
+		}
+		return fmt.Sprintf("Updated job '%s' (id: %s, next run %s)", j.Name, j.ID, r.formatNextRun(j)), nil
+	case "pause", "resume":
+		if !r.Cron.Toggle(jobID, action == "pause") {
+			return "Job not found: " + jobID, nil
+		}
+		if action == "pause" {
+			return "Paused job " + jobID, nil
+		}
+		j, _ := r.Cron.Get(jobID)
+		return fmt.Sprintf("Resumed job %s (next run %s)", jobID, r.formatNextRun(j)), nil
+	case "run":
+		if _, ok := r.Cron.Get(jobID); !ok {
+			return "Job not found: " + jobID, nil
+		}
+		// The run may be answered in this very session, so it cannot be
+		// waited for here.
+		go func() {
+			_, _ = r.Cron.RunNow(context.WithoutCancel(ctx), jobID, true)
+		}()
+		return "Started job " + jobID + "; its result will be delivered as usual.", nil
+	case "remove":
+		if r.Cron.Remove(jobID) {
+			return "Removed job " + jobID, nil
+		}
+		return "Job not found: " + jobID, nil
 	default:
 		return "", fmt.Errorf("unknown action: %s", action)
 	}
 }
 
+// cronSchedule builds the schedule given by every_seconds, cron_expr or at,
+// reporting false if none was given. Times are in tz, or the default zone.
+func (r *Registry) cronSchedule(a cronArgs) (cron.Schedule, bool, error) {
+	tz := strings.TrimSpace(a.TZ)
+	if tz == "" {
+		tz = r.CronTimezone
+	}
+	cronExpr, at := strings.TrimSpace(a.CronExpr), strings.TrimSpace(a.At)
+	n := 0
+	for _, set := range []bool{a.EverySeconds > 0, cronExpr != "", at != ""} {
+		if set {
+			n++
+		}
+	}
+	switch {
+	case n > 1:
+		return cron.Schedule{}, false, errors.New("give only one of every_seconds, cron_expr or at")
+	case a.EverySeconds > 0:
+		return cron.Schedule{Kind: "every", EveryMS: int64(a.EverySeconds) * 1000}, true, nil
+	case cronExpr != "":
+		return cron.Schedule{Kind: "cron", Expr: cronExpr, TZ: tz}, true, nil
+	case at != "":
+		loc, err := cron.LoadLocation(tz)
+		if err != nil {
+			return cron.Schedule{}, false, err
+		}
+		t, err := cron.ParseTime(at, loc)
+		if err != nil {
+			return cron.Schedule{}, false, err
+		}
+		return cron.Schedule{Kind: "at", AtMS: t.UnixMilli()}, true, nil
+	default:
+		return cron.Schedule{}, false, nil
+	}
+}
+
+func describeSchedule(s cron.Schedule) string {
+	switch s.Kind {
+	case "every":
+		return fmt.Sprintf("every %ds", s.EveryMS/1000)
+	case "cron":
+		if s.TZ != "" {
+			return fmt.Sprintf("cron %q %s", s.Expr, s.TZ)
+		}
+		return fmt.Sprintf("cron %q", s.Expr)
+	case "at":
+		return "once"
+	default:
+		return s.Kind
+	}
+}
+
+// formatNextRun shows the next run in the user's timezone.
+func (r *Registry) formatNextRun(j cron.Job) string {
+	if j.State.NextRunAtMS <= 0 {
+		return "none"
+	}
+	return time.UnixMilli(j.State.NextRunAtMS).In(r.cronLocation()).Format("2006-01-02 15:04 MST (Mon)")
+}
+
+// cronLocation is the zone times are shown in: the configured default
+// timezone, else the server's.
+func (r *Registry) cronLocation() *time.Location {
+	loc, err := cron.LoadLocation(r.CronTimezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// cronListRuns is how many recent runs the list action shows per job.
+const cronListRuns = 3
+
+func (r *Registry) writeRecentRuns(b *strings.Builder, j cron.Job, n int) {
+	runs := j.State.History
+	if len(runs) > n {
+		runs = runs[len(runs)-n:]
+	}
+	loc := r.cronLocation()
+	for i := len(runs) - 1; i >= 0; i-- {
+		run := runs[i]
+		fmt.Fprintf(b, "  - run %s: %s (%s, %s", time.UnixMilli(run.StartedAtMS).In(loc).Format("2006-01-02 15:04"), run.Status, run.Trigger, run.Duration().Round(time.Second))
+		if run.Attempt > 1 {
+			fmt.Fprintf(b, ", attempt %d", run.Attempt)
+		}
+		b.WriteString(")")
+		detail := run.Output
+		if run.Error != "" {
+			detail = run.Error
+		}
+		if detail = strings.Join(strings.Fields(detail), " "); detail != "" {
+			if len(detail) > 120 {
+				detail = strings.ToValidUTF8(detail[:120], "") + "…"
+			}
+			b.WriteString(": " + detail)
+		}
+		b.WriteString("\n")
+	}
+}
+
 func shortName(s string) string {
 	s = strings.TrimSpace(s)
 	if len(s) <= 30 {
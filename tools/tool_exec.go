@@ -39,6 +39,32 @@ func (r *Registry) exec(ctx context.Context, command string) (string, error) {
 	if msg := guardExecCommand(command, r.WorkspaceDir, r.RestrictToWorkspace); msg != "" {
 		return msg, nil
 	}
+	res, _, _ := r.runCommand(ctx, command)
+	return res, nil
+}
+
+// Exec runs command like the exec tool, under the same guard policy. A
+// blocked command, a non-zero exit status or a timeout is an error.
+func (r *Registry) Exec(ctx context.Context, command string) (string, error) {
+	if strings.TrimSpace(command) == "" {
+		return "", errors.New("command is empty")
+	}
+	if msg := guardExecCommand(command, r.WorkspaceDir, r.RestrictToWorkspace); msg != "" {
+		return "", errors.New(strings.TrimPrefix(msg, "Error: "))
+	}
+	res, exit, timedOut := r.runCommand(ctx, command)
+	switch {
+	case timedOut:
+		return res, errors.New("command timed out")
+	case exit != 0:
+		return res, fmt.Errorf("command exited with status %d", exit)
+	}
+	return res, nil
+}
+
+// runCommand runs command in the workspace and returns its formatted result
+// and exit status.
+func (r *Registry) runCommand(ctx context.Context, command string) (string, int, bool) {
 	timeout := r.ExecTimeout
 	if timeout <= 0 {
 		timeout = 60 * time.Second
@@ -76,8 +102,8 @@ func (r *Registry) exec(ctx context.Context, command string) (string, error) {
 	}
 	if err != nil && cctx.Err() == context.DeadlineExceeded {
 		res += "error: timeout\n"
-		return res, nil
+		return res, exit, true
 	}
 	// Return output even if non-zero; the model can decide next step.
-	return strings.TrimRight(res, "\n"), nil
+	return strings.TrimRight(res, "\n"), exit, false
 }
@@ -44,3 +44,22 @@ func TestExec_PreservesSafeEnvironmentVariables(t *testing.T) {
 		t.Fatalf("expected non-empty PATH in output, got: %q", out)
 	}
 }
+
+func TestRegistryExec_ReturnsErrorOnFailure(t *testing.T) {
+	r := &Registry{
+		WorkspaceDir:        t.TempDir(),
+		RestrictToWorkspace: true,
+		ExecTimeout:         5 * time.Second,
+	}
+
+	out, err := r.Exec(context.Background(), "echo hello")
+	if err != nil || !strings.Contains(out, "hello") {
+		t.Fatalf("out=%q err=%v", out, err)
+	}
+	if _, err := r.Exec(context.Background(), "exit 3"); err == nil || !strings.Contains(err.Error(), "status 3") {
+		t.Fatalf("exit 3: err=%v", err)
+	}
+	if _, err := r.Exec(context.Background(), "rm -rf /"); err == nil {
+		t.Fatal("guarded command ran")
+	}
+}
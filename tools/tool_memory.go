@@ -3,12 +3,14 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"strings"
 
 	"github.com/mosaxiv/clawlet/memory"
 )
 
-func (r *Registry) memorySearch(ctx context.Context, query string, maxResults *int, minScore *float64) (string, error) {
+func (r *Registry) memorySearch(ctx context.Context, tctx Context, query string, maxResults *int, minScore *float64) (string, error) {
 	query = strings.TrimSpace(query)
 	if query == "" {
 		return `{"results":[]}`, nil
@@ -16,7 +18,7 @@ func (r *Registry) memorySearch(ctx context.Context, query string, maxResults *i
 	if r.MemorySearch == nil {
 		return `{"results":[],"disabled":true,"error":"memory search disabled"}`, nil
 	}
-	opts := memory.SearchOptions{}
+	opts := memory.SearchOptions{Scopes: r.memoryView(tctx).SearchScopes()}
 	if maxResults != nil {
 		opts.MaxResults = *maxResults
 	}
@@ -39,11 +41,11 @@ func (r *Registry) memorySearch(ctx context.Context, query string, maxResults *i
 	})
 }
 
-func (r *Registry) memoryGet(path string, from *int, lines *int) (string, error) {
+func (r *Registry) memoryGet(tctx Context, path string, from *int, lines *int) (string, error) {
 	if r.MemorySearch == nil {
 		return `{"path":"","text":"","disabled":true,"error":"memory search disabled"}`, nil
 	}
-	opts := memory.ReadFileOptions{}
+	opts := memory.ReadFileOptions{Scopes: r.memoryView(tctx).SearchScopes()}
 	if from != nil {
 		opts.From = *from
 	}
@@ -65,6 +67,44 @@ func (r *Registry) memoryGet(path string, from *int, lines *int) (string, error)
 	})
 }
 
+func (r *Registry) memoryWrite(tctx Context, id, text string, tags []string, scope string) (string, error) {
+	if strings.TrimSpace(text) == "" {
+		return "", errors.New("text is empty")
+	}
+	diff, err := r.memoryView(tctx).ApplyFacts([]memory.FactChange{{ID: id, Text: text, Tags: tags, Scope: scope}}, tctx.SessionKey)
+	if err != nil {
+		return "", err
+	}
+	if len(diff.Unknown) > 0 {
+		return "", fmt.Errorf("unknown memory id: %s", diff.Unknown[0])
+	}
+	if len(diff.Added) > 0 {
+		return jsonResult(map[string]any{"fact": diff.Added[0], "created": true})
+	}
+	return jsonResult(map[string]any{"fact": diff.Updated[0], "created": false})
+}
+
+func (r *Registry) memoryForget(tctx Context, id string) (string, error) {
+	if strings.TrimSpace(id) == "" {
+		return "", errors.New("id is empty")
+	}
+	diff, err := r.memoryView(tctx).ApplyFacts([]memory.FactChange{{ID: id, Forget: true}}, tctx.SessionKey)
+	if err != nil {
+		return "", err
+	}
+	if len(diff.Forgotten) == 0 {
+		return "", fmt.Errorf("unknown memory id: %s", id)
+	}
+	return jsonResult(map[string]any{"forgotten": diff.Forgotten[0]})
+}
+
+func (r *Registry) memoryView(tctx Context) memory.View {
+	if tctx.Memory != nil {
+		return *tctx.Memory
+	}
+	return memory.GlobalView(r.WorkspaceDir)
+}
+
 func jsonResult(v any) (string, error) {
 	b, err := json.Marshal(v)
 	if err != nil {
@@ -0,0 +1,68 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/memory"
+)
+
+func TestRegistryMemoryWriteAndForget(t *testing.T) {
+	ws := t.TempDir()
+	r := &Registry{WorkspaceDir: ws}
+	tctx := Context{Channel: "telegram", ChatID: "1", SessionKey: "telegram:1"}
+
+	out, err := r.Execute(context.Background(), tctx, "memory_write", json.RawMessage(`{"text":"Prefers tea over coffee","tags":["preferences"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res struct {
+		Fact    memory.Fact `json:"fact"`
+		Created bool        `json:"created"`
+	}
+	if err := json.Unmarshal([]byte(out), &res); err != nil || !res.Created || res.Fact.ID != "f1" || res.Fact.Session != "telegram:1" {
+		t.Fatalf("out=%s err=%v", out, err)
+	}
+	if _, err := r.Execute(context.Background(), tctx, "memory_write", json.RawMessage(`{"id":"f1","text":"Prefers green tea over coffee"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if md := memory.New(ws).ReadLongTerm(); !strings.Contains(md, "- [f1] Prefers green tea over coffee") {
+		t.Fatalf("MEMORY.md=%s", md)
+	}
+
+	if _, err := r.Execute(context.Background(), tctx, "memory_forget", json.RawMessage(`{"id":"f1"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Execute(context.Background(), tctx, "memory_forget", json.RawMessage(`{"id":"f1"}`)); err == nil || !strings.Contains(err.Error(), "unknown memory id") {
+		t.Fatalf("second forget err=%v", err)
+	}
+	if _, err := r.Execute(context.Background(), tctx, "memory_write", json.RawMessage(`{"text":"  "}`)); err == nil {
+		t.Fatal("empty text accepted")
+	}
+}
+
+func TestRegistryMemoryWrite_Scoped(t *testing.T) {
+	ws := t.TempDir()
+	r := &Registry{WorkspaceDir: ws}
+	enabled := true
+	view := memory.ViewFor(config.MemoryScopesConfig{Enabled: &enabled}, ws, "telegram", "telegram:7", "telegram:7")
+	tctx := Context{Channel: "telegram", ChatID: "7", SessionKey: "telegram:7", Memory: &view}
+
+	out, err := r.Execute(context.Background(), tctx, "memory_write", json.RawMessage(`{"text":"Works night shifts"}`))
+	if err != nil || !strings.Contains(out, `"id":"u1"`) {
+		t.Fatalf("out=%s err=%v", out, err)
+	}
+	out, err = r.Execute(context.Background(), tctx, "memory_write", json.RawMessage(`{"text":"Office closes at 6pm","scope":"global"}`))
+	if err != nil || !strings.Contains(out, `"id":"f1"`) {
+		t.Fatalf("out=%s err=%v", out, err)
+	}
+	if md := memory.New(ws).ReadLongTerm(); strings.Contains(md, "night shifts") {
+		t.Fatalf("global MEMORY.md=%s", md)
+	}
+	if _, err := r.Execute(context.Background(), Context{}, "memory_forget", json.RawMessage(`{"id":"u1"}`)); err == nil {
+		t.Fatal("forgot a user fact without its scope")
+	}
+}
@@ -26,3 +26,18 @@ func (r *Registry) message(ctx context.Context, channel, chatID, content string)
 	}
 	return fmt.Sprintf("Message sent to %s:%s", channel, chatID), nil
 }
+
+func (r *Registry) messageTool(ctx context.Context, tctx Context, channel, chatID, content string) (string, error) {
+	ch := strings.TrimSpace(channel)
+	cid := strings.TrimSpace(chatID)
+	if ch == "" || cid == "" {
+		return "", errors.New("message requires explicit channel and chat_id")
+	}
+	// Avoid duplicate sends to the active conversation; reply with normal assistant text instead.
+	if strings.TrimSpace(tctx.Channel) != "" && strings.TrimSpace(tctx.ChatID) != "" {
+		if ch == strings.TrimSpace(tctx.Channel) && cid == strings.TrimSpace(tctx.ChatID) {
+			return "", errors.New("message to current session is not allowed; respond with assistant text instead")
+		}
+	}
+	return r.message(ctx, ch, cid, content)
+}
@@ -0,0 +1,107 @@
+package tools
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/mosaxiv/clawlet/cron"
+)
+
+func TestCronTool_TimezoneDefaultsToRegistry(t *testing.T) {
+	svc := cron.NewService(filepath.Join(t.TempDir(), "jobs.json"), nil)
+	r := &Registry{Cron: svc, CronTimezone: "UTC"}
+	tctx := Context{Channel: "telegram", ChatID: "1"}
+
+	if _, err := r.cronTool(t.Context(), tctx, cronArgs{Action: "add", Message: "a", CronExpr: "0 8 * * 1-5"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.cronTool(t.Context(), tctx, cronArgs{Action: "add", Message: "b", CronExpr: "0 8 * * 1-5", TZ: "Asia/Tokyo"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.cronTool(t.Context(), tctx, cronArgs{Action: "add", Message: "c", CronExpr: "0 8 * * *", TZ: "Mars/Olympus"}); err == nil || !strings.Contains(err.Error(), "timezone") {
+		t.Fatalf("err=%v", err)
+	}
+
+	tzs := map[string]string{}
+	for _, j := range svc.List(true) {
+		tzs[j.Name] = j.Schedule.TZ
+	}
+	if tzs["a"] != "UTC" || tzs["b"] != "Asia/Tokyo" || len(tzs) != 2 {
+		t.Fatalf("tzs=%v", tzs)
+	}
+}
+
+func TestCronTool_ListShowsRecentRuns(t *testing.T) {
+	svc := cron.NewService(filepath.Join(t.TempDir(), "jobs.json"), func(ctx context.Context, job cron.Job) (string, error) {
+		return "Inbox summary:\n3 new mails", nil
+	})
+	r := &Registry{Cron: svc}
+	j, err := svc.Add("inbox", cron.Schedule{Kind: "every", EveryMS: 3_600_000}, cron.Payload{Kind: "agent_turn", Message: "summarize"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.RunNow(t.Context(), j.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.cronTool(t.Context(), Context{}, cronArgs{Action: "list"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "ok (manual") || !strings.Contains(out, "Inbox summary: 3 new mails") {
+		t.Fatalf("out=%q", out)
+	}
+}
+
+func TestCronTool_OneShotUpdatePauseResume(t *testing.T) {
+	svc := cron.NewService(filepath.Join(t.TempDir(), "jobs.json"), nil)
+	r := &Registry{Cron: svc, CronTimezone: "Asia/Tokyo"}
+	tctx := Context{Channel: "telegram", ChatID: "1"}
+
+	at := time.Now().In(mustLocation(t, "Asia/Tokyo")).Add(24 * time.Hour).Format("2006-01-02T15:04")
+	if _, err := r.cronTool(t.Context(), tctx, cronArgs{Action: "add", Message: "call the dentist", At: at}); err != nil {
+		t.Fatal(err)
+	}
+	jobs := svc.List(true)
+	if len(jobs) != 1 || jobs[0].Schedule.Kind != "at" || !jobs[0].DeleteAfterRun {
+		t.Fatalf("jobs=%+v", jobs)
+	}
+	id := jobs[0].ID
+	if got := time.UnixMilli(jobs[0].Schedule.AtMS).In(mustLocation(t, "Asia/Tokyo")).Format("2006-01-02T15:04"); got != at {
+		t.Fatalf("at=%s, want %s", got, at)
+	}
+
+	out, err := r.cronTool(t.Context(), tctx, cronArgs{Action: "update", JobID: id, Message: "call the vet", CronExpr: "30 17 * * 5L"})
+	if err != nil || !strings.Contains(out, "JST") {
+		t.Fatalf("update: %q %v", out, err)
+	}
+	j, _ := svc.Get(id)
+	if j.Payload.Message != "call the vet" || j.Schedule.Kind != "cron" || j.Schedule.TZ != "Asia/Tokyo" || j.DeleteAfterRun {
+		t.Fatalf("after update=%+v", j)
+	}
+
+	if out, _ := r.cronTool(t.Context(), tctx, cronArgs{Action: "pause", JobID: id}); !strings.HasPrefix(out, "Paused") {
+		t.Fatalf("pause: %q", out)
+	}
+	if out, _ := r.cronTool(t.Context(), tctx, cronArgs{Action: "list"}); !strings.Contains(out, "paused") {
+		t.Fatalf("list: %q", out)
+	}
+	if out, _ := r.cronTool(t.Context(), tctx, cronArgs{Action: "resume", JobID: id}); !strings.Contains(out, "next run") {
+		t.Fatalf("resume: %q", out)
+	}
+	if _, err := r.cronTool(t.Context(), tctx, cronArgs{Action: "update", JobID: id, EverySeconds: 60, CronExpr: "* * * * *"}); err == nil {
+		t.Fatal("expected error for two schedules")
+	}
+}
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := cron.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
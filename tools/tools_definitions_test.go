@@ -3,9 +3,14 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/mosaxiv/clawlet/config"
+	"github.com/mosaxiv/clawlet/mcp"
 	"github.com/mosaxiv/clawlet/memory"
 )
 
@@ -48,7 +53,7 @@ func TestRegistryDefinitions_GatedByCapabilities(t *testing.T) {
 	}
 
 	// Always present.
-	for _, n := range []string{"read_file", "write_file", "edit_file", "list_dir", "exec", "web_fetch"} {
+	for _, n := range []string{"read_file", "write_file", "edit_file", "list_dir", "exec", "web_fetch", "memory_write", "memory_forget"} {
 		if !has[n] {
 			t.Fatalf("expected tool definition: %s", n)
 		}
@@ -85,3 +90,56 @@ func TestRegistryDefinitions_IncludesMemoryToolsWhenEnabled(t *testing.T) {
 		}
 	}
 }
+
+func newStubMCPServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&msg)
+		if len(msg.ID) == 0 {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		result := `{}`
+		switch msg.Method {
+		case "tools/list":
+			result = `{"tools":[{"name":"create_issue"},{"name":"delete_repo"}]}`
+		case "tools/call":
+			result = `{"content":[{"type":"text","text":"created"}]}`
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":%s}`, msg.ID, result)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRegistry_MCPToolsRespectAllowTools(t *testing.T) {
+	srv := newStubMCPServer(t)
+	m := mcp.NewManager(map[string]config.MCPServerConfig{"github": {URL: srv.URL}})
+	defer m.Close()
+	r := &Registry{
+		WorkspaceDir: "/tmp",
+		AllowTools:   []string{"read_file", "mcp_github__create_issue"},
+		MCP:          m,
+	}
+
+	has := map[string]bool{}
+	for _, d := range r.Definitions() {
+		has[d.Function.Name] = true
+	}
+	if !has["mcp_github__create_issue"] || has["mcp_github__delete_repo"] {
+		t.Fatalf("unexpected definitions: %v", has)
+	}
+
+	out, err := r.Execute(context.Background(), Context{}, "mcp_github__create_issue", json.RawMessage(`{}`))
+	if err != nil || out != "created" {
+		t.Fatalf("out=%q err=%v", out, err)
+	}
+	if _, err := r.Execute(context.Background(), Context{}, "mcp_github__delete_repo", json.RawMessage(`{}`)); err == nil {
+		t.Fatal("expected disallowed mcp tool to fail")
+	}
+}
@@ -0,0 +1,132 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/mosaxiv/clawlet/llm"
+)
+
+func defGreet() llm.ToolDefinition {
+	return llm.ToolDefinition{
+		Type: "function",
+		Function: llm.FunctionDefinition{
+			Name: "greet",
+			Parameters: llm.JSONSchema{
+				Type:       "object",
+				Properties: map[string]llm.JSONSchema{"name": {Type: "string"}},
+			},
+		},
+	}
+}
+
+type greetArgs struct {
+	Name string `json:"name"`
+}
+
+func greetTool() Tool {
+	return NewTool(defGreet(), func(ctx context.Context, tctx Context, a greetArgs) (string, error) {
+		return "hello " + a.Name + " from " + tctx.Channel, nil
+	})
+}
+
+func TestRegistry_RegisterAndExecuteCustomTool(t *testing.T) {
+	var calls []CallInfo
+	r := &Registry{WorkspaceDir: "/tmp", OnCall: func(ci CallInfo) { calls = append(calls, ci) }}
+	if err := r.Register(greetTool()); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	found := false
+	for _, d := range r.Definitions() {
+		if d.Function.Name == "greet" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("expected registered tool in definitions")
+	}
+
+	out, err := r.Execute(context.Background(), Context{Channel: "cli"}, "greet", json.RawMessage(`{"name":"ana"}`))
+	if err != nil || out != "hello ana from cli" {
+		t.Fatalf("out=%q err=%v", out, err)
+	}
+	if len(calls) != 1 || calls[0].Name != "greet" || calls[0].OutputBytes != len(out) || calls[0].Err != nil {
+		t.Fatalf("calls=%+v", calls)
+	}
+}
+
+func TestRegistry_RegisterRejectsConflicts(t *testing.T) {
+	r := &Registry{WorkspaceDir: "/tmp"}
+	if err := r.Register(NewTool(defReadFile(), func(ctx context.Context, tctx Context, a struct{}) (string, error) {
+		return "", nil
+	})); err == nil {
+		t.Fatal("expected conflict with built-in tool")
+	}
+	// Built-ins are reserved even when their capability is not configured.
+	if err := r.Register(NewTool(defCron(), func(ctx context.Context, tctx Context, a struct{}) (string, error) {
+		return "", nil
+	})); err == nil {
+		t.Fatal("expected conflict with built-in cron tool")
+	}
+	if err := r.Register(greetTool()); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if err := r.Register(greetTool()); err == nil {
+		t.Fatal("expected duplicate registration to fail")
+	}
+}
+
+func TestRegistry_ExecuteReportsArgsError(t *testing.T) {
+	r := &Registry{WorkspaceDir: "/tmp"}
+	if err := r.Register(greetTool()); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	_, err := r.Execute(context.Background(), Context{}, "greet", json.RawMessage(`{"name":42}`))
+	var ae *ArgsError
+	if !errors.As(err, &ae) || ae.Tool != "greet" {
+		t.Fatalf("err=%v", err)
+	}
+	// Missing arguments decode as an empty object.
+	if _, err := r.Execute(context.Background(), Context{}, "greet", nil); err != nil {
+		t.Fatalf("empty args: %v", err)
+	}
+}
+
+func TestRegistry_AllowToolsAppliesToCustomTools(t *testing.T) {
+	r := &Registry{WorkspaceDir: "/tmp", AllowTools: []string{"read_file"}}
+	if err := r.Register(greetTool()); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	for _, d := range r.Definitions() {
+		if d.Function.Name == "greet" {
+			t.Fatal("did not expect disallowed custom tool in definitions")
+		}
+	}
+	if _, err := r.Execute(context.Background(), Context{}, "greet", json.RawMessage(`{}`)); err == nil {
+		t.Fatal("expected disallowed custom tool to fail")
+	}
+}
+
+func TestRegistry_SerialKeys(t *testing.T) {
+	r := &Registry{WorkspaceDir: "/tmp/ws"}
+	a := r.SerialKey("write_file", json.RawMessage(`{"path":"notes.md","content":"x"}`))
+	b := r.SerialKey("edit_file", json.RawMessage(`{"path":"./notes.md"}`))
+	if a == "" || a != b {
+		t.Fatalf("expected same key for same file: %q %q", a, b)
+	}
+	if c := r.SerialKey("write_file", json.RawMessage(`{"path":"other.md"}`)); c == a {
+		t.Fatalf("expected different key for different file: %q", c)
+	}
+	if k := r.SerialKey("exec", json.RawMessage(`{"command":"ls"}`)); k != SerialAll {
+		t.Fatalf("expected exec to serialize the round, got %q", k)
+	}
+	if k := r.SerialKey("read_file", json.RawMessage(`{"path":"notes.md"}`)); k != a {
+		t.Fatalf("expected read_file to serialize with writes to the file, got %q", k)
+	}
+	if k := r.SerialKey("list_dir", json.RawMessage(`{"path":"."}`)); k != "" {
+		t.Fatalf("expected list_dir to run in parallel, got %q", k)
+	}
+}